@@ -0,0 +1,350 @@
+// Package archive writes and reads account archives, ZIP files that hold
+// everything a user keeps in trip-tracker so the account can be moved to
+// another instance. manifest.json describes the archive and lists every other
+// file with its SHA-256 checksum. Records keep the ids they had on the
+// instance that exported them, references between records (journey members,
+// itinerary legs, attachments) use those ids and are remapped on import.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+const (
+	// Format identifies trip-tracker account archives in the manifest
+	Format = "trip-tracker-account"
+	// Version is the archive layout written by Write. Read accepts archives
+	// up to this version; readers of older versions reject newer archives.
+	Version = 1
+
+	ManifestName    = "manifest.json"
+	profileName     = "profile.json"
+	tripsName       = "trips.json"
+	placesName      = "places.json"
+	journeysName    = "journeys.json"
+	itinerariesName = "itineraries.json"
+	sessionsName    = "sessions.json"
+	attachmentsDir  = "attachments/"
+
+	// maxFileSize limits every file in an archive once decompressed, so a
+	// small upload can not expand into gigabytes
+	maxFileSize = 64 << 20
+	// maxArchiveSize limits all files together, a manifest may list the same
+	// file many times
+	maxArchiveSize = 512 << 20
+)
+
+var (
+	ErrNotArchive         = errors.New("not a trip-tracker account archive")
+	ErrUnsupportedVersion = errors.New("the archive was made by a newer version of trip-tracker")
+)
+
+// Manifest is manifest.json
+type Manifest struct {
+	Format      string       `json:"format"`
+	Version     int          `json:"version"`
+	ExportedAt  time.Time    `json:"exported_at"`
+	Files       []File       `json:"files"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// File is a file of the archive other than the manifest. Records is the
+// number of entries of JSON files holding a list.
+type File struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Records int    `json:"records,omitempty"`
+}
+
+// Account is the content of an archive
+type Account struct {
+	Profile     Profile
+	Trips       []m.Trip
+	Places      []m.Place
+	Journeys    []Journey
+	Itineraries []Itinerary
+	Sessions    []Session
+	Attachments []Attachment
+}
+
+// Profile is the user's account. Passwords and linked Google accounts are not
+// exported, they only work on the instance they were set up on.
+type Profile struct {
+	Username          string `json:"username"`
+	Email             string `json:"email"`
+	FirstName         string `json:"first_name"`
+	LastName          string `json:"last_name"`
+	MaxLayoverMinutes int    `json:"max_layover_minutes"`
+}
+
+// Journey lists its members by the ids of the exported trips and places
+type Journey struct {
+	ID            int     `json:"id"`
+	Title         string  `json:"title"`
+	StartDate     uint32  `json:"start_date"`
+	EndDate       uint32  `json:"end_date"`
+	CoverLocation *string `json:"cover_location,omitempty"`
+	TripIDs       []int   `json:"trip_ids"`
+	PlaceIDs      []int   `json:"place_ids"`
+}
+
+// Itinerary is a pinned itinerary, its legs are the ids of exported trips
+type Itinerary struct {
+	ID      int   `json:"id"`
+	TripIDs []int `json:"trip_ids"`
+}
+
+// Session describes a sign-in. Session tokens are never exported.
+type Session struct {
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"` // The session the archive was exported with
+}
+
+// Attachment is a file kept with a trip or place, stored in the archive
+// under Path. Data is only set on attachments read from or written to an
+// archive.
+type Attachment struct {
+	ID          int    `json:"id"`
+	EntityType  string `json:"entity_type"` // models.EntityTrip or models.EntityPlace
+	EntityID    int    `json:"entity_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	Data        []byte `json:"-"`
+}
+
+// Write writes the account as a version Version archive
+func Write(w io.Writer, account Account, now time.Time) error {
+	z := zip.NewWriter(w)
+	manifest := Manifest{
+		Format:      Format,
+		Version:     Version,
+		ExportedAt:  now.UTC().Truncate(time.Second),
+		Files:       []File{},
+		Attachments: []Attachment{},
+	}
+
+	lists := []struct {
+		name    string
+		value   any
+		records int
+	}{
+		{tripsName, nonNil(account.Trips), len(account.Trips)},
+		{placesName, nonNil(account.Places), len(account.Places)},
+		{journeysName, nonNil(account.Journeys), len(account.Journeys)},
+		{itinerariesName, nonNil(account.Itineraries), len(account.Itineraries)},
+		{sessionsName, nonNil(account.Sessions), len(account.Sessions)},
+	}
+	profile, err := json.MarshalIndent(account.Profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := writeFile(z, profileName, profile, now)
+	if err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, file)
+	for _, list := range lists {
+		data, err := json.MarshalIndent(list.value, "", "  ")
+		if err != nil {
+			return err
+		}
+		file, err := writeFile(z, list.name, data, now)
+		if err != nil {
+			return err
+		}
+		file.Records = list.records
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	for _, attachment := range account.Attachments {
+		attachment.Path = fmt.Sprintf("%s%d/%s", attachmentsDir, attachment.ID, safeName(attachment.Name))
+		file, err := writeFile(z, attachment.Path, attachment.Data, now)
+		if err != nil {
+			return err
+		}
+		attachment.Size, attachment.SHA256 = file.Size, file.SHA256
+		manifest.Attachments = append(manifest.Attachments, attachment)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := writeFile(z, ManifestName, data, now); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// Read reads and verifies an archive. Every file listed in the manifest must
+// be present with the listed checksum.
+func Read(r io.ReaderAt, size int64) (Manifest, Account, error) {
+	return read(r, size, maxArchiveSize)
+}
+
+// read is Read with the budget for all files, tests pass a smaller one
+func read(r io.ReaderAt, size int64, budget int64) (Manifest, Account, error) {
+	var manifest Manifest
+	var account Account
+
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return manifest, account, ErrNotArchive
+	}
+	files := &zipFiles{files: make(map[string]*zip.File, len(z.File)), remaining: budget}
+	for _, f := range z.File {
+		files.files[f.Name] = f
+	}
+
+	data, err := files.read(ManifestName)
+	if err != nil {
+		return manifest, account, ErrNotArchive
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Format != Format {
+		return manifest, account, ErrNotArchive
+	}
+	if manifest.Version < 1 || manifest.Version > Version {
+		return manifest, account, fmt.Errorf("%w (version %d, this instance reads up to %d)",
+			ErrUnsupportedVersion, manifest.Version, Version)
+	}
+
+	contents := make(map[string][]byte, len(manifest.Files))
+	for _, file := range manifest.Files {
+		data, err := files.readVerified(file.Name, file.SHA256)
+		if err != nil {
+			return manifest, account, err
+		}
+		contents[file.Name] = data
+	}
+	targets := []struct {
+		name  string
+		value any
+	}{
+		{profileName, &account.Profile},
+		{tripsName, &account.Trips},
+		{placesName, &account.Places},
+		{journeysName, &account.Journeys},
+		{itinerariesName, &account.Itineraries},
+		{sessionsName, &account.Sessions},
+	}
+	for _, target := range targets {
+		data, ok := contents[target.name]
+		if !ok {
+			return manifest, account, fmt.Errorf("%s is missing from the manifest", target.name)
+		}
+		if err := json.Unmarshal(data, target.value); err != nil {
+			return manifest, account, fmt.Errorf("reading %s: %w", target.name, err)
+		}
+	}
+
+	for _, attachment := range manifest.Attachments {
+		if !strings.HasPrefix(attachment.Path, attachmentsDir) || path.Clean(attachment.Path) != attachment.Path {
+			return manifest, account, fmt.Errorf("attachment %d is outside %s", attachment.ID, attachmentsDir)
+		}
+		attachment.Data, err = files.readVerified(attachment.Path, attachment.SHA256)
+		if err != nil {
+			return manifest, account, err
+		}
+		account.Attachments = append(account.Attachments, attachment)
+	}
+	sort.Slice(account.Attachments, func(i, j int) bool {
+		return account.Attachments[i].ID < account.Attachments[j].ID
+	})
+
+	return manifest, account, nil
+}
+
+func writeFile(z *zip.Writer, name string, data []byte, now time.Time) (File, error) {
+	w, err := z.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: now.UTC(),
+	})
+	if err != nil {
+		return File{}, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return File{}, err
+	}
+	sum := sha256.Sum256(data)
+	return File{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// zipFiles are the files of an archive being read. Together they may not
+// decompress to more than remaining bytes.
+type zipFiles struct {
+	files     map[string]*zip.File
+	remaining int64
+}
+
+func (z *zipFiles) read(name string) ([]byte, error) {
+	f, ok := z.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	var b bytes.Buffer
+	n, err := io.Copy(&b, io.LimitReader(rc, min(maxFileSize, z.remaining)+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	if n > maxFileSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", name, maxFileSize>>20)
+	}
+	if n > z.remaining {
+		return nil, fmt.Errorf("the archive is larger than %d MB once decompressed", maxArchiveSize>>20)
+	}
+	z.remaining -= n
+	return b.Bytes(), nil
+}
+
+func (z *zipFiles) readVerified(name, checksum string) ([]byte, error) {
+	data, err := z.read(name)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), checksum) {
+		return nil, fmt.Errorf("%s does not match its checksum, the archive is damaged", name)
+	}
+	return data, nil
+}
+
+// safeName keeps attachment file names inside their directory
+func safeName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// nonNil makes empty lists encode as [] instead of null
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
@@ -0,0 +1,231 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+var exportedAt = time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+
+// testArchive is an account with a trip, a place and an attachment
+func testArchive(t *testing.T) []byte {
+	t.Helper()
+	account := Account{
+		Profile: Profile{Username: "owner", Email: "owner@example.com"},
+		Trips:   []m.Trip{{ID: 7, Departure: "JFK", Arrival: "NRT", DepartureTime: 1743501600, ArrivalTime: 1743552000}},
+		Places:  []m.Place{{ID: 3, Name: "Temple", VisitDate: 1743600000}},
+		Attachments: []Attachment{
+			{ID: 5, EntityType: m.EntityTrip, EntityID: 7, Name: "../boarding pass.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4")},
+		},
+	}
+	var b bytes.Buffer
+	if err := Write(&b, account, exportedAt); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// rewrite copies an archive, passing every file through edit
+func rewrite(t *testing.T, data []byte, edit func(name string, content []byte) []byte) []byte {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, f := range z.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw, err := w.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(edit(f.Name, content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// editManifest rewrites an archive with a changed manifest
+func editManifest(t *testing.T, data []byte, edit func(manifest *Manifest)) []byte {
+	t.Helper()
+	return rewrite(t, data, func(name string, content []byte) []byte {
+		if name != ManifestName {
+			return content
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			t.Fatal(err)
+		}
+		edit(&manifest)
+		content, err := json.Marshal(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return content
+	})
+}
+
+func readArchive(data []byte) (Manifest, Account, error) {
+	return Read(bytes.NewReader(data), int64(len(data)))
+}
+
+func TestRoundTrip(t *testing.T) {
+	manifest, account, err := readArchive(testArchive(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Version != Version || !manifest.ExportedAt.Equal(exportedAt) || len(manifest.Files) != 6 {
+		t.Errorf("manifest %+v", manifest)
+	}
+	if account.Profile.Username != "owner" || len(account.Trips) != 1 || account.Trips[0].ID != 7 || len(account.Places) != 1 || account.Journeys == nil {
+		t.Errorf("account %+v", account)
+	}
+	if len(account.Attachments) != 1 {
+		t.Fatalf("attachments %+v", account.Attachments)
+	}
+	if attachment := account.Attachments[0]; attachment.Path != "attachments/5/boarding pass.pdf" || string(attachment.Data) != "%PDF-1.4" || attachment.Size != 8 {
+		t.Errorf("attachment %+v", attachment)
+	}
+}
+
+func TestReadRejectsDamagedArchives(t *testing.T) {
+	archive := testArchive(t)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "a changed trip",
+			data: rewrite(t, archive, func(name string, content []byte) []byte {
+				if name == tripsName {
+					return bytes.Replace(content, []byte("NRT"), []byte("HND"), 1)
+				}
+				return content
+			}),
+			want: "trips.json does not match its checksum",
+		},
+		{
+			name: "a changed attachment",
+			data: rewrite(t, archive, func(name string, content []byte) []byte {
+				if strings.HasPrefix(name, attachmentsDir) {
+					return []byte("%PDF-1.5")
+				}
+				return content
+			}),
+			want: "boarding pass.pdf does not match its checksum",
+		},
+		{
+			name: "a missing file",
+			data: editManifest(t, archive, func(manifest *Manifest) {
+				manifest.Files = append(manifest.Files, File{Name: "extra.json"})
+			}),
+			want: "extra.json is missing",
+		},
+		{
+			name: "a list left out of the manifest",
+			data: editManifest(t, archive, func(manifest *Manifest) {
+				manifest.Files = manifest.Files[:len(manifest.Files)-1]
+			}),
+			want: "sessions.json is missing from the manifest",
+		},
+	}
+	for _, path := range []string{"trips.json", "attachments/../trips.json", "attachments/5/../../profile.json", "/attachments/5/boarding pass.pdf", "attachments"} {
+		tests = append(tests, struct {
+			name string
+			data []byte
+			want string
+		}{
+			name: "an attachment at " + path,
+			data: editManifest(t, archive, func(manifest *Manifest) { manifest.Attachments[0].Path = path }),
+			want: "attachment 5 is outside attachments/",
+		})
+	}
+	for _, test := range tests {
+		if _, _, err := readArchive(test.data); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: %v, want %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestReadRejectsOtherFormatsAndVersions(t *testing.T) {
+	archive := testArchive(t)
+
+	for _, version := range []int{0, Version + 1} {
+		data := editManifest(t, archive, func(manifest *Manifest) { manifest.Version = version })
+		if _, _, err := readArchive(data); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("version %d: %v, want ErrUnsupportedVersion", version, err)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"another format": editManifest(t, archive, func(manifest *Manifest) { manifest.Format = "other-app" }),
+		"no manifest": rewrite(t, archive, func(name string, content []byte) []byte {
+			if name == ManifestName {
+				return []byte("not json")
+			}
+			return content
+		}),
+		"not a zip": []byte("trips.json"),
+	} {
+		if _, _, err := readArchive(data); !errors.Is(err, ErrNotArchive) {
+			t.Errorf("%s: %v, want ErrNotArchive", name, err)
+		}
+	}
+}
+
+// TestReadLimitsTheDecompressedSize lists the same attachment again and
+// again, each file is small but together they are over the budget
+func TestReadLimitsTheDecompressedSize(t *testing.T) {
+	archive := testArchive(t)
+	size := func(data []byte) int64 {
+		z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var total int64
+		for _, f := range z.File {
+			total += int64(f.UncompressedSize64)
+		}
+		return total
+	}
+	total := size(archive)
+
+	if _, _, err := read(bytes.NewReader(archive), int64(len(archive)), total); err != nil {
+		t.Errorf("an archive exactly at the budget: %v", err)
+	}
+	if _, _, err := read(bytes.NewReader(archive), int64(len(archive)), total-1); err == nil || !strings.Contains(err.Error(), "once decompressed") {
+		t.Errorf("an archive one byte over the budget: %v", err)
+	}
+
+	repeated := editManifest(t, archive, func(manifest *Manifest) {
+		for i := 0; i < 100; i++ {
+			manifest.Attachments = append(manifest.Attachments, manifest.Attachments[0])
+		}
+	})
+	if _, _, err := read(bytes.NewReader(repeated), int64(len(repeated)), size(repeated)); err == nil || !strings.Contains(err.Error(), "once decompressed") {
+		t.Errorf("an attachment listed 101 times: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package archive
+
+import "fmt"
+
+// Report describes what an import did with the records of an archive
+type Report struct {
+	Trips       Count
+	Places      Count
+	Journeys    Count
+	Itineraries Count
+	Attachments Count
+	Sessions    int // Listed in the archive, sessions are never restored
+	Conflicts   []Conflict
+}
+
+// Count is what happened to the records of one kind. Existing records
+// matched one the account already had and were kept unchanged, later
+// references to them point at the existing record.
+type Count struct {
+	Imported int
+	Existing int
+	Skipped  int
+}
+
+// Conflict is a record that could not be restored as it was exported. ID is
+// the id the record had in the archive, 0 for the profile.
+type Conflict struct {
+	Kind    string
+	ID      int
+	Message string
+}
+
+// Conflict records a conflict, message is formatted with fmt.Sprintf
+func (r *Report) Conflict(kind string, id int, format string, args ...any) {
+	r.Conflicts = append(r.Conflicts, Conflict{Kind: kind, ID: id, Message: fmt.Sprintf(format, args...)})
+}
+
+// Kinds of records in a Report besides models.EntityTrip and models.EntityPlace
+const (
+	KindProfile    = "profile"
+	KindJourney    = "journey"
+	KindItinerary  = "itinerary"
+	KindAttachment = "attachment"
+)
@@ -0,0 +1,212 @@
+// Package bcbp reads the IATA Bar Coded Boarding Pass (Resolution 792) data
+// printed in the PDF417 or Aztec barcode of boarding passes. Only the "M"
+// format is read; the security data at the end of the barcode is ignored.
+package bcbp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+// ErrNotBCBP is returned when the text does not start like a boarding pass
+var ErrNotBCBP = errors.New("not a BCBP boarding pass")
+
+// Pass is a decoded boarding pass. A pass has one leg per flight segment, a
+// connection on one pass has two or more.
+type Pass struct {
+	Name             string // "SURNAME/GIVEN NAMES" as printed, up to 20 characters
+	ElectronicTicket bool
+	Version          int // Version of the conditional items, 0 when the pass has none
+	Legs             []Leg
+}
+
+// Leg is one flight of a pass. The mandatory items are always set, the
+// conditional ones only when the issuer included them.
+type Leg struct {
+	PNR             string // Booking reference
+	From            string // IATA airport codes
+	To              string
+	Carrier         string // Operating carrier designator, e.g. "AC"
+	FlightNumber    string // Without the leading zeros, e.g. "834" or "1234A"
+	JulianDate      int    // Day of the year of the flight, local to From
+	Compartment     string // Booking class letter, e.g. "J"
+	Seat            string // Without the leading zeros, e.g. "1A", empty for "INF" or "GATE"
+	CheckInSequence string
+	PassengerStatus string
+
+	AirlineNumericCode   string
+	DocumentNumber       string // Ticket number without the airline code
+	MarketingCarrier     string
+	FrequentFlyerAirline string
+	FrequentFlyerNumber  string
+}
+
+var seatPattern = regexp.MustCompile(`^0*([0-9]{1,3})([A-Z])$`)
+
+// Parse decodes the text of a boarding pass barcode. Trailing spaces lost
+// when the text was copied are tolerated.
+func Parse(text string) (Pass, error) {
+	text = strings.TrimLeft(strings.TrimRight(text, "\r\n"), " \t\r\n")
+	if len(text) < 2 || text[0] != 'M' || text[1] < '1' || text[1] > '9' {
+		return Pass{}, ErrNotBCBP
+	}
+	r := &reader{text: text}
+	r.take(1)
+	legCount := int(r.take(1)[0] - '0')
+
+	pass := Pass{
+		Name:             strings.TrimSpace(r.take(20)),
+		ElectronicTicket: r.take(1) == "E",
+	}
+	for i := 0; i < legCount; i++ {
+		leg, err := r.leg(&pass, i == 0)
+		if err != nil {
+			return Pass{}, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		pass.Legs = append(pass.Legs, leg)
+	}
+	return pass, nil
+}
+
+// reader reads fixed width items. Reading past the end returns the missing
+// characters as spaces so a pass that lost its trailing spaces still reads.
+type reader struct {
+	text string
+	pos  int
+}
+
+func (r *reader) take(n int) string {
+	start := min(r.pos, len(r.text))
+	end := min(r.pos+n, len(r.text))
+	r.pos += n
+	return r.text[start:end] + strings.Repeat(" ", n-(end-start))
+}
+
+// size reads the two hexadecimal digits of a variable size field
+func (r *reader) size() (int, error) {
+	field := strings.TrimSpace(r.take(2))
+	if field == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(field, 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid field size %q", field)
+	}
+	return int(n), nil
+}
+
+func (r *reader) leg(pass *Pass, first bool) (Leg, error) {
+	if r.pos >= len(r.text) {
+		return Leg{}, errors.New("the pass ends before the leg")
+	}
+	leg := Leg{
+		PNR:          strings.TrimSpace(r.take(7)),
+		From:         strings.TrimSpace(r.take(3)),
+		To:           strings.TrimSpace(r.take(3)),
+		Carrier:      strings.TrimSpace(r.take(3)),
+		FlightNumber: strings.TrimLeft(strings.TrimSpace(r.take(5)), "0"),
+	}
+	date := strings.TrimSpace(r.take(3))
+	leg.Compartment = strings.TrimSpace(r.take(1))
+	leg.Seat = seat(r.take(4))
+	leg.CheckInSequence = strings.TrimLeft(strings.TrimSpace(r.take(5)), "0")
+	leg.PassengerStatus = strings.TrimSpace(r.take(1))
+
+	if len(leg.From) != 3 || len(leg.To) != 3 {
+		return Leg{}, fmt.Errorf("invalid airports %q and %q", leg.From, leg.To)
+	}
+	if leg.Carrier == "" || leg.FlightNumber == "" {
+		return Leg{}, errors.New("no flight number")
+	}
+	julian, err := strconv.Atoi(date)
+	if err != nil || julian < 1 || julian > 366 {
+		return Leg{}, fmt.Errorf("invalid date of flight %q", date)
+	}
+	leg.JulianDate = julian
+
+	size, err := r.size()
+	if err != nil {
+		return Leg{}, err
+	}
+	variable := &reader{text: r.take(size)}
+	if first && strings.HasPrefix(variable.text, ">") {
+		variable.take(1)
+		pass.Version, _ = strconv.Atoi(variable.take(1))
+		// Passenger description, check-in source, issue date, document type
+		// and bag tags are not kept
+		uniqueSize, err := variable.size()
+		if err != nil {
+			return Leg{}, err
+		}
+		variable.take(uniqueSize)
+	}
+	if variable.pos < len(variable.text) {
+		repeatedSize, err := variable.size()
+		if err != nil {
+			return Leg{}, err
+		}
+		repeated := &reader{text: variable.take(repeatedSize)}
+		leg.AirlineNumericCode = strings.TrimSpace(repeated.take(3))
+		leg.DocumentNumber = strings.TrimSpace(repeated.take(10))
+		repeated.take(2) // Selectee indicator, international document verification
+		leg.MarketingCarrier = strings.TrimSpace(repeated.take(3))
+		leg.FrequentFlyerAirline = strings.TrimSpace(repeated.take(3))
+		leg.FrequentFlyerNumber = strings.TrimSpace(repeated.take(16))
+		// The ID/AD indicator, baggage allowance, fast track and the rest of
+		// the field, which is for the airline's own use, are not kept
+	}
+	return leg, nil
+}
+
+func seat(field string) string {
+	match := seatPattern.FindStringSubmatch(strings.TrimSpace(field))
+	if match == nil {
+		return ""
+	}
+	return match[1] + match[2]
+}
+
+// Date returns the local date of the flight, in the year that puts it
+// closest to reference. The barcode only has the day of the year; passes are
+// scanned close to the flight, so the nearest year is the right one.
+func (l Leg) Date(reference time.Time) time.Time {
+	var best time.Time
+	for year := reference.Year() - 1; year <= reference.Year()+1; year++ {
+		date := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, l.JulianDate-1)
+		if date.Year() != year {
+			// Day 366 of a year that is not a leap year
+			continue
+		}
+		if best.IsZero() || absDuration(date.Sub(reference)) < absDuration(best.Sub(reference)) {
+			best = date
+		}
+	}
+	return best
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// CabinClass maps the compartment letter to a models.CabinClasses value.
+// Airlines choose their own letters; these are the ones most of them use.
+func (l Leg) CabinClass() string {
+	switch l.Compartment {
+	case "F", "A", "P":
+		return models.CabinFirst
+	case "J", "C", "D", "I", "Z", "R":
+		return models.CabinBusiness
+	case "W":
+		return models.CabinPremiumEconomy
+	}
+	return models.CabinEconomy
+}
@@ -0,0 +1,86 @@
+package bcbp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+// The examples of IATA Resolution 792
+const (
+	singleLeg = "M1DESMARAIS/LUC       EABC123 YULFRAAC 0834 326J001A0025 100"
+	twoLegs   = "M2DESMARAIS/LUC       EABC123 YULFRAAC 0834 326J003A0027 167>5321WW1325BAC 0014123456002001412346700100141234789012A0141234567890 1AC AC 1234567890123    4PCYLX58ZDEF456 FRAGVALH 3664 327C012C0002 12E2A0140987654321 1AC AC 1234567890123    3PCNWQ^164GIWVC5EH7JNT684FVNJ91W2QA4DVN5J8K4F0L0GEQ3DF5TGBN8709HKT5D3DW3GBHFCVHMY7J5T6HFR41W2QA4DVN5J8K4F0L0GE"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		want Pass
+	}{
+		"single leg": {
+			text: singleLeg,
+			want: Pass{Name: "DESMARAIS/LUC", ElectronicTicket: true, Legs: []Leg{
+				{PNR: "ABC123", From: "YUL", To: "FRA", Carrier: "AC", FlightNumber: "834", JulianDate: 326, Compartment: "J", Seat: "1A", CheckInSequence: "25", PassengerStatus: "1"},
+			}},
+		},
+		"pasted without trailing spaces": {
+			text: "M1DESMARAIS/LUC       EABC123 YULFRAAC 0834 326J001A0025 1\n",
+			want: Pass{Name: "DESMARAIS/LUC", ElectronicTicket: true, Legs: []Leg{
+				{PNR: "ABC123", From: "YUL", To: "FRA", Carrier: "AC", FlightNumber: "834", JulianDate: 326, Compartment: "J", Seat: "1A", CheckInSequence: "25", PassengerStatus: "1"},
+			}},
+		},
+		"two legs with conditional items": {
+			text: twoLegs,
+			want: Pass{Name: "DESMARAIS/LUC", ElectronicTicket: true, Version: 5, Legs: []Leg{
+				{
+					PNR: "ABC123", From: "YUL", To: "FRA", Carrier: "AC", FlightNumber: "834", JulianDate: 326, Compartment: "J", Seat: "3A", CheckInSequence: "27", PassengerStatus: "1",
+					AirlineNumericCode: "014", DocumentNumber: "1234567890", MarketingCarrier: "AC", FrequentFlyerAirline: "AC", FrequentFlyerNumber: "1234567890123",
+				},
+				{
+					PNR: "DEF456", From: "FRA", To: "GVA", Carrier: "LH", FlightNumber: "3664", JulianDate: 327, Compartment: "C", Seat: "12C", CheckInSequence: "2", PassengerStatus: "1",
+					AirlineNumericCode: "014", DocumentNumber: "0987654321", MarketingCarrier: "AC", FrequentFlyerAirline: "AC", FrequentFlyerNumber: "1234567890123",
+				},
+			}},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse(c.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got  %+v\nwant %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, text := range []string{"", "hello", "https://example.com/pass", "S1DESMARAIS/LUC"} {
+		if _, err := Parse(text); err != ErrNotBCBP {
+			t.Errorf("Parse(%q): got %v, want ErrNotBCBP", text, err)
+		}
+	}
+	// A pass announcing two legs that only has one
+	if _, err := Parse(singleLeg[:1] + "2" + singleLeg[2:]); err == nil {
+		t.Error("a missing leg was not reported")
+	}
+}
+
+func TestLegDateAndCabin(t *testing.T) {
+	leg := Leg{JulianDate: 3, Compartment: "C"}
+	reference := time.Date(2025, time.December, 30, 12, 0, 0, 0, time.UTC)
+	if got, want := leg.Date(reference), time.Date(2026, time.January, 3, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("date: got %v, want %v", got, want)
+	}
+	leg.JulianDate = 366
+	if got := leg.Date(reference); got.Year() != 2024 {
+		t.Errorf("day 366 near 2025: got %v, want the leap year 2024", got)
+	}
+	if got := leg.CabinClass(); got != models.CabinBusiness {
+		t.Errorf("cabin: got %q, want %q", got, models.CabinBusiness)
+	}
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// accessTokenPrefix marks access tokens so secret scanners and people can
+// tell them apart from other secrets
+const accessTokenPrefix = "tt_"
+
+// ErrAccessTokenExpired is returned for a token that exists but has expired
+var ErrAccessTokenExpired = errors.New("access token expired")
+
+// Handles the functions accessing table access_tokens
+type AccessTokenStore struct {
+	db *sql.DB
+}
+
+type NewAccessTokenStoreParams struct {
+	DB *sql.DB
+}
+
+func NewAccessTokenStore(params NewAccessTokenStoreParams) *AccessTokenStore {
+	return &AccessTokenStore{db: params.DB}
+}
+
+const accessTokenColumns = `id, user_id, name, scopes, created_at, last_used_at, expires_at`
+
+func scanAccessToken(row rowScanner) (m.AccessToken, error) {
+	var token m.AccessToken
+	var scopes string
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&scopes,
+		&token.CreatedAt,
+		&token.LastUsedAt,
+		&token.ExpiresAt,
+	)
+	if scopes != "" {
+		token.Scopes = strings.Split(scopes, ",")
+	}
+	return token, err
+}
+
+// CreateAccessToken generates a token for the user. The returned token holds
+// the secret, which is not stored and can not be read again.
+func (s *AccessTokenStore) CreateAccessToken(token m.AccessToken) (m.AccessToken, error) {
+	rawToken := make([]byte, 32)
+	if _, err := rand.Read(rawToken); err != nil {
+		return m.AccessToken{}, err
+	}
+	token.Token = accessTokenPrefix + hex.EncodeToString(rawToken)
+	token.CreatedAt = uint32(time.Now().Unix())
+	token.LastUsedAt = nil
+
+	res, err := s.db.Exec(`
+		INSERT INTO access_tokens (user_id, name, token_hash, scopes, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		token.UserID, token.Name, hashToken(token.Token), strings.Join(token.Scopes, ","), token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return m.AccessToken{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return m.AccessToken{}, err
+	}
+	token.ID = int(id)
+	return token, nil
+}
+
+// GetAccessTokens returns the user's tokens, newest first
+func (s *AccessTokenStore) GetAccessTokens(userID int) ([]m.AccessToken, error) {
+	rows, err := s.db.Query(`
+		SELECT `+accessTokenColumns+`
+		FROM access_tokens
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []m.AccessToken
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAccessToken deletes one of the user's tokens, ErrNotFound is returned
+// when the token does not exist or belongs to someone else
+func (s *AccessTokenStore) RevokeAccessToken(id int, userID int) error {
+	res, err := s.db.Exec(`DELETE FROM access_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AuthenticateAccessToken looks up a token sent with a request and records
+// that it was used. sql.ErrNoRows is returned for unknown or revoked tokens
+// and ErrAccessTokenExpired for expired ones.
+func (s *AccessTokenStore) AuthenticateAccessToken(secret string) (m.AccessToken, error) {
+	if !strings.HasPrefix(secret, accessTokenPrefix) {
+		return m.AccessToken{}, sql.ErrNoRows
+	}
+	token, err := scanAccessToken(s.db.QueryRow(`
+		SELECT `+accessTokenColumns+`
+		FROM access_tokens
+		WHERE token_hash = ?`, hashToken(secret)))
+	if err != nil {
+		return m.AccessToken{}, err
+	}
+
+	now := uint32(time.Now().Unix())
+	if token.Expired(now) {
+		return token, ErrAccessTokenExpired
+	}
+	if _, err := s.db.Exec(`UPDATE access_tokens SET last_used_at = ? WHERE id = ?`, now, token.ID); err != nil {
+		return token, err
+	}
+	token.LastUsedAt = &now
+	return token, nil
+}
@@ -2,6 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
 )
 
 type AirportStore struct {
@@ -12,11 +18,342 @@ type NewAirportStoreParams struct {
 	DB *sql.DB
 }
 
-func NewAirportStore(params NewAirportStoreParams) *TripStore {
-	return &TripStore{db: params.DB}
+func NewAirportStore(params NewAirportStoreParams) *AirportStore {
+	return &AirportStore{db: params.DB}
 }
 
 // Criteria for airport search
 // This file should only contain methods to query Airport focused queries
 // For example, trip.go focuses on Trip related queries even though it joins with airports
-// This helps keep the code organized and maintainable
\ No newline at end of file
+// This helps keep the code organized and maintainable
+
+const airportColumns = `
+	iata_code,
+	COALESCE(icao_code, '') AS icao_code,
+	COALESCE(name,      '') AS name,
+	COALESCE(city,      '') AS city,
+	COALESCE(country,   '') AS country,
+	COALESCE(latitude,   0) AS latitude,
+	COALESCE(longitude,  0) AS longitude,
+	COALESCE(region,    '') AS region,
+	COALESCE(timezone,  '') AS timezone
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAirport(row rowScanner) (m.Airport, error) {
+	var airport m.Airport
+	err := row.Scan(
+		&airport.IataCode,
+		&airport.IcaoCode,
+		&airport.Name,
+		&airport.City,
+		&airport.Country,
+		&airport.Latitude,
+		&airport.Longitude,
+		&airport.Region,
+		&airport.Timezone,
+	)
+	return airport, err
+}
+
+func (a *AirportStore) queryAirports(q string, args ...any) ([]m.Airport, error) {
+	rows, err := a.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var airports []m.Airport
+	for rows.Next() {
+		airport, err := scanAirport(rows)
+		if err != nil {
+			return nil, err
+		}
+		airports = append(airports, airport)
+	}
+	return airports, rows.Err()
+}
+
+func (a *AirportStore) GetAirportByIATA(iataCode string) (m.Airport, error) {
+	row := a.db.QueryRow(`SELECT `+airportColumns+` FROM airports WHERE iata_code = ?`,
+		strings.ToUpper(strings.TrimSpace(iataCode)))
+	return scanAirport(row)
+}
+
+func (a *AirportStore) GetAirportByICAO(icaoCode string) (m.Airport, error) {
+	row := a.db.QueryRow(`SELECT `+airportColumns+` FROM airports WHERE icao_code = ?`,
+		strings.ToUpper(strings.TrimSpace(icaoCode)))
+	return scanAirport(row)
+}
+
+// GetAirportByCode looks up a 3 letter IATA or 4 letter ICAO code
+func (a *AirportStore) GetAirportByCode(code string) (m.Airport, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 4 {
+		return a.GetAirportByICAO(code)
+	}
+	return a.GetAirportByIATA(code)
+}
+
+// SearchAirports is used for autocompleting airport inputs.
+// Exact code matches come first, followed by prefix matches on code, name and city,
+// then substring matches on name, city and country. If that does not fill the limit
+// a fuzzy pass tolerates small typos such as "Heathraw" or "Frankfrut".
+func (a *AirportStore) SearchAirports(query string, limit int) ([]m.Airport, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []m.Airport{}, nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	airports, err := a.queryAirports(`
+		SELECT `+airportColumns+`
+		FROM airports
+		WHERE iata_code LIKE ?1 || '%'
+			OR icao_code LIKE ?1 || '%'
+			OR name LIKE '%' || ?1 || '%'
+			OR city LIKE '%' || ?1 || '%'
+			OR country LIKE ?1 || '%'
+		ORDER BY
+			CASE
+				WHEN iata_code = UPPER(?1) THEN 0
+				WHEN icao_code = UPPER(?1) THEN 1
+				WHEN iata_code LIKE ?1 || '%' THEN 2
+				WHEN city LIKE ?1 || '%' THEN 3
+				WHEN name LIKE ?1 || '%' THEN 4
+				WHEN icao_code LIKE ?1 || '%' THEN 5
+				ELSE 6
+			END,
+			name
+		LIMIT ?2`, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(airports) >= limit || len([]rune(query)) < 4 {
+		return airports, nil
+	}
+
+	fuzzy, err := a.fuzzySearchAirports(query, limit-len(airports))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(airports))
+	for _, airport := range airports {
+		seen[airport.IataCode] = true
+	}
+	for _, airport := range fuzzy {
+		if !seen[airport.IataCode] {
+			airports = append(airports, airport)
+		}
+	}
+
+	return airports, nil
+}
+
+// maxFuzzyCandidates caps how many airports the fuzzy pass scores
+const maxFuzzyCandidates = 500
+
+// fuzzySearchAirports narrows the candidates with the pieces of the query and
+// ranks them by edit distance in SQL, only the airports that are returned are
+// read. A query within maxDistance edits of a word keeps at least one of
+// maxDistance+1 pieces unchanged, so only airports that contain a piece can
+// match, whichever letter the typo is in. Those containing the most pieces are
+// scored first.
+func (a *AirportStore) fuzzySearchAirports(query string, limit int) ([]m.Airport, error) {
+	query = strings.ToLower(query)
+
+	// Allow roughly one typo for every four characters typed
+	maxDistance := len([]rune(query)) / 4
+
+	var matches []string
+	args := []any{query, maxDistance, limit, maxFuzzyCandidates}
+	for _, piece := range queryPieces(query, maxDistance+1) {
+		args = append(args, piece)
+		n := len(args)
+		matches = append(matches, fmt.Sprintf(
+			`(name LIKE '%%' || ?%[1]d || '%%' OR city LIKE '%%' || ?%[1]d || '%%' OR country LIKE '%%' || ?%[1]d || '%%')`, n))
+	}
+
+	return a.queryAirports(`
+		WITH candidates AS (
+			SELECT iata_code, name, city, country
+			FROM airports
+			WHERE `+strings.Join(matches, " OR ")+`
+			ORDER BY `+strings.Join(matches, " + ")+` DESC
+			LIMIT ?4
+		), scored AS (
+			SELECT iata_code AS code,
+				fuzzy_distance(?1, name, COALESCE(city, ''), country) AS distance
+			FROM candidates
+		)
+		SELECT `+airportColumns+`
+		FROM airports
+		JOIN scored ON scored.code = airports.iata_code
+		WHERE scored.distance <= ?2
+		ORDER BY scored.distance, name
+		LIMIT ?3`, args...)
+}
+
+// queryPieces splits query into n pieces of about the same length
+func queryPieces(query string, n int) []string {
+	runes := []rune(query)
+	pieces := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		pieces = append(pieces, string(runes[i*len(runes)/n:(i+1)*len(runes)/n]))
+	}
+	return pieces
+}
+
+// fuzzyDistance is the fuzzy_distance SQL function. It returns the smallest edit
+// distance between the lower case query and a word of the fields, or the start
+// of a longer word so partially typed input still matches.
+func fuzzyDistance(query string, fields ...string) int {
+	length := len([]rune(query))
+	best := math.MaxInt
+	for _, field := range fields {
+		for _, word := range strings.Fields(strings.ToLower(field)) {
+			best = min(best, levenshtein(query, word))
+			if r := []rune(word); len(r) > length {
+				best = min(best, levenshtein(query, string(r[:length])))
+			}
+		}
+	}
+	return best
+}
+
+// NearestAirports returns the closest airports to a coordinate ordered by distance.
+// Candidates are first narrowed with a bounding box which is widened until enough
+// airports are found, then sorted by great-circle distance.
+func (a *AirportStore) NearestAirports(lat, lon float64, limit int) ([]m.Airport, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	var candidates []m.Airport
+	for radiusDeg := 1.0; radiusDeg <= 180; radiusDeg *= 2 {
+		// Longitude degrees shrink towards the poles so widen the box accordingly
+		lonRadius := radiusDeg / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+
+		minLon, maxLon := lon-lonRadius, lon+lonRadius
+		lonClause := `longitude BETWEEN ? AND ?`
+		lonArgs := []any{minLon, maxLon}
+		switch {
+		case lonRadius >= 180:
+			lonClause, lonArgs = `1 = 1`, nil
+		case minLon < -180:
+			// The box crosses the antimeridian so wrap the western edge around
+			lonClause = `(longitude >= ? OR longitude <= ?)`
+			lonArgs = []any{minLon + 360, maxLon}
+		case maxLon > 180:
+			lonClause = `(longitude >= ? OR longitude <= ?)`
+			lonArgs = []any{minLon, maxLon - 360}
+		}
+
+		var err error
+		candidates, err = a.queryAirports(`
+			SELECT `+airportColumns+`
+			FROM airports
+			WHERE latitude BETWEEN ? AND ? AND `+lonClause,
+			append([]any{lat - radiusDeg, lat + radiusDeg}, lonArgs...)...)
+		if err != nil {
+			return nil, err
+		}
+		if len(candidates) >= limit {
+			break
+		}
+	}
+
+	for i := range candidates {
+		candidates[i].DistanceKm = m.GreatCircleDistanceKm(lat, lon, candidates[i].Latitude, candidates[i].Longitude)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].DistanceKm < candidates[j].DistanceKm
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// UpsertAirports inserts or updates airports keyed by IATA code in a single transaction,
+// so re-running an import with the same file leaves the table unchanged.
+// Empty values in the import never overwrite data that is already stored.
+func (a *AirportStore) UpsertAirports(airports []m.Airport) (int, error) {
+	q := `
+		INSERT INTO airports
+		(iata_code, icao_code, name, city, country, latitude, longitude, region, timezone)
+		VALUES (?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+		ON CONFLICT(iata_code) DO UPDATE SET
+			icao_code = COALESCE(excluded.icao_code, airports.icao_code),
+			name      = excluded.name,
+			city      = COALESCE(excluded.city, airports.city),
+			country   = excluded.country,
+			latitude  = excluded.latitude,
+			longitude = excluded.longitude,
+			region    = COALESCE(excluded.region, airports.region),
+			timezone  = COALESCE(excluded.timezone, airports.timezone)
+	`
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(q)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, airport := range airports {
+		_, err := stmt.Exec(
+			airport.IataCode,
+			airport.IcaoCode,
+			airport.Name,
+			airport.City,
+			airport.Country,
+			airport.Latitude,
+			airport.Longitude,
+			airport.Region,
+			airport.Timezone,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("airport %s: %w", airport.IataCode, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(airports), nil
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// searchAirports are added to testAirports, NAN, TBU and APW are on both sides
+// of the antimeridian
+var searchAirports = []m.Airport{
+	{IataCode: "FRA", IcaoCode: "EDDF", Name: "Frankfurt am Main Airport", City: "Frankfurt", Country: "DE", Latitude: 50.03, Longitude: 8.57},
+	{IataCode: "HAM", IcaoCode: "EDDH", Name: "Hamburg Airport", City: "Hamburg", Country: "DE", Latitude: 53.63, Longitude: 9.99},
+	{IataCode: "LGW", IcaoCode: "EGKK", Name: "London Gatwick Airport", City: "London", Country: "GB", Latitude: 51.15, Longitude: -0.19},
+	{IataCode: "NAN", IcaoCode: "NFFN", Name: "Nadi International Airport", City: "Nadi", Country: "FJ", Latitude: -17.76, Longitude: 177.44},
+	{IataCode: "TBU", IcaoCode: "NFTF", Name: "Fua'amotu International Airport", City: "Nuku'alofa", Country: "TO", Latitude: -21.24, Longitude: -175.15},
+	{IataCode: "APW", IcaoCode: "NSFA", Name: "Faleolo International Airport", City: "Apia", Country: "WS", Latitude: -13.83, Longitude: -172.01},
+	{IataCode: "QQQ", Name: "Nowhere Field", Country: "US"},
+}
+
+func newAirportStore(t testing.TB) *AirportStore {
+	t.Helper()
+	store := NewAirportStore(NewAirportStoreParams{DB: newTestDB(t)})
+	if _, err := store.UpsertAirports(searchAirports); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func airportCodes(airports []m.Airport) []string {
+	codes := []string{}
+	for _, airport := range airports {
+		codes = append(codes, airport.IataCode)
+	}
+	return codes
+}
+
+func TestSearchAirports(t *testing.T) {
+	store := newAirportStore(t)
+
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		{query: "lhr", want: []string{"LHR"}},
+		{query: "EGKK", want: []string{"LGW"}},
+		{query: "London", want: []string{"LGW", "LHR"}},
+		{query: "tokyo", want: []string{"NRT", "HND"}},
+		{query: "Narita", want: []string{"NRT"}},
+		// Typos, about one for every four characters
+		{query: "Heathraw", want: []string{"LHR"}},
+		{query: "Frankfrut", want: []string{"FRA"}},
+		{query: "Hambueg", want: []string{"HAM"}},
+		{query: "Narrita", want: []string{"NRT"}},
+		// In the first letter
+		{query: "Geathrow", want: []string{"LHR"}},
+		{query: "Lamburg", want: []string{"HAM"}},
+		{query: "Brankfurt", want: []string{"FRA"}},
+		// The start of a longer word
+		{query: "Frenkfu", want: []string{"FRA"}},
+		// Too many typos, and short queries are not fuzzy
+		{query: "Hmbrgg", want: []string{}},
+		{query: "Nxt", want: []string{}},
+		{query: "zzzzzzzz", want: []string{}},
+		{query: "   ", want: []string{}},
+		// Substring matches are ordered by name and cut at the limit
+		{query: "International", limit: 2, want: []string{"APW", "TBU"}},
+	}
+	for _, test := range tests {
+		airports, err := store.SearchAirports(test.query, test.limit)
+		if err != nil {
+			t.Fatalf("%q: %v", test.query, err)
+		}
+		if got := airportCodes(airports); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestFuzzyDistance(t *testing.T) {
+	for _, test := range []struct {
+		query  string
+		fields []string
+		want   int
+	}{
+		{"heathraw", []string{"London Heathrow Airport", "London"}, 1},
+		{"frankfrut", []string{"Frankfurt am Main Airport"}, 2},
+		{"frenkfu", []string{"Frankfurt am Main Airport"}, 1},
+		{"london", []string{"LONDON"}, 0},
+		{"nadi", []string{"", "Nadi"}, 0},
+	} {
+		if got := fuzzyDistance(test.query, test.fields...); got != test.want {
+			t.Errorf("fuzzyDistance(%q, %q) = %d, want %d", test.query, test.fields, got, test.want)
+		}
+	}
+}
+
+func TestQueryPieces(t *testing.T) {
+	for _, test := range []struct {
+		query string
+		n     int
+		want  []string
+	}{
+		{"lamburg", 2, []string{"lam", "burg"}},
+		{"heathraw", 3, []string{"he", "ath", "raw"}},
+		{"zürich", 2, []string{"zür", "ich"}},
+	} {
+		if got := queryPieces(test.query, test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("queryPieces(%q, %d) = %q, want %q", test.query, test.n, got, test.want)
+		}
+	}
+}
+
+// BenchmarkSearchAirportsFuzzy searches a table about the size of a full
+// airport import for queries that go through the fuzzy pass
+func BenchmarkSearchAirportsFuzzy(b *testing.B) {
+	store := newAirportStore(b)
+	syllables := []string{"ka", "ri", "to", "man", "sel", "dor", "vi", "lan", "bu", "ne", "sto", "gra", "pol", "en", "ta"}
+	random := rand.New(rand.NewSource(1))
+	word := func() string {
+		var w strings.Builder
+		for i := 0; i < 2+random.Intn(3); i++ {
+			w.WriteString(syllables[random.Intn(len(syllables))])
+		}
+		return strings.ToUpper(w.String()[:1]) + w.String()[1:]
+	}
+	var airports []m.Airport
+	for i := 0; i < 8000; i++ {
+		city := word()
+		airports = append(airports, m.Airport{
+			IataCode: fmt.Sprintf("%c%c%c", 'A'+i/676%26, 'A'+i/26%26, 'A'+i%26) + "X",
+			Name:     city + " " + word() + " International Airport",
+			City:     city,
+			Country:  "ZZ",
+		})
+	}
+	if _, err := store.UpsertAirports(airports); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, query := range []string{"Heathraw", "Geathrow", "Manselvi", "Internatoinal"} {
+		b.Run(query, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := store.SearchAirports(query, 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNearestAirports(t *testing.T) {
+	store := newAirportStore(t)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		limit    int
+		want     []string
+	}{
+		{name: "at Heathrow", lat: 51.47, lon: -0.45, limit: 2, want: []string{"LHR", "LGW"}},
+		{name: "central Tokyo", lat: 35.68, lon: 139.77, limit: 2, want: []string{"HND", "NRT"}},
+		{name: "the default limit", lat: 35.68, lon: 139.77, want: []string{"HND", "NRT", "NAN", "APW", "TBU"}},
+		{name: "west of the antimeridian", lat: -18, lon: 179.9, limit: 2, want: []string{"NAN", "TBU"}},
+		{name: "east of the antimeridian", lat: -18, lon: -179.9, limit: 3, want: []string{"NAN", "TBU", "APW"}},
+		{name: "near the north pole", lat: 89, lon: 0, limit: 1, want: []string{"HAM"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			airports, err := store.NearestAirports(test.lat, test.lon, test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := airportCodes(airports); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+			for i := 1; i < len(airports); i++ {
+				if airports[i].DistanceKm < airports[i-1].DistanceKm || airports[i].DistanceKm == 0 {
+					t.Errorf("%s at %.0f km comes after %s at %.0f km", airports[i].IataCode, airports[i].DistanceKm, airports[i-1].IataCode, airports[i-1].DistanceKm)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Handles the functions accessing table attachments
+type AttachmentStore struct {
+	db *sql.DB
+}
+
+type NewAttachmentStoreParams struct {
+	DB *sql.DB
+}
+
+func NewAttachmentStore(params NewAttachmentStoreParams) *AttachmentStore {
+	return &AttachmentStore{db: params.DB}
+}
+
+// CreateAttachment keeps a file with one of the user's trips or places and
+// returns its id. A file the record already has is not stored twice, the id
+// of the existing attachment and false are returned instead.
+func (s *AttachmentStore) CreateAttachment(attachment m.Attachment) (int, bool, error) {
+	if err := requireOwner(s.db, Resource(attachment.EntityType), attachment.EntityID, attachment.UserID); err != nil {
+		return 0, false, err
+	}
+	sum := sha256.Sum256(attachment.Data)
+	attachment.SHA256 = hex.EncodeToString(sum[:])
+
+	var id int
+	err := s.db.QueryRow(`SELECT id FROM attachments
+		WHERE user_id = ? AND entity_type = ? AND entity_id = ? AND sha256 = ?`,
+		attachment.UserID, attachment.EntityType, attachment.EntityID, attachment.SHA256).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, err
+	}
+
+	res, err := s.db.Exec(`
+		INSERT INTO attachments (
+			user_id, entity_type, entity_id, name, content_type, size, sha256, data, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		attachment.UserID,
+		attachment.EntityType,
+		attachment.EntityID,
+		attachment.Name,
+		attachment.ContentType,
+		len(attachment.Data),
+		attachment.SHA256,
+		attachment.Data,
+		uint32(time.Now().Unix()),
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	newID, err := res.LastInsertId()
+	return int(newID), err == nil, err
+}
+
+const attachmentColumns = `id, user_id, entity_type, entity_id, name, content_type, size, sha256, created_at`
+
+func scanAttachment(row rowScanner, data *[]byte) (m.Attachment, error) {
+	var attachment m.Attachment
+	dest := []any{
+		&attachment.ID,
+		&attachment.UserID,
+		&attachment.EntityType,
+		&attachment.EntityID,
+		&attachment.Name,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.CreatedAt,
+	}
+	if data != nil {
+		dest = append(dest, data)
+	}
+	err := row.Scan(dest...)
+	return attachment, err
+}
+
+func (s *AttachmentStore) queryAttachments(withData bool, q string, args ...any) ([]m.Attachment, error) {
+	columns := attachmentColumns
+	if withData {
+		columns += ", data"
+	}
+	rows, err := s.db.Query(`SELECT `+columns+` FROM attachments `+q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []m.Attachment
+	for rows.Next() {
+		var data []byte
+		var dest *[]byte
+		if withData {
+			dest = &data
+		}
+		attachment, err := scanAttachment(rows, dest)
+		if err != nil {
+			return nil, err
+		}
+		attachment.Data = data
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachmentsForEntity lists the files of a trip or place without their
+// data, oldest first
+func (s *AttachmentStore) GetAttachmentsForEntity(userID int, entityType string, entityID int) ([]m.Attachment, error) {
+	return s.queryAttachments(false, `WHERE user_id = ? AND entity_type = ? AND entity_id = ? ORDER BY id`,
+		userID, entityType, entityID)
+}
+
+// GetAttachmentsGivenUser returns every file of the user with its data, for
+// the account archive. Files of records in the trash are left out.
+func (s *AttachmentStore) GetAttachmentsGivenUser(userID int) ([]m.Attachment, error) {
+	return s.queryAttachments(true, `WHERE user_id = ? AND NOT EXISTS (
+			SELECT 1 FROM trips WHERE entity_type = 'trip' AND trips.id = entity_id AND trips.deleted_at IS NOT NULL
+		) AND NOT EXISTS (
+			SELECT 1 FROM places WHERE entity_type = 'place' AND places.id = entity_id AND places.deleted_at IS NOT NULL
+		) ORDER BY id`, userID)
+}
+
+// GetAttachment returns the file with its data, ErrNotFound is returned
+// unless it belongs to the user
+func (s *AttachmentStore) GetAttachment(id int, userID int) (m.Attachment, error) {
+	var data []byte
+	attachment, err := scanAttachment(s.db.QueryRow(`SELECT `+attachmentColumns+`, data FROM attachments
+		WHERE id = ? AND user_id = ?`, id, userID), &data)
+	attachment.Data = data
+	return attachment, err
+}
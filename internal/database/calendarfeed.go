@@ -0,0 +1,80 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Handles the functions accessing table calendar_feeds
+type CalendarFeedStore struct {
+	db *sql.DB
+}
+
+type NewCalendarFeedStoreParams struct {
+	DB *sql.DB
+}
+
+func NewCalendarFeedStore(params NewCalendarFeedStoreParams) *CalendarFeedStore {
+	return &CalendarFeedStore{db: params.DB}
+}
+
+// hashToken hashes a secret token for storage, tokens are random so a fast
+// hash is enough
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// RotateCalendarFeed generates a new token for the user, the previous feed URL
+// stops working
+func (s *CalendarFeedStore) RotateCalendarFeed(userID int) (m.CalendarFeed, error) {
+	rawToken := make([]byte, 32)
+	if _, err := rand.Read(rawToken); err != nil {
+		return m.CalendarFeed{}, err
+	}
+	feed := m.CalendarFeed{
+		UserID:    userID,
+		Token:     hex.EncodeToString(rawToken),
+		CreatedAt: uint32(time.Now().Unix()),
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO calendar_feeds (user_id, token_hash, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		feed.UserID, hashToken(feed.Token), feed.CreatedAt)
+	if err != nil {
+		return m.CalendarFeed{}, err
+	}
+	return feed, nil
+}
+
+func (s *CalendarFeedStore) RevokeCalendarFeed(userID int) error {
+	_, err := s.db.Exec(`DELETE FROM calendar_feeds WHERE user_id = ?`, userID)
+	return err
+}
+
+// GetCalendarFeed returns nil when the user has no feed
+func (s *CalendarFeedStore) GetCalendarFeed(userID int) (*m.CalendarFeed, error) {
+	feed := m.CalendarFeed{UserID: userID}
+	err := s.db.QueryRow(`SELECT created_at FROM calendar_feeds WHERE user_id = ?`, userID).Scan(&feed.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// GetUserIDForCalendarToken returns sql.ErrNoRows for unknown or revoked tokens
+func (s *CalendarFeedStore) GetUserIDForCalendarToken(token string) (int, error) {
+	var userID int
+	err := s.db.QueryRow(`SELECT user_id FROM calendar_feeds WHERE token_hash = ?`, hashToken(token)).Scan(&userID)
+	return userID, err
+}
@@ -3,15 +3,61 @@ package database
 import (
 	"database/sql"
 	"log"
+	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
-func InitDB(filepath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", filepath)
+// driverName is the sqlite3 driver with the app's SQL functions registered on
+// every connection
+const driverName = "sqlite3_trip_tracker"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("fuzzy_distance", fuzzyDistance, true)
+		},
+	})
+}
+
+// OpenDB opens the sqlite database without touching the schema. Foreign keys
+// are enforced on every connection, sqlite leaves them off by default and the
+// ON DELETE CASCADE clauses of the schema would do nothing.
+func OpenDB(filepath string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, withForeignKeys(filepath))
 	if err != nil {
 		log.Fatal(err)
 		return nil, err
 	}
 	return db, nil
-}
\ No newline at end of file
+}
+
+// InitDB opens the sqlite database, upgrades it to the latest schema version
+// and sets up the search index
+func InitDB(filepath string) (*sql.DB, error) {
+	db, err := OpenDB(filepath)
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := EnsureSearchIndex(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// withForeignKeys adds _foreign_keys=1 to the data source name unless it
+// already sets them
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys=") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=1"
+	}
+	return dsn + "?_foreign_keys=1"
+}
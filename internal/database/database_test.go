@@ -0,0 +1,42 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// testAirports are enough for the trips of the tests, every trip read joins
+// its airports
+var testAirports = []m.Airport{
+	{IataCode: "JFK", Name: "John F Kennedy International Airport", City: "New York", Country: "US", Latitude: 40.64, Longitude: -73.78, Timezone: "America/New_York"},
+	{IataCode: "NRT", Name: "Narita International Airport", City: "Tokyo", Country: "JP", Latitude: 35.76, Longitude: 140.39, Timezone: "Asia/Tokyo"},
+	{IataCode: "HND", Name: "Tokyo Haneda Airport", City: "Tokyo", Country: "JP", Latitude: 35.55, Longitude: 139.78, Timezone: "Asia/Tokyo"},
+	{IataCode: "LHR", Name: "London Heathrow Airport", City: "London", Country: "GB", Latitude: 51.47, Longitude: -0.45, Timezone: "Europe/London"},
+}
+
+// newTestDB returns a migrated database in a temporary file with testAirports
+func newTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := InitDB("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := NewAirportStore(NewAirportStoreParams{DB: db}).UpsertAirports(testAirports); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestUser(t testing.TB, db *sql.DB, name string) int {
+	t.Helper()
+	id, err := NewUserStore(NewUserStoreParams{DB: db}).CreateUser(m.User{Username: name, Password: "x", Email: name + "@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Every write to trips and places records a row in change_history inside the
+// same transaction, holding the row as JSON before and after the write, and
+// queues the webhook deliveries of the change.
+// Snapshots are read straight from the table, without the airports join, so they
+// mirror exactly what was stored.
+
+type HistoryStore struct {
+	db *sql.DB
+}
+
+type NewHistoryStoreParams struct {
+	DB *sql.DB
+}
+
+func NewHistoryStore(params NewHistoryStoreParams) *HistoryStore {
+	return &HistoryStore{db: params.DB}
+}
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx
+type dbExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Only changes to records the user owns are visible to them
+var historyOwnerCheck = map[string]string{
+	m.EntityTrip:  `EXISTS (SELECT 1 FROM trips WHERE trips.id = h.entity_id AND trips.user_id = ?)`,
+	m.EntityPlace: `EXISTS (SELECT 1 FROM places WHERE places.id = h.entity_id AND places.user_id = ?)`,
+}
+
+const changeColumns = `h.id, h.user_id, h.entity_type, h.entity_id, h.action, COALESCE(h.before_json, ''), COALESCE(h.after_json, ''), h.created_at`
+
+func scanChange(row rowScanner) (m.Change, error) {
+	var change m.Change
+	var before, after string
+	err := row.Scan(
+		&change.ID,
+		&change.UserID,
+		&change.EntityType,
+		&change.EntityID,
+		&change.Action,
+		&before,
+		&after,
+		&change.CreatedAt,
+	)
+	if before != "" {
+		change.Before = json.RawMessage(before)
+	}
+	if after != "" {
+		change.After = json.RawMessage(after)
+	}
+	return change, err
+}
+
+// GetHistory returns the changes to a trip or place, newest first
+func (h *HistoryStore) GetHistory(entityType string, entityID int, userID int) ([]m.Change, error) {
+	ownerCheck, ok := historyOwnerCheck[entityType]
+	if !ok {
+		return nil, errors.New("unknown entity type " + entityType)
+	}
+
+	rows, err := h.db.Query(`
+		SELECT `+changeColumns+`
+		FROM change_history h
+		WHERE h.entity_type = ? AND h.entity_id = ? AND `+ownerCheck+`
+		ORDER BY h.id DESC`, entityType, entityID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []m.Change
+	for rows.Next() {
+		change, err := scanChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// GetChange returns a single change to a record the user owns
+func (h *HistoryStore) GetChange(changeID int, userID int) (m.Change, error) {
+	return getChange(h.db, changeID, userID)
+}
+
+func getChange(q dbExecutor, changeID int, userID int) (m.Change, error) {
+	var entityType string
+	if err := q.QueryRow(`SELECT entity_type FROM change_history WHERE id = ?`, changeID).Scan(&entityType); err != nil {
+		return m.Change{}, err
+	}
+	ownerCheck, ok := historyOwnerCheck[entityType]
+	if !ok {
+		return m.Change{}, sql.ErrNoRows
+	}
+
+	row := q.QueryRow(`
+		SELECT `+changeColumns+`
+		FROM change_history h
+		WHERE h.id = ? AND `+ownerCheck, changeID, userID)
+	return scanChange(row)
+}
+
+// recordChange writes one audit entry and queues its webhook deliveries.
+// before and after are marshalled to JSON, a nil snapshot is stored as NULL.
+func recordChange(q dbExecutor, userID int, entityType string, entityID int, action string, before, after any) error {
+	beforeJSON, err := snapshotJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := snapshotJSON(after)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	res, err := q.Exec(`
+		INSERT INTO change_history (user_id, entity_type, entity_id, action, before_json, after_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, entityType, entityID, action, beforeJSON, afterJSON, now)
+	if err != nil {
+		return err
+	}
+	changeID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return enqueueWebhookEvent(q, userID, entityType, action, changeID, now, afterJSON)
+}
+
+func snapshotJSON(snapshot any) (*string, error) {
+	switch s := snapshot.(type) {
+	case nil:
+		return nil, nil
+	case *m.Trip:
+		if s == nil {
+			return nil, nil
+		}
+	case *m.Place:
+		if s == nil {
+			return nil, nil
+		}
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	str := string(b)
+	return &str, nil
+}
+
+// getTripRow reads the stored trip row, including trips in the trash
+func getTripRow(q dbExecutor, tripID int) (*m.Trip, error) {
+	var trip m.Trip
+	err := q.QueryRow(`
+		SELECT id, user_id, departure, arrival, departure_time, arrival_time, airline, flight_number,
+			reservation, terminal, gate, seat, cabin_class, aircraft_type, tail_number, booking_class,
+			ticket_price, ticket_currency, deleted_at
+		FROM trips WHERE id = ?`, tripID).Scan(
+		&trip.ID,
+		&trip.UserId,
+		&trip.Departure,
+		&trip.Arrival,
+		&trip.DepartureTime,
+		&trip.ArrivalTime,
+		&trip.Airline,
+		&trip.FlightNumber,
+		&trip.Reservation,
+		&trip.Terminal,
+		&trip.Gate,
+		&trip.Seat,
+		&trip.CabinClass,
+		&trip.AircraftType,
+		&trip.TailNumber,
+		&trip.BookingClass,
+		&trip.TicketPrice,
+		&trip.TicketCurrency,
+		&trip.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &trip, nil
+}
+
+// getPlaceRow reads the stored place row, including places in the trash
+func getPlaceRow(q dbExecutor, placeID int) (*m.Place, error) {
+	var place m.Place
+	err := q.QueryRow(`
+		SELECT id, user_id, place_id, name, address, latitude, longitude,
+			visit_date, category, notes, marker_color, created_at, updated_at, deleted_at
+		FROM places WHERE id = ?`, placeID).Scan(
+		&place.ID,
+		&place.UserID,
+		&place.PlaceID,
+		&place.Name,
+		&place.Address,
+		&place.Latitude,
+		&place.Longitude,
+		&place.VisitDate,
+		&place.Category,
+		&place.Notes,
+		&place.MarkerColor,
+		&place.CreatedAt,
+		&place.UpdatedAt,
+		&place.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &place, nil
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Handles the functions accessing table import_reviews
+type ImportReviewStore struct {
+	db *sql.DB
+}
+
+type NewImportReviewStoreParams struct {
+	DB *sql.DB
+}
+
+func NewImportReviewStore(params NewImportReviewStoreParams) *ImportReviewStore {
+	return &ImportReviewStore{db: params.DB}
+}
+
+// QueueImportReview adds a row to the user's review queue. A row that is
+// already queued from an earlier upload is left alone and false is returned.
+func (s *ImportReviewStore) QueueImportReview(review m.ImportReview) (bool, error) {
+	trip, err := json.Marshal(review.Trip)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.db.Exec(`
+		INSERT OR IGNORE INTO import_reviews (
+			user_id, format, line, problem, trip, departure_local, arrival_local,
+			duration_minutes, source, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		review.UserID,
+		review.Format,
+		review.Line,
+		review.Problem,
+		string(trip),
+		review.DepartureLocal,
+		review.ArrivalLocal,
+		review.DurationMinutes,
+		review.Source,
+		uint32(time.Now().Unix()),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+const importReviewColumns = `id, user_id, format, line, problem, trip, departure_local,
+	arrival_local, duration_minutes, source, created_at`
+
+func scanImportReview(row rowScanner) (m.ImportReview, error) {
+	var review m.ImportReview
+	var trip string
+	err := row.Scan(
+		&review.ID,
+		&review.UserID,
+		&review.Format,
+		&review.Line,
+		&review.Problem,
+		&trip,
+		&review.DepartureLocal,
+		&review.ArrivalLocal,
+		&review.DurationMinutes,
+		&review.Source,
+		&review.CreatedAt,
+	)
+	if err != nil {
+		return review, err
+	}
+	err = json.Unmarshal([]byte(trip), &review.Trip)
+	return review, err
+}
+
+// GetImportReviews returns the user's queue in the order the rows were read
+func (s *ImportReviewStore) GetImportReviews(userID int) ([]m.ImportReview, error) {
+	rows, err := s.db.Query(`SELECT `+importReviewColumns+` FROM import_reviews
+		WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []m.ImportReview
+	for rows.Next() {
+		review, err := scanImportReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+// GetImportReview returns ErrNotFound unless the row is in the user's queue
+func (s *ImportReviewStore) GetImportReview(id int, userID int) (m.ImportReview, error) {
+	row := s.db.QueryRow(`SELECT `+importReviewColumns+` FROM import_reviews
+		WHERE id = ? AND user_id = ?`, id, userID)
+	return scanImportReview(row)
+}
+
+// DeleteImportReview removes a row from the user's queue once it was saved or
+// discarded
+func (s *ImportReviewStore) DeleteImportReview(id int, userID int) error {
+	res, err := s.db.Exec(`DELETE FROM import_reviews WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
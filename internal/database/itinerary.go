@@ -0,0 +1,317 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Itineraries are built from trips so their queries live on TripStore.
+// Pinned itineraries are stored in itineraries/itinerary_legs, everything else
+// is chained on the fly using the user's maximum layover.
+
+// GetItinerariesGivenUser splits the user's trips into standalone flights and
+// itineraries of two or more legs. Every trip appears exactly once.
+func (t *TripStore) GetItinerariesGivenUser(userID int) ([]m.Trip, []m.Itinerary, error) {
+	trips, err := t.GetTripsGivenUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxLayover, err := t.getMaxLayover(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pinned, err := t.getPinnedItineraries(userID, trips)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pinnedIDs := make(map[int]bool)
+	var standalone []m.Trip
+	var itineraries []m.Itinerary
+	for _, itinerary := range pinned {
+		for _, leg := range itinerary.Legs {
+			pinnedIDs[leg.ID] = true
+		}
+		// A pinned itinerary left with a single leg (the others were deleted) is a standalone flight
+		if len(itinerary.Legs) == 1 {
+			standalone = append(standalone, itinerary.Legs[0])
+			continue
+		}
+		itineraries = append(itineraries, itinerary)
+	}
+
+	var unpinned []m.Trip
+	for _, trip := range trips {
+		if !pinnedIDs[trip.ID] {
+			unpinned = append(unpinned, trip)
+		}
+	}
+
+	chainedStandalone, chained := chainTrips(unpinned, maxLayover)
+	for i := range chained {
+		chained[i].UserID = userID
+	}
+	standalone = append(standalone, chainedStandalone...)
+	itineraries = append(itineraries, chained...)
+
+	sort.Slice(standalone, func(i, j int) bool {
+		return standalone[i].DepartureTime < standalone[j].DepartureTime
+	})
+	sort.Slice(itineraries, func(i, j int) bool {
+		return itineraries[i].DepartureTime() < itineraries[j].DepartureTime()
+	})
+
+	return standalone, itineraries, nil
+}
+
+// chainTrips links flights into itineraries. Starting from the earliest unused flight
+// each chain is extended with the first flight that departs from the airport it
+// arrived at within maxLayover. Flying straight back to where the previous leg
+// departed is treated as a return trip, not a connection.
+func chainTrips(trips []m.Trip, maxLayover time.Duration) ([]m.Trip, []m.Itinerary) {
+	sorted := make([]m.Trip, len(trips))
+	copy(sorted, trips)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].DepartureTime < sorted[j].DepartureTime
+	})
+
+	maxLayoverSeconds := int64(maxLayover / time.Second)
+	used := make(map[int]bool)
+
+	var standalone []m.Trip
+	var itineraries []m.Itinerary
+
+	for i, first := range sorted {
+		if used[first.ID] {
+			continue
+		}
+		used[first.ID] = true
+		legs := []m.Trip{first}
+		current := first
+
+		for {
+			next := -1
+			for j := i + 1; j < len(sorted); j++ {
+				candidate := sorted[j]
+				if int64(candidate.DepartureTime) > int64(current.ArrivalTime)+maxLayoverSeconds {
+					break // sorted by departure so nothing later can connect
+				}
+				if used[candidate.ID] || candidate.Departure != current.Arrival {
+					continue
+				}
+				if candidate.DepartureTime <= current.ArrivalTime || candidate.Arrival == current.Departure {
+					continue
+				}
+				next = j
+				break
+			}
+			if next < 0 {
+				break
+			}
+			current = sorted[next]
+			used[current.ID] = true
+			legs = append(legs, current)
+		}
+
+		if len(legs) == 1 {
+			standalone = append(standalone, first)
+		} else {
+			itineraries = append(itineraries, m.Itinerary{Legs: legs})
+		}
+	}
+
+	return standalone, itineraries
+}
+
+func (t *TripStore) getMaxLayover(userID int) (time.Duration, error) {
+	var minutes int
+	err := t.db.QueryRow(`SELECT max_layover_minutes FROM users WHERE id = ?`, userID).Scan(&minutes)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && minutes <= 0) {
+		return m.DefaultMaxLayover, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// getPinnedItineraries resolves the pinned legs against trips that were already loaded
+func (t *TripStore) getPinnedItineraries(userID int, trips []m.Trip) ([]m.Itinerary, error) {
+	tripsByID := make(map[int]m.Trip, len(trips))
+	for _, trip := range trips {
+		tripsByID[trip.ID] = trip
+	}
+
+	rows, err := t.db.Query(`
+		SELECT l.itinerary_id, l.trip_id
+		FROM itinerary_legs l
+		JOIN itineraries i ON i.id = l.itinerary_id
+		WHERE i.user_id = ?
+		ORDER BY l.itinerary_id, l.position`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var itineraries []m.Itinerary
+	for rows.Next() {
+		var itineraryID, tripID int
+		if err := rows.Scan(&itineraryID, &tripID); err != nil {
+			return nil, err
+		}
+		trip, ok := tripsByID[tripID]
+		if !ok {
+			continue
+		}
+		if len(itineraries) == 0 || itineraries[len(itineraries)-1].ID != itineraryID {
+			itineraries = append(itineraries, m.Itinerary{ID: itineraryID, UserID: userID, Pinned: true})
+		}
+		last := &itineraries[len(itineraries)-1]
+		last.Legs = append(last.Legs, trip)
+	}
+
+	return itineraries, rows.Err()
+}
+
+// PinItinerary persists the given flights as one itinerary ordered by departure time.
+// Flights that were pinned to another itinerary are moved to the new one, a
+// flight listed twice is pinned once.
+func (t *TripStore) PinItinerary(userID int, tripIDs []int) (int64, error) {
+	tripIDs = slices.Compact(slices.Sorted(slices.Values(tripIDs)))
+	if len(tripIDs) < 2 {
+		return 0, errors.New("an itinerary needs at least two flights")
+	}
+
+	tx, err := t.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tripIDs)), ",")
+	args := []any{userID}
+	for _, id := range tripIDs {
+		args = append(args, id)
+	}
+
+	// Only the user's own flights can be pinned, ordered the way they are flown
+	rows, err := tx.Query(`
+		SELECT id FROM trips
+		WHERE user_id = ? AND deleted_at IS NULL AND id IN (`+placeholders+`)
+		ORDER BY departure_time`, args...)
+	if err != nil {
+		return 0, err
+	}
+	var ordered []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ordered = append(ordered, id)
+	}
+	rows.Close()
+	if len(ordered) != len(tripIDs) {
+		return 0, sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`DELETE FROM itinerary_legs WHERE trip_id IN (`+placeholders+`)`, args[1:]...); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`INSERT INTO itineraries (user_id, created_at) VALUES (?, ?)`, userID, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	itineraryID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for position, tripID := range ordered {
+		_, err := tx.Exec(`INSERT INTO itinerary_legs (itinerary_id, trip_id, position) VALUES (?, ?, ?)`,
+			itineraryID, tripID, position)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := deleteEmptyItineraries(tx, userID); err != nil {
+		return 0, err
+	}
+
+	return itineraryID, tx.Commit()
+}
+
+// UnpinItinerary removes a pinned itinerary, its flights go back to automatic chaining
+func (t *TripStore) UnpinItinerary(userID int, itineraryID int) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM itineraries WHERE id = ? AND user_id = ?`, itineraryID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM itinerary_legs WHERE itinerary_id = ?`, itineraryID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnpinLeg removes a single flight from its pinned itinerary. An itinerary left
+// with fewer than two legs is removed as well.
+func (t *TripStore) UnpinLeg(userID int, tripID int) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		DELETE FROM itinerary_legs
+		WHERE trip_id = ?
+		AND itinerary_id IN (SELECT id FROM itineraries WHERE user_id = ?)`, tripID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := deleteEmptyItineraries(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func deleteEmptyItineraries(tx *sql.Tx, userID int) error {
+	const emptyItineraries = `
+		SELECT i.id FROM itineraries i
+		LEFT JOIN itinerary_legs l ON l.itinerary_id = i.id
+		WHERE i.user_id = ?
+		GROUP BY i.id
+		HAVING COUNT(l.trip_id) < 2`
+
+	if _, err := tx.Exec(`DELETE FROM itinerary_legs WHERE itinerary_id IN (`+emptyItineraries+`)`, userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM itineraries WHERE id IN (`+emptyItineraries+`)`, userID)
+	return err
+}
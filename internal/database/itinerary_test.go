@@ -0,0 +1,213 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// 2025-04-01 10:00 UTC
+const itineraryStart = 1743501600
+
+func leg(id int, from, to string, departsAfter, flightTime time.Duration) m.Trip {
+	departure := uint32(itineraryStart + departsAfter.Seconds())
+	return m.Trip{ID: id, Departure: from, Arrival: to, DepartureTime: departure, ArrivalTime: departure + uint32(flightTime.Seconds())}
+}
+
+func legIDs(itineraries []m.Itinerary) [][]int {
+	var ids [][]int
+	for _, itinerary := range itineraries {
+		ids = append(ids, itinerary.TripIDs())
+	}
+	return ids
+}
+
+func tripIDs(trips []m.Trip) []int {
+	var ids []int
+	for _, trip := range trips {
+		ids = append(ids, trip.ID)
+	}
+	return ids
+}
+
+func TestChainTrips(t *testing.T) {
+	tests := []struct {
+		name        string
+		trips       []m.Trip
+		itineraries [][]int
+		standalone  []int
+	}{
+		{
+			name:        "connection within the layover",
+			trips:       []m.Trip{leg(2, "NRT", "HND", 15*time.Hour, time.Hour), leg(1, "JFK", "NRT", 0, 14*time.Hour)},
+			itineraries: [][]int{{1, 2}},
+		},
+		{
+			name:        "connection on the limit of the layover",
+			trips:       []m.Trip{leg(1, "JFK", "NRT", 0, 14*time.Hour), leg(2, "NRT", "HND", 38*time.Hour, time.Hour)},
+			itineraries: [][]int{{1, 2}},
+		},
+		{
+			name:       "layover too long",
+			trips:      []m.Trip{leg(1, "JFK", "NRT", 0, 14*time.Hour), leg(2, "NRT", "HND", 39*time.Hour, time.Hour)},
+			standalone: []int{1, 2},
+		},
+		{
+			name:       "departs before the arrival",
+			trips:      []m.Trip{leg(1, "JFK", "NRT", 0, 14*time.Hour), leg(2, "NRT", "HND", 13*time.Hour, time.Hour)},
+			standalone: []int{1, 2},
+		},
+		{
+			name:       "different airport",
+			trips:      []m.Trip{leg(1, "JFK", "NRT", 0, 14*time.Hour), leg(2, "HND", "LHR", 16*time.Hour, 12*time.Hour)},
+			standalone: []int{1, 2},
+		},
+		{
+			name:       "return leg is not a connection",
+			trips:      []m.Trip{leg(1, "JFK", "NRT", 0, 14*time.Hour), leg(2, "NRT", "JFK", 16*time.Hour, 13*time.Hour)},
+			standalone: []int{1, 2},
+		},
+		{
+			name: "first connection wins",
+			trips: []m.Trip{
+				leg(1, "JFK", "NRT", 0, 14*time.Hour),
+				leg(3, "NRT", "LHR", 17*time.Hour, 12*time.Hour),
+				leg(2, "NRT", "HND", 16*time.Hour, time.Hour),
+			},
+			itineraries: [][]int{{1, 2}},
+			standalone:  []int{3},
+		},
+		{
+			name: "three legs and a return on its own",
+			trips: []m.Trip{
+				leg(1, "LHR", "JFK", 0, 8*time.Hour),
+				leg(2, "JFK", "NRT", 10*time.Hour, 14*time.Hour),
+				leg(3, "NRT", "HND", 26*time.Hour, time.Hour),
+				leg(4, "HND", "NRT", 28*time.Hour, time.Hour),
+			},
+			itineraries: [][]int{{1, 2, 3}},
+			standalone:  []int{4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			standalone, itineraries := chainTrips(test.trips, m.DefaultMaxLayover)
+			if got := legIDs(itineraries); !reflect.DeepEqual(got, test.itineraries) {
+				t.Errorf("itineraries %v, want %v", got, test.itineraries)
+			}
+			if got := tripIDs(standalone); !reflect.DeepEqual(got, test.standalone) {
+				t.Errorf("standalone %v, want %v", got, test.standalone)
+			}
+		})
+	}
+}
+
+func TestPinItinerary(t *testing.T) {
+	db := newTestDB(t)
+	store := NewTripStore(NewTripStoreParams{DB: db})
+	userID := newTestUser(t, db, "owner")
+	otherID := newTestUser(t, db, "other")
+
+	// Days apart, so nothing is chained on its own
+	create := func(user int, trip m.Trip) int {
+		t.Helper()
+		trip.UserId = user
+		id, err := store.CreateTrip(trip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+	a := create(userID, leg(0, "JFK", "NRT", 0, 14*time.Hour))
+	b := create(userID, leg(0, "NRT", "HND", 72*time.Hour, time.Hour))
+	c := create(userID, leg(0, "HND", "LHR", 144*time.Hour, 14*time.Hour))
+	others := create(otherID, leg(0, "LHR", "JFK", 200*time.Hour, 8*time.Hour))
+
+	assertItineraries := func(want [][]int, standaloneWant []int) {
+		t.Helper()
+		standalone, itineraries, err := store.GetItinerariesGivenUser(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := legIDs(itineraries); !reflect.DeepEqual(got, want) {
+			t.Errorf("itineraries %v, want %v", got, want)
+		}
+		if got := tripIDs(standalone); !reflect.DeepEqual(got, standaloneWant) {
+			t.Errorf("standalone %v, want %v", got, standaloneWant)
+		}
+		for _, itinerary := range itineraries {
+			if !itinerary.Pinned || itinerary.ID == 0 {
+				t.Errorf("itinerary %v is not pinned", itinerary.TripIDs())
+			}
+		}
+	}
+	assertItineraries(nil, []int{a, b, c})
+
+	// Legs are ordered by departure and a flight listed twice is one leg
+	first, err := store.PinItinerary(userID, []int{b, a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItineraries([][]int{{a, b}}, []int{c})
+
+	for name, ids := range map[string][]int{
+		"one flight":             {a, a},
+		"another user's flight":  {c, others},
+		"a flight that is gone":  {c, 9999},
+		"an empty list of legs":  nil,
+		"one flight of the list": {c},
+	} {
+		if _, err := store.PinItinerary(userID, ids); err == nil {
+			t.Errorf("pinning %s: no error", name)
+		}
+	}
+	if _, err := store.PinItinerary(userID, []int{c, others}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pinning another user's flight: %v, want ErrNotFound", err)
+	}
+	assertItineraries([][]int{{a, b}}, []int{c})
+
+	// Pinning b again moves it, the first itinerary is left with one leg and removed
+	second, err := store.PinItinerary(userID, []int{c, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItineraries([][]int{{b, c}}, []int{a})
+	if err := store.UnpinItinerary(userID, int(first)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("the emptied itinerary is still there: %v", err)
+	}
+
+	// Removing a leg of a two leg itinerary removes the itinerary
+	if err := store.UnpinLeg(userID, c); err != nil {
+		t.Fatal(err)
+	}
+	assertItineraries(nil, []int{a, b, c})
+	if err := store.UnpinLeg(userID, c); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unpinning a leg that is not pinned: %v, want ErrNotFound", err)
+	}
+	if err := store.UnpinItinerary(userID, int(second)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unpinning a removed itinerary: %v, want ErrNotFound", err)
+	}
+
+	third, err := store.PinItinerary(userID, []int{a, b, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UnpinItinerary(otherID, int(third)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("another user unpinned the itinerary: %v", err)
+	}
+	if err := store.UnpinItinerary(userID, int(third)); err != nil {
+		t.Fatal(err)
+	}
+	assertItineraries(nil, []int{a, b, c})
+
+	// Flights in the trash can not be pinned
+	if err := store.DeleteTrip(a, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.PinItinerary(userID, []int{a, b}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pinning a trashed flight: %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,334 @@
+package database
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+type JourneyStore struct {
+	db *sql.DB
+}
+
+type NewJourneyStoreParams struct {
+	DB *sql.DB
+}
+
+func NewJourneyStore(params NewJourneyStoreParams) *JourneyStore {
+	return &JourneyStore{db: params.DB}
+}
+
+// Journeys only store which flights and places belong to them. The members
+// themselves are loaded through TripStore and PlaceStore, the same way
+// PlaceStore.GetCombinedTimeline does, so timezones and airport coordinates
+// are resolved in one place.
+
+const journeyColumns = `
+	id, user_id, title, start_date, end_date, cover_location, created_at, updated_at,
+	(SELECT COUNT(*) FROM journey_trips jt JOIN trips t ON t.id = jt.trip_id WHERE jt.journey_id = journeys.id AND t.deleted_at IS NULL) AS trip_count,
+	(SELECT COUNT(*) FROM journey_places jp JOIN places p ON p.id = jp.place_id WHERE jp.journey_id = journeys.id AND p.deleted_at IS NULL) AS place_count
+`
+
+func scanJourney(row rowScanner) (m.Journey, error) {
+	var journey m.Journey
+	err := row.Scan(
+		&journey.ID,
+		&journey.UserID,
+		&journey.Title,
+		&journey.StartDate,
+		&journey.EndDate,
+		&journey.CoverLocation,
+		&journey.CreatedAt,
+		&journey.UpdatedAt,
+		&journey.TripCount,
+		&journey.PlaceCount,
+	)
+	return journey, err
+}
+
+// CreateJourney inserts a new journey without any members
+func (j *JourneyStore) CreateJourney(journey m.Journey) (int, error) {
+	now := uint32(time.Now().Unix())
+
+	result, err := j.db.Exec(`
+		INSERT INTO journeys (user_id, title, start_date, end_date, cover_location, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		journey.UserID,
+		journey.Title,
+		journey.StartDate,
+		journey.EndDate,
+		journey.CoverLocation,
+		now,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetJourneysForUser returns the user's journeys, most recent first, with member counts
+func (j *JourneyStore) GetJourneysForUser(userID int) ([]m.Journey, error) {
+	rows, err := j.db.Query(`
+		SELECT `+journeyColumns+`
+		FROM journeys
+		WHERE user_id = ?
+		ORDER BY start_date DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var journeys []m.Journey
+	for rows.Next() {
+		journey, err := scanJourney(rows)
+		if err != nil {
+			return nil, err
+		}
+		journeys = append(journeys, journey)
+	}
+	return journeys, rows.Err()
+}
+
+// GetJourneyByID returns a journey without its members
+func (j *JourneyStore) GetJourneyByID(journeyID, userID int) (m.Journey, error) {
+	row := j.db.QueryRow(`
+		SELECT `+journeyColumns+`
+		FROM journeys
+		WHERE id = ? AND user_id = ?`, journeyID, userID)
+	return scanJourney(row)
+}
+
+// GetJourneyWithMembers returns a journey with its flights and places ordered by date
+func (j *JourneyStore) GetJourneyWithMembers(journeyID, userID int, tripStore *TripStore, placeStore *PlaceStore) (m.Journey, error) {
+	journey, err := j.GetJourneyByID(journeyID, userID)
+	if err != nil {
+		return journey, err
+	}
+
+	tripIDs, placeIDs, err := j.getMemberIDs(journeyID)
+	if err != nil {
+		return journey, err
+	}
+
+	trips, err := tripStore.GetTripsGivenUser(userID)
+	if err != nil {
+		return journey, err
+	}
+	for _, trip := range trips {
+		if tripIDs[trip.ID] {
+			journey.Trips = append(journey.Trips, trip)
+		}
+	}
+
+	places, err := placeStore.GetPlacesForUser(userID)
+	if err != nil {
+		return journey, err
+	}
+	for _, place := range places {
+		if placeIDs[place.ID] {
+			journey.Places = append(journey.Places, place)
+		}
+	}
+
+	sort.Slice(journey.Trips, func(a, b int) bool {
+		return journey.Trips[a].DepartureTime < journey.Trips[b].DepartureTime
+	})
+	sort.Slice(journey.Places, func(a, b int) bool {
+		return journey.Places[a].VisitDate < journey.Places[b].VisitDate
+	})
+
+	return journey, nil
+}
+
+// GetJourneySuggestions returns the user's flights departing and places visited
+// within the journey's date range that are not members yet, see
+// Journey.ContainsTrip for the day a flight departs on
+func (j *JourneyStore) GetJourneySuggestions(journey m.Journey, tripStore *TripStore, placeStore *PlaceStore) ([]m.Trip, []m.Place, error) {
+	tripIDs, placeIDs, err := j.getMemberIDs(journey.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trips, err := tripStore.GetTripsGivenUser(journey.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var suggestedTrips []m.Trip
+	for _, trip := range trips {
+		if !tripIDs[trip.ID] && journey.ContainsTrip(trip) {
+			suggestedTrips = append(suggestedTrips, trip)
+		}
+	}
+
+	places, err := placeStore.GetPlacesForUser(journey.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var suggestedPlaces []m.Place
+	for _, place := range places {
+		if !placeIDs[place.ID] && journey.ContainsPlace(place) {
+			suggestedPlaces = append(suggestedPlaces, place)
+		}
+	}
+
+	sort.Slice(suggestedTrips, func(a, b int) bool {
+		return suggestedTrips[a].DepartureTime < suggestedTrips[b].DepartureTime
+	})
+	sort.Slice(suggestedPlaces, func(a, b int) bool {
+		return suggestedPlaces[a].VisitDate < suggestedPlaces[b].VisitDate
+	})
+
+	return suggestedTrips, suggestedPlaces, nil
+}
+
+func (j *JourneyStore) getMemberIDs(journeyID int) (map[int]bool, map[int]bool, error) {
+	tripIDs, err := j.queryIDs(`SELECT trip_id FROM journey_trips WHERE journey_id = ?`, journeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	placeIDs, err := j.queryIDs(`SELECT place_id FROM journey_places WHERE journey_id = ?`, journeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tripIDs, placeIDs, nil
+}
+
+func (j *JourneyStore) queryIDs(q string, args ...any) (map[int]bool, error) {
+	rows, err := j.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// UpdateJourney updates the title, dates and cover location. Members are left untouched.
+func (j *JourneyStore) UpdateJourney(journey m.Journey) error {
+	res, err := j.db.Exec(`
+		UPDATE journeys
+		SET title = ?, start_date = ?, end_date = ?, cover_location = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`,
+		journey.Title,
+		journey.StartDate,
+		journey.EndDate,
+		journey.CoverLocation,
+		uint32(time.Now().Unix()),
+		journey.ID,
+		journey.UserID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteJourney deletes the journey, its flights and places are kept
+func (j *JourneyStore) DeleteJourney(journeyID, userID int) error {
+	tx, err := j.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM journeys WHERE id = ? AND user_id = ?`, journeyID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM journey_trips WHERE journey_id = ?`, journeyID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM journey_places WHERE journey_id = ?`, journeyID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddTripToJourney adds one of the user's flights to one of their journeys.
+// Adding a flight that is already a member is a no-op.
+func (j *JourneyStore) AddTripToJourney(journeyID, tripID, userID int) error {
+	return j.addMember(
+		`SELECT 1 FROM journeys j, trips t WHERE j.id = ?1 AND t.id = ?2 AND j.user_id = ?3 AND t.user_id = ?3 AND t.deleted_at IS NULL`,
+		`INSERT OR IGNORE INTO journey_trips (journey_id, trip_id) VALUES (?, ?)`,
+		journeyID, tripID, userID)
+}
+
+// AddPlaceToJourney adds one of the user's places to one of their journeys.
+// Adding a place that is already a member is a no-op.
+func (j *JourneyStore) AddPlaceToJourney(journeyID, placeID, userID int) error {
+	return j.addMember(
+		`SELECT 1 FROM journeys j, places p WHERE j.id = ?1 AND p.id = ?2 AND j.user_id = ?3 AND p.user_id = ?3 AND p.deleted_at IS NULL`,
+		`INSERT OR IGNORE INTO journey_places (journey_id, place_id) VALUES (?, ?)`,
+		journeyID, placeID, userID)
+}
+
+// addMember only inserts when ownedQuery finds both the journey and the member
+// belong to the user, otherwise sql.ErrNoRows is returned
+func (j *JourneyStore) addMember(ownedQuery, insertQuery string, journeyID, memberID, userID int) error {
+	tx, err := j.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owned int
+	if err := tx.QueryRow(ownedQuery, journeyID, memberID, userID).Scan(&owned); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(insertQuery, journeyID, memberID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveTripFromJourney removes a flight from the journey, the flight itself is kept
+func (j *JourneyStore) RemoveTripFromJourney(journeyID, tripID, userID int) error {
+	return j.removeMember(`
+		DELETE FROM journey_trips
+		WHERE journey_id = ? AND trip_id = ?
+		AND journey_id IN (SELECT id FROM journeys WHERE user_id = ?)`,
+		journeyID, tripID, userID)
+}
+
+// RemovePlaceFromJourney removes a place from the journey, the place itself is kept
+func (j *JourneyStore) RemovePlaceFromJourney(journeyID, placeID, userID int) error {
+	return j.removeMember(`
+		DELETE FROM journey_places
+		WHERE journey_id = ? AND place_id = ?
+		AND journey_id IN (SELECT id FROM journeys WHERE user_id = ?)`,
+		journeyID, placeID, userID)
+}
+
+func (j *JourneyStore) removeMember(q string, journeyID, memberID, userID int) error {
+	res, err := j.db.Exec(q, journeyID, memberID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
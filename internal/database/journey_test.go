@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+func TestJourneySuggestionsAndMembers(t *testing.T) {
+	db := newTestDB(t)
+	userID := newTestUser(t, db, "owner")
+	otherID := newTestUser(t, db, "other")
+	tripStore := NewTripStore(NewTripStoreParams{DB: db})
+	placeStore := NewPlaceStore(db)
+	journeyStore := NewJourneyStore(NewJourneyStoreParams{DB: db})
+
+	newYork, _ := time.LoadLocation("America/New_York")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	createTrip := func(userID int, from, to string, departure time.Time) int {
+		t.Helper()
+		id, err := tripStore.CreateTrip(m.Trip{UserId: userID, Departure: from, Arrival: to,
+			DepartureTime: uint32(departure.Unix()), ArrivalTime: uint32(departure.Add(14 * time.Hour).Unix())})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+	createPlace := func(name string, year int, month time.Month, day int) int {
+		t.Helper()
+		id, err := placeStore.CreatePlace(m.Place{UserID: userID, Name: name, VisitDate: uint32(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix())})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// The journey runs from 2025-04-01 to 2025-04-10, the flights are on its
+	// first and last day at the airport and on the day before or after in UTC
+	journey := m.Journey{
+		UserID:    userID,
+		Title:     "Japan",
+		StartDate: uint32(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		EndDate:   uint32(time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC).Unix()),
+	}
+	var err error
+	journey.ID, err = journeyStore.CreateJourney(journey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbound := createTrip(userID, "JFK", "NRT", time.Date(2025, 4, 10, 20, 0, 0, 0, newYork))
+	domestic := createTrip(userID, "NRT", "HND", time.Date(2025, 4, 1, 8, 0, 0, 0, tokyo))
+	createTrip(userID, "JFK", "NRT", time.Date(2025, 3, 31, 22, 0, 0, 0, newYork))
+	createTrip(userID, "HND", "LHR", time.Date(2025, 4, 11, 7, 0, 0, 0, tokyo))
+	createTrip(otherID, "JFK", "NRT", time.Date(2025, 4, 5, 10, 0, 0, 0, newYork))
+	trashed := createTrip(userID, "NRT", "HND", time.Date(2025, 4, 5, 10, 0, 0, 0, tokyo))
+	if err := tripStore.DeleteTrip(trashed, userID); err != nil {
+		t.Fatal(err)
+	}
+	temple := createPlace("Temple", 2025, time.April, 10)
+	createPlace("Before", 2025, time.March, 31)
+	createPlace("After", 2025, time.April, 11)
+
+	suggestions := func() ([]int, []int) {
+		t.Helper()
+		trips, places, err := journeyStore.GetJourneySuggestions(journey, tripStore, placeStore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var placeIDs []int
+		for _, place := range places {
+			placeIDs = append(placeIDs, place.ID)
+		}
+		return tripIDs(trips), placeIDs
+	}
+	members := func() m.Journey {
+		t.Helper()
+		withMembers, err := journeyStore.GetJourneyWithMembers(journey.ID, userID, tripStore, placeStore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return withMembers
+	}
+
+	trips, places := suggestions()
+	if want := []int{domestic, outbound}; !reflect.DeepEqual(trips, want) {
+		t.Errorf("suggested flights %v, want %v", trips, want)
+	}
+	if want := []int{temple}; !reflect.DeepEqual(places, want) {
+		t.Errorf("suggested places %v, want %v", places, want)
+	}
+
+	// Members are no longer suggested, adding one twice keeps one membership
+	for i := 0; i < 2; i++ {
+		if err := journeyStore.AddTripToJourney(journey.ID, outbound, userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := journeyStore.AddPlaceToJourney(journey.ID, temple, userID); err != nil {
+		t.Fatal(err)
+	}
+	if trips, places := suggestions(); !reflect.DeepEqual(trips, []int{domestic}) || len(places) != 0 {
+		t.Errorf("suggestions with members: %v %v", trips, places)
+	}
+	if got := members(); !reflect.DeepEqual(tripIDs(got.Trips), []int{outbound}) || len(got.Places) != 1 || got.TripCount != 1 || got.PlaceCount != 1 {
+		t.Errorf("members: %v %+v", tripIDs(got.Trips), got)
+	}
+
+	// Only the user's own flights outside the trash join their own journeys
+	otherJourney, err := journeyStore.CreateJourney(m.Journey{UserID: otherID, Title: "Other", StartDate: journey.StartDate, EndDate: journey.EndDate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"a trip in the trash":     journeyStore.AddTripToJourney(journey.ID, trashed, userID),
+		"another user's journey":  journeyStore.AddTripToJourney(otherJourney, domestic, userID),
+		"as another user":         journeyStore.AddTripToJourney(journey.ID, domestic, otherID),
+		"a place that is missing": journeyStore.AddPlaceToJourney(journey.ID, 9999, userID),
+	} {
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("adding %s: %v, want sql.ErrNoRows", name, err)
+		}
+	}
+
+	// A member in the trash is not listed until it is restored
+	if err := tripStore.DeleteTrip(outbound, userID); err != nil {
+		t.Fatal(err)
+	}
+	if got := members(); len(got.Trips) != 0 || got.TripCount != 0 {
+		t.Errorf("a flight in the trash is listed: %v, count %d", tripIDs(got.Trips), got.TripCount)
+	}
+	if err := tripStore.RestoreTrip(outbound, userID); err != nil {
+		t.Fatal(err)
+	}
+	if got := members(); got.TripCount != 1 {
+		t.Errorf("the restored flight is not a member again")
+	}
+
+	// Removed flights are suggested again, the flight itself is kept
+	if err := journeyStore.RemoveTripFromJourney(journey.ID, outbound, userID); err != nil {
+		t.Fatal(err)
+	}
+	if err := journeyStore.RemoveTripFromJourney(journey.ID, outbound, userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("removing a flight that is not a member: %v", err)
+	}
+	if trips, _ := suggestions(); !reflect.DeepEqual(trips, []int{domestic, outbound}) {
+		t.Errorf("suggestions after removing a member: %v", trips)
+	}
+
+	// Deleting the journey keeps its flights and places
+	if err := journeyStore.DeleteJourney(journey.ID, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := placeStore.GetPlaceByID(temple, userID); err != nil {
+		t.Errorf("the place was deleted with its journey: %v", err)
+	}
+	if _, err := journeyStore.GetJourneyByID(journey.ID, userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("the journey is still there: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Handles the functions accessing table mail_inboxes
+type MailInboxStore struct {
+	db *sql.DB
+}
+
+type NewMailInboxStoreParams struct {
+	DB *sql.DB
+}
+
+func NewMailInboxStore(params NewMailInboxStoreParams) *MailInboxStore {
+	return &MailInboxStore{db: params.DB}
+}
+
+// RotateMailInbox generates a new forwarding address for the user, mail to the
+// previous address is refused. The token is 32 lowercase hex characters, mail
+// servers may change the case of the local part so lookups ignore it.
+func (s *MailInboxStore) RotateMailInbox(userID int) (m.MailInbox, error) {
+	rawToken := make([]byte, 16)
+	if _, err := rand.Read(rawToken); err != nil {
+		return m.MailInbox{}, err
+	}
+	inbox := m.MailInbox{
+		UserID:    userID,
+		Token:     hex.EncodeToString(rawToken),
+		CreatedAt: uint32(time.Now().Unix()),
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO mail_inboxes (user_id, token_hash, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		inbox.UserID, hashToken(inbox.Token), inbox.CreatedAt)
+	if err != nil {
+		return m.MailInbox{}, err
+	}
+	return inbox, nil
+}
+
+func (s *MailInboxStore) RevokeMailInbox(userID int) error {
+	_, err := s.db.Exec(`DELETE FROM mail_inboxes WHERE user_id = ?`, userID)
+	return err
+}
+
+// GetMailInbox returns nil when the user has no forwarding address
+func (s *MailInboxStore) GetMailInbox(userID int) (*m.MailInbox, error) {
+	inbox := m.MailInbox{UserID: userID}
+	err := s.db.QueryRow(`SELECT created_at FROM mail_inboxes WHERE user_id = ?`, userID).Scan(&inbox.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inbox, nil
+}
+
+// GetUserIDForMailToken returns sql.ErrNoRows for unknown or revoked tokens
+func (s *MailInboxStore) GetUserIDForMailToken(token string) (int, error) {
+	var userID int
+	err := s.db.QueryRow(`SELECT user_id FROM mail_inboxes WHERE token_hash = ?`,
+		hashToken(strings.ToLower(token))).Scan(&userID)
+	return userID, err
+}
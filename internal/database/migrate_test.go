@@ -0,0 +1,127 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := OpenDB("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("migrations do not start at version 1: %+v", migrations)
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("migration %d comes after %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+
+	// A dry run lists everything and changes nothing
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(migrations) {
+		t.Errorf("%d pending migrations on an empty database, want %d", len(pending), len(migrations))
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil || tables != 0 {
+		t.Errorf("the dry run created %d tables: %v", tables, err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, state := range states {
+		if !state.Applied || state.AppliedAt == nil || state.Version != migrations[i].Version {
+			t.Errorf("migration %04d_%s is not applied: %+v", migrations[i].Version, migrations[i].Name, state)
+		}
+	}
+
+	// Running again applies nothing
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrating twice: %v", err)
+	}
+	if pending, err := PendingMigrations(db); err != nil || len(pending) != 0 {
+		t.Errorf("pending after migrating: %+v %v", pending, err)
+	}
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil || applied != len(migrations) {
+		t.Errorf("schema_migrations has %d rows, want %d: %v", applied, len(migrations), err)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := newTestDB(t)
+
+	err := applyMigration(db, Migration{Version: 9999, Name: "broken", SQL: `
+		CREATE TABLE half_done (id INTEGER PRIMARY KEY);
+		INSERT INTO no_such_table VALUES (1);`})
+	if err == nil {
+		t.Fatal("the broken migration was applied")
+	}
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'`).Scan(&count)
+	if count != 0 {
+		t.Errorf("the table of the failed migration was kept")
+	}
+	db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = 9999`).Scan(&count)
+	if count != 0 {
+		t.Errorf("the failed migration was recorded as applied")
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	db := newTestDB(t)
+
+	var enabled int
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&enabled); err != nil || enabled != 1 {
+		t.Fatalf("foreign keys are not enforced: %d %v", enabled, err)
+	}
+
+	userID := newTestUser(t, db, "owner")
+	if _, err := db.Exec(`
+		INSERT INTO trips (user_id, departure, arrival, departure_time, arrival_time, airline, flight_number)
+		VALUES (?, 'JFK', 'NRT', 1, 2, '', '')`, userID+1); err == nil {
+		t.Errorf("a trip of a user that does not exist was saved")
+	}
+	if _, err := db.Exec(`
+		INSERT INTO trips (user_id, departure, arrival, departure_time, arrival_time, airline, flight_number)
+		VALUES (?, 'JFK', 'NRT', 1, 2, '', '')`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM users WHERE id = ?`, userID); err != nil {
+		t.Fatal(err)
+	}
+	var trips int
+	db.QueryRow(`SELECT COUNT(*) FROM trips`).Scan(&trips)
+	if trips != 0 {
+		t.Errorf("deleting the user left %d trips", trips)
+	}
+}
+
+func TestWithForeignKeys(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"trips.db":                               "trips.db?_foreign_keys=1",
+		"file:trips.db?_enable_math_functions=1": "file:trips.db?_enable_math_functions=1&_foreign_keys=1",
+		"file:trips.db?_foreign_keys=0":          "file:trips.db?_foreign_keys=0",
+		"file:trips.db?_fk=false":                "file:trips.db?_fk=false",
+	} {
+		if got := withForeignKeys(dsn); got != want {
+			t.Errorf("withForeignKeys(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
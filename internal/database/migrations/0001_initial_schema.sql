@@ -1,3 +1,6 @@
+-- Initial schema. Every statement is guarded with IF NOT EXISTS so databases
+-- that were created by hand before migrations existed upgrade cleanly.
+
 CREATE TABLE IF NOT EXISTS airports (
     iata_code TEXT PRIMARY KEY,
     name TEXT,
@@ -68,13 +71,13 @@ CREATE TABLE IF NOT EXISTS places (
     FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_trips_user_id ON trips(user_id);
-CREATE INDEX idx_trips_departure ON trips(departure);
-CREATE INDEX idx_trips_arrival ON trips(arrival);
-CREATE INDEX idx_users_username ON users(username);
-CREATE INDEX idx_users_email ON users(email);
-CREATE INDEX idx_sessions_user_id ON sessions(user_id);
-CREATE INDEX idx_sessions_session_id ON sessions(session_id);
+CREATE INDEX IF NOT EXISTS idx_trips_user_id ON trips(user_id);
+CREATE INDEX IF NOT EXISTS idx_trips_departure ON trips(departure);
+CREATE INDEX IF NOT EXISTS idx_trips_arrival ON trips(arrival);
+CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_session_id ON sessions(session_id);
 CREATE INDEX IF NOT EXISTS idx_places_user_id ON places(user_id);
 CREATE INDEX IF NOT EXISTS idx_places_visit_date ON places(visit_date);
-CREATE INDEX IF NOT EXISTS idx_places_category ON places(category);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_places_category ON places(category);
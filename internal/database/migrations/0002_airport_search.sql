@@ -0,0 +1,8 @@
+-- Extra airport columns needed for ICAO lookups and searching by city
+ALTER TABLE airports ADD COLUMN icao_code TEXT;
+ALTER TABLE airports ADD COLUMN city TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_airports_icao_code ON airports(icao_code);
+CREATE INDEX IF NOT EXISTS idx_airports_name ON airports(name COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS idx_airports_city ON airports(city COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS idx_airports_lat_lon ON airports(latitude, longitude);
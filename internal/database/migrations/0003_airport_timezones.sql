@@ -0,0 +1,3 @@
+-- Store the IANA timezone next to the airport so it can't drift from the
+-- coordinates. Filled in by the airport importer (go run . -import-airports).
+ALTER TABLE airports ADD COLUMN timezone TEXT;
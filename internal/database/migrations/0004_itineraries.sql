@@ -0,0 +1,23 @@
+-- Longest layover (in minutes) for two flights to be chained into one itinerary
+ALTER TABLE users ADD COLUMN max_layover_minutes INTEGER NOT NULL DEFAULT 1440;
+
+-- Itineraries the user pinned. Pinned legs are always shown together and are
+-- skipped by the automatic layover based chaining.
+CREATE TABLE IF NOT EXISTS itineraries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS itinerary_legs (
+    itinerary_id INTEGER NOT NULL,
+    trip_id INTEGER NOT NULL UNIQUE, -- A flight belongs to at most one itinerary
+    position INTEGER NOT NULL,
+    PRIMARY KEY (itinerary_id, trip_id),
+    FOREIGN KEY (itinerary_id) REFERENCES itineraries(id) ON DELETE CASCADE,
+    FOREIGN KEY (trip_id) REFERENCES trips(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_itineraries_user_id ON itineraries(user_id);
+CREATE INDEX IF NOT EXISTS idx_itinerary_legs_trip_id ON itinerary_legs(trip_id);
@@ -0,0 +1,33 @@
+-- Journeys group flights and places into one named trip, e.g. "Japan 2025".
+-- Dates are unix timestamps at midnight UTC, end_date is the last day of the journey.
+CREATE TABLE IF NOT EXISTS journeys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    start_date INTEGER NOT NULL,
+    end_date INTEGER NOT NULL,
+    cover_location TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS journey_trips (
+    journey_id INTEGER NOT NULL,
+    trip_id INTEGER NOT NULL,
+    PRIMARY KEY (journey_id, trip_id),
+    FOREIGN KEY (journey_id) REFERENCES journeys(id) ON DELETE CASCADE,
+    FOREIGN KEY (trip_id) REFERENCES trips(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS journey_places (
+    journey_id INTEGER NOT NULL,
+    place_id INTEGER NOT NULL,
+    PRIMARY KEY (journey_id, place_id),
+    FOREIGN KEY (journey_id) REFERENCES journeys(id) ON DELETE CASCADE,
+    FOREIGN KEY (place_id) REFERENCES places(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_journeys_user_id ON journeys(user_id);
+CREATE INDEX IF NOT EXISTS idx_journey_trips_trip_id ON journey_trips(trip_id);
+CREATE INDEX IF NOT EXISTS idx_journey_places_place_id ON journey_places(place_id);
@@ -0,0 +1,8 @@
+-- Deleting a trip or place moves it to the trash by setting deleted_at (unix time).
+-- Rows stay in the trash until the user restores or purges them, or the purge job
+-- removes them once the retention period has passed.
+ALTER TABLE trips ADD COLUMN deleted_at INTEGER;
+ALTER TABLE places ADD COLUMN deleted_at INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_trips_deleted_at ON trips(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_places_deleted_at ON places(deleted_at);
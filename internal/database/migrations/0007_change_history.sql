@@ -0,0 +1,16 @@
+-- Audit trail for trips and places. Every create, update, delete, restore and
+-- revert stores the row as JSON before and after the change. before_json is
+-- NULL for creates.
+CREATE TABLE IF NOT EXISTS change_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,      -- The user that made the change
+    entity_type TEXT NOT NULL,     -- "trip" or "place"
+    entity_id INTEGER NOT NULL,
+    action TEXT NOT NULL,          -- "create", "update", "delete", "restore" or "revert"
+    before_json TEXT,
+    after_json TEXT,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_history_entity ON change_history(entity_type, entity_id);
@@ -0,0 +1,28 @@
+-- One searchable document per trip and place that is not in the trash.
+-- The FTS5 index (see search.go) is filled from this view, builds of the sqlite
+-- driver without FTS5 search the view directly.
+CREATE VIEW IF NOT EXISTS search_documents AS
+SELECT
+    'trip' AS entity_type,
+    t.id AS entity_id,
+    t.user_id AS user_id,
+    t.departure_time AS date,
+    t.departure || ' → ' || t.arrival AS title,
+    t.airline || ' ' || t.flight_number || COALESCE(' · ' || NULLIF(t.reservation, ''), '') AS subtitle,
+    COALESCE(d.name, t.departure) || COALESCE(' (' || d.city || ')', '') || ' → ' ||
+        COALESCE(a.name, t.arrival) || COALESCE(' (' || a.city || ')', '') AS body
+FROM trips t
+LEFT JOIN airports d ON d.iata_code = t.departure
+LEFT JOIN airports a ON a.iata_code = t.arrival
+WHERE t.deleted_at IS NULL
+UNION ALL
+SELECT
+    'place' AS entity_type,
+    p.id AS entity_id,
+    p.user_id AS user_id,
+    p.visit_date AS date,
+    p.name AS title,
+    LTRIM(COALESCE(p.address, '') || COALESCE(' · ' || NULLIF(p.category, ''), ''), ' ·') AS subtitle,
+    COALESCE(p.notes, '') AS body
+FROM places p
+WHERE p.deleted_at IS NULL;
@@ -0,0 +1,10 @@
+-- Optional details of a flight. cabin_class is one of economy, premium_economy,
+-- business or first. The distance is not stored, it is computed from the
+-- airport coordinates when trips are read.
+ALTER TABLE trips ADD COLUMN seat TEXT;
+ALTER TABLE trips ADD COLUMN cabin_class TEXT;
+ALTER TABLE trips ADD COLUMN aircraft_type TEXT;
+ALTER TABLE trips ADD COLUMN tail_number TEXT;
+ALTER TABLE trips ADD COLUMN booking_class TEXT;
+ALTER TABLE trips ADD COLUMN ticket_price REAL;
+ALTER TABLE trips ADD COLUMN ticket_currency TEXT;
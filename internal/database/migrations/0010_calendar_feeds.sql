@@ -0,0 +1,8 @@
+-- Secret calendar subscription token per user. Only the SHA-256 hash of the
+-- token is stored, rotating replaces the row and revoking deletes it.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id INTEGER PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
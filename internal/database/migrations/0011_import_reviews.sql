@@ -0,0 +1,20 @@
+-- Rows of imported flight logs that could not be turned into a trip, kept
+-- until the user fixes or discards them. source is the row as it appeared in
+-- the file, uploading the same file again does not queue a row twice.
+CREATE TABLE IF NOT EXISTS import_reviews (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    format TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    problem TEXT NOT NULL,
+    trip TEXT NOT NULL,
+    departure_local TEXT NOT NULL DEFAULT '',
+    arrival_local TEXT NOT NULL DEFAULT '',
+    duration_minutes INTEGER NOT NULL DEFAULT 0,
+    source TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    UNIQUE (user_id, format, source),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_reviews_user ON import_reviews(user_id, id);
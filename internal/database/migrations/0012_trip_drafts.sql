@@ -0,0 +1,28 @@
+-- Flights read from forwarded confirmation emails, kept as drafts until the
+-- user confirms or discards them. The same email delivered twice does not
+-- queue its flights again.
+CREATE TABLE IF NOT EXISTS trip_drafts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    trip TEXT NOT NULL,
+    departure_local TEXT NOT NULL DEFAULT '',
+    arrival_local TEXT NOT NULL DEFAULT '',
+    subject TEXT NOT NULL DEFAULT '',
+    sender TEXT NOT NULL DEFAULT '',
+    message_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    UNIQUE (user_id, message_id, position),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_drafts_user ON trip_drafts(user_id, id);
+
+-- Secret forwarding address per user, the token is the local part of the
+-- address. Only its SHA-256 hash is stored like calendar feed tokens.
+CREATE TABLE IF NOT EXISTS mail_inboxes (
+    user_id INTEGER PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
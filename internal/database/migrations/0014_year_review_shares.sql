@@ -0,0 +1,11 @@
+-- Public links to the year in review card, one per user and year. Only the
+-- SHA-256 hash of the token is stored, publishing again replaces the row and
+-- unpublishing deletes it.
+CREATE TABLE IF NOT EXISTS year_review_shares (
+    user_id INTEGER NOT NULL,
+    year INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, year),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
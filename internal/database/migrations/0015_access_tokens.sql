@@ -0,0 +1,17 @@
+-- Personal access tokens for scripts calling the API. Only the SHA-256 hash of
+-- the token is stored; scopes is a comma separated list such as
+-- "trips:read,stats:read". Expired tokens are kept until they are revoked so
+-- the settings page can show them.
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    last_used_at INTEGER,
+    expires_at INTEGER,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_tokens_user ON access_tokens(user_id);
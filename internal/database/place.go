@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"sort"
 	"time"
 
@@ -27,7 +29,13 @@ func (p *PlaceStore) CreatePlace(place m.Place) (int, error) {
         ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	result, err := p.db.Exec(
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
 		query,
 		place.UserID,
 		place.PlaceID,
@@ -52,7 +60,11 @@ func (p *PlaceStore) CreatePlace(place m.Place) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	if err := recordPlaceChange(tx, place.UserID, int(id), m.ChangeCreate, nil); err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
 }
 
 // GetPlacesForUser retrieves all places for a user
@@ -62,7 +74,7 @@ func (p *PlaceStore) GetPlacesForUser(userID int) ([]m.Place, error) {
             id, user_id, place_id, name, address, latitude, longitude,
             visit_date, category, notes, marker_color, created_at, updated_at
         FROM places
-        WHERE user_id = ?
+        WHERE user_id = ? AND deleted_at IS NULL
         ORDER BY visit_date DESC
     `
 
@@ -106,7 +118,7 @@ func (p *PlaceStore) GetPlaceByID(placeID, userID int) (m.Place, error) {
             id, user_id, place_id, name, address, latitude, longitude,
             visit_date, category, notes, marker_color, created_at, updated_at
         FROM places
-        WHERE id = ? AND user_id = ?
+        WHERE id = ? AND user_id = ? AND deleted_at IS NULL
     `
 
 	var place m.Place
@@ -129,18 +141,48 @@ func (p *PlaceStore) GetPlaceByID(placeID, userID int) (m.Place, error) {
 	return place, err
 }
 
-// UpdatePlace updates an existing place
-func (p *PlaceStore) UpdatePlace(place m.Place) error {
+// HasMatchingPlace reports whether the user already has a place with the same
+// name visited on the same day, including places in the trash, so imports
+// do not add it twice
+func (p *PlaceStore) HasMatchingPlace(userID int, place m.Place) (bool, error) {
+	_, err := p.matchingPlaceID(userID, place, true)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetMatchingPlaceID returns the id of the place HasMatchingPlace finds,
+// leaving out places in the trash. ErrNotFound is returned when there is none.
+func (p *PlaceStore) GetMatchingPlaceID(userID int, place m.Place) (int, error) {
+	return p.matchingPlaceID(userID, place, false)
+}
+
+func (p *PlaceStore) matchingPlaceID(userID int, place m.Place, includeTrash bool) (int, error) {
+	var id int
+	err := p.db.QueryRow(`
+        SELECT id FROM places
+        WHERE user_id = ? AND name = ?
+        AND date(visit_date, 'unixepoch') = date(?, 'unixepoch')
+        AND (? OR deleted_at IS NULL)
+        ORDER BY id
+        LIMIT 1`, userID, place.Name, place.VisitDate, includeTrash).Scan(&id)
+	return id, err
+}
+
+// UpdatePlace updates one of the user's places, ErrNotFound is returned when the
+// place does not exist or belongs to someone else
+func (p *PlaceStore) UpdatePlace(place m.Place, userID int) error {
 	query := `
         UPDATE places
         SET name = ?, address = ?, visit_date = ?, category = ?,
             notes = ?, marker_color = ?, updated_at = ?
-        WHERE id = ? AND user_id = ?
+        WHERE id = ? AND user_id = ? AND deleted_at IS NULL
     `
 
 	now := uint32(time.Now().Unix())
 
-	_, err := p.db.Exec(
+	return p.writePlace(place.ID, userID, m.ChangeUpdate,
 		query,
 		place.Name,
 		place.Address,
@@ -150,19 +192,197 @@ func (p *PlaceStore) UpdatePlace(place m.Place) error {
 		place.MarkerColor,
 		now,
 		place.ID,
-		place.UserID,
+		userID,
 	)
+}
 
-	return err
+// writePlace runs a single row update and records the place before and after it
+// in the change history. sql.ErrNoRows is returned when no row was updated.
+func (p *PlaceStore) writePlace(placeID, userID int, action string, query string, args ...any) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireOwner(tx, ResourcePlace, placeID, userID); err != nil {
+		return err
+	}
+
+	before, err := getPlaceRow(tx, placeID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := recordPlaceChange(tx, userID, placeID, action, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func recordPlaceChange(tx *sql.Tx, userID, placeID int, action string, before *m.Place) error {
+	after, err := getPlaceRow(tx, placeID)
+	if err != nil {
+		return err
+	}
+	return recordChange(tx, userID, m.EntityPlace, placeID, action, before, after)
+}
+
+// RevertPlace restores the place to the version saved by an earlier change.
+// The revert itself is recorded as a new change so it can be undone as well.
+func (p *PlaceStore) RevertPlace(changeID, userID int) error {
+	change, err := getChange(p.db, changeID, userID)
+	if err != nil {
+		return err
+	}
+	if change.EntityType != m.EntityPlace || !change.Revertable() {
+		return sql.ErrNoRows
+	}
+
+	var version m.Place
+	if err := json.Unmarshal(change.After, &version); err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE places
+        SET name = ?, address = ?, latitude = ?, longitude = ?, visit_date = ?, category = ?,
+            notes = ?, marker_color = ?, updated_at = ?
+        WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+    `
+
+	return p.writePlace(change.EntityID, userID, m.ChangeRevert,
+		query,
+		version.Name,
+		version.Address,
+		version.Latitude,
+		version.Longitude,
+		version.VisitDate,
+		version.Category,
+		version.Notes,
+		version.MarkerColor,
+		uint32(time.Now().Unix()),
+		change.EntityID,
+		userID,
+	)
 }
 
-// DeletePlace deletes a place
+// DeletePlace moves a place to the trash
 func (p *PlaceStore) DeletePlace(placeID, userID int) error {
-	query := `DELETE FROM places WHERE id = ? AND user_id = ?`
-	_, err := p.db.Exec(query, placeID, userID)
+	query := `UPDATE places SET deleted_at = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL`
+	return p.writePlace(placeID, userID, m.ChangeDelete, query, time.Now().Unix(), placeID, userID)
+}
+
+// GetDeletedPlacesForUser retrieves the places in the user's trash, most recently deleted first
+func (p *PlaceStore) GetDeletedPlacesForUser(userID int) ([]m.Place, error) {
+	query := `
+        SELECT
+            id, user_id, place_id, name, address, latitude, longitude,
+            visit_date, category, notes, marker_color, created_at, updated_at, deleted_at
+        FROM places
+        WHERE user_id = ? AND deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC
+    `
+
+	rows, err := p.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var places []m.Place
+	for rows.Next() {
+		var place m.Place
+		err := rows.Scan(
+			&place.ID,
+			&place.UserID,
+			&place.PlaceID,
+			&place.Name,
+			&place.Address,
+			&place.Latitude,
+			&place.Longitude,
+			&place.VisitDate,
+			&place.Category,
+			&place.Notes,
+			&place.MarkerColor,
+			&place.CreatedAt,
+			&place.UpdatedAt,
+			&place.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		places = append(places, place)
+	}
+
+	return places, rows.Err()
+}
+
+// RestorePlace takes a place out of the trash
+func (p *PlaceStore) RestorePlace(placeID, userID int) error {
+	query := `UPDATE places SET deleted_at = NULL WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL`
+	return p.writePlace(placeID, userID, m.ChangeRestore, query, placeID, userID)
+}
+
+// PurgePlace permanently deletes a place that is in the trash
+func (p *PlaceStore) PurgePlace(placeID, userID int) error {
+	n, err := p.purgePlaces(`id = ? AND user_id = ? AND deleted_at IS NOT NULL`, placeID, userID)
+	if err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
 	return err
 }
 
+// PurgeDeletedPlaces permanently deletes the places in the user's trash
+func (p *PlaceStore) PurgeDeletedPlaces(userID int) (int64, error) {
+	return p.purgePlaces(`user_id = ? AND deleted_at IS NOT NULL`, userID)
+}
+
+// PurgePlacesDeletedBefore permanently deletes every user's places that went to the trash before the cutoff
+func (p *PlaceStore) PurgePlacesDeletedBefore(cutoff time.Time) (int64, error) {
+	return p.purgePlaces(`deleted_at IS NOT NULL AND deleted_at < ?`, cutoff.Unix())
+}
+
+// purgePlaces hard deletes the matching places, their journey memberships go
+// with them through ON DELETE CASCADE, change history and attachments are
+// deleted here
+func (p *PlaceStore) purgePlaces(where string, args ...any) (int64, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		`DELETE FROM change_history WHERE entity_type = 'place' AND entity_id IN (SELECT id FROM places WHERE ` + where + `)`,
+		`DELETE FROM attachments WHERE entity_type = 'place' AND entity_id IN (SELECT id FROM places WHERE ` + where + `)`,
+	} {
+		if _, err := tx.Exec(q, args...); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.Exec(`DELETE FROM places WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
 // GetPlacesFilteredByYear gets places for a specific year
 func (p *PlaceStore) GetPlacesFilteredByYear(userID int, year string) ([]m.Place, error) {
 	query := `
@@ -170,7 +390,7 @@ func (p *PlaceStore) GetPlacesFilteredByYear(userID int, year string) ([]m.Place
             id, user_id, place_id, name, address, latitude, longitude,
             visit_date, category, notes, marker_color, created_at, updated_at
         FROM places
-        WHERE user_id = ?
+        WHERE user_id = ? AND deleted_at IS NULL
         AND strftime('%Y', datetime(visit_date, 'unixepoch')) = ?
         ORDER BY visit_date DESC
     `
@@ -215,7 +435,7 @@ func (p *PlaceStore) GetPlacesFilteredByCategory(userID int, category string) ([
             id, user_id, place_id, name, address, latitude, longitude,
             visit_date, category, notes, marker_color, created_at, updated_at
         FROM places
-        WHERE user_id = ? AND category = ?
+        WHERE user_id = ? AND category = ? AND deleted_at IS NULL
         ORDER BY visit_date DESC
     `
 
@@ -252,13 +472,81 @@ func (p *PlaceStore) GetPlacesFilteredByCategory(userID int, category string) ([
 	return places, nil
 }
 
+const placeColumns = `id, user_id, place_id, name, address, latitude, longitude,
+	visit_date, category, notes, marker_color, created_at, updated_at`
+
+// scanPlace reads a row of placeColumns
+func scanPlace(row rowScanner) (m.Place, error) {
+	var place m.Place
+	err := row.Scan(
+		&place.ID,
+		&place.UserID,
+		&place.PlaceID,
+		&place.Name,
+		&place.Address,
+		&place.Latitude,
+		&place.Longitude,
+		&place.VisitDate,
+		&place.Category,
+		&place.Notes,
+		&place.MarkerColor,
+		&place.CreatedAt,
+		&place.UpdatedAt,
+	)
+	return place, err
+}
+
+// ListPlaces returns a page of the user's places by visit date, oldest first.
+// The page starts after filter.After and holds up to filter.Limit places.
+func (p *PlaceStore) ListPlaces(userID int, filter m.PlaceFilter) ([]m.Place, error) {
+	query := `
+		SELECT ` + placeColumns + `
+		FROM places
+		WHERE user_id = ? AND deleted_at IS NULL`
+	args := []any{userID}
+	if filter.From != 0 {
+		query += ` AND visit_date >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != 0 {
+		query += ` AND visit_date <= ?`
+		args = append(args, filter.To)
+	}
+	if filter.Category != "" {
+		query += ` AND category = ?`
+		args = append(args, filter.Category)
+	}
+	if filter.After != nil {
+		query += ` AND (visit_date > ? OR (visit_date = ? AND id > ?))`
+		args = append(args, filter.After.Time, filter.After.Time, filter.After.ID)
+	}
+	query += ` ORDER BY visit_date, id LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var places []m.Place
+	for rows.Next() {
+		place, err := scanPlace(rows)
+		if err != nil {
+			return nil, err
+		}
+		places = append(places, place)
+	}
+	return places, rows.Err()
+}
+
 // GetPlaceStats returns statistics about places
 func (p *PlaceStore) GetPlaceStats(userID int) (map[string]int, error) {
 	stats := make(map[string]int)
 
 	// Total places
 	var totalPlaces int
-	err := p.db.QueryRow(`SELECT COUNT(*) FROM places WHERE user_id = ?`, userID).Scan(&totalPlaces)
+	err := p.db.QueryRow(`SELECT COUNT(*) FROM places WHERE user_id = ? AND deleted_at IS NULL`, userID).Scan(&totalPlaces)
 	if err != nil {
 		return nil, err
 	}
@@ -268,7 +556,7 @@ func (p *PlaceStore) GetPlaceStats(userID int) (map[string]int, error) {
 	rows, err := p.db.Query(`
         SELECT category, COUNT(*)
         FROM places
-        WHERE user_id = ? AND category IS NOT NULL
+        WHERE user_id = ? AND category IS NOT NULL AND deleted_at IS NULL
         GROUP BY category
     `, userID)
 	if err != nil {
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Search runs against the search_documents view (migration 0008). When the
+// sqlite driver is built with FTS5 (-tags sqlite_fts5) the view is copied into
+// the search_index table, which triggers keep in sync with every write to trips
+// and places. The index only holds derived data so it is created on startup
+// instead of in a migration, builds without FTS5 fall back to LIKE queries
+// against the view.
+
+const searchIndexColumns = `entity_type, entity_id, user_id, date, title, subtitle, body`
+
+const createSearchIndex = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+		entity_type UNINDEXED,
+		entity_id UNINDEXED,
+		user_id UNINDEXED,
+		date UNINDEXED,
+		title,
+		subtitle,
+		body,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)
+`
+
+// searchTriggers re-index a document whenever its row changes. Soft deletes are
+// updates and the view skips rows in the trash, so deleted rows drop out too.
+var searchTriggers = map[string]string{
+	"search_trips_insert":  `AFTER INSERT ON trips BEGIN ` + indexDocument("trip", "new") + ` END`,
+	"search_trips_update":  `AFTER UPDATE ON trips BEGIN ` + unindexDocument("trip", "old") + indexDocument("trip", "new") + ` END`,
+	"search_trips_delete":  `AFTER DELETE ON trips BEGIN ` + unindexDocument("trip", "old") + ` END`,
+	"search_places_insert": `AFTER INSERT ON places BEGIN ` + indexDocument("place", "new") + ` END`,
+	"search_places_update": `AFTER UPDATE ON places BEGIN ` + unindexDocument("place", "old") + indexDocument("place", "new") + ` END`,
+	"search_places_delete": `AFTER DELETE ON places BEGIN ` + unindexDocument("place", "old") + ` END`,
+}
+
+func indexDocument(entityType, row string) string {
+	return `INSERT INTO search_index (` + searchIndexColumns + `)
+		SELECT ` + searchIndexColumns + ` FROM search_documents
+		WHERE entity_type = '` + entityType + `' AND entity_id = ` + row + `.id;`
+}
+
+func unindexDocument(entityType, row string) string {
+	return `DELETE FROM search_index WHERE entity_type = '` + entityType + `' AND entity_id = ` + row + `.id;`
+}
+
+// ftsAvailable reports whether the sqlite driver was compiled with FTS5
+func ftsAvailable(q dbExecutor) (bool, error) {
+	var enabled bool
+	err := q.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&enabled)
+	return enabled, err
+}
+
+// EnsureSearchIndex creates the search index and its triggers. The index is
+// only rebuilt from search_documents when it is new or its triggers are not the
+// ones of this build: a build without FTS5 drops them, so writes made since
+// then are missing from the index, and a release may index other columns.
+// Without FTS5 the triggers are dropped, they would make every write to trips
+// and places fail.
+func EnsureSearchIndex(db *sql.DB) error {
+	fts, err := ftsAvailable(db)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !fts {
+		for name := range searchTriggers {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				return err
+			}
+		}
+		log.Printf("SQLite was built without FTS5, search falls back to LIKE queries. Build with -tags sqlite_fts5 to enable the search index")
+		return tx.Commit()
+	}
+
+	current, err := searchTriggersCurrent(tx)
+	if err != nil || current {
+		return err
+	}
+
+	if _, err := tx.Exec(createSearchIndex); err != nil {
+		return err
+	}
+	for name, body := range searchTriggers {
+		if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`CREATE TRIGGER ` + name + ` ` + body); err != nil {
+			return err
+		}
+	}
+	if err := rebuildSearchIndex(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// searchTriggersCurrent reports whether the search index exists with every
+// trigger as this build creates it
+func searchTriggersCurrent(q dbExecutor) (bool, error) {
+	rows, err := q.Query(`SELECT name, sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name IN ('trips', 'places')`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	matching := 0
+	for rows.Next() {
+		var name, stored string
+		if err := rows.Scan(&name, &stored); err != nil {
+			return false, err
+		}
+		if body, ok := searchTriggers[name]; ok && stored == `CREATE TRIGGER `+name+` `+body {
+			matching++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if matching != len(searchTriggers) {
+		return false, nil
+	}
+
+	var tables int
+	err = q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'search_index'`).Scan(&tables)
+	return tables == 1, err
+}
+
+// RebuildSearchIndex copies search_documents into the index again. Trip
+// documents hold airport names and cities, the triggers only see writes to
+// trips and places so the index is rebuilt after airports are imported.
+// Without FTS5 there is no index and nothing is done.
+func RebuildSearchIndex(db *sql.DB) error {
+	fts, err := ftsAvailable(db)
+	if err != nil || !fts {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := rebuildSearchIndex(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func rebuildSearchIndex(q dbExecutor) error {
+	if _, err := q.Exec(`DELETE FROM search_index`); err != nil {
+		return err
+	}
+	_, err := q.Exec(`INSERT INTO search_index (` + searchIndexColumns + `) SELECT ` + searchIndexColumns + ` FROM search_documents`)
+	return err
+}
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+type NewSearchStoreParams struct {
+	DB *sql.DB
+}
+
+func NewSearchStore(params NewSearchStoreParams) *SearchStore {
+	return &SearchStore{db: params.DB}
+}
+
+// Search finds the user's trips and places matching every word of the query.
+// Words match as prefixes, so "ram osa" finds "Ramen Ichiran, Osaka".
+func (s *SearchStore) Search(userID int, query string, limit int) ([]m.SearchResult, error) {
+	fts, err := ftsAvailable(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return s.search(userID, query, limit, fts)
+}
+
+// search queries the FTS5 index, or the view when useIndex is false. LIKE also
+// matches inside words, rows from the view are kept only when every term
+// starts a word like it does in the index.
+func (s *SearchStore) search(userID int, query string, limit int, useIndex bool) ([]m.SearchResult, error) {
+	terms := m.SearchTerms(query)
+	if len(terms) == 0 {
+		return []m.SearchResult{}, nil
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var rows *sql.Rows
+	var err error
+	if useIndex {
+		// Quoting every term keeps FTS5 operators such as OR, NEAR or - literal
+		match := make([]string, len(terms))
+		for i, term := range terms {
+			match[i] = `"` + term + `"*`
+		}
+		rows, err = s.db.Query(`
+			SELECT `+searchIndexColumns+`
+			FROM search_index
+			WHERE search_index MATCH ? AND user_id = ?
+			ORDER BY rank
+			LIMIT ?`, strings.Join(match, " "), userID, limit)
+	} else {
+		where := []string{"user_id = ?"}
+		args := []any{userID}
+		for _, term := range terms {
+			where = append(where, `(title LIKE ? OR subtitle LIKE ? OR body LIKE ?)`)
+			pattern := "%" + term + "%"
+			args = append(args, pattern, pattern, pattern)
+		}
+		rows, err = s.db.Query(`
+			SELECT `+searchIndexColumns+`
+			FROM search_documents
+			WHERE `+strings.Join(where, " AND ")+`
+			ORDER BY date DESC`, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []m.SearchResult{}
+	for rows.Next() && len(results) < limit {
+		var result m.SearchResult
+		var resultUserID int
+		var title, subtitle, body string
+		err := rows.Scan(&result.EntityType, &result.EntityID, &resultUserID, &result.Date, &title, &subtitle, &body)
+		if err != nil {
+			return nil, err
+		}
+		if !useIndex && !m.MatchesTerms(terms, title, subtitle, body) {
+			continue
+		}
+		result.Title = m.Highlight(title, terms)
+		result.Subtitle = m.Highlight(subtitle, terms)
+		result.Body = m.Highlight(body, terms)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// searchPaths runs test against the FTS5 index and against the LIKE queries of
+// builds without it. The index is only there with -tags sqlite_fts5.
+func searchPaths(t *testing.T, test func(t *testing.T, db *sql.DB, search func(userID int, query string, limit int) []m.SearchResult)) {
+	for _, useIndex := range []bool{true, false} {
+		name := "view"
+		if useIndex {
+			name = "index"
+		}
+		t.Run(name, func(t *testing.T) {
+			db := newTestDB(t)
+			if fts, err := ftsAvailable(db); err != nil || (useIndex && !fts) {
+				t.Skip("built without FTS5, run with -tags sqlite_fts5 to cover the index")
+			}
+			store := NewSearchStore(NewSearchStoreParams{DB: db})
+			test(t, db, func(userID int, query string, limit int) []m.SearchResult {
+				t.Helper()
+				results, err := store.search(userID, query, limit, useIndex)
+				if err != nil {
+					t.Fatalf("%q: %v", query, err)
+				}
+				return results
+			})
+		})
+	}
+}
+
+type searchHit struct {
+	entityType string
+	entityID   int
+}
+
+func searchHits(results []m.SearchResult) []searchHit {
+	hits := []searchHit{}
+	for _, result := range results {
+		hits = append(hits, searchHit{result.EntityType, result.EntityID})
+	}
+	return hits
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestSearch(t *testing.T) {
+	searchPaths(t, func(t *testing.T, db *sql.DB, search func(int, string, int) []m.SearchResult) {
+		userID := newTestUser(t, db, "owner")
+		otherID := newTestUser(t, db, "other")
+		tripStore := NewTripStore(NewTripStoreParams{DB: db})
+		placeStore := NewPlaceStore(db)
+
+		tripID64, err := tripStore.CreateTrip(m.Trip{UserId: userID, Departure: "JFK", Arrival: "NRT", DepartureTime: 1743501600, ArrivalTime: 1743552000,
+			Airline: "Japan Airlines", FlightNumber: "JL5", Reservation: strPtr("ABC123")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tripID := int(tripID64)
+		placeID, err := placeStore.CreatePlace(m.Place{UserID: userID, Name: "Ramen Ichiran", Address: strPtr("Osaka"), Category: strPtr("food"), VisitDate: 1743600000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := placeStore.CreatePlace(m.Place{UserID: otherID, Name: "Ramen Elsewhere", Address: strPtr("Osaka"), VisitDate: 1743600000}); err != nil {
+			t.Fatal(err)
+		}
+		trip := []searchHit{{m.EntityTrip, tripID}}
+		place := []searchHit{{m.EntityPlace, placeID}}
+		none := []searchHit{}
+
+		tests := []struct {
+			query string
+			want  []searchHit
+		}{
+			{"ram osa", place},
+			{"RAMEN", place},
+			{"tokyo", trip},          // the arrival airport's city
+			{"narita int", trip},     // and its name
+			{"abc123", trip},         // the reservation
+			{"japan jl5", trip},      // airline and flight number
+			{"ram -osa", place},      // not the FTS5 NOT operator
+			{"ramen OR tokyo", none}, // nor OR, every word has to match
+			{"rita", none},           // words match from their start only
+			{"ramen tokyo", none},    // across records
+			{"", none},
+		}
+		for _, test := range tests {
+			if got := searchHits(search(userID, test.query, 0)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%q: %v, want %v", test.query, got, test.want)
+			}
+		}
+
+		// The matched start of each word is highlighted
+		results := search(userID, "ram osa", 0)
+		if want := []m.HighlightSegment{{Text: "Ram", Match: true}, {Text: "en Ichiran"}}; !reflect.DeepEqual(results[0].Title, want) {
+			t.Errorf("title %+v, want %+v", results[0].Title, want)
+		}
+		if want := []m.HighlightSegment{{Text: "Osa", Match: true}, {Text: "ka · food"}}; !reflect.DeepEqual(results[0].Subtitle, want) {
+			t.Errorf("subtitle %+v, want %+v", results[0].Subtitle, want)
+		}
+		results = search(userID, "tokyo jfk", 0)
+		want := []m.HighlightSegment{
+			{Text: "JFK", Match: true},
+			{Text: " → NRT"},
+		}
+		if !reflect.DeepEqual(results[0].Title, want) {
+			t.Errorf("title %+v, want %+v", results[0].Title, want)
+		}
+		want = []m.HighlightSegment{
+			{Text: "John F Kennedy International Airport (New York) → Narita International Airport ("},
+			{Text: "Tokyo", Match: true},
+			{Text: ")"},
+		}
+		if !reflect.DeepEqual(results[0].Body, want) {
+			t.Errorf("body %+v, want %+v", results[0].Body, want)
+		}
+
+		// Edits are searched as soon as they are saved
+		edited := m.Trip{ID: tripID, Departure: "JFK", Arrival: "LHR", DepartureTime: 1743501600, ArrivalTime: 1743552000, Airline: "Japan Airlines", FlightNumber: "JL6"}
+		if err := tripStore.EditTrip(edited, userID); err != nil {
+			t.Fatal(err)
+		}
+		if err := placeStore.UpdatePlace(m.Place{ID: placeID, Name: "Udon Shin", Address: strPtr("Tokyo"), VisitDate: 1743600000}, userID); err != nil {
+			t.Fatal(err)
+		}
+		for query, want := range map[string][]searchHit{
+			"jl5":       none,
+			"tokyo":     place,
+			"heathrow":  trip,
+			"jl6":       trip,
+			"ramen":     none,
+			"udon shin": place,
+			"abc123":    none, // the edit cleared the reservation
+		} {
+			if got := searchHits(search(userID, query, 0)); !reflect.DeepEqual(got, want) {
+				t.Errorf("%q after the edits: %v, want %v", query, got, want)
+			}
+		}
+
+		// Records in the trash are not found until they are restored
+		if err := tripStore.DeleteTrip(tripID, userID); err != nil {
+			t.Fatal(err)
+		}
+		if err := placeStore.DeletePlace(placeID, userID); err != nil {
+			t.Fatal(err)
+		}
+		if got := searchHits(search(userID, "jl6", 0)); len(got) != 0 {
+			t.Errorf("a trip in the trash is found: %v", got)
+		}
+		if got := searchHits(search(userID, "udon", 0)); len(got) != 0 {
+			t.Errorf("a place in the trash is found: %v", got)
+		}
+		if err := tripStore.RestoreTrip(tripID, userID); err != nil {
+			t.Fatal(err)
+		}
+		if got := searchHits(search(userID, "jl6", 0)); !reflect.DeepEqual(got, trip) {
+			t.Errorf("the restored trip: %v", got)
+		}
+		if _, err := placeStore.PurgeDeletedPlaces(userID); err != nil {
+			t.Fatal(err)
+		}
+		if got := searchHits(search(userID, "udon", 0)); len(got) != 0 {
+			t.Errorf("a purged place is found: %v", got)
+		}
+
+		// The limit caps the results, the other user's place is never found
+		for _, name := range []string{"Ramen One", "Ramen Two", "Ramen Three"} {
+			if _, err := placeStore.CreatePlace(m.Place{UserID: userID, Name: name, VisitDate: 1743600000}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := search(userID, "ramen", 2); len(got) != 2 {
+			t.Errorf("limit 2: %d results", len(got))
+		}
+		if got := search(userID, "elsewhere", 0); len(got) != 0 {
+			t.Errorf("another user's place is found: %v", searchHits(got))
+		}
+	})
+}
+
+// TestEnsureSearchIndex only rebuilds the index when it is new or its triggers
+// are missing, which they are after a build without FTS5 ran against the file
+func TestEnsureSearchIndex(t *testing.T) {
+	db := newTestDB(t)
+	if fts, err := ftsAvailable(db); err != nil || !fts {
+		t.Skip("built without FTS5, run with -tags sqlite_fts5 to cover the index")
+	}
+	// A document the rebuild would drop, it is not in search_documents
+	if _, err := db.Exec(`INSERT INTO search_index (` + searchIndexColumns + `) VALUES ('trip', 999, 1, 0, 'stale', '', '')`); err != nil {
+		t.Fatal(err)
+	}
+	stale := func() bool {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM search_index WHERE entity_id = 999`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n == 1
+	}
+
+	if err := EnsureSearchIndex(db); err != nil {
+		t.Fatal(err)
+	}
+	if !stale() {
+		t.Errorf("the index was rebuilt although it was up to date")
+	}
+
+	if _, err := db.Exec(`DROP TRIGGER search_trips_insert`); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureSearchIndex(db); err != nil {
+		t.Fatal(err)
+	}
+	if stale() {
+		t.Errorf("the index was not rebuilt after a trigger went missing")
+	}
+	current, err := searchTriggersCurrent(db)
+	if err != nil || !current {
+		t.Errorf("the triggers were not created again: %v", err)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"log"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
+	m "github.com/skywall34/trip-tracker/internal/models"
 )
 
 type SessionStore struct {
@@ -68,4 +70,27 @@ func (s *SessionStore) GetUserFromSession(sessionID string) (int, error) {
 	}
 
 	return numId, nil
-}
\ No newline at end of file
+}
+// GetSessionsForUser returns the user's sessions, oldest first
+func (s *SessionStore) GetSessionsForUser(userID int) ([]m.Session, error) {
+	rows, err := s.db.Query(`
+		SELECT id, session_id, user_id, created_at FROM sessions
+		WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []m.Session
+	for rows.Next() {
+		var session m.Session
+		var createdAt string
+		if err := rows.Scan(&session.ID, &session.SessionID, &session.UserID, &createdAt); err != nil {
+			return nil, err
+		}
+		// CURRENT_TIMESTAMP is UTC
+		session.CreatedAt, _ = time.Parse(time.DateTime, createdAt)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
@@ -2,9 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"log"
+	"math"
 	"sort"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	m "github.com/skywall34/trip-tracker/internal/models"
@@ -26,17 +29,19 @@ func NewTripStore(params NewTripStoreParams) *TripStore {
 func (t *TripStore) CreateTrip(newTrip m.Trip) (int64, error) {
 	q := `
 		INSERT INTO trips 
-		(user_id, departure, arrival, departure_time, arrival_time, airline, flight_number, reservation, terminal, gate)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(user_id, departure, arrival, departure_time, arrival_time, airline, flight_number, reservation, terminal, gate,
+		seat, cabin_class, aircraft_type, tail_number, booking_class, ticket_price, ticket_currency)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	stmt, err := t.db.Prepare(q)
+	tx, err := t.db.Begin()
 	if err != nil {
 		return 0, err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(
+	res, err := tx.Exec(
+		q,
 		newTrip.UserId, 
 		newTrip.Departure, 
 		newTrip.Arrival, 
@@ -47,6 +52,13 @@ func (t *TripStore) CreateTrip(newTrip m.Trip) (int64, error) {
 		newTrip.Reservation, 
 		newTrip.Terminal, 
 		newTrip.Gate,
+		newTrip.Seat,
+		newTrip.CabinClass,
+		newTrip.AircraftType,
+		newTrip.TailNumber,
+		newTrip.BookingClass,
+		newTrip.TicketPrice,
+		newTrip.TicketCurrency,
 	)
 	if err != nil {
 		return 0, err
@@ -57,10 +69,16 @@ func (t *TripStore) CreateTrip(newTrip m.Trip) (int64, error) {
 		return 0, err
 	}
 
-	return id, nil
+	if err := t.recordTripChange(tx, newTrip.UserId, int(id), m.ChangeCreate, nil); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
 }
 
-func (t *TripStore) EditTrip(newTrip m.Trip) (error) {
+// EditTrip updates one of the user's trips, ErrNotFound is returned when the
+// trip does not exist or belongs to someone else
+func (t *TripStore) EditTrip(newTrip m.Trip, userID int) (error) {
 
 	q := `
 		UPDATE trips
@@ -73,17 +91,34 @@ func (t *TripStore) EditTrip(newTrip m.Trip) (error) {
 			flight_number = ?, 
 			reservation = ?, 
 			terminal = ?, 
-			gate = ?
-		WHERE id = ?
+			gate = ?,
+			seat = ?,
+			cabin_class = ?,
+			aircraft_type = ?,
+			tail_number = ?,
+			booking_class = ?,
+			ticket_price = ?,
+			ticket_currency = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
 	`
 
-	stmt, err := t.db.Prepare(q)
+	tx, err := t.db.Begin()
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.Exec(
+	if err := requireOwner(tx, ResourceTrip, newTrip.ID, userID); err != nil {
+		return err
+	}
+
+	before, err := getTripRow(tx, newTrip.ID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(
+		q,
 		newTrip.Departure, 
 		newTrip.Arrival, 
 		newTrip.DepartureTime, 
@@ -93,62 +128,154 @@ func (t *TripStore) EditTrip(newTrip m.Trip) (error) {
 		newTrip.Reservation, 
 		newTrip.Terminal, 
 		newTrip.Gate,
+		newTrip.Seat,
+		newTrip.CabinClass,
+		newTrip.AircraftType,
+		newTrip.TailNumber,
+		newTrip.BookingClass,
+		newTrip.TicketPrice,
+		newTrip.TicketCurrency,
 		newTrip.ID,
+		userID,
 	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	if err := t.recordTripChange(tx, userID, newTrip.ID, m.ChangeUpdate, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordTripChange stores the trip as it is now in the change history next to
+// the snapshot taken before the write
+func (t *TripStore) recordTripChange(tx *sql.Tx, userID int, tripID int, action string, before *m.Trip) error {
+	after, err := getTripRow(tx, tripID)
+	if err != nil {
+		return err
+	}
+	return recordChange(tx, userID, m.EntityTrip, tripID, action, before, after)
+}
+
+// RevertTrip restores the trip to the version saved by an earlier change.
+// The revert itself is recorded as a new change so it can be undone as well.
+func (t *TripStore) RevertTrip(changeID int, userID int) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	change, err := getChange(tx, changeID, userID)
 	if err != nil {
 		return err
 	}
+	if change.EntityType != m.EntityTrip || !change.Revertable() {
+		return sql.ErrNoRows
+	}
+
+	var version m.Trip
+	if err := json.Unmarshal(change.After, &version); err != nil {
+		return err
+	}
 
-	return nil
+	before, err := getTripRow(tx, change.EntityID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`
+		UPDATE trips
+		SET departure = ?, arrival = ?, departure_time = ?, arrival_time = ?, airline = ?,
+			flight_number = ?, reservation = ?, terminal = ?, gate = ?, seat = ?, cabin_class = ?,
+			aircraft_type = ?, tail_number = ?, booking_class = ?, ticket_price = ?, ticket_currency = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL`,
+		version.Departure,
+		version.Arrival,
+		version.DepartureTime,
+		version.ArrivalTime,
+		version.Airline,
+		version.FlightNumber,
+		version.Reservation,
+		version.Terminal,
+		version.Gate,
+		version.Seat,
+		version.CabinClass,
+		version.AircraftType,
+		version.TailNumber,
+		version.BookingClass,
+		version.TicketPrice,
+		version.TicketCurrency,
+		change.EntityID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := t.recordTripChange(tx, userID, change.EntityID, m.ChangeRevert, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 
+// Timezones are selected from the airports table alongside the coordinates.
+// SetTimezonesForTrips only falls back to the airport2timezone.json lookup for
+// airports that have not been given a timezone by the airport importer yet.
 func SetTimezonesForTrips(trips []m.Trip) ([]m.Trip, error) {
 	for i := range trips {
-		arrivalTZ, ok1 := m.AirportTimezoneLookup[trips[i].Arrival]
-		departureTZ, ok2 := m.AirportTimezoneLookup[trips[i].Departure]
-
-		if ok1 {
-			trips[i].ArrivalTimezone = &arrivalTZ
-		} else {
-			log.Printf("No timezone found for arrival airport: %s", trips[i].Arrival)
+		if trips[i].ArrivalTimezone != nil && *trips[i].ArrivalTimezone == "" {
+			trips[i].ArrivalTimezone = nil
 		}
-		if ok2 {
-			trips[i].DepartureTimezone = &departureTZ
-		} else {
-			log.Printf("No timezone found for departure airport: %s", trips[i].Departure)
+		if trips[i].DepartureTimezone != nil && *trips[i].DepartureTimezone == "" {
+			trips[i].DepartureTimezone = nil
+		}
+		if trips[i].ArrivalTimezone == nil {
+			if arrivalTZ, ok := m.AirportTimezoneLookup[trips[i].Arrival]; ok {
+				trips[i].ArrivalTimezone = &arrivalTZ
+			} else {
+				log.Printf("No timezone found for arrival airport: %s", trips[i].Arrival)
+			}
+		}
+		if trips[i].DepartureTimezone == nil {
+			if departureTZ, ok := m.AirportTimezoneLookup[trips[i].Departure]; ok {
+				trips[i].DepartureTimezone = &departureTZ
+			} else {
+				log.Printf("No timezone found for departure airport: %s", trips[i].Departure)
+			}
 		}
 	}
 	return trips, nil
 }
 
-func (t *TripStore) GetTripGivenId(tripID int, userID int) (m.Trip, error) {
-	var trip m.Trip
+const tripColumns = `
+	t.id, t.user_id, t.departure, t.arrival, t.departure_time, t.arrival_time, t.airline, t.flight_number,
+	COALESCE(t.reservation, ''), COALESCE(t.terminal, ''), COALESCE(t.gate, ''),
+	NULLIF(t.seat, ''), NULLIF(t.cabin_class, ''), NULLIF(t.aircraft_type, ''), NULLIF(t.tail_number, ''),
+	NULLIF(t.booking_class, ''), t.ticket_price, NULLIF(t.ticket_currency, ''),
+	d.latitude, d.longitude, a.latitude, a.longitude,
+	NULLIF(d.timezone, ''), NULLIF(a.timezone, '')`
 
-	const q = `
-    SELECT 
-        t.id, 
-        t.user_id, 
-        t.departure, 
-        t.arrival, 
-        t.departure_time, 
-        t.arrival_time, 
-        t.airline, 
-        t.flight_number,
-		COALESCE(t.reservation, '') AS reservation,
-        COALESCE(t.terminal,    '') AS terminal,
-        COALESCE(t.gate,        '') AS gate,
-        d.latitude, 
-        d.longitude, 
-        a.latitude, 
-        a.longitude
-    FROM trips t
-    JOIN airports d ON t.departure = d.iata_code
-    JOIN airports a ON t.arrival   = a.iata_code
-    WHERE t.id = ? AND t.user_id = ?`
-
-	err := t.db.QueryRow(q, tripID, userID).Scan(
+// tripsWithAirports is the FROM clause that goes with tripColumns
+const tripsWithAirports = `
+	FROM trips t
+	JOIN airports d ON t.departure = d.iata_code
+	JOIN airports a ON t.arrival   = a.iata_code`
+
+// scanTrip reads a row of tripColumns
+func scanTrip(row rowScanner) (m.Trip, error) {
+	var trip m.Trip
+	err := row.Scan(
 		&trip.ID,
 		&trip.UserId,
 		&trip.Departure,
@@ -160,149 +287,114 @@ func (t *TripStore) GetTripGivenId(tripID int, userID int) (m.Trip, error) {
 		&trip.Reservation,
 		&trip.Terminal,
 		&trip.Gate,
+		&trip.Seat,
+		&trip.CabinClass,
+		&trip.AircraftType,
+		&trip.TailNumber,
+		&trip.BookingClass,
+		&trip.TicketPrice,
+		&trip.TicketCurrency,
 		&trip.DepartureLat,
 		&trip.DepartureLon,
 		&trip.ArrivalLat,
 		&trip.ArrivalLon,
+		&trip.DepartureTimezone,
+		&trip.ArrivalTimezone,
 	)
+	trip.SetDistance()
+	return trip, err
+}
+
+func (t *TripStore) GetTripGivenId(tripID int, userID int) (m.Trip, error) {
+	trip, err := scanTrip(t.db.QueryRow(`
+		SELECT ` + tripColumns + tripsWithAirports + `
+		WHERE t.id = ? AND t.user_id = ? AND t.deleted_at IS NULL`, tripID, userID))
 	if err != nil {
-		return trip, err
+		return m.Trip{}, err
 	}
 
 	tripsWithTZ, err := SetTimezonesForTrips([]m.Trip{trip})
 	if err != nil {
 		return trip, err
 	}
-	trip = tripsWithTZ[0]
-
-	return trip, nil
+	return tripsWithTZ[0], nil
 }
 
-
 func (t *TripStore) GetTripsGivenUser(userID int) ([]m.Trip, error) {
-    var trips []m.Trip
-
-    const q = `
-    SELECT 
-        t.id, 
-        t.user_id, 
-        t.departure, 
-        t.arrival, 
-        t.departure_time, 
-        t.arrival_time, 
-        t.airline, 
-        t.flight_number,
-		COALESCE(t.reservation, '') AS reservation,
-        COALESCE(t.terminal,    '') AS terminal,
-        COALESCE(t.gate,        '') AS gate,
-        d.latitude, 
-        d.longitude, 
-        a.latitude, 
-        a.longitude
-    FROM trips t
-    JOIN airports d ON t.departure = d.iata_code
-    JOIN airports a ON t.arrival   = a.iata_code
-    WHERE t.user_id = ?`
-
-    rows, err := t.db.Query(q, userID)
-    if err != nil {
-        return trips, err
-    }
-    defer rows.Close()
-
-    for rows.Next() {
-        var trip m.Trip
-        err := rows.Scan(
-			&trip.ID, 
-			&trip.UserId, 
-			&trip.Departure, 
-			&trip.Arrival, 
-			&trip.DepartureTime, 
-			&trip.ArrivalTime, 
-			&trip.Airline, 
-			&trip.FlightNumber, 
-			&trip.Reservation, 
-			&trip.Terminal, 
-			&trip.Gate,
-			&trip.DepartureLat,
-			&trip.DepartureLon,
-			&trip.ArrivalLat,
-			&trip.ArrivalLon,
-		)
-        if err != nil {
-            return trips, err
-        }
-        trips = append(trips, trip)
-    }
+	var trips []m.Trip
 
-	trips, err = SetTimezonesForTrips(trips)
+	rows, err := t.db.Query(`
+		SELECT ` + tripColumns + tripsWithAirports + `
+		WHERE t.user_id = ? AND t.deleted_at IS NULL`, userID)
 	if err != nil {
 		return trips, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		trip, err := scanTrip(rows)
+		if err != nil {
+			return trips, err
+		}
+		trips = append(trips, trip)
+	}
+	if err := rows.Err(); err != nil {
+		return trips, err
+	}
 
-    return trips, nil
+	return SetTimezonesForTrips(trips)
 }
 
-func (t *TripStore) GetConnectingTripsGivenUser(userID int) ([]m.Trip, []m.ConnectingTrip, error) {
-	trips, err := t.GetTripsGivenUser(userID)
-	if err != nil {
-		return nil, nil, err
+// ListTrips returns a page of the user's trips in departure order, oldest
+// first. The page starts after filter.After and holds up to filter.Limit trips.
+func (t *TripStore) ListTrips(userID int, filter m.TripFilter) ([]m.Trip, error) {
+	q := `
+		SELECT ` + tripColumns + tripsWithAirports + `
+		WHERE t.user_id = ? AND t.deleted_at IS NULL`
+	args := []any{userID}
+	if filter.From != 0 {
+		q += ` AND t.departure_time >= ?`
+		args = append(args, filter.From)
 	}
-
-	// Group trips by departure airport
-	airportToTrips := make(map[string][]m.Trip)
-	for _, trip := range trips {
-		airportToTrips[trip.Departure] = append(airportToTrips[trip.Departure], trip)
+	if filter.To != 0 {
+		q += ` AND t.departure_time <= ?`
+		args = append(args, filter.To)
 	}
-
-	// Sort each departure airport group by departure time
-	for airport, list := range airportToTrips {
-		sort.Slice(list, func(i, j int) bool {
-			return list[i].DepartureTime < list[j].DepartureTime
-		})
-		airportToTrips[airport] = list
+	if filter.Airport != "" {
+		q += ` AND (t.departure = ? OR t.arrival = ?)`
+		args = append(args, filter.Airport, filter.Airport)
 	}
+	if filter.Airline != "" {
+		q += ` AND t.airline = ? COLLATE NOCASE`
+		args = append(args, filter.Airline)
+	}
+	if filter.After != nil {
+		q += ` AND (t.departure_time > ? OR (t.departure_time = ? AND t.id > ?))`
+		args = append(args, filter.After.Time, filter.After.Time, filter.After.ID)
+	}
+	q += ` ORDER BY t.departure_time, t.id LIMIT ?`
+	args = append(args, filter.Limit)
 
-	// Track flights that are part of connections
-	connectedIDs := make(map[int]bool)
-	var connectingFlights []m.ConnectingTrip
-
-	for _, from := range trips {
-		candidates := airportToTrips[from.Arrival]
-		windowStart := from.ArrivalTime
-		windowEnd := from.ArrivalTime + 86400 // 24 hours
-
-		for _, to := range candidates {
-			if from.ID == to.ID {
-				continue
-			}
-			if to.DepartureTime > windowEnd {
-				break // no point continuing
-			}
-			if to.DepartureTime > windowStart {
-				connectingFlights = append(connectingFlights, m.ConnectingTrip{
-					FromTrip: from,
-					ToTrip:   to,
-				})
-				connectedIDs[from.ID] = true
-				connectedIDs[to.ID] = true
-			}
-		}
+	rows, err := t.db.Query(q, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Collect non-connecting flights
-	var standaloneFlights []m.Trip
-	for _, trip := range trips {
-		if !connectedIDs[trip.ID] {
-			standaloneFlights = append(standaloneFlights, trip)
+	var trips []m.Trip
+	for rows.Next() {
+		trip, err := scanTrip(rows)
+		if err != nil {
+			return nil, err
 		}
+		trips = append(trips, trip)
 	}
-
-	return standaloneFlights, connectingFlights, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return SetTimezonesForTrips(trips)
 }
 
-
-
 func (t *TripStore) getFlightsForYears(userID int) ([]m.FlightAggregation, error) {
 	var flights []m.FlightAggregation
 	var total int
@@ -320,7 +412,7 @@ func (t *TripStore) getFlightsForYears(userID int) ([]m.FlightAggregation, error
 					strftime('%Y', datetime(departure_time, 'unixepoch')) AS label,
 					COUNT(*) AS count
                 FROM trips
-                WHERE user_id = ?
+                WHERE user_id = ? AND deleted_at IS NULL
                 AND departure_time >= strftime('%s', datetime('now', '-10 years'))
                 GROUP BY label
         )
@@ -382,14 +474,14 @@ func (t *TripStore) getFlightsPerMonthForYear(userID int, year string) ([]m.Flig
 				strftime('%m', datetime(departure_time, 'unixepoch')) AS month,
 				COUNT(*) AS trip_count
 			FROM trips
-			WHERE user_id = ?
+			WHERE user_id = ? AND deleted_at IS NULL
 			AND strftime('%Y', datetime(departure_time, 'unixepoch')) = ?
 			GROUP BY month
 		),
 		total_trips AS (
 			SELECT COUNT(ID) AS total
 			FROM trips
-			WHERE user_id = ?
+			WHERE user_id = ? AND deleted_at IS NULL
 			AND strftime('%Y', datetime(departure_time, 'unixepoch')) = ?
 		)
 		SELECT 
@@ -431,7 +523,7 @@ func (t *TripStore) getAirlinesCount(userID int) ([]m.AirlineAggregation, error)
 			airline,
 			COUNT(*) AS flight_count
 		FROM trips
-		WHERE user_id = ?
+		WHERE user_id = ? AND deleted_at IS NULL
 		GROUP BY airline
 		ORDER BY flight_count DESC`, userID)
 	
@@ -463,7 +555,7 @@ func (t *TripStore) getAirlinesCountForYear(userID int, year string) ([]m.Airlin
 		airline,                                                       
 		COUNT(*) AS flight_count
 	FROM trips
-	WHERE user_id = ?
+	WHERE user_id = ? AND deleted_at IS NULL
 		AND strftime('%Y', datetime(departure_time, 'unixepoch')) = ?
 	GROUP BY airline
 	ORDER BY flight_count DESC`, userID, year)
@@ -497,7 +589,7 @@ func (t *TripStore) getCountriesCount(userID int) ([]m.CountryAggregation, error
 		COUNT(DISTINCT t.arrival) AS country_count
 	FROM trips t
 	JOIN airports d ON t.arrival = d.iata_code
-	WHERE user_id = ?
+	WHERE user_id = ? AND t.deleted_at IS NULL
 	GROUP BY label
 	ORDER BY label`, userID)
 
@@ -530,7 +622,7 @@ func (t *TripStore) getCountriesCountForYear(userID int, year string) ([]m.Count
 		COUNT(DISTINCT t.arrival) AS country_count
 	FROM trips t
 	JOIN airports d ON t.arrival = d.iata_code
-	WHERE user_id = ?
+	WHERE user_id = ? AND t.deleted_at IS NULL
 		AND strftime('%Y', datetime(t.departure_time, 'unixepoch')) = ?
 	GROUP BY label
 	ORDER BY label`, userID, year)
@@ -598,16 +690,169 @@ func (t *TripStore) GetTripsPerAggregation(userID int, year string, agg string)
 	return flights, airlines, countries, nil
 }
 
-func (t *TripStore) DeleteTrip(id int) (error) {
-	_, err := t.db.Exec("DELETE FROM trips WHERE id = ?", id)
+// DeleteTrip moves the trip to the trash, it is only removed for good by
+// PurgeTrip or once the trash retention period has passed
+func (t *TripStore) DeleteTrip(id int, userID int) (error) {
+	return t.setTripDeletedAt(id, userID, m.ChangeDelete, `
+		UPDATE trips SET deleted_at = ?
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL`, time.Now().Unix(), id, userID)
+}
+
+// setTripDeletedAt moves a trip in or out of the trash and records the change
+func (t *TripStore) setTripDeletedAt(id int, userID int, action string, q string, args ...any) error {
+	tx, err := t.db.Begin()
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	if err := requireOwner(tx, ResourceTrip, id, userID); err != nil {
+		return err
+	}
+
+	before, err := getTripRow(tx, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(q, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := t.recordTripChange(tx, userID, id, action, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetDeletedTripsGivenUser returns the trips in the user's trash, most recently deleted first
+func (t *TripStore) GetDeletedTripsGivenUser(userID int) ([]m.Trip, error) {
+	var trips []m.Trip
+
+	rows, err := t.db.Query(`
+		SELECT
+			t.id,
+			t.user_id,
+			t.departure,
+			t.arrival,
+			t.departure_time,
+			t.arrival_time,
+			t.airline,
+			t.flight_number,
+			t.deleted_at
+		FROM trips t
+		WHERE t.user_id = ? AND t.deleted_at IS NOT NULL
+		ORDER BY t.deleted_at DESC`, userID)
+	if err != nil {
+		return trips, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trip m.Trip
+		err := rows.Scan(
+			&trip.ID,
+			&trip.UserId,
+			&trip.Departure,
+			&trip.Arrival,
+			&trip.DepartureTime,
+			&trip.ArrivalTime,
+			&trip.Airline,
+			&trip.FlightNumber,
+			&trip.DeletedAt,
+		)
+		if err != nil {
+			return trips, err
+		}
+		trips = append(trips, trip)
+	}
+
+	return trips, rows.Err()
+}
+
+// RestoreTrip takes a trip out of the trash
+func (t *TripStore) RestoreTrip(id int, userID int) error {
+	return t.setTripDeletedAt(id, userID, m.ChangeRestore, `
+		UPDATE trips SET deleted_at = NULL
+		WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL`, id, userID)
+}
+
+// PurgeTrip permanently deletes a trip that is in the trash
+func (t *TripStore) PurgeTrip(id int, userID int) error {
+	n, err := t.purgeTrips(`id = ? AND user_id = ? AND deleted_at IS NOT NULL`, id, userID)
+	if err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// PurgeDeletedTrips permanently deletes the user's trash
+func (t *TripStore) PurgeDeletedTrips(userID int) (int64, error) {
+	return t.purgeTrips(`user_id = ? AND deleted_at IS NOT NULL`, userID)
+}
+
+// PurgeTripsDeletedBefore permanently deletes every user's trips that went to the trash before the cutoff
+func (t *TripStore) PurgeTripsDeletedBefore(cutoff time.Time) (int64, error) {
+	return t.purgeTrips(`deleted_at IS NOT NULL AND deleted_at < ?`, cutoff.Unix())
+}
+
+// purgeTrips hard deletes the matching trips. Their itinerary legs and journey
+// memberships go with them through ON DELETE CASCADE, change history and
+// attachments only name the trip by type and id and are deleted here.
+func (t *TripStore) purgeTrips(where string, args ...any) (int64, error) {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		`DELETE FROM change_history WHERE entity_type = 'trip' AND entity_id IN (SELECT id FROM trips WHERE ` + where + `)`,
+		`DELETE FROM attachments WHERE entity_type = 'trip' AND entity_id IN (SELECT id FROM trips WHERE ` + where + `)`,
+	} {
+		if _, err := tx.Exec(q, args...); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.Exec(`DELETE FROM trips WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	// A pinned itinerary needs two flights, see UnpinLeg
+	if _, err := tx.Exec(`
+		DELETE FROM itineraries WHERE id NOT IN (
+			SELECT itinerary_id FROM itinerary_legs GROUP BY itinerary_id HAVING COUNT(*) >= 2
+		)`); err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
 }
 
 
 func (t *TripStore) GetTotalMileageAndTime(userID int) (m.TimeSpaceAggregation, error) {
+	return t.getTotalMileageAndTime(userID, "")
+}
+
+// GetTotalMileageAndTimeForYear sums the flights departing in year (UTC) like
+// the other yearly statistics
+func (t *TripStore) GetTotalMileageAndTimeForYear(userID int, year string) (m.TimeSpaceAggregation, error) {
+	return t.getTotalMileageAndTime(userID, year)
+}
+
+// getTotalMileageAndTime sums every flight when year is empty
+func (t *TripStore) getTotalMileageAndTime(userID int, year string) (m.TimeSpaceAggregation, error) {
 	var tsAggregation m.TimeSpaceAggregation
 	row := t.db.QueryRow(`
 		WITH trip_data AS (
@@ -621,7 +866,8 @@ func (t *TripStore) GetTotalMileageAndTime(userID int) (m.TimeSpaceAggregation,
 			FROM trips t
 			JOIN airports d ON t.departure = d.iata_code
 			JOIN airports a ON t.arrival = a.iata_code
-			WHERE t.user_id = ?
+			WHERE t.user_id = ? AND t.deleted_at IS NULL
+			AND (? = '' OR strftime('%Y', datetime(t.departure_time, 'unixepoch')) = ?)
 		)
 		SELECT
 			COALESCE(SUM((arrival_time - departure_time) / 3600.0), 0) AS total_hours,
@@ -634,7 +880,7 @@ func (t *TripStore) GetTotalMileageAndTime(userID int) (m.TimeSpaceAggregation,
 					)
 				)
 			) AS INTEGER), 0) AS total_km
-		FROM trip_data;`, userID)
+		FROM trip_data;`, userID, year, year)
 
 	err := row.Scan(
 		&tsAggregation.TotalHours,
@@ -647,6 +893,107 @@ func (t *TripStore) GetTotalMileageAndTime(userID int) (m.TimeSpaceAggregation,
 	return tsAggregation, nil
 }
 
+// GetFlightDetailBreakdown sums flights and kilometers per cabin class and per
+// aircraft type, most flown first. Flights without the detail are left out.
+// Distances come from GetTripsGivenUser so this works without SQL math functions.
+func (t *TripStore) GetFlightDetailBreakdown(userID int) ([]m.FlightDetailAggregation, []m.FlightDetailAggregation, error) {
+	trips, err := t.GetTripsGivenUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cabins := map[string]*m.FlightDetailAggregation{}
+	aircraft := map[string]*m.FlightDetailAggregation{}
+	add := func(groups map[string]*m.FlightDetailAggregation, label string, km float64) {
+		group, ok := groups[label]
+		if !ok {
+			group = &m.FlightDetailAggregation{Label: label}
+			groups[label] = group
+		}
+		group.Flights++
+		group.Km += int(math.Round(km))
+	}
+	for _, trip := range trips {
+		if trip.CabinClass != nil {
+			add(cabins, m.CabinClassLabel(*trip.CabinClass), trip.DistanceKm)
+		}
+		if trip.AircraftType != nil {
+			add(aircraft, *trip.AircraftType, trip.DistanceKm)
+		}
+	}
+
+	return sortFlightDetails(cabins), sortFlightDetails(aircraft), nil
+}
+
+func sortFlightDetails(groups map[string]*m.FlightDetailAggregation) []m.FlightDetailAggregation {
+	sorted := make([]m.FlightDetailAggregation, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Flights != sorted[j].Flights {
+			return sorted[i].Flights > sorted[j].Flights
+		}
+		if sorted[i].Km != sorted[j].Km {
+			return sorted[i].Km > sorted[j].Km
+		}
+		return sorted[i].Label < sorted[j].Label
+	})
+	return sorted
+}
+
+// DuplicateTripWindow is how far apart two departures on the same route may be
+// to count as the same flight. Imported times are often rounded or off by the
+// timezone of the calendar they came from.
+const DuplicateTripWindow = 6 * time.Hour
+
+// HasMatchingTrip reports whether the user already has a trip on the same route
+// departing within DuplicateTripWindow of the given trip. Trips in the trash
+// count too, restoring them is better than importing them again.
+func (t *TripStore) HasMatchingTrip(userID int, trip m.Trip) (bool, error) {
+	_, err := t.matchingTripID(userID, trip, true)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetMatchingTripID returns the id of the trip that is not in the trash on the
+// same route departing closest to the given trip, within DuplicateTripWindow.
+// Callers go on to read or edit it, which trashed trips are left out of.
+// ErrNotFound is returned when there is none.
+func (t *TripStore) GetMatchingTripID(userID int, trip m.Trip) (int, error) {
+	return t.matchingTripID(userID, trip, false)
+}
+
+func (t *TripStore) matchingTripID(userID int, trip m.Trip, includeTrash bool) (int, error) {
+	var id int
+	err := t.db.QueryRow(`
+		SELECT id FROM trips
+		WHERE user_id = ? AND departure = ? AND arrival = ?
+		AND ABS(departure_time - ?) < ?
+		AND (? OR deleted_at IS NULL)
+		ORDER BY ABS(departure_time - ?), id
+		LIMIT 1`, userID, trip.Departure, trip.Arrival, trip.DepartureTime,
+		int64(DuplicateTripWindow.Seconds()), includeTrash, trip.DepartureTime).Scan(&id)
+	return id, err
+}
+
+// GetTripIDForFlight returns the id of the user's trip on the route that
+// departs between from and to, preferring one with the given flight number.
+// Boarding passes only have the date of the flight, from and to span that
+// day at the departure airport. ErrNotFound is returned when there is none.
+func (t *TripStore) GetTripIDForFlight(userID int, departure, arrival, flightNumber string, from, to time.Time) (int, error) {
+	var id int
+	err := t.db.QueryRow(`
+		SELECT id FROM trips
+		WHERE user_id = ? AND departure = ? AND arrival = ? AND deleted_at IS NULL
+		AND departure_time >= ? AND departure_time < ?
+		ORDER BY REPLACE(UPPER(flight_number), ' ', '') LIKE '%' || ? DESC, departure_time, id
+		LIMIT 1`, userID, departure, arrival, from.Unix(), to.Unix(), flightNumber).Scan(&id)
+	return id, err
+}
+
 func (t *TripStore) GetVisitedCountryMap(userID int) (map[string]bool, error) {
 	visited := make(map[string]bool)
 
@@ -654,7 +1001,7 @@ func (t *TripStore) GetVisitedCountryMap(userID int) (map[string]bool, error) {
 		SELECT DISTINCT d.country
 		FROM trips t
 		JOIN airports d ON t.arrival = d.iata_code
-		WHERE t.user_id = ?`, userID)
+		WHERE t.user_id = ? AND t.deleted_at IS NULL`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+func TestTripDistances(t *testing.T) {
+	db := newTestDB(t)
+	userID := newTestUser(t, db, "owner")
+	tripStore := NewTripStore(NewTripStoreParams{DB: db})
+
+	// Great-circle kilometers between the testAirports coordinates
+	want := map[string]float64{"JFK-NRT": 10831, "NRT-HND": 60, "LHR-JFK": 5541}
+	ids := map[int]string{}
+	for route := range want {
+		id, err := tripStore.CreateTrip(m.Trip{UserId: userID, Departure: route[:3], Arrival: route[4:], DepartureTime: 1743501600, ArrivalTime: 1743552000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[int(id)] = route
+	}
+	check := func(read string, trips []m.Trip) {
+		t.Helper()
+		if len(trips) != len(want) {
+			t.Fatalf("%s: %d trips", read, len(trips))
+		}
+		for _, trip := range trips {
+			route := ids[trip.ID]
+			if math.Abs(trip.DistanceKm-want[route]) > 1 {
+				t.Errorf("%s: %s is %.1f km, want %.0f", read, route, trip.DistanceKm, want[route])
+			}
+		}
+	}
+
+	trips, err := tripStore.GetTripsGivenUser(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("GetTripsGivenUser", trips)
+	listed, err := tripStore.ListTrips(userID, m.TripFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("ListTrips", listed)
+	var read []m.Trip
+	for id := range ids {
+		trip, err := tripStore.GetTripGivenId(id, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read = append(read, trip)
+	}
+	check("GetTripGivenId", read)
+
+	// Builds with SQL math functions sum the same distances in the query
+	totals, err := tripStore.GetTotalMileageAndTime(userID)
+	if err != nil {
+		t.Logf("no SQL math functions, run with -tags sqlite_math_functions to compare the totals: %v", err)
+		return
+	}
+	var sum float64
+	for _, trip := range trips {
+		sum += trip.DistanceKm
+	}
+	if math.Abs(float64(totals.TotalKm)-sum) > 1 {
+		t.Errorf("the query sums %v km, the trips %.1f", totals.TotalKm, sum)
+	}
+}
+
+func TestGetFlightDetailBreakdown(t *testing.T) {
+	db := newTestDB(t)
+	userID := newTestUser(t, db, "owner")
+	otherID := newTestUser(t, db, "other")
+	tripStore := NewTripStore(NewTripStoreParams{DB: db})
+
+	createTrip := func(userID int, route string, cabin, aircraft *string) int {
+		t.Helper()
+		id, err := tripStore.CreateTrip(m.Trip{UserId: userID, Departure: route[:3], Arrival: route[4:], DepartureTime: 1743501600, ArrivalTime: 1743552000,
+			CabinClass: cabin, AircraftType: aircraft})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+	business, economy := strPtr(m.CabinBusiness), strPtr(m.CabinEconomy)
+	boeing, airbus := strPtr("Boeing 777"), strPtr("Airbus A350")
+	createTrip(userID, "JFK-NRT", business, boeing)
+	createTrip(userID, "NRT-HND", economy, airbus)
+	createTrip(userID, "LHR-JFK", economy, nil)
+	createTrip(userID, "NRT-HND", nil, airbus)
+	createTrip(userID, "LHR-JFK", nil, nil)
+	createTrip(otherID, "JFK-NRT", business, boeing)
+	trashed := createTrip(userID, "JFK-NRT", business, boeing)
+	if err := tripStore.DeleteTrip(trashed, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	cabins, aircraft, err := tripStore.GetFlightDetailBreakdown(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Most flights first, then most kilometers
+	wantCabins := []m.FlightDetailAggregation{
+		{Label: "Economy", Flights: 2, Km: 60 + 5541},
+		{Label: "Business", Flights: 1, Km: 10831},
+	}
+	if !reflect.DeepEqual(cabins, wantCabins) {
+		t.Errorf("cabins %+v, want %+v", cabins, wantCabins)
+	}
+	wantAircraft := []m.FlightDetailAggregation{
+		{Label: "Airbus A350", Flights: 2, Km: 60 + 60},
+		{Label: "Boeing 777", Flights: 1, Km: 10831},
+	}
+	if !reflect.DeepEqual(aircraft, wantAircraft) {
+		t.Errorf("aircraft %+v, want %+v", aircraft, wantAircraft)
+	}
+
+	newcomer := newTestUser(t, db, "newcomer")
+	if cabins, aircraft, err := tripStore.GetFlightDetailBreakdown(newcomer); err != nil || len(cabins) != 0 || len(aircraft) != 0 {
+		t.Errorf("a user without flights: %v %v %v", cabins, aircraft, err)
+	}
+}
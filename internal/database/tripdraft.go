@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Handles the functions accessing table trip_drafts
+type TripDraftStore struct {
+	db *sql.DB
+}
+
+type NewTripDraftStoreParams struct {
+	DB *sql.DB
+}
+
+func NewTripDraftStore(params NewTripDraftStoreParams) *TripDraftStore {
+	return &TripDraftStore{db: params.DB}
+}
+
+// QueueTripDraft adds a draft for the user. A flight of an email that was
+// already delivered is left alone and false is returned.
+func (s *TripDraftStore) QueueTripDraft(draft m.TripDraft) (bool, error) {
+	trip, err := json.Marshal(draft.Trip)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.db.Exec(`
+		INSERT OR IGNORE INTO trip_drafts (
+			user_id, trip, departure_local, arrival_local, subject, sender,
+			message_id, position, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		draft.UserID,
+		string(trip),
+		draft.DepartureLocal,
+		draft.ArrivalLocal,
+		draft.Subject,
+		draft.Sender,
+		draft.MessageID,
+		draft.Position,
+		uint32(time.Now().Unix()),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+const tripDraftColumns = `id, user_id, trip, departure_local, arrival_local, subject,
+	sender, message_id, position, created_at`
+
+func scanTripDraft(row rowScanner) (m.TripDraft, error) {
+	var draft m.TripDraft
+	var trip string
+	err := row.Scan(
+		&draft.ID,
+		&draft.UserID,
+		&trip,
+		&draft.DepartureLocal,
+		&draft.ArrivalLocal,
+		&draft.Subject,
+		&draft.Sender,
+		&draft.MessageID,
+		&draft.Position,
+		&draft.CreatedAt,
+	)
+	if err != nil {
+		return draft, err
+	}
+	err = json.Unmarshal([]byte(trip), &draft.Trip)
+	return draft, err
+}
+
+// GetTripDrafts returns the user's drafts, the flights of an email in the
+// order they were found
+func (s *TripDraftStore) GetTripDrafts(userID int) ([]m.TripDraft, error) {
+	rows, err := s.db.Query(`SELECT `+tripDraftColumns+` FROM trip_drafts
+		WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drafts []m.TripDraft
+	for rows.Next() {
+		draft, err := scanTripDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, rows.Err()
+}
+
+// GetTripDraft returns ErrNotFound unless the draft belongs to the user
+func (s *TripDraftStore) GetTripDraft(id int, userID int) (m.TripDraft, error) {
+	row := s.db.QueryRow(`SELECT `+tripDraftColumns+` FROM trip_drafts
+		WHERE id = ? AND user_id = ?`, id, userID)
+	return scanTripDraft(row)
+}
+
+// DeleteTripDraft removes a draft once it was confirmed or discarded
+func (s *TripDraftStore) DeleteTripDraft(id int, userID int) error {
+	res, err := s.db.Exec(`DELETE FROM trip_drafts WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -147,3 +147,19 @@ func (u *UserStore) UpdatePassword(userID int, newPassword string) error {
 	return nil
 }
 
+
+
+func (u *UserStore) GetMaxLayoverMinutes(userID int) (int, error) {
+	var minutes int
+	err := u.db.QueryRow(`SELECT max_layover_minutes FROM users WHERE id = ?`, userID).Scan(&minutes)
+	if err != nil {
+		return 0, err
+	}
+	return minutes, nil
+}
+
+// UpdateMaxLayover sets the longest layover for flights to be chained into an itinerary
+func (u *UserStore) UpdateMaxLayover(userID int, minutes int) error {
+	_, err := u.db.Exec(`UPDATE users SET max_layover_minutes = ? WHERE id = ?`, minutes, userID)
+	return err
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// webhookSecretPrefix marks signing secrets like accessTokenPrefix marks tokens
+const webhookSecretPrefix = "whsec_"
+
+// Handles the functions accessing tables webhooks and webhook_deliveries.
+// Deliveries are queued by recordChange, see enqueueWebhookEvent, and sent
+// by internal/webhook.
+type WebhookStore struct {
+	db *sql.DB
+}
+
+type NewWebhookStoreParams struct {
+	DB *sql.DB
+}
+
+func NewWebhookStore(params NewWebhookStoreParams) *WebhookStore {
+	return &WebhookStore{db: params.DB}
+}
+
+const webhookColumns = `id, user_id, url, events, created_at`
+
+func scanWebhook(row rowScanner) (m.Webhook, error) {
+	var webhook m.Webhook
+	var events string
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&events,
+		&webhook.CreatedAt,
+	)
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+	return webhook, err
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, error, created_at`
+
+func scanWebhookDelivery(row rowScanner) (m.WebhookDelivery, error) {
+	var delivery m.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&delivery.CreatedAt,
+	)
+	return delivery, err
+}
+
+// CreateWebhook adds an endpoint for the user with a new signing secret. The
+// returned webhook holds the secret, GetWebhooks leaves it out.
+func (s *WebhookStore) CreateWebhook(webhook m.Webhook) (m.Webhook, error) {
+	rawSecret := make([]byte, 24)
+	if _, err := rand.Read(rawSecret); err != nil {
+		return m.Webhook{}, err
+	}
+	webhook.Secret = webhookSecretPrefix + hex.EncodeToString(rawSecret)
+	webhook.CreatedAt = uint32(time.Now().Unix())
+
+	res, err := s.db.Exec(`
+		INSERT INTO webhooks (user_id, url, secret, events, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		webhook.UserID, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.CreatedAt)
+	if err != nil {
+		return m.Webhook{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return m.Webhook{}, err
+	}
+	webhook.ID = int(id)
+	return webhook, nil
+}
+
+// GetWebhooks returns the user's webhooks without their secrets, newest first
+func (s *WebhookStore) GetWebhooks(userID int) ([]m.Webhook, error) {
+	rows, err := s.db.Query(`
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []m.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook returns one of the user's webhooks without its secret
+func (s *WebhookStore) GetWebhook(id int, userID int) (m.Webhook, error) {
+	return scanWebhook(s.db.QueryRow(`
+		SELECT `+webhookColumns+`
+		FROM webhooks
+		WHERE id = ? AND user_id = ?`, id, userID))
+}
+
+// DeleteWebhook removes one of the user's webhooks with its deliveries,
+// ErrNotFound is returned when the webhook does not exist or belongs to
+// someone else
+func (s *WebhookStore) DeleteWebhook(id int, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetWebhookDeliveries returns the latest deliveries of one of the user's
+// webhooks, newest first
+func (s *WebhookStore) GetWebhookDeliveries(webhookID int, userID int, limit int) ([]m.WebhookDelivery, error) {
+	rows, err := s.db.Query(`
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = ? AND EXISTS (SELECT 1 FROM webhooks WHERE webhooks.id = webhook_id AND webhooks.user_id = ?)
+		ORDER BY id DESC
+		LIMIT ?`, webhookID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []m.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// DueWebhookDelivery is a pending delivery with the endpoint to send it to
+type DueWebhookDelivery struct {
+	m.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// GetDueWebhookDeliveries returns pending deliveries whose next attempt is
+// due, oldest first
+func (s *WebhookStore) GetDueWebhookDeliveries(now time.Time, limit int) ([]DueWebhookDelivery, error) {
+	rows, err := s.db.Query(`
+		SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at,
+			d.response_status, d.error, d.created_at, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?`, m.DeliveryPending, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []DueWebhookDelivery
+	for rows.Next() {
+		var due DueWebhookDelivery
+		d := &due.WebhookDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.Event,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastAttemptAt,
+			&d.ResponseStatus,
+			&d.Error,
+			&d.CreatedAt,
+			&due.URL,
+			&due.Secret,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, due)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordWebhookAttempt stores the outcome of an attempt. A nil nextAttempt
+// ends the delivery: it is delivered when problem is empty, failed otherwise.
+func (s *WebhookStore) RecordWebhookAttempt(id int, at time.Time, responseStatus int, problem string, nextAttempt *time.Time) error {
+	status := m.DeliveryPending
+	var next *int64
+	switch {
+	case nextAttempt != nil:
+		unix := nextAttempt.Unix()
+		next = &unix
+	case problem == "":
+		status = m.DeliveryDelivered
+	default:
+		status = m.DeliveryFailed
+	}
+	var response *int
+	if responseStatus != 0 {
+		response = &responseStatus
+	}
+	var errorText *string
+	if problem != "" {
+		errorText = &problem
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_attempt_at = ?, response_status = ?, error = ?
+		WHERE id = ?`,
+		status, next, at.Unix(), response, errorText, id)
+	return err
+}
+
+// PurgeWebhookDeliveriesBefore removes finished deliveries created before the
+// cutoff so the log does not grow without end
+func (s *WebhookStore) PurgeWebhookDeliveriesBefore(cutoff time.Time) (int64, error) {
+	res, err := s.db.Exec(`
+		DELETE FROM webhook_deliveries
+		WHERE status != ? AND created_at < ?`, m.DeliveryPending, cutoff.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// enqueueWebhookEvent queues a delivery of a change for every webhook of the
+// user subscribed to its event. It runs in the transaction of the change, so
+// an event is only sent for a change that was committed.
+func enqueueWebhookEvent(q dbExecutor, userID int, entityType string, action string, changeID int64, createdAt int64, after *string) error {
+	event, ok := m.WebhookEventFor(entityType, action)
+	if !ok || after == nil {
+		return nil
+	}
+	payload, err := json.Marshal(m.WebhookPayload{
+		ID:        "evt_" + strconv.FormatInt(changeID, 10),
+		Event:     event,
+		CreatedAt: uint32(createdAt),
+		Data:      json.RawMessage(*after),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, created_at)
+		SELECT id, ?, ?, ?, ?, ?
+		FROM webhooks
+		WHERE user_id = ? AND ',' || events || ',' LIKE '%,' || ? || ',%'`,
+		event, string(payload), m.DeliveryPending, createdAt, createdAt, userID, event)
+	return err
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// The year in review is built from the yearly statistics so its query lives
+// on TripStore, the public links to it are kept in year_review_shares.
+
+// GetYearInReview collects the statistics of one year (UTC): the flights per
+// month, the arrival countries and the distance and time flown. The name is
+// left to the caller.
+func (t *TripStore) GetYearInReview(userID int, year int) (m.YearInReview, error) {
+	review := m.YearInReview{Year: year}
+	label := strconv.Itoa(year)
+
+	var err error
+	review.FlightsPerMonth, err = t.getFlightsPerMonthForYear(userID, label)
+	if err != nil {
+		return review, err
+	}
+	for _, month := range review.FlightsPerMonth {
+		review.Flights += month.Count
+	}
+	review.Countries, err = t.getCountriesCountForYear(userID, label)
+	if err != nil {
+		return review, err
+	}
+	review.Totals, err = t.GetTotalMileageAndTimeForYear(userID, label)
+	if err != nil {
+		return review, err
+	}
+	return review, nil
+}
+
+// GetYearsWithFlights returns the years of the last decade in which the user
+// flew, oldest first
+func (t *TripStore) GetYearsWithFlights(userID int) ([]int, error) {
+	flights, err := t.getFlightsForYears(userID)
+	if err != nil {
+		return nil, err
+	}
+	var years []int
+	for _, flight := range flights {
+		if flight.Count == 0 {
+			continue
+		}
+		if year, err := strconv.Atoi(flight.Label); err == nil {
+			years = append(years, year)
+		}
+	}
+	return years, nil
+}
+
+// Handles the functions accessing table year_review_shares
+type YearReviewShareStore struct {
+	db *sql.DB
+}
+
+type NewYearReviewShareStoreParams struct {
+	DB *sql.DB
+}
+
+func NewYearReviewShareStore(params NewYearReviewShareStoreParams) *YearReviewShareStore {
+	return &YearReviewShareStore{db: params.DB}
+}
+
+// RotateYearReviewShare publishes the card of the year under a new token, a
+// previous link to the same year stops working
+func (s *YearReviewShareStore) RotateYearReviewShare(userID int, year int) (m.YearReviewShare, error) {
+	rawToken := make([]byte, 32)
+	if _, err := rand.Read(rawToken); err != nil {
+		return m.YearReviewShare{}, err
+	}
+	share := m.YearReviewShare{
+		UserID:    userID,
+		Year:      year,
+		Token:     hex.EncodeToString(rawToken),
+		CreatedAt: uint32(time.Now().Unix()),
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO year_review_shares (user_id, year, token_hash, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, year) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		share.UserID, share.Year, hashToken(share.Token), share.CreatedAt)
+	if err != nil {
+		return m.YearReviewShare{}, err
+	}
+	return share, nil
+}
+
+func (s *YearReviewShareStore) RevokeYearReviewShare(userID int, year int) error {
+	_, err := s.db.Exec(`DELETE FROM year_review_shares WHERE user_id = ? AND year = ?`, userID, year)
+	return err
+}
+
+// GetYearReviewShares returns the published years of the user, without their tokens
+func (s *YearReviewShareStore) GetYearReviewShares(userID int) ([]m.YearReviewShare, error) {
+	rows, err := s.db.Query(`
+		SELECT year, created_at FROM year_review_shares WHERE user_id = ? ORDER BY year`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []m.YearReviewShare
+	for rows.Next() {
+		share := m.YearReviewShare{UserID: userID}
+		if err := rows.Scan(&share.Year, &share.CreatedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// GetYearReviewShareForToken returns sql.ErrNoRows for unknown or unpublished tokens
+func (s *YearReviewShareStore) GetYearReviewShareForToken(token string) (m.YearReviewShare, error) {
+	var share m.YearReviewShare
+	err := s.db.QueryRow(`
+		SELECT user_id, year, created_at FROM year_review_shares WHERE token_hash = ?`, hashToken(token)).
+		Scan(&share.UserID, &share.Year, &share.CreatedAt)
+	return share, err
+}
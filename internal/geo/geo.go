@@ -0,0 +1,201 @@
+// Package geo writes the user's flights and places in standard geospatial
+// formats (GeoJSON, KML and GPX) so they can be opened in GIS, globe and GPS
+// apps. Flights are drawn as great-circle paths that are cut where they cross
+// the antimeridian, places as points.
+package geo
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Format is one of the export formats
+type Format string
+
+const (
+	FormatGeoJSON Format = "geojson"
+	FormatKML     Format = "kml"
+	FormatGPX     Format = "gpx"
+)
+
+// Formats lists the export formats in the order they are offered
+var Formats = []Format{FormatGeoJSON, FormatKML, FormatGPX}
+
+// ParseFormat reads a format name such as "kml"
+func ParseFormat(name string) (Format, bool) {
+	for _, format := range Formats {
+		if string(format) == strings.ToLower(name) {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// ContentType is the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	case FormatGPX:
+		return "application/gpx+xml"
+	default:
+		return "application/geo+json"
+	}
+}
+
+// Label is the name shown to users
+func (f Format) Label() string {
+	switch f {
+	case FormatKML:
+		return "KML"
+	case FormatGPX:
+		return "GPX"
+	default:
+		return "GeoJSON"
+	}
+}
+
+// Write writes the flights and places in the given format. Flights whose
+// airports have no coordinates are left out.
+func Write(w io.Writer, format Format, name string, trips []m.Trip, places []m.Place, now time.Time) error {
+	var flights []m.Trip
+	for _, trip := range trips {
+		if hasCoordinates(trip) {
+			flights = append(flights, trip)
+		}
+	}
+
+	switch format {
+	case FormatGeoJSON:
+		return writeGeoJSON(w, flights, places)
+	case FormatKML:
+		return writeKML(w, name, flights, places)
+	case FormatGPX:
+		return writeGPX(w, name, flights, places, now)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// Airports missing from the airports table are joined as 0, 0
+func hasCoordinates(trip m.Trip) bool {
+	return (trip.DepartureLat != 0 || trip.DepartureLon != 0) &&
+		(trip.ArrivalLat != 0 || trip.ArrivalLon != 0)
+}
+
+// flightName is e.g. "JAL 5 JFK → NRT"
+func flightName(trip m.Trip) string {
+	flight := strings.TrimSpace(trip.Airline + " " + trip.FlightNumber)
+	return strings.TrimSpace(flight + " " + trip.Departure + " → " + trip.Arrival)
+}
+
+// Point is a position on a great-circle path. Fraction is how far along the
+// path it is, from 0 at the departure to 1 at the arrival.
+type Point struct {
+	Lat      float64
+	Lon      float64
+	Fraction float64
+}
+
+const (
+	// stepKm is the distance between interpolated points, short enough that
+	// the path looks curved when drawn with straight lines
+	stepKm   = 100.0
+	maxSteps = 256
+)
+
+// GreatCircle returns the shortest path between two coordinates as one or
+// more parts. The path is cut where it crosses the antimeridian, the first
+// part ends on one side at ±180 and the next one starts on the other side, as
+// RFC 7946 asks for GeoJSON.
+func GreatCircle(fromLat, fromLon, toLat, toLon float64) [][]Point {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	toDeg := func(rad float64) float64 { return rad * 180 / math.Pi }
+
+	lat1, lon1, lat2, lon2 := toRad(fromLat), toRad(fromLon), toRad(toLat), toRad(toLon)
+	distance := m.GreatCircleDistanceKm(fromLat, fromLon, toLat, toLon)
+	// angle is the distance in radians as seen from the centre of the earth
+	angle := distance / m.EarthRadiusKm
+	steps := int(math.Min(maxSteps, math.Max(1, math.Ceil(distance/stepKm))))
+
+	points := make([]Point, 0, steps+1)
+	for i := 0; i <= steps; i++ {
+		f := float64(i) / float64(steps)
+		if math.Sin(angle) < 1e-9 {
+			// The same airport, or exactly antipodal ones where every great
+			// circle is as short; fall back to a straight line
+			points = append(points, Point{
+				Lat:      fromLat + f*(toLat-fromLat),
+				Lon:      fromLon + f*(toLon-fromLon),
+				Fraction: f,
+			})
+			continue
+		}
+		a := math.Sin((1-f)*angle) / math.Sin(angle)
+		b := math.Sin(f*angle) / math.Sin(angle)
+		x := a*math.Cos(lat1)*math.Cos(lon1) + b*math.Cos(lat2)*math.Cos(lon2)
+		y := a*math.Cos(lat1)*math.Sin(lon1) + b*math.Cos(lat2)*math.Sin(lon2)
+		z := a*math.Sin(lat1) + b*math.Sin(lat2)
+		points = append(points, Point{
+			Lat:      toDeg(math.Atan2(z, math.Sqrt(x*x+y*y))),
+			Lon:      toDeg(math.Atan2(y, x)),
+			Fraction: f,
+		})
+	}
+
+	return splitAtAntimeridian(points)
+}
+
+// splitAtAntimeridian starts a new part wherever consecutive points are more
+// than half the world apart in longitude, adding the crossing point at ±180
+// to both parts. A path over a pole turns by exactly half the world without
+// crossing, rounding must not make that a crossing.
+func splitAtAntimeridian(points []Point) [][]Point {
+	parts := [][]Point{{points[0]}}
+	for i := 1; i < len(points); i++ {
+		prev, next := points[i-1], points[i]
+		if math.Abs(next.Lon-prev.Lon) > 180+1e-6 {
+			edge, nextLon := 180.0, next.Lon+360
+			if prev.Lon < 0 {
+				edge, nextLon = -180.0, next.Lon-360
+			}
+			t := (edge - prev.Lon) / (nextLon - prev.Lon)
+			crossing := Point{
+				Lat:      prev.Lat + t*(next.Lat-prev.Lat),
+				Lon:      edge,
+				Fraction: prev.Fraction + t*(next.Fraction-prev.Fraction),
+			}
+			parts[len(parts)-1] = append(parts[len(parts)-1], crossing)
+			crossing.Lon = -edge
+			parts = append(parts, []Point{crossing})
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], next)
+	}
+	return parts
+}
+
+// round keeps about a metre of precision, which keeps the files small
+func round(degrees float64) float64 {
+	return math.Round(degrees*1e5) / 1e5
+}
+
+// flightTime is the time at a fraction of the flight, assuming a constant speed
+func flightTime(trip m.Trip, fraction float64) time.Time {
+	seconds := float64(trip.ArrivalTime) - float64(trip.DepartureTime)
+	return time.Unix(int64(trip.DepartureTime)+int64(math.Round(fraction*seconds)), 0).UTC()
+}
+
+func unixUTC(unix uint32) time.Time {
+	return time.Unix(int64(unix), 0).UTC()
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
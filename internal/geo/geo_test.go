@@ -0,0 +1,140 @@
+package geo
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGreatCircle(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to [2]float64
+		// edges is where each part but the last ends, +180 or -180
+		edges  []float64
+		minLat float64
+		maxLat float64
+	}{
+		{name: "JFK to LHR", from: [2]float64{40.64, -73.78}, to: [2]float64{51.47, -0.45}, maxLat: 60},
+		{name: "NRT to LAX, eastbound over the antimeridian", from: [2]float64{35.76, 140.39}, to: [2]float64{33.94, -118.41}, edges: []float64{180}, maxLat: 50},
+		{name: "LAX to NRT, westbound over the antimeridian", from: [2]float64{33.94, -118.41}, to: [2]float64{35.76, 140.39}, edges: []float64{-180}, maxLat: 50},
+		{name: "SYD to SCL, southern hemisphere", from: [2]float64{-33.95, 151.18}, to: [2]float64{-33.39, -70.79}, edges: []float64{180}, minLat: -75, maxLat: -33},
+		{name: "the same airport", from: [2]float64{35.76, 140.39}, to: [2]float64{35.76, 140.39}, maxLat: 36},
+		{name: "over the north pole", from: [2]float64{60, 10}, to: [2]float64{60, -170}, maxLat: 90},
+		{name: "near the north pole, over the antimeridian", from: [2]float64{80, 150}, to: [2]float64{80, -60}, edges: []float64{180}, maxLat: 90},
+		{name: "near the south pole, over the antimeridian", from: [2]float64{-80, -150}, to: [2]float64{-80, 60}, edges: []float64{-180}, minLat: -90, maxLat: -79},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parts := GreatCircle(test.from[0], test.from[1], test.to[0], test.to[1])
+			if len(parts) != len(test.edges)+1 {
+				t.Fatalf("%d parts, want %d", len(parts), len(test.edges)+1)
+			}
+
+			first, last := parts[0][0], parts[len(parts)-1][len(parts[len(parts)-1])-1]
+			if !near(first.Lat, test.from[0]) || !near(first.Lon, test.from[1]) || first.Fraction != 0 {
+				t.Errorf("starts at %+v, want %v", first, test.from)
+			}
+			if !near(last.Lat, test.to[0]) || !near(last.Lon, test.to[1]) || last.Fraction != 1 {
+				t.Errorf("ends at %+v, want %v", last, test.to)
+			}
+
+			fraction := 0.0
+			for i, part := range parts {
+				for j, point := range part {
+					if point.Lat < test.minLat-1 || point.Lat > test.maxLat {
+						t.Errorf("part %d point %d at latitude %v, want between %v and %v", i, j, point.Lat, test.minLat, test.maxLat)
+					}
+					if point.Lon < -180 || point.Lon > 180 {
+						t.Errorf("part %d point %d at longitude %v", i, j, point.Lon)
+					}
+					if point.Fraction < fraction {
+						t.Errorf("part %d point %d goes back to %v from %v", i, j, point.Fraction, fraction)
+					}
+					fraction = point.Fraction
+					if j > 0 && math.Abs(point.Lon-part[j-1].Lon) > 180+1e-6 {
+						t.Errorf("part %d jumps from %v to %v", i, part[j-1].Lon, point.Lon)
+					}
+				}
+				if i == len(parts)-1 {
+					continue
+				}
+
+				end, start := part[len(part)-1], parts[i+1][0]
+				if end.Lon != test.edges[i] || start.Lon != -test.edges[i] {
+					t.Errorf("part %d ends at %v and the next starts at %v, want %v and %v", i, end.Lon, start.Lon, test.edges[i], -test.edges[i])
+				}
+				if end.Lat != start.Lat || end.Fraction != start.Fraction {
+					t.Errorf("part %d ends at %+v, the next starts at %+v", i, end, start)
+				}
+			}
+		})
+	}
+}
+
+func TestGreatCircleSplitsLongRoutes(t *testing.T) {
+	// JFK to SIN is about 15,000 km, the steps are capped
+	parts := GreatCircle(40.64, -73.78, 1.36, 103.99)
+	points := 0
+	for _, part := range parts {
+		points += len(part)
+	}
+	if points < 100 || points > maxSteps+1+2*(len(parts)-1) {
+		t.Errorf("%d points", points)
+	}
+}
+
+func TestSplitAtAntimeridian(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []Point
+		want   [][]Point
+	}{
+		{
+			name:   "eastbound",
+			points: []Point{{Lat: 0, Lon: 170, Fraction: 0}, {Lat: 10, Lon: -170, Fraction: 1}},
+			want: [][]Point{
+				{{Lat: 0, Lon: 170, Fraction: 0}, {Lat: 5, Lon: 180, Fraction: 0.5}},
+				{{Lat: 5, Lon: -180, Fraction: 0.5}, {Lat: 10, Lon: -170, Fraction: 1}},
+			},
+		},
+		{
+			name:   "westbound, off the middle",
+			points: []Point{{Lat: 0, Lon: -175, Fraction: 0}, {Lat: 20, Lon: 165, Fraction: 1}},
+			want: [][]Point{
+				{{Lat: 0, Lon: -175, Fraction: 0}, {Lat: 5, Lon: -180, Fraction: 0.25}},
+				{{Lat: 5, Lon: 180, Fraction: 0.25}, {Lat: 20, Lon: 165, Fraction: 1}},
+			},
+		},
+		{
+			name:   "there and back",
+			points: []Point{{Lon: 170}, {Lon: -170, Fraction: 0.5}, {Lon: 170, Fraction: 1}},
+			want: [][]Point{
+				{{Lon: 170}, {Lon: 180, Fraction: 0.25}},
+				{{Lon: -180, Fraction: 0.25}, {Lon: -170, Fraction: 0.5}, {Lon: -180, Fraction: 0.75}},
+				{{Lon: 180, Fraction: 0.75}, {Lon: 170, Fraction: 1}},
+			},
+		},
+		{
+			name:   "half the world apart is not a crossing",
+			points: []Point{{Lat: 89, Lon: 10}, {Lat: 89, Lon: -170, Fraction: 1}},
+			want:   [][]Point{{{Lat: 89, Lon: 10}, {Lat: 89, Lon: -170, Fraction: 1}}},
+		},
+		{
+			name:   "along the antimeridian",
+			points: []Point{{Lat: 0, Lon: 180}, {Lat: 10, Lon: 180, Fraction: 1}},
+			want:   [][]Point{{{Lat: 0, Lon: 180}, {Lat: 10, Lon: 180, Fraction: 1}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := splitAtAntimeridian(test.points); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got  %+v\nwant %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
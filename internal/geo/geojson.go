@@ -0,0 +1,98 @@
+package geo
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// RFC 7946 objects, coordinates are [longitude, latitude]
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+func writeGeoJSON(w io.Writer, trips []m.Trip, places []m.Place) error {
+	collection := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	for _, trip := range trips {
+		var lines [][][2]float64
+		for _, part := range GreatCircle(trip.DepartureLat, trip.DepartureLon, trip.ArrivalLat, trip.ArrivalLon) {
+			line := make([][2]float64, 0, len(part))
+			for _, point := range part {
+				line = append(line, [2]float64{round(point.Lon), round(point.Lat)})
+			}
+			lines = append(lines, line)
+		}
+		geometry := geoJSONGeometry{Type: "MultiLineString", Coordinates: lines}
+		if len(lines) == 1 {
+			geometry = geoJSONGeometry{Type: "LineString", Coordinates: lines[0]}
+		}
+
+		properties := map[string]any{
+			"kind":           "flight",
+			"id":             trip.ID,
+			"name":           flightName(trip),
+			"departure":      trip.Departure,
+			"arrival":        trip.Arrival,
+			"departure_time": unixUTC(trip.DepartureTime).Format(time.RFC3339),
+			"arrival_time":   unixUTC(trip.ArrivalTime).Format(time.RFC3339),
+			"airline":        trip.Airline,
+			"flight_number":  trip.FlightNumber,
+			"distance_km":    math.Round(trip.DistanceKm),
+		}
+		addOptional(properties, "cabin_class", trip.CabinClass)
+		addOptional(properties, "aircraft_type", trip.AircraftType)
+		addOptional(properties, "seat", trip.Seat)
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geometry,
+			Properties: properties,
+		})
+	}
+
+	for _, place := range places {
+		properties := map[string]any{
+			"kind":         "place",
+			"id":           place.ID,
+			"name":         place.Name,
+			"visit_date":   unixUTC(place.VisitDate).Format(m.VisitDateLayout),
+			"marker_color": place.MarkerColor,
+			// simplestyle-spec, used by geojson.io and GitHub to color the marker
+			"marker-color": place.MarkerColor,
+		}
+		addOptional(properties, "address", place.Address)
+		addOptional(properties, "category", place.Category)
+		addOptional(properties, "notes", place.Notes)
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{round(place.Longitude), round(place.Latitude)},
+			},
+			Properties: properties,
+		})
+	}
+
+	return json.NewEncoder(w).Encode(collection)
+}
+
+func addOptional(properties map[string]any, name string, value *string) {
+	if value != nil && *value != "" {
+		properties[name] = *value
+	}
+}
@@ -0,0 +1,99 @@
+package geo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// GPX 1.1 documents, waypoints have to come before tracks
+type gpxDocument struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	XMLNS     string        `xml:"xmlns,attr"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Name      string        `xml:"metadata>name"`
+	Time      string        `xml:"metadata>time"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+	Tracks    []gpxTrack    `xml:"trk"`
+}
+
+type gpxWaypoint struct {
+	Lat         float64 `xml:"lat,attr"`
+	Lon         float64 `xml:"lon,attr"`
+	Time        string  `xml:"time,omitempty"`
+	Name        string  `xml:"name,omitempty"`
+	Comment     string  `xml:"cmt,omitempty"`
+	Description string  `xml:"desc,omitempty"`
+	Type        string  `xml:"type,omitempty"`
+}
+
+type gpxTrack struct {
+	Name        string       `xml:"name"`
+	Description string       `xml:"desc,omitempty"`
+	Type        string       `xml:"type"`
+	Segments    []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxWaypoint `xml:"trkpt"`
+}
+
+func writeGPX(w io.Writer, name string, trips []m.Trip, places []m.Place, now time.Time) error {
+	document := gpxDocument{
+		XMLNS:   "http://www.topografix.com/GPX/1/1",
+		Version: "1.1",
+		Creator: "trip-tracker",
+		Name:    name,
+		Time:    now.UTC().Format(time.RFC3339),
+	}
+
+	// GPX has no field for a marker color, the address goes in the comment
+	for _, place := range places {
+		document.Waypoints = append(document.Waypoints, gpxWaypoint{
+			Lat:         round(place.Latitude),
+			Lon:         round(place.Longitude),
+			Time:        unixUTC(place.VisitDate).Format(time.RFC3339),
+			Name:        place.Name,
+			Comment:     stringValue(place.Address),
+			Description: stringValue(place.Notes),
+			Type:        stringValue(place.Category),
+		})
+	}
+
+	// Track points are timed as if the aircraft flew at a constant speed,
+	// which lets GPS apps replay the flight
+	for _, trip := range trips {
+		track := gpxTrack{
+			Name:        flightName(trip),
+			Description: fmt.Sprintf("%.0f km", trip.DistanceKm),
+			Type:        "flight",
+		}
+		for _, part := range GreatCircle(trip.DepartureLat, trip.DepartureLon, trip.ArrivalLat, trip.ArrivalLon) {
+			var segment gpxSegment
+			for _, point := range part {
+				segment.Points = append(segment.Points, gpxWaypoint{
+					Lat:  round(point.Lat),
+					Lon:  round(point.Lon),
+					Time: flightTime(trip, point.Fraction).Format(time.RFC3339),
+				})
+			}
+			track.Segments = append(track.Segments, segment)
+		}
+		document.Tracks = append(document.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
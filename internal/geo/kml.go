@@ -0,0 +1,177 @@
+package geo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// flightColor is the mint accent of the app as a KML color (aabbggrr)
+const flightColor = "ffb0e026"
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{2})([0-9a-fA-F]{2})([0-9a-fA-F]{2})$`)
+
+type kmlDocument struct {
+	XMLName xml.Name    `xml:"kml"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Name    string      `xml:"Document>name"`
+	Styles  []kmlStyle  `xml:"Document>Style"`
+	Folders []kmlFolder `xml:"Document>Folder"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+// Parent elements of a>b paths are written even when the value is empty, so
+// optional elements are pointers
+type kmlStyle struct {
+	ID        string        `xml:"id,attr,omitempty"`
+	LineStyle *kmlLineStyle `xml:"LineStyle,omitempty"`
+	IconStyle *kmlIconStyle `xml:"IconStyle,omitempty"`
+}
+
+type kmlLineStyle struct {
+	Color string `xml:"color"`
+	Width int    `xml:"width"`
+}
+
+type kmlIconStyle struct {
+	Color string `xml:"color"`
+}
+
+type kmlPlacemark struct {
+	Name          string            `xml:"name"`
+	Description   string            `xml:"description,omitempty"`
+	TimeSpan      *kmlTimeSpan      `xml:"TimeSpan,omitempty"`
+	TimeStamp     *kmlTimeStamp     `xml:"TimeStamp,omitempty"`
+	StyleURL      string            `xml:"styleUrl,omitempty"`
+	Style         *kmlStyle         `xml:"Style,omitempty"`
+	Data          []kmlData         `xml:"ExtendedData>Data,omitempty"`
+	Point         *kmlPoint         `xml:"Point,omitempty"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+type kmlTimeSpan struct {
+	Begin string `xml:"begin"`
+	End   string `xml:"end"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlMultiGeometry struct {
+	LineStrings []kmlLineString `xml:"LineString"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+func writeKML(w io.Writer, name string, trips []m.Trip, places []m.Place) error {
+	document := kmlDocument{
+		XMLNS:  "http://www.opengis.net/kml/2.2",
+		Name:   name,
+		Styles: []kmlStyle{{ID: "flight", LineStyle: &kmlLineStyle{Color: flightColor, Width: 2}}},
+	}
+
+	flights := kmlFolder{Name: "Flights"}
+	for _, trip := range trips {
+		geometry := &kmlMultiGeometry{}
+		for _, part := range GreatCircle(trip.DepartureLat, trip.DepartureLon, trip.ArrivalLat, trip.ArrivalLon) {
+			coordinates := make([]string, 0, len(part))
+			for _, point := range part {
+				coordinates = append(coordinates, kmlCoordinate(point.Lat, point.Lon))
+			}
+			geometry.LineStrings = append(geometry.LineStrings, kmlLineString{
+				Tessellate:  1,
+				Coordinates: strings.Join(coordinates, " "),
+			})
+		}
+
+		data := []kmlData{
+			{Name: "departure", Value: trip.Departure},
+			{Name: "arrival", Value: trip.Arrival},
+			{Name: "airline", Value: trip.Airline},
+			{Name: "flight_number", Value: trip.FlightNumber},
+			{Name: "distance_km", Value: fmt.Sprintf("%.0f", trip.DistanceKm)},
+		}
+		data = appendData(data, "cabin_class", trip.CabinClass)
+		data = appendData(data, "aircraft_type", trip.AircraftType)
+		data = appendData(data, "seat", trip.Seat)
+		flights.Placemarks = append(flights.Placemarks, kmlPlacemark{
+			Name: flightName(trip),
+			TimeSpan: &kmlTimeSpan{
+				Begin: unixUTC(trip.DepartureTime).Format(time.RFC3339),
+				End:   unixUTC(trip.ArrivalTime).Format(time.RFC3339),
+			},
+			StyleURL:      "#flight",
+			Data:          data,
+			MultiGeometry: geometry,
+		})
+	}
+
+	placeFolder := kmlFolder{Name: "Places"}
+	for _, place := range places {
+		data := []kmlData{{Name: "marker_color", Value: place.MarkerColor}}
+		data = appendData(data, "address", place.Address)
+		data = appendData(data, "category", place.Category)
+		placeFolder.Placemarks = append(placeFolder.Placemarks, kmlPlacemark{
+			Name:        place.Name,
+			Description: stringValue(place.Notes),
+			TimeStamp:   &kmlTimeStamp{When: unixUTC(place.VisitDate).Format(m.VisitDateLayout)},
+			Style:       &kmlStyle{IconStyle: &kmlIconStyle{Color: kmlColor(place.MarkerColor)}},
+			Data:        data,
+			Point:       &kmlPoint{Coordinates: kmlCoordinate(place.Latitude, place.Longitude)},
+		})
+	}
+	document.Folders = []kmlFolder{flights, placeFolder}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func kmlCoordinate(lat, lon float64) string {
+	return fmt.Sprintf("%g,%g", round(lon), round(lat))
+}
+
+// kmlColor turns a CSS hex color into the aabbggrr order KML uses
+func kmlColor(hex string) string {
+	match := hexColorPattern.FindStringSubmatch(hex)
+	if match == nil {
+		return flightColor
+	}
+	return strings.ToLower("ff" + match[3] + match[2] + match[1])
+}
+
+func appendData(data []kmlData, name string, value *string) []kmlData {
+	if value != nil && *value != "" {
+		data = append(data, kmlData{Name: name, Value: *value})
+	}
+	return data
+}
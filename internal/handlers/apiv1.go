@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+// The /api/v1 handlers speak JSON only. Every error is a models.APIError so
+// clients can switch on its code, validation errors name the failing fields.
+
+const (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 200
+	// apiMaxBody limits request bodies, a trip or place is a few hundred bytes
+	apiMaxBody = 64 << 10
+)
+
+// apiList is a page of records, NextCursor is set when there are more
+type apiList[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string, fields map[string]string) {
+	m.WriteAPIError(w, status, models.APIError{Code: code, Message: message, Fields: fields})
+}
+
+func apiNotFound(w http.ResponseWriter) {
+	writeAPIError(w, http.StatusNotFound, "not_found", "Not found", nil)
+}
+
+func apiInternalError(w http.ResponseWriter) {
+	writeAPIError(w, http.StatusInternalServerError, "internal_error", "Something went wrong, try again later", nil)
+}
+
+// apiUser returns the user of the request or answers 401. AddAPIUserToContext
+// already rejects calls without a user, this keeps the handlers safe on their own.
+func apiUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := r.Context().Value(m.UserKey).(int)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "Sign in or send an access token", nil)
+	}
+	return userID, ok
+}
+
+// apiID reads the {id} path value, a malformed id is a record that does not exist
+func apiID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		apiNotFound(w)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeJSON reads the request body into v. Unknown fields are ignored so a
+// record read from the API can be sent back with its read-only fields.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBody)).Decode(v)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &typeErr):
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", "The request body is not valid", map[string]string{typeErr.Field: "must be a " + typeErr.Type.String()})
+	case errors.As(err, &sizeErr):
+		writeAPIError(w, http.StatusRequestEntityTooLarge, "too_large", "The request body is too large", nil)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", "The request body must be a JSON object", nil)
+	default:
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", "The request body is not valid", nil)
+	}
+	return false
+}
+
+// validationErrors collects the problems of a request by field
+type validationErrors map[string]string
+
+func (v validationErrors) add(field, problem string) {
+	if _, ok := v[field]; !ok {
+		v[field] = problem
+	}
+}
+
+// write answers 422 when there are errors
+func (v validationErrors) write(w http.ResponseWriter, message string) bool {
+	if len(v) == 0 {
+		return false
+	}
+	writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", message, v)
+	return true
+}
+
+// trimmed returns nil for a missing or blank string
+func trimmed(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	value := strings.TrimSpace(*s)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// encodeCursor makes the opaque next_cursor of a page
+func encodeCursor(c models.ListCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", c.Time, c.ID)))
+}
+
+func decodeCursor(s string) (*models.ListCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	timePart, idPart, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	t, err := strconv.ParseUint(timePart, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ListCursor{Time: uint32(t), ID: id}, nil
+}
+
+// listParams are the query parameters shared by every list
+type listParams struct {
+	from, to uint32
+	after    *models.ListCursor
+	limit    int
+}
+
+// parseListParams reads from and to (dates, inclusive, UTC), cursor and limit
+func parseListParams(query map[string][]string, problems validationErrors) listParams {
+	get := func(name string) string {
+		if values := query[name]; len(values) > 0 {
+			return strings.TrimSpace(values[0])
+		}
+		return ""
+	}
+	params := listParams{limit: apiDefaultLimit}
+
+	if value := get("from"); value != "" {
+		from, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			problems.add("from", "must be a date like 2025-01-31")
+		} else {
+			params.from = uint32(from.Unix())
+		}
+	}
+	if value := get("to"); value != "" {
+		to, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			problems.add("to", "must be a date like 2025-12-31")
+		} else {
+			// The whole day is included
+			params.to = uint32(to.AddDate(0, 0, 1).Unix() - 1)
+		}
+	}
+	if params.from != 0 && params.to != 0 && params.to < params.from {
+		problems.add("to", "must not be before from")
+	}
+	if value := get("cursor"); value != "" {
+		after, err := decodeCursor(value)
+		if err != nil {
+			problems.add("cursor", "is not a cursor of this list")
+		} else {
+			params.after = after
+		}
+	}
+	if value := get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > apiMaxLimit {
+			problems.add("limit", fmt.Sprintf("must be between 1 and %d", apiMaxLimit))
+		} else {
+			params.limit = limit
+		}
+	}
+	return params
+}
+
+// page cuts records, fetched with one extra, to the limit and makes the cursor
+// of the next page from the last record shown
+func page[T any](records []T, limit int, cursor func(T) models.ListCursor) apiList[T] {
+	list := apiList[T]{Data: records}
+	if list.Data == nil {
+		list.Data = []T{}
+	}
+	if len(records) > limit {
+		list.Data = records[:limit]
+		list.NextCursor = encodeCursor(cursor(records[limit-1]))
+	}
+	return list
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteAccessTokenHandler struct {
+	accessTokenStore *db.AccessTokenStore
+}
+
+type DeleteAccessTokenHandlerParams struct {
+	AccessTokenStore *db.AccessTokenStore
+}
+
+func NewDeleteAccessTokenHandler(params DeleteAccessTokenHandlerParams) *DeleteAccessTokenHandler {
+	return &DeleteAccessTokenHandler{
+		accessTokenStore: params.AccessTokenStore,
+	}
+}
+
+// DELETE /settings/tokens?id= revokes an access token, calls made with it are
+// refused from then on
+func (h *DeleteAccessTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid token id", http.StatusBadRequest)
+		return
+	}
+	err = h.accessTokenStore.RevokeAccessToken(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error revoking access token %d: %v", id, err)
+		http.Error(w, "Error revoking access token", http.StatusInternalServerError)
+		return
+	}
+	renderAccessTokens(w, r, h.accessTokenStore, userID, nil, "")
+}
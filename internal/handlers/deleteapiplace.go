@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+type DeleteApiPlaceHandler struct {
+	placeStore *db.PlaceStore
+}
+
+type DeleteApiPlaceHandlerParams struct {
+	PlaceStore *db.PlaceStore
+}
+
+func NewDeleteApiPlaceHandler(params DeleteApiPlaceHandlerParams) *DeleteApiPlaceHandler {
+	return &DeleteApiPlaceHandler{
+		placeStore: params.PlaceStore,
+	}
+}
+
+// DELETE /api/v1/places/{id} moves the place to the trash like the places page
+func (h *DeleteApiPlaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.placeStore.DeletePlace(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		apiNotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting place %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+type DeleteApiTripHandler struct {
+	tripStore *db.TripStore
+}
+
+type DeleteApiTripHandlerParams struct {
+	TripStore *db.TripStore
+}
+
+func NewDeleteApiTripHandler(params DeleteApiTripHandlerParams) *DeleteApiTripHandler {
+	return &DeleteApiTripHandler{
+		tripStore: params.TripStore,
+	}
+}
+
+// DELETE /api/v1/trips/{id} moves the trip to the trash like the trips page
+func (h *DeleteApiTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.tripStore.DeleteTrip(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		apiNotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting trip %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
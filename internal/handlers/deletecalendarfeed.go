@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type DeleteCalendarFeedHandler struct {
+	calendarFeedStore *db.CalendarFeedStore
+}
+
+type DeleteCalendarFeedHandlerParams struct {
+	CalendarFeedStore *db.CalendarFeedStore
+}
+
+func NewDeleteCalendarFeedHandler(params DeleteCalendarFeedHandlerParams) *DeleteCalendarFeedHandler {
+	return &DeleteCalendarFeedHandler{
+		calendarFeedStore: params.CalendarFeedStore,
+	}
+}
+
+// DELETE /settings/calendar revokes the calendar feed, subscribed calendars
+// stop receiving updates
+func (h *DeleteCalendarFeedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.calendarFeedStore.RevokeCalendarFeed(userID); err != nil {
+		http.Error(w, "Error revoking calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	err := templates.CalendarFeedSettings(nil, "").Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type DeleteImportReviewHandler struct {
+	importReviewStore *db.ImportReviewStore
+}
+
+type DeleteImportReviewHandlerParams struct {
+	ImportReviewStore *db.ImportReviewStore
+}
+
+func NewDeleteImportReviewHandler(params DeleteImportReviewHandlerParams) *DeleteImportReviewHandler {
+	return &DeleteImportReviewHandler{
+		importReviewStore: params.ImportReviewStore,
+	}
+}
+
+// DELETE /import/reviews?id= discards a row of the review queue
+func (h *DeleteImportReviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid review id", http.StatusBadRequest)
+		return
+	}
+	err = h.importReviewStore.DeleteImportReview(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error discarding import review %d: %v", id, err)
+		http.Error(w, "Error discarding row", http.StatusInternalServerError)
+		return
+	}
+
+	reviews, err := h.importReviewStore.GetImportReviews(userID)
+	if err != nil {
+		log.Printf("Error getting import reviews: %v", err)
+		http.Error(w, "Error getting review queue", http.StatusInternalServerError)
+		return
+	}
+	err = templates.ImportReviewQueue(reviews, nil).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
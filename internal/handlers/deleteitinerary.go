@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteItineraryHandler struct {
+	tripStore *db.TripStore
+}
+
+type DeleteItineraryHandlerParams struct {
+	TripStore *db.TripStore
+}
+
+func NewDeleteItineraryHandler(params DeleteItineraryHandlerParams) *DeleteItineraryHandler {
+	return &DeleteItineraryHandler{
+		tripStore: params.TripStore,
+	}
+}
+
+// Unpins a whole itinerary (?id=) or a single leg (?trip_id=). The flights themselves are kept.
+func (h *DeleteItineraryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var err error
+	if tripIDStr := r.URL.Query().Get("trip_id"); tripIDStr != "" {
+		tripID, convErr := strconv.Atoi(tripIDStr)
+		if convErr != nil {
+			http.Error(w, "Invalid trip ID", http.StatusBadRequest)
+			return
+		}
+		err = h.tripStore.UnpinLeg(userID, tripID)
+	} else {
+		itineraryID, convErr := strconv.Atoi(r.URL.Query().Get("id"))
+		if convErr != nil {
+			http.Error(w, "Invalid itinerary ID", http.StatusBadRequest)
+			return
+		}
+		err = h.tripStore.UnpinItinerary(userID, itineraryID)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error unpinning itinerary", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", `{"itinerary:changed":{}}`)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteJourneyHandler struct {
+	journeyStore *db.JourneyStore
+}
+
+type DeleteJourneyHandlerParams struct {
+	JourneyStore *db.JourneyStore
+}
+
+func NewDeleteJourneyHandler(params DeleteJourneyHandlerParams) *DeleteJourneyHandler {
+	return &DeleteJourneyHandler{
+		journeyStore: params.JourneyStore,
+	}
+}
+
+// Deletes the journey only, its flights and places are kept
+func (h *DeleteJourneyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	journeyID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid journey ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.journeyStore.DeleteJourney(journeyID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting journey", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/journeys")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteJourneyMemberHandler struct {
+	journeyStore *db.JourneyStore
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+}
+
+type DeleteJourneyMemberHandlerParams struct {
+	JourneyStore *db.JourneyStore
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+}
+
+func NewDeleteJourneyMemberHandler(params DeleteJourneyMemberHandlerParams) *DeleteJourneyMemberHandler {
+	return &DeleteJourneyMemberHandler{
+		journeyStore: params.JourneyStore,
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+	}
+}
+
+// Removes a flight (?trip_id=) or place (?place_id=) from the journey without
+// deleting it and re-renders the journey's members
+func (h *DeleteJourneyMemberHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	journeyID, err := strconv.Atoi(query.Get("journey_id"))
+	if err != nil {
+		http.Error(w, "Invalid journey ID", http.StatusBadRequest)
+		return
+	}
+
+	if tripIDStr := query.Get("trip_id"); tripIDStr != "" {
+		tripID, convErr := strconv.Atoi(tripIDStr)
+		if convErr != nil {
+			http.Error(w, "Invalid trip ID", http.StatusBadRequest)
+			return
+		}
+		err = h.journeyStore.RemoveTripFromJourney(journeyID, tripID, userID)
+	} else {
+		placeID, convErr := strconv.Atoi(query.Get("place_id"))
+		if convErr != nil {
+			http.Error(w, "Invalid place ID", http.StatusBadRequest)
+			return
+		}
+		err = h.journeyStore.RemovePlaceFromJourney(journeyID, placeID, userID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error removing from journey", http.StatusInternalServerError)
+		return
+	}
+
+	renderJourneyMembers(w, r, h.journeyStore, journeyID, userID, h.tripStore, h.placeStore)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type DeleteMailInboxHandler struct {
+	mailInboxStore *db.MailInboxStore
+}
+
+type DeleteMailInboxHandlerParams struct {
+	MailInboxStore *db.MailInboxStore
+}
+
+func NewDeleteMailInboxHandler(params DeleteMailInboxHandlerParams) *DeleteMailInboxHandler {
+	return &DeleteMailInboxHandler{
+		mailInboxStore: params.MailInboxStore,
+	}
+}
+
+// DELETE /settings/mail revokes the forwarding address, mail sent to it is
+// refused
+func (h *DeleteMailInboxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.mailInboxStore.RevokeMailInbox(userID); err != nil {
+		http.Error(w, "Error revoking forwarding address", http.StatusInternalServerError)
+		return
+	}
+
+	err := templates.MailInboxSettings(nil, mailDomain()).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -37,7 +39,12 @@ func (h *DeletePlaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Deleted places go to the trash and can be restored from /trash
 	err = h.placeStore.DeletePlace(placeID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error deleting place", http.StatusInternalServerError)
 		return
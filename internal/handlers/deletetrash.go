@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteTrashHandler struct {
+	tripStore  *db.TripStore
+	placeStore *db.PlaceStore
+}
+
+type DeleteTrashHandlerParams struct {
+	TripStore  *db.TripStore
+	PlaceStore *db.PlaceStore
+}
+
+func NewDeleteTrashHandler(params DeleteTrashHandlerParams) *DeleteTrashHandler {
+	return &DeleteTrashHandler{
+		tripStore:  params.TripStore,
+		placeStore: params.PlaceStore,
+	}
+}
+
+// Permanently deletes a single item from the trash with ?type=trip|place&id=,
+// or empties the whole trash when no type is given
+func (h *DeleteTrashHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	itemType := query.Get("type")
+
+	var err error
+	if itemType == "" {
+		_, err = h.tripStore.PurgeDeletedTrips(userID)
+		if err == nil {
+			_, err = h.placeStore.PurgeDeletedPlaces(userID)
+		}
+	} else {
+		id, convErr := strconv.Atoi(query.Get("id"))
+		if convErr != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		switch itemType {
+		case "trip":
+			err = h.tripStore.PurgeTrip(id, userID)
+		case "place":
+			err = h.placeStore.PurgePlace(id, userID)
+		default:
+			http.Error(w, "type must be trip or place", http.StatusBadRequest)
+			return
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error emptying trash", http.StatusInternalServerError)
+		return
+	}
+
+	renderTrashContents(w, r, h.tripStore, h.placeStore, userID)
+}
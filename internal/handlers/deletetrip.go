@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -28,7 +30,7 @@ func (t *DeleteTripHandler) ServeHTTP (w http.ResponseWriter, r *http.Request) {
 	tripID := r.URL.Query().Get("id")
 
 	ctx := r.Context()
-	_, ok := ctx.Value(m.UserKey).(int)
+	userID, ok := ctx.Value(m.UserKey).(int)
 	if !ok {
 		// redirect to home
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
@@ -40,7 +42,12 @@ func (t *DeleteTripHandler) ServeHTTP (w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error processing tripID for deletion", http.StatusInternalServerError)
 		return
 	}
-	err = t.tripStore.DeleteTrip(numTripId)
+	// Deleted trips go to the trash and can be restored from /trash
+	err = t.tripStore.DeleteTrip(numTripId, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
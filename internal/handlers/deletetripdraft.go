@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteTripDraftHandler struct {
+	tripDraftStore *db.TripDraftStore
+}
+
+type DeleteTripDraftHandlerParams struct {
+	TripDraftStore *db.TripDraftStore
+}
+
+func NewDeleteTripDraftHandler(params DeleteTripDraftHandlerParams) *DeleteTripDraftHandler {
+	return &DeleteTripDraftHandler{
+		tripDraftStore: params.TripDraftStore,
+	}
+}
+
+// DELETE /trips/drafts?id= discards a draft
+func (h *DeleteTripDraftHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid draft id", http.StatusBadRequest)
+		return
+	}
+	err = h.tripDraftStore.DeleteTripDraft(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error discarding trip draft %d: %v", id, err)
+		http.Error(w, "Error discarding draft", http.StatusInternalServerError)
+		return
+	}
+
+	renderTripDrafts(w, r, h.tripDraftStore, userID, nil, "")
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteWebhookHandler struct {
+	webhookStore *db.WebhookStore
+}
+
+type DeleteWebhookHandlerParams struct {
+	WebhookStore *db.WebhookStore
+}
+
+func NewDeleteWebhookHandler(params DeleteWebhookHandlerParams) *DeleteWebhookHandler {
+	return &DeleteWebhookHandler{
+		webhookStore: params.WebhookStore,
+	}
+}
+
+// DELETE /settings/webhooks?id= removes a webhook, its pending deliveries are
+// dropped with its log
+func (h *DeleteWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook id", http.StatusBadRequest)
+		return
+	}
+	err = h.webhookStore.DeleteWebhook(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting webhook %d: %v", id, err)
+		http.Error(w, "Error deleting webhook", http.StatusInternalServerError)
+		return
+	}
+	renderWebhooks(w, r, h.webhookStore, userID, nil, "")
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type DeleteYearReviewShareHandler struct {
+	tripStore            *db.TripStore
+	yearReviewShareStore *db.YearReviewShareStore
+}
+
+type DeleteYearReviewShareHandlerParams struct {
+	TripStore            *db.TripStore
+	YearReviewShareStore *db.YearReviewShareStore
+}
+
+func NewDeleteYearReviewShareHandler(params DeleteYearReviewShareHandlerParams) *DeleteYearReviewShareHandler {
+	return &DeleteYearReviewShareHandler{
+		tripStore:            params.TripStore,
+		yearReviewShareStore: params.YearReviewShareStore,
+	}
+}
+
+// DELETE /statistics/review/share?year= unpublishes the card of the year, its
+// public link stops working
+func (h *DeleteYearReviewShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	year, ok := parseReviewYear(r.FormValue("year"))
+	if !ok {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+	if err := h.yearReviewShareStore.RevokeYearReviewShare(userID, year); err != nil {
+		log.Printf("Error unpublishing year in review: %v", err)
+		http.Error(w, "Error unpublishing year in review", http.StatusInternalServerError)
+		return
+	}
+
+	renderYearReviewPanel(w, r, h.tripStore, h.yearReviewShareStore, userID, year, "")
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -9,29 +11,25 @@ import (
 )
 
 type EditTripHandler struct {
-	tripStore *db.TripStore
+	tripStore    *db.TripStore
+	airportStore *db.AirportStore
 }
 
 type EditTripHandlerParams struct {
-	TripStore *db.TripStore
+	TripStore    *db.TripStore
+	AirportStore *db.AirportStore
 }
 
 func NewEditTripHandler(params EditTripHandlerParams) *EditTripHandler {
 	return &EditTripHandler{
-		tripStore: params.TripStore,
+		tripStore:    params.TripStore,
+		airportStore: params.AirportStore,
 	}
 }
 
 // Get the single trip, edit it in code, then reupload to DB as edited trip
 // TODO: Bug -> Editing the trip with the same timestamps changes the time of the trip (timezone issue)
 func (t *EditTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	tripID := r.URL.Query().Get("id")
-	numTripID, err := strconv.Atoi(tripID)
-	if err != nil {
-		http.Error(w, "Error parsing tripID", http.StatusInternalServerError)
-		return
-	}
-
 	ctx := r.Context()
 	userID, ok := ctx.Value(m.UserKey).(int)
 	if !ok {
@@ -39,10 +37,17 @@ func (t *EditTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tripID := r.URL.Query().Get("id")
+	numTripID, err := strconv.Atoi(tripID)
+	if err != nil {
+		http.Error(w, "Error parsing tripID", http.StatusInternalServerError)
+		return
+	}
+
 	// First, get the existing trip from the database
 	existingTrip, err := t.tripStore.GetTripGivenId(numTripID, userID)
 	if err != nil {
-		http.Error(w, "Trip not found", http.StatusNotFound)
+		m.NotFound(w)
 		return
 	}
 
@@ -57,7 +62,7 @@ func (t *EditTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if departureTimeString := r.FormValue("departuretime"); departureTimeString != "" {
 		timezone := r.FormValue("timezone")
-		parsedDepartureTime, err := parseLocalToUTC(departureTimeString, existingTrip.Departure, timezone)
+		parsedDepartureTime, err := parseLocalToUTC(departureTimeString, existingTrip.Departure, formTimezone(t.airportStore, existingTrip.Departure, timezone))
 		if err != nil {
 			http.Error(w, "Error parsing departure time", http.StatusBadRequest)
 			return
@@ -67,7 +72,7 @@ func (t *EditTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	if arrivalTimeString := r.FormValue("arrivaltime"); arrivalTimeString != "" {
 		timezone := r.FormValue("timezone")
-		parsedArrivalTime, err := parseLocalToUTC(arrivalTimeString, existingTrip.Arrival, timezone)
+		parsedArrivalTime, err := parseLocalToUTC(arrivalTimeString, existingTrip.Arrival, formTimezone(t.airportStore, existingTrip.Arrival, timezone))
 		if err != nil {
 			http.Error(w, "Error parsing arrival time", http.StatusBadRequest)
 			return
@@ -111,8 +116,17 @@ func (t *EditTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := parseFlightDetails(r, &existingTrip); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Update the trip in the database
-	err = t.tripStore.EditTrip(existingTrip)
+	err = t.tripStore.EditTrip(existingTrip, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error editing trip", http.StatusInternalServerError)
 		return
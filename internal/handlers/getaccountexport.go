@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/skywall34/trip-tracker/internal/archive"
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type GetAccountExportHandler struct {
+	userStore       *db.UserStore
+	sessionStore    *db.SessionStore
+	tripStore       *db.TripStore
+	placeStore      *db.PlaceStore
+	journeyStore    *db.JourneyStore
+	attachmentStore *db.AttachmentStore
+}
+
+type GetAccountExportHandlerParams struct {
+	UserStore       *db.UserStore
+	SessionStore    *db.SessionStore
+	TripStore       *db.TripStore
+	PlaceStore      *db.PlaceStore
+	JourneyStore    *db.JourneyStore
+	AttachmentStore *db.AttachmentStore
+}
+
+func NewGetAccountExportHandler(params GetAccountExportHandlerParams) *GetAccountExportHandler {
+	return &GetAccountExportHandler{
+		userStore:       params.UserStore,
+		sessionStore:    params.SessionStore,
+		tripStore:       params.TripStore,
+		placeStore:      params.PlaceStore,
+		journeyStore:    params.JourneyStore,
+		attachmentStore: params.AttachmentStore,
+	}
+}
+
+// GET /settings/account/export downloads the account archive, which can be
+// uploaded to POST /settings/account/import on this or another instance.
+// Records in the trash are not included.
+func (h *GetAccountExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var currentSession string
+	if cookie, err := r.Cookie("session_id"); err == nil {
+		currentSession = cookie.Value
+	}
+	account, err := h.account(userID, currentSession)
+	if err != nil {
+		log.Printf("Error collecting account %d for export: %v", userID, err)
+		http.Error(w, "Error exporting account", http.StatusInternalServerError)
+		return
+	}
+
+	// The archive is built in memory so a failure is an error response
+	// instead of a truncated download
+	now := time.Now()
+	var b bytes.Buffer
+	if err := archive.Write(&b, account, now); err != nil {
+		log.Printf("Error writing account archive: %v", err)
+		http.Error(w, "Error exporting account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="trip-tracker-account-%s.zip"`, now.UTC().Format("2006-01-02")))
+	w.Write(b.Bytes())
+}
+
+func (h *GetAccountExportHandler) account(userID int, currentSession string) (archive.Account, error) {
+	var account archive.Account
+
+	user, err := h.userStore.GetUserGivenID(userID)
+	if err != nil {
+		return account, err
+	}
+	layover, err := h.userStore.GetMaxLayoverMinutes(userID)
+	if err != nil {
+		return account, err
+	}
+	account.Profile = archive.Profile{
+		Username:          user.Username,
+		Email:             user.Email,
+		FirstName:         user.FirstName,
+		LastName:          user.LastName,
+		MaxLayoverMinutes: layover,
+	}
+
+	account.Trips, err = h.tripStore.GetTripsGivenUser(userID)
+	if err != nil {
+		return account, err
+	}
+	// Only pinned itineraries are kept, the others are chained again from
+	// the layover setting
+	_, itineraries, err := h.tripStore.GetItinerariesGivenUser(userID)
+	if err != nil {
+		return account, err
+	}
+	for _, itinerary := range itineraries {
+		if itinerary.Pinned {
+			account.Itineraries = append(account.Itineraries, archive.Itinerary{ID: itinerary.ID, TripIDs: itinerary.TripIDs()})
+		}
+	}
+	account.Places, err = h.placeStore.GetPlacesForUser(userID)
+	if err != nil {
+		return account, err
+	}
+
+	journeys, err := h.journeyStore.GetJourneysForUser(userID)
+	if err != nil {
+		return account, err
+	}
+	for _, journey := range journeys {
+		journey, err := h.journeyStore.GetJourneyWithMembers(journey.ID, userID, h.tripStore, h.placeStore)
+		if err != nil {
+			return account, err
+		}
+		exported := archive.Journey{
+			ID:            journey.ID,
+			Title:         journey.Title,
+			StartDate:     journey.StartDate,
+			EndDate:       journey.EndDate,
+			CoverLocation: journey.CoverLocation,
+			TripIDs:       []int{},
+			PlaceIDs:      []int{},
+		}
+		for _, trip := range journey.Trips {
+			exported.TripIDs = append(exported.TripIDs, trip.ID)
+		}
+		for _, place := range journey.Places {
+			exported.PlaceIDs = append(exported.PlaceIDs, place.ID)
+		}
+		account.Journeys = append(account.Journeys, exported)
+	}
+
+	attachments, err := h.attachmentStore.GetAttachmentsGivenUser(userID)
+	if err != nil {
+		return account, err
+	}
+	for _, attachment := range attachments {
+		account.Attachments = append(account.Attachments, archive.Attachment{
+			ID:          attachment.ID,
+			EntityType:  attachment.EntityType,
+			EntityID:    attachment.EntityID,
+			Name:        attachment.Name,
+			ContentType: attachment.ContentType,
+			Data:        attachment.Data,
+		})
+	}
+
+	sessions, err := h.sessionStore.GetSessionsForUser(userID)
+	if err != nil {
+		return account, err
+	}
+	for _, session := range sessions {
+		account.Sessions = append(account.Sessions, archive.Session{
+			CreatedAt: session.CreatedAt,
+			Current:   session.SessionID == currentSession,
+		})
+	}
+
+	return account, nil
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetAirportsHandler struct {
+	airportStore *db.AirportStore
+}
+
+type GetAirportsHandlerParams struct {
+	AirportStore *db.AirportStore
+}
+
+func NewGetAirportsHandler(params GetAirportsHandlerParams) *GetAirportsHandler {
+	return &GetAirportsHandler{
+		airportStore: params.AirportStore,
+	}
+}
+
+// Supports three kinds of lookups:
+//   - ?code=JFK or ?code=KJFK returns a single airport
+//   - ?lat=40.6&lon=-73.7 returns the nearest airports (used by PWA geolocation)
+//   - ?q=heath returns autocomplete suggestions. The trip forms send the input
+//     name instead of q, so departure and arrival are accepted as well.
+//
+// HTMX requests get <option> elements for a datalist, everything else gets JSON.
+func (h *GetAirportsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	if code := query.Get("code"); code != "" {
+		airport, err := h.airportStore.GetAirportByCode(code)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Airport not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error looking up airport", http.StatusInternalServerError)
+			return
+		}
+		h.respond(w, r, []models.Airport{airport})
+		return
+	}
+
+	if latStr, lonStr := query.Get("lat"), query.Get("lon"); latStr != "" && lonStr != "" {
+		lat, err := strconv.ParseFloat(latStr, 64)
+		if err != nil || lat < -90 || lat > 90 {
+			http.Error(w, "Invalid latitude", http.StatusBadRequest)
+			return
+		}
+		lon, err := strconv.ParseFloat(lonStr, 64)
+		if err != nil || lon < -180 || lon > 180 {
+			http.Error(w, "Invalid longitude", http.StatusBadRequest)
+			return
+		}
+
+		airports, err := h.airportStore.NearestAirports(lat, lon, limit)
+		if err != nil {
+			http.Error(w, "Error finding nearest airports", http.StatusInternalServerError)
+			return
+		}
+		h.respond(w, r, airports)
+		return
+	}
+
+	search := query.Get("q")
+	for _, field := range []string{"departure", "arrival"} {
+		if search == "" {
+			search = query.Get(field)
+		}
+	}
+
+	airports, err := h.airportStore.SearchAirports(search, limit)
+	if err != nil {
+		http.Error(w, "Error searching airports", http.StatusInternalServerError)
+		return
+	}
+	h.respond(w, r, airports)
+}
+
+func (h *GetAirportsHandler) respond(w http.ResponseWriter, r *http.Request, airports []models.Airport) {
+	if airports == nil {
+		airports = []models.Airport{}
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := templates.AirportOptions(airports).Render(r.Context(), w)
+		if err != nil {
+			http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(airports)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetAPIDocsHandler struct{}
+
+func NewGetAPIDocsHandler() *GetAPIDocsHandler {
+	return &GetAPIDocsHandler{}
+}
+
+// GET /docs/api renders the OpenAPI document as a page, served by the app so
+// it works offline and under the CSP
+func (h *GetAPIDocsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	doc := apiDocumentFor(r)
+	err := templates.Layout(templates.APIDocs(&doc), "Mia's Trips").Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering API reference: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
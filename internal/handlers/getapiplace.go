@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+type GetApiPlaceHandler struct {
+	placeStore *db.PlaceStore
+}
+
+type GetApiPlaceHandlerParams struct {
+	PlaceStore *db.PlaceStore
+}
+
+func NewGetApiPlaceHandler(params GetApiPlaceHandlerParams) *GetApiPlaceHandler {
+	return &GetApiPlaceHandler{
+		placeStore: params.PlaceStore,
+	}
+}
+
+// GET /api/v1/places/{id}
+func (h *GetApiPlaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+
+	place, err := h.placeStore.GetPlaceByID(id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiNotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting place %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, place)
+}
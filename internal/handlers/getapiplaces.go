@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type GetApiPlacesHandler struct {
+	placeStore *db.PlaceStore
+}
+
+type GetApiPlacesHandlerParams struct {
+	PlaceStore *db.PlaceStore
+}
+
+func NewGetApiPlacesHandler(params GetApiPlacesHandlerParams) *GetApiPlacesHandler {
+	return &GetApiPlacesHandler{
+		placeStore: params.PlaceStore,
+	}
+}
+
+// GET /api/v1/places?from=&to=&category=&cursor=&limit= lists the user's
+// places by visit date, a page at a time
+func (h *GetApiPlacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	problems := validationErrors{}
+	params := parseListParams(query, problems)
+	if len(problems) > 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_parameter", "The query parameters are not valid", problems)
+		return
+	}
+
+	places, err := h.placeStore.ListPlaces(userID, models.PlaceFilter{
+		From:     params.from,
+		To:       params.to,
+		Category: strings.TrimSpace(query.Get("category")),
+		After:    params.after,
+		Limit:    params.limit + 1,
+	})
+	if err != nil {
+		log.Printf("Error listing places: %v", err)
+		apiInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, page(places, params.limit, func(place models.Place) models.ListCursor {
+		return models.ListCursor{Time: place.VisitDate, ID: place.ID}
+	}))
+}
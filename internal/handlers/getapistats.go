@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type GetApiStatsHandler struct {
+	tripStore *db.TripStore
+}
+
+type GetApiStatsHandlerParams struct {
+	TripStore *db.TripStore
+}
+
+func NewGetApiStatsHandler(params GetApiStatsHandlerParams) *GetApiStatsHandler {
+	return &GetApiStatsHandler{
+		tripStore: params.TripStore,
+	}
+}
+
+// GET /api/v1/stats sums up every year with flights per year, ?year= one
+// year with flights per month, like the statistics page
+func (h *GetApiStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+
+	stats := models.Statistics{}
+	agg := "y"
+	if value := r.URL.Query().Get("year"); value != "" {
+		year, ok := parseReviewYear(value)
+		if !ok {
+			writeAPIError(w, http.StatusBadRequest, "invalid_parameter", "The query parameters are not valid", map[string]string{"year": "must be a year like 2025"})
+			return
+		}
+		stats.Year = year
+		agg = "m"
+	}
+
+	yearString := ""
+	if stats.Year != 0 {
+		yearString = strconv.Itoa(stats.Year)
+	}
+	var err error
+	stats.Flights, stats.Airlines, stats.Countries, err = h.tripStore.GetTripsPerAggregation(userID, yearString, agg)
+	if err == nil {
+		stats.Totals, err = h.tripStore.GetTotalMileageAndTimeForYear(userID, yearString)
+	}
+	if err != nil {
+		log.Printf("Error getting statistics: %v", err)
+		apiInternalError(w)
+		return
+	}
+
+	if stats.Flights == nil {
+		stats.Flights = []models.FlightAggregation{}
+	}
+	if stats.Airlines == nil {
+		stats.Airlines = []models.AirlineAggregation{}
+	}
+	if stats.Countries == nil {
+		stats.Countries = []models.CountryAggregation{}
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+type GetApiTripHandler struct {
+	tripStore *db.TripStore
+}
+
+type GetApiTripHandlerParams struct {
+	TripStore *db.TripStore
+}
+
+func NewGetApiTripHandler(params GetApiTripHandlerParams) *GetApiTripHandler {
+	return &GetApiTripHandler{
+		tripStore: params.TripStore,
+	}
+}
+
+// GET /api/v1/trips/{id}
+func (h *GetApiTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+
+	trip, err := h.tripStore.GetTripGivenId(id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apiNotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting trip %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, trip)
+}
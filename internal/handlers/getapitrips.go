@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type GetApiTripsHandler struct {
+	tripStore *db.TripStore
+}
+
+type GetApiTripsHandlerParams struct {
+	TripStore *db.TripStore
+}
+
+func NewGetApiTripsHandler(params GetApiTripsHandlerParams) *GetApiTripsHandler {
+	return &GetApiTripsHandler{
+		tripStore: params.TripStore,
+	}
+}
+
+// GET /api/v1/trips?from=&to=&airport=&airline=&cursor=&limit= lists the
+// user's trips by departure time, a page at a time
+func (h *GetApiTripsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	problems := validationErrors{}
+	params := parseListParams(query, problems)
+	airport := strings.ToUpper(strings.TrimSpace(query.Get("airport")))
+	if airport != "" && len(airport) != 3 {
+		problems.add("airport", "must be an IATA code like JFK")
+	}
+	if len(problems) > 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_parameter", "The query parameters are not valid", problems)
+		return
+	}
+
+	trips, err := h.tripStore.ListTrips(userID, models.TripFilter{
+		From:    params.from,
+		To:      params.to,
+		Airport: airport,
+		Airline: strings.TrimSpace(query.Get("airline")),
+		After:   params.after,
+		Limit:   params.limit + 1,
+	})
+	if err != nil {
+		log.Printf("Error listing trips: %v", err)
+		apiInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, page(trips, params.limit, func(trip models.Trip) models.ListCursor {
+		return models.ListCursor{Time: trip.DepartureTime, ID: trip.ID}
+	}))
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type GetAttachmentFileHandler struct {
+	attachmentStore *db.AttachmentStore
+}
+
+type GetAttachmentFileHandlerParams struct {
+	AttachmentStore *db.AttachmentStore
+}
+
+func NewGetAttachmentFileHandler(params GetAttachmentFileHandlerParams) *GetAttachmentFileHandler {
+	return &GetAttachmentFileHandler{
+		attachmentStore: params.AttachmentStore,
+	}
+}
+
+// GET /attachments/file?id= downloads a file as it was uploaded
+func (h *GetAttachmentFileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	attachment, err := h.attachmentStore.GetAttachment(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting attachment %d: %v", id, err)
+		http.Error(w, "Error fetching file", http.StatusInternalServerError)
+		return
+	}
+
+	// Always a download, an uploaded file is never rendered by the browser
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(attachment.Data)
+}
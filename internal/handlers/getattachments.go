@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetAttachmentsHandler struct {
+	attachmentStore *db.AttachmentStore
+}
+
+type GetAttachmentsHandlerParams struct {
+	AttachmentStore *db.AttachmentStore
+}
+
+func NewGetAttachmentsHandler(params GetAttachmentsHandlerParams) *GetAttachmentsHandler {
+	return &GetAttachmentsHandler{
+		attachmentStore: params.AttachmentStore,
+	}
+}
+
+// Renders the files panel of a trip or place, ?type=trip|place&id=
+func (h *GetAttachmentsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	entityType := query.Get("type")
+	if entityType != models.EntityTrip && entityType != models.EntityPlace {
+		http.Error(w, "type must be trip or place", http.StatusBadRequest)
+		return
+	}
+	entityID, err := strconv.Atoi(query.Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	attachments, err := h.attachmentStore.GetAttachmentsForEntity(userID, entityType, entityID)
+	if err != nil {
+		log.Printf("Error getting attachments of %s %d: %v", entityType, entityID, err)
+		http.Error(w, "Error fetching files", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.AttachmentsPanel(attachments).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
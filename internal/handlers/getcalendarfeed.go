@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/ical"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type GetCalendarFeedHandler struct {
+	calendarFeedStore *db.CalendarFeedStore
+	tripStore         *db.TripStore
+}
+
+type GetCalendarFeedHandlerParams struct {
+	CalendarFeedStore *db.CalendarFeedStore
+	TripStore         *db.TripStore
+}
+
+func NewGetCalendarFeedHandler(params GetCalendarFeedHandlerParams) *GetCalendarFeedHandler {
+	return &GetCalendarFeedHandler{
+		calendarFeedStore: params.CalendarFeedStore,
+		tripStore:         params.TripStore,
+	}
+}
+
+// GET /calendar/{token}.ics is requested by calendar apps without a session,
+// the secret token identifies the user
+func (h *GetCalendarFeedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+
+	userID, err := h.calendarFeedStore.GetUserIDForCalendarToken(token)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error looking up calendar token: %v", err)
+		http.Error(w, "Error getting calendar", http.StatusInternalServerError)
+		return
+	}
+
+	trips, err := h.tripStore.GetTripsGivenUser(userID)
+	if err != nil {
+		log.Printf("Error getting trips for calendar: %v", err)
+		http.Error(w, "Error getting calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="flights.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	if err := ical.WriteFlights(w, "Flights", trips, time.Now()); err != nil {
+		log.Printf("Error writing calendar: %v", err)
+	}
+}
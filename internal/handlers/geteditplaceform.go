@@ -42,7 +42,7 @@ func (h *GetEditPlaceFormHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	// Fetch place from database
 	place, err := h.placeStore.GetPlaceByID(placeID, userID)
 	if err != nil {
-		http.Error(w, "Place not found", http.StatusNotFound)
+		m.NotFound(w)
 		return
 	}
 
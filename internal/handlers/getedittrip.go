@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -41,6 +43,10 @@ func (t *GetEditTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	trip, err := t.tripStore.GetTripGivenId(numTripId, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error getting trip", http.StatusInternalServerError)
 		return
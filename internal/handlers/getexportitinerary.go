@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/internal/pdf"
+)
+
+type GetExportItineraryHandler struct {
+	airportStore *db.AirportStore
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+	journeyStore *db.JourneyStore
+}
+
+type GetExportItineraryHandlerParams struct {
+	AirportStore *db.AirportStore
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+	JourneyStore *db.JourneyStore
+}
+
+func NewGetExportItineraryHandler(params GetExportItineraryHandlerParams) *GetExportItineraryHandler {
+	return &GetExportItineraryHandler{
+		airportStore: params.AirportStore,
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+		journeyStore: params.JourneyStore,
+	}
+}
+
+// GET /export/itinerary?trip= downloads a printable PDF of the itinerary the
+// flight belongs to, with all its connecting legs, ?journey= of every flight
+// in a journey grouped into its connections.
+func (h *GetExportItineraryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	var document pdf.Itinerary
+	var filename string
+	var err error
+	switch {
+	case query.Get("trip") != "":
+		tripID, convErr := strconv.Atoi(query.Get("trip"))
+		if convErr != nil {
+			http.Error(w, "Invalid trip", http.StatusBadRequest)
+			return
+		}
+		document, filename, err = h.tripItinerary(userID, tripID)
+	case query.Get("journey") != "":
+		journeyID, convErr := strconv.Atoi(query.Get("journey"))
+		if convErr != nil {
+			http.Error(w, "Invalid journey", http.StatusBadRequest)
+			return
+		}
+		document, filename, err = h.journeyItinerary(userID, journeyID)
+	default:
+		http.Error(w, "Choose a trip or a journey", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error collecting itinerary for user %d: %v", userID, err)
+		http.Error(w, "Error exporting itinerary", http.StatusInternalServerError)
+		return
+	}
+	document.Airports = importAirports(h.airportStore)
+
+	// Written to memory first so a failure is an error response instead of
+	// a truncated download
+	var b bytes.Buffer
+	if err := pdf.WriteItinerary(&b, document, time.Now()); err != nil {
+		log.Printf("Error writing itinerary PDF: %v", err)
+		http.Error(w, "Error exporting itinerary", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+	w.Write(b.Bytes())
+}
+
+// tripItinerary is the connecting flights of the trip, or the trip alone
+func (h *GetExportItineraryHandler) tripItinerary(userID, tripID int) (pdf.Itinerary, string, error) {
+	standalone, itineraries, err := h.tripStore.GetItinerariesGivenUser(userID)
+	if err != nil {
+		return pdf.Itinerary{}, "", err
+	}
+	group, ok := findItinerary(standalone, itineraries, tripID)
+	if !ok {
+		// Trips in the trash are not chained
+		return pdf.Itinerary{}, "", db.ErrNotFound
+	}
+	title := fmt.Sprintf("%s to %s", group.Origin(), group.Destination())
+	departure := time.Unix(int64(group.DepartureTime()), 0).UTC()
+	filename := fmt.Sprintf("itinerary-%s-%s-%s", group.Origin(), group.Destination(), departure.Format("2006-01-02"))
+	return pdf.Itinerary{Title: title, Groups: []models.Itinerary{group}}, filename, nil
+}
+
+// journeyItinerary is every flight of the journey. Connections are kept as
+// they are on the trips page, without the legs outside the journey.
+func (h *GetExportItineraryHandler) journeyItinerary(userID, journeyID int) (pdf.Itinerary, string, error) {
+	journey, err := h.journeyStore.GetJourneyWithMembers(journeyID, userID, h.tripStore, h.placeStore)
+	if err != nil {
+		return pdf.Itinerary{}, "", err
+	}
+	members := make(map[int]bool)
+	for _, trip := range journey.Trips {
+		members[trip.ID] = true
+	}
+
+	standalone, itineraries, err := h.tripStore.GetItinerariesGivenUser(userID)
+	if err != nil {
+		return pdf.Itinerary{}, "", err
+	}
+	var groups []models.Itinerary
+	for _, itinerary := range itineraries {
+		var legs []models.Trip
+		for _, leg := range itinerary.Legs {
+			if members[leg.ID] {
+				legs = append(legs, leg)
+			}
+		}
+		if len(legs) > 0 {
+			itinerary.Legs = legs
+			groups = append(groups, itinerary)
+		}
+	}
+	for _, trip := range standalone {
+		if members[trip.ID] {
+			groups = append(groups, models.Itinerary{UserID: userID, Legs: []models.Trip{trip}})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].DepartureTime() < groups[j].DepartureTime()
+	})
+
+	return pdf.Itinerary{Title: journey.Title, Groups: groups}, fmt.Sprintf("journey-%d", journey.ID), nil
+}
+
+// findItinerary returns the itinerary with the trip, a standalone flight is
+// an itinerary of one leg
+func findItinerary(standalone []models.Trip, itineraries []models.Itinerary, tripID int) (models.Itinerary, bool) {
+	for _, itinerary := range itineraries {
+		for _, leg := range itinerary.Legs {
+			if leg.ID == tripID {
+				return itinerary, true
+			}
+		}
+	}
+	for _, trip := range standalone {
+		if trip.ID == tripID {
+			return models.Itinerary{UserID: trip.UserId, Legs: []models.Trip{trip}}, true
+		}
+	}
+	return models.Itinerary{}, false
+}
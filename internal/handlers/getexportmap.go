@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/geo"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type GetExportMapHandler struct {
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+	journeyStore *db.JourneyStore
+}
+
+type GetExportMapHandlerParams struct {
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+	JourneyStore *db.JourneyStore
+}
+
+func NewGetExportMapHandler(params GetExportMapHandlerParams) *GetExportMapHandler {
+	return &GetExportMapHandler{
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+		journeyStore: params.JourneyStore,
+	}
+}
+
+// GET /export/map?format=geojson|kml|gpx downloads the user's flights and
+// places as a map file. ?year= keeps the flights departing and places visited
+// in that year (UTC), ?journey= the members of one journey.
+func (h *GetExportMapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	format, ok := geo.ParseFormat(query.Get("format"))
+	if !ok {
+		http.Error(w, "Unknown format, use geojson, kml or gpx", http.StatusBadRequest)
+		return
+	}
+
+	year := 0
+	if value := query.Get("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+
+	name := "Flights and places"
+	filename := "travel"
+	var trips []models.Trip
+	var places []models.Place
+	if value := query.Get("journey"); value != "" {
+		journeyID, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid journey", http.StatusBadRequest)
+			return
+		}
+		journey, err := h.journeyStore.GetJourneyWithMembers(journeyID, userID, h.tripStore, h.placeStore)
+		if errors.Is(err, db.ErrNotFound) {
+			m.NotFound(w)
+			return
+		}
+		if err != nil {
+			log.Printf("Error getting journey %d for export: %v", journeyID, err)
+			http.Error(w, "Error exporting map", http.StatusInternalServerError)
+			return
+		}
+		name, filename = journey.Title, fmt.Sprintf("journey-%d", journey.ID)
+		trips, places = journey.Trips, journey.Places
+	} else {
+		var err error
+		if trips, err = h.tripStore.GetTripsGivenUser(userID); err != nil {
+			log.Printf("Error getting trips for export: %v", err)
+			http.Error(w, "Error exporting map", http.StatusInternalServerError)
+			return
+		}
+		if places, err = h.placeStore.GetPlacesForUser(userID); err != nil {
+			log.Printf("Error getting places for export: %v", err)
+			http.Error(w, "Error exporting map", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if year != 0 {
+		trips, places = filterYear(trips, places, year)
+		name = fmt.Sprintf("%s %d", name, year)
+		filename = fmt.Sprintf("%s-%d", filename, year)
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	if err := geo.Write(w, format, name, trips, places, time.Now()); err != nil {
+		log.Printf("Error writing %s export: %v", format, err)
+	}
+}
+
+// filterYear keeps the flights departing and places visited in year, in UTC
+// like the statistics page
+func filterYear(trips []models.Trip, places []models.Place, year int) ([]models.Trip, []models.Place) {
+	var keptTrips []models.Trip
+	for _, trip := range trips {
+		if time.Unix(int64(trip.DepartureTime), 0).UTC().Year() == year {
+			keptTrips = append(keptTrips, trip)
+		}
+	}
+	var keptPlaces []models.Place
+	for _, place := range places {
+		if time.Unix(int64(place.VisitDate), 0).UTC().Year() == year {
+			keptPlaces = append(keptPlaces, place)
+		}
+	}
+	return keptTrips, keptPlaces
+}
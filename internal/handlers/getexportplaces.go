@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type GetExportPlacesHandler struct {
+	placeStore *db.PlaceStore
+}
+
+type GetExportPlacesHandlerParams struct {
+	PlaceStore *db.PlaceStore
+}
+
+func NewGetExportPlacesHandler(params GetExportPlacesHandlerParams) *GetExportPlacesHandler {
+	return &GetExportPlacesHandler{
+		placeStore: params.PlaceStore,
+	}
+}
+
+// GET /export/places.csv downloads the user's places, the file can be uploaded
+// to POST /import/csv/places again
+func (h *GetExportPlacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	places, err := h.placeStore.GetPlacesForUser(userID)
+	if err != nil {
+		log.Printf("Error getting places for export: %v", err)
+		http.Error(w, "Error exporting places", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="places.csv"`)
+	if err := models.WritePlacesCSV(w, places); err != nil {
+		log.Printf("Error writing places CSV: %v", err)
+	}
+}
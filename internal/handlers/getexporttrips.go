@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type GetExportTripsHandler struct {
+	tripStore *db.TripStore
+}
+
+type GetExportTripsHandlerParams struct {
+	TripStore *db.TripStore
+}
+
+func NewGetExportTripsHandler(params GetExportTripsHandlerParams) *GetExportTripsHandler {
+	return &GetExportTripsHandler{
+		tripStore: params.TripStore,
+	}
+}
+
+// GET /export/trips.csv downloads the user's trips, the file can be uploaded
+// to POST /import/csv/trips again
+func (h *GetExportTripsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	trips, err := h.tripStore.GetTripsGivenUser(userID)
+	if err != nil {
+		log.Printf("Error getting trips for export: %v", err)
+		http.Error(w, "Error exporting trips", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="trips.csv"`)
+	if err := models.WriteTripsCSV(w, trips); err != nil {
+		log.Printf("Error writing trips CSV: %v", err)
+	}
+}
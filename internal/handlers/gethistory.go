@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetHistoryHandler struct {
+	historyStore *db.HistoryStore
+}
+
+type GetHistoryHandlerParams struct {
+	HistoryStore *db.HistoryStore
+}
+
+func NewGetHistoryHandler(params GetHistoryHandlerParams) *GetHistoryHandler {
+	return &GetHistoryHandler{
+		historyStore: params.HistoryStore,
+	}
+}
+
+// Renders the history panel of a trip or place, ?type=trip|place&id=
+func (h *GetHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	entityType := query.Get("type")
+	if entityType != models.EntityTrip && entityType != models.EntityPlace {
+		http.Error(w, "type must be trip or place", http.StatusBadRequest)
+		return
+	}
+	entityID, err := strconv.Atoi(query.Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.historyStore.GetHistory(entityType, entityID, userID)
+	if err != nil {
+		http.Error(w, "Error fetching history", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.HistoryPanel(changes).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/importer"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetImportHandler struct {
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+	journeyStore *db.JourneyStore
+}
+
+type GetImportHandlerParams struct {
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+	JourneyStore *db.JourneyStore
+}
+
+func NewGetImportHandler(params GetImportHandlerParams) *GetImportHandler {
+	return &GetImportHandler{
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+		journeyStore: params.JourneyStore,
+	}
+}
+
+// GET /import lists the files flights can be imported from and exported to
+func (h *GetImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	trips, err := h.tripStore.GetTripsGivenUser(userID)
+	if err != nil {
+		log.Printf("Error getting trips: %v", err)
+		http.Error(w, "Error getting trips", http.StatusInternalServerError)
+		return
+	}
+	places, err := h.placeStore.GetPlacesForUser(userID)
+	if err != nil {
+		log.Printf("Error getting places: %v", err)
+		http.Error(w, "Error getting places", http.StatusInternalServerError)
+		return
+	}
+	journeys, err := h.journeyStore.GetJourneysForUser(userID)
+	if err != nil {
+		log.Printf("Error getting journeys: %v", err)
+		http.Error(w, "Error getting journeys", http.StatusInternalServerError)
+		return
+	}
+
+	// The years the map export can be filtered by, newest first
+	seen := make(map[int]bool)
+	for _, trip := range trips {
+		seen[time.Unix(int64(trip.DepartureTime), 0).UTC().Year()] = true
+	}
+	for _, place := range places {
+		seen[time.Unix(int64(place.VisitDate), 0).UTC().Year()] = true
+	}
+	years := make([]int, 0, len(seen))
+	for year := range seen {
+		years = append(years, year)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+
+	err = templates.Layout(templates.ImportPage(importer.Parsers(), years, journeys), "Import").Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetImportReviewsHandler struct {
+	importReviewStore *db.ImportReviewStore
+}
+
+type GetImportReviewsHandlerParams struct {
+	ImportReviewStore *db.ImportReviewStore
+}
+
+func NewGetImportReviewsHandler(params GetImportReviewsHandlerParams) *GetImportReviewsHandler {
+	return &GetImportReviewsHandler{
+		importReviewStore: params.ImportReviewStore,
+	}
+}
+
+// GET /import/reviews lists the imported rows waiting for review
+func (h *GetImportReviewsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	reviews, err := h.importReviewStore.GetImportReviews(userID)
+	if err != nil {
+		log.Printf("Error getting import reviews: %v", err)
+		http.Error(w, "Error getting review queue", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.ImportReviewQueue(reviews, nil).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
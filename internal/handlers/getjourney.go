@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetJourneyHandler struct {
+	journeyStore *db.JourneyStore
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+}
+
+type GetJourneyHandlerParams struct {
+	JourneyStore *db.JourneyStore
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+}
+
+func NewGetJourneyHandler(params GetJourneyHandlerParams) *GetJourneyHandler {
+	return &GetJourneyHandler{
+		journeyStore: params.JourneyStore,
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+	}
+}
+
+// Journey detail page, the members are shown as one timeline followed by
+// suggestions from the journey's date range
+func (h *GetJourneyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	journeyID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid journey ID", http.StatusBadRequest)
+		return
+	}
+
+	journey, err := h.journeyStore.GetJourneyWithMembers(journeyID, userID, h.tripStore, h.placeStore)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error fetching journey", http.StatusInternalServerError)
+		return
+	}
+
+	suggestedTrips, suggestedPlaces, err := h.journeyStore.GetJourneySuggestions(journey, h.tripStore, h.placeStore)
+	if err != nil {
+		http.Error(w, "Error fetching suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	c := templates.JourneyPage(journey, suggestedTrips, suggestedPlaces)
+	err = templates.Layout(c, journey.Title).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
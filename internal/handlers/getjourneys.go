@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetJourneysHandler struct {
+	journeyStore *db.JourneyStore
+}
+
+type GetJourneysHandlerParams struct {
+	JourneyStore *db.JourneyStore
+}
+
+func NewGetJourneysHandler(params GetJourneysHandlerParams) *GetJourneysHandler {
+	return &GetJourneysHandler{
+		journeyStore: params.JourneyStore,
+	}
+}
+
+func (h *GetJourneysHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	journeys, err := h.journeyStore.GetJourneysForUser(userID)
+	if err != nil {
+		http.Error(w, "Error fetching journeys", http.StatusInternalServerError)
+		return
+	}
+
+	c := templates.JourneysPage(journeys)
+	err = templates.Layout(c, "Journeys").Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/openapi"
+)
+
+type GetOpenAPIHandler struct{}
+
+func NewGetOpenAPIHandler() *GetOpenAPIHandler {
+	return &GetOpenAPIHandler{}
+}
+
+// apiDocumentFor returns APIDocument with the server of the request, the
+// document is shared so it is copied first
+func apiDocumentFor(r *http.Request) openapi.Document {
+	doc := *APIDocument()
+	server := m.GetBasePath(r.Context())
+	if server == "" {
+		server = "/"
+	}
+	doc.Servers = []openapi.Server{{URL: server}}
+	return doc
+}
+
+// GET /api/openapi.json is the OpenAPI 3 document of the routes under /api,
+// public since it holds no user data
+func (h *GetOpenAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, http.StatusOK, apiDocumentFor(r))
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetSearchHandler struct {
+	searchStore *db.SearchStore
+}
+
+type GetSearchHandlerParams struct {
+	SearchStore *db.SearchStore
+}
+
+func NewGetSearchHandler(params GetSearchHandlerParams) *GetSearchHandler {
+	return &GetSearchHandler{
+		searchStore: params.SearchStore,
+	}
+}
+
+// GET /search?q=ramen+osaka renders the search page, HTMX requests made while
+// typing only get the result list
+func (h *GetSearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	results, err := h.searchStore.Search(userID, query, 50)
+	if err != nil {
+		log.Printf("Error searching for %q: %v", query, err)
+		http.Error(w, "Error searching", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		err = templates.SearchResults(query, results).Render(ctx, w)
+	} else {
+		err = templates.Layout(templates.SearchPage(query, results), "Search").Render(ctx, w)
+	}
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetSettingsHandler struct {
+	userStore         *db.UserStore
+	calendarFeedStore *db.CalendarFeedStore
+	mailInboxStore    *db.MailInboxStore
+	accessTokenStore  *db.AccessTokenStore
+	webhookStore      *db.WebhookStore
+}
+
+type GetSettingsHandlerParams struct {
+	UserStore         *db.UserStore
+	CalendarFeedStore *db.CalendarFeedStore
+	MailInboxStore    *db.MailInboxStore
+	AccessTokenStore  *db.AccessTokenStore
+	WebhookStore      *db.WebhookStore
+}
+
+func NewGetSettingsHandler(params GetSettingsHandlerParams) *GetSettingsHandler {
+	return &GetSettingsHandler{
+		userStore:         params.UserStore,
+		calendarFeedStore: params.CalendarFeedStore,
+		mailInboxStore:    params.MailInboxStore,
+		accessTokenStore:  params.AccessTokenStore,
+		webhookStore:      params.WebhookStore,
+	}
+}
+
+func (h *GetSettingsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	user, err := h.userStore.GetUserGivenID(userID)
+	if err != nil {
+		http.Error(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	user.MaxLayoverMinutes, err = h.userStore.GetMaxLayoverMinutes(userID)
+	if err != nil {
+		http.Error(w, "Error getting settings", http.StatusInternalServerError)
+		return
+	}
+
+	calendarFeed, err := h.calendarFeedStore.GetCalendarFeed(userID)
+	if err != nil {
+		http.Error(w, "Error getting settings", http.StatusInternalServerError)
+		return
+	}
+
+	mailInbox, err := h.mailInboxStore.GetMailInbox(userID)
+	if err != nil {
+		http.Error(w, "Error getting settings", http.StatusInternalServerError)
+		return
+	}
+
+	accessTokens, err := h.accessTokenStore.GetAccessTokens(userID)
+	if err != nil {
+		http.Error(w, "Error getting settings", http.StatusInternalServerError)
+		return
+	}
+
+	webhooks, err := h.webhookStore.GetWebhooks(userID)
+	if err != nil {
+		http.Error(w, "Error getting settings", http.StatusInternalServerError)
+		return
+	}
+
+	c := templates.Settings(user, calendarFeed, mailInbox, mailDomain(), accessTokens, webhooks)
+	err = templates.Layout(c, "Settings").Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -52,7 +52,14 @@ func (u *GetStatisticsPageHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	c := templates.Statistics(user.FirstName, tsAggregation)
+	cabins, aircraft, err := u.tripStore.GetFlightDetailBreakdown(userID)
+	if err != nil {
+		log.Printf("Error getting flight details: %v", err)
+		http.Error(w, "Error getting flight details", http.StatusInternalServerError)
+		return
+	}
+
+	c := templates.Statistics(user.FirstName, tsAggregation, cabins, aircraft)
 	err = templates.Layout(c, "Mia's Trips").Render(r.Context(), w)
 
 	if err != nil {
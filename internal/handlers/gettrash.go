@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetTrashHandler struct {
+	tripStore     *db.TripStore
+	placeStore    *db.PlaceStore
+	retentionDays int
+}
+
+type GetTrashHandlerParams struct {
+	TripStore     *db.TripStore
+	PlaceStore    *db.PlaceStore
+	RetentionDays int
+}
+
+func NewGetTrashHandler(params GetTrashHandlerParams) *GetTrashHandler {
+	return &GetTrashHandler{
+		tripStore:     params.TripStore,
+		placeStore:    params.PlaceStore,
+		retentionDays: params.RetentionDays,
+	}
+}
+
+func (h *GetTrashHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	trips, err := h.tripStore.GetDeletedTripsGivenUser(userID)
+	if err != nil {
+		http.Error(w, "Error fetching deleted trips", http.StatusInternalServerError)
+		return
+	}
+	places, err := h.placeStore.GetDeletedPlacesForUser(userID)
+	if err != nil {
+		http.Error(w, "Error fetching deleted places", http.StatusInternalServerError)
+		return
+	}
+
+	c := templates.TrashPage(trips, places, h.retentionDays)
+	err = templates.Layout(c, "Trash").Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// renderTrashContents re-renders the trash list after a restore or purge
+func renderTrashContents(w http.ResponseWriter, r *http.Request, tripStore *db.TripStore, placeStore *db.PlaceStore, userID int) {
+	trips, err := tripStore.GetDeletedTripsGivenUser(userID)
+	if err != nil {
+		http.Error(w, "Error fetching deleted trips", http.StatusInternalServerError)
+		return
+	}
+	places, err := placeStore.GetDeletedPlacesForUser(userID)
+	if err != nil {
+		http.Error(w, "Error fetching deleted places", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.TrashContents(trips, places).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
@@ -38,13 +38,13 @@ func (t *GetTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    userTrips, userConnectingTrips, err := t.tripStore.GetConnectingTripsGivenUser(userId)
+    userTrips, userItineraries, err := t.tripStore.GetItinerariesGivenUser(userId)
     if err != nil {
         fmt.Printf("Error getting trips for user %d: %v\n", userId, err)
         http.Error(w, "Error getting trips", http.StatusInternalServerError)
         return
     }
-    if len(userTrips) == 0 {
+    if len(userTrips) == 0 && len(userItineraries) == 0 {
         http.Error(w, "No trips found for this user", http.StatusNotFound)
         return
     }
@@ -53,7 +53,7 @@ func (t *GetTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
     if filterPast == "true" {
         var pastTrips []models.Trip
-        var pastConnectingTrips []models.ConnectingTrip
+        var pastItineraries []models.Itinerary
 
         // Filter only the past trips before now
         for _, trip := range userTrips {
@@ -62,14 +62,15 @@ func (t *GetTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
             }
         }
 
-        // Filter the connecting trips to only include those that are past
-        for _, connectingTrip := range userConnectingTrips {
-            if connectingTrip.FromTrip.DepartureTime < uint32(currentUnixTime) {
-                pastConnectingTrips = append(pastConnectingTrips, connectingTrip)
+        // Filter the itineraries to only include those whose every leg is past,
+        // one still under way is listed with the upcoming trips
+        for _, itinerary := range userItineraries {
+            if itinerary.LastDepartureTime() < uint32(currentUnixTime) {
+                pastItineraries = append(pastItineraries, itinerary)
             }
         }
 
-        renderErr := templates.RenderPastTrips(pastTrips, pastConnectingTrips).Render(r.Context(), w)
+        renderErr := templates.RenderPastTrips(pastTrips, pastItineraries).Render(r.Context(), w)
         if renderErr != nil {
             http.Error(w, "Error rendering template", http.StatusInternalServerError)
             return
@@ -77,7 +78,7 @@ func (t *GetTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
     } else {
         // We want upcoming, which are trips that are coming in the future (up to 1 year)
         var filteredTrips []models.Trip
-        var filteredConnectingTrips []models.ConnectingTrip
+        var filteredItineraries []models.Itinerary
         oneYearFromNow := currentUnixTime + (365 * 24 * 60 * 60) 
         for _, trip := range userTrips {
             // get the unix time for 1 year from now
@@ -85,12 +86,12 @@ func (t *GetTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
                 filteredTrips = append(filteredTrips, trip)
             }
         }
-        for _, connectingTrip := range userConnectingTrips {
-            if connectingTrip.FromTrip.DepartureTime > uint32(currentUnixTime) && connectingTrip.FromTrip.DepartureTime < uint32(oneYearFromNow) {
-                filteredConnectingTrips = append(filteredConnectingTrips, connectingTrip)
+        for _, itinerary := range userItineraries {
+            if itinerary.LastDepartureTime() > uint32(currentUnixTime) && itinerary.DepartureTime() < uint32(oneYearFromNow) {
+                filteredItineraries = append(filteredItineraries, itinerary)
             }
         }
-        renderErr := templates.RenderTrips(filteredTrips, filteredConnectingTrips).Render(r.Context(), w)
+        renderErr := templates.RenderTrips(filteredTrips, filteredItineraries).Render(r.Context(), w)
         if renderErr != nil {
             http.Error(w, "Error rendering template", http.StatusInternalServerError)
             return
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetTripDraftsHandler struct {
+	tripDraftStore *db.TripDraftStore
+}
+
+type GetTripDraftsHandlerParams struct {
+	TripDraftStore *db.TripDraftStore
+}
+
+func NewGetTripDraftsHandler(params GetTripDraftsHandlerParams) *GetTripDraftsHandler {
+	return &GetTripDraftsHandler{
+		tripDraftStore: params.TripDraftStore,
+	}
+}
+
+// GET /trips/drafts lists the flights read from emails waiting to be confirmed
+func (h *GetTripDraftsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	renderTripDrafts(w, r, h.tripDraftStore, userID, nil, "")
+}
+
+// renderTripDrafts renders the drafts section of the trips page
+func renderTripDrafts(w http.ResponseWriter, r *http.Request, tripDraftStore *db.TripDraftStore, userID int, problems map[int]string, notice string) {
+	drafts, err := tripDraftStore.GetTripDrafts(userID)
+	if err != nil {
+		log.Printf("Error getting trip drafts: %v", err)
+		http.Error(w, "Error getting drafts", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.TripDrafts(drafts, problems, notice).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -34,20 +34,27 @@ func (t *GetTripMapApiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
         return
     }
 
-	// Fetch trips and connecting trips from the store
-	standaloneTrips, connectingTrips, err := t.tripStore.GetConnectingTripsGivenUser(userId)
+	// Fetch standalone trips and itineraries from the store
+	standaloneTrips, itineraries, err := t.tripStore.GetItinerariesGivenUser(userId)
 	if err != nil {
 		http.Error(w, "Error fetching trips", http.StatusInternalServerError)
 		return
 	}
 
-	// Structure the response to include both standalone and connecting flights
+	if standaloneTrips == nil {
+		standaloneTrips = []models.Trip{}
+	}
+	if itineraries == nil {
+		itineraries = []models.Itinerary{}
+	}
+
+	// Structure the response to include both standalone flights and itineraries
 	response := struct {
-		StandaloneTrips  []models.Trip           `json:"standalone_trips"`
-		ConnectingTrips  []models.ConnectingTrip `json:"connecting_trips"`
+		StandaloneTrips []models.Trip      `json:"standalone_trips"`
+		Itineraries     []models.Itinerary `json:"itineraries"`
 	}{
 		StandaloneTrips: standaloneTrips,
-		ConnectingTrips: connectingTrips,
+		Itineraries:     itineraries,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
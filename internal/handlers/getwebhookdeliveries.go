@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+// webhookLogLength is the number of deliveries shown in the log
+const webhookLogLength = 25
+
+type GetWebhookDeliveriesHandler struct {
+	webhookStore *db.WebhookStore
+}
+
+type GetWebhookDeliveriesHandlerParams struct {
+	WebhookStore *db.WebhookStore
+}
+
+func NewGetWebhookDeliveriesHandler(params GetWebhookDeliveriesHandlerParams) *GetWebhookDeliveriesHandler {
+	return &GetWebhookDeliveriesHandler{
+		webhookStore: params.WebhookStore,
+	}
+}
+
+// GET /settings/webhooks/deliveries?id= renders the latest deliveries of a
+// webhook with the outcome of their last attempt
+func (h *GetWebhookDeliveriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook id", http.StatusBadRequest)
+		return
+	}
+	webhook, err := h.webhookStore.GetWebhook(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting webhook %d: %v", id, err)
+		http.Error(w, "Error getting webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+	deliveries, err := h.webhookStore.GetWebhookDeliveries(id, userID, webhookLogLength)
+	if err != nil {
+		log.Printf("Error getting deliveries of webhook %d: %v", id, err)
+		http.Error(w, "Error getting webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err := templates.WebhookDeliveries(webhook, deliveries).Render(ctx, w); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/internal/yearcard"
+)
+
+type GetYearReviewHandler struct {
+	userStore *db.UserStore
+	tripStore *db.TripStore
+}
+
+type GetYearReviewHandlerParams struct {
+	UserStore *db.UserStore
+	TripStore *db.TripStore
+}
+
+func NewGetYearReviewHandler(params GetYearReviewHandlerParams) *GetYearReviewHandler {
+	return &GetYearReviewHandler{
+		userStore: params.UserStore,
+		tripStore: params.TripStore,
+	}
+}
+
+// parseReviewYear reads the year parameter, the current year when it is empty
+func parseReviewYear(value string) (int, bool) {
+	if value == "" {
+		return time.Now().Year(), true
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 1900 || year > 9999 {
+		return 0, false
+	}
+	return year, true
+}
+
+// writeYearReview draws the card of the user's year. The image is drawn in
+// memory first so a failure is an error response instead of a broken image.
+func writeYearReview(w http.ResponseWriter, userStore *db.UserStore, tripStore *db.TripStore, userID int, year int, format yearcard.Format) {
+	user, err := userStore.GetUserGivenID(userID)
+	if err != nil {
+		log.Printf("Error getting user %d for year in review: %v", userID, err)
+		http.Error(w, "Error drawing year in review", http.StatusInternalServerError)
+		return
+	}
+	review, err := tripStore.GetYearInReview(userID, year)
+	if err != nil {
+		log.Printf("Error getting year in review %d of user %d: %v", year, userID, err)
+		http.Error(w, "Error drawing year in review", http.StatusInternalServerError)
+		return
+	}
+	review.Name = user.FirstName
+
+	var b bytes.Buffer
+	if err := yearcard.Write(&b, format, review, models.CountryMap); err != nil {
+		log.Printf("Error drawing year in review: %v", err)
+		http.Error(w, "Error drawing year in review", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="year-in-review-%d.%s"`, year, format))
+	w.Write(b.Bytes())
+}
+
+// GET /statistics/review?year=&format=png|svg draws the year in review card
+// of the signed in user, the current year and PNG by default
+func (h *GetYearReviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	year, ok := parseReviewYear(query.Get("year"))
+	if !ok {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+	format := yearcard.FormatPNG
+	if value := query.Get("format"); value != "" {
+		if format, ok = yearcard.ParseFormat(value); !ok {
+			http.Error(w, "Unknown format, use png or svg", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	writeYearReview(w, h.userStore, h.tripStore, userID, year, format)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type GetYearReviewPanelHandler struct {
+	tripStore            *db.TripStore
+	yearReviewShareStore *db.YearReviewShareStore
+}
+
+type GetYearReviewPanelHandlerParams struct {
+	TripStore            *db.TripStore
+	YearReviewShareStore *db.YearReviewShareStore
+}
+
+func NewGetYearReviewPanelHandler(params GetYearReviewPanelHandlerParams) *GetYearReviewPanelHandler {
+	return &GetYearReviewPanelHandler{
+		tripStore:            params.TripStore,
+		yearReviewShareStore: params.YearReviewShareStore,
+	}
+}
+
+// renderYearReviewPanel renders the year in review section of the statistics
+// page. year 0 picks the last year with flights. shareURL is only set right
+// after a link was published, the token is not stored.
+func renderYearReviewPanel(w http.ResponseWriter, r *http.Request, tripStore *db.TripStore, yearReviewShareStore *db.YearReviewShareStore, userID int, year int, shareURL string) {
+	years, err := tripStore.GetYearsWithFlights(userID)
+	if err != nil {
+		log.Printf("Error getting years with flights: %v", err)
+		http.Error(w, "Error getting year in review", http.StatusInternalServerError)
+		return
+	}
+	if year == 0 && len(years) > 0 {
+		year = years[len(years)-1]
+	}
+	shares, err := yearReviewShareStore.GetYearReviewShares(userID)
+	if err != nil {
+		log.Printf("Error getting year in review links: %v", err)
+		http.Error(w, "Error getting year in review", http.StatusInternalServerError)
+		return
+	}
+	var share *models.YearReviewShare
+	for i := range shares {
+		if shares[i].Year == year {
+			share = &shares[i]
+		}
+	}
+
+	if err := templates.YearReviewPanel(years, year, share, shareURL).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /statistics/review/panel?year= is the year in review section of the
+// statistics page with the card of the year and its public link
+func (h *GetYearReviewPanelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	year := 0
+	if value := r.URL.Query().Get("year"); value != "" {
+		if year, ok = parseReviewYear(value); !ok {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+	renderYearReviewPanel(w, r, h.tripStore, h.yearReviewShareStore, userID, year, "")
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/yearcard"
+)
+
+type GetYearReviewShareHandler struct {
+	userStore            *db.UserStore
+	tripStore            *db.TripStore
+	yearReviewShareStore *db.YearReviewShareStore
+}
+
+type GetYearReviewShareHandlerParams struct {
+	UserStore            *db.UserStore
+	TripStore            *db.TripStore
+	YearReviewShareStore *db.YearReviewShareStore
+}
+
+func NewGetYearReviewShareHandler(params GetYearReviewShareHandlerParams) *GetYearReviewShareHandler {
+	return &GetYearReviewShareHandler{
+		userStore:            params.UserStore,
+		tripStore:            params.TripStore,
+		yearReviewShareStore: params.YearReviewShareStore,
+	}
+}
+
+// GET /review/{token}.png or .svg is the public link to a published year in
+// review card, the secret token identifies the user and the year
+func (h *GetYearReviewShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("token")
+	format := yearcard.FormatPNG
+	if extension := path.Ext(name); extension != "" {
+		var ok bool
+		if format, ok = yearcard.ParseFormat(strings.TrimPrefix(extension, ".")); !ok {
+			m.NotFound(w)
+			return
+		}
+	}
+
+	share, err := h.yearReviewShareStore.GetYearReviewShareForToken(strings.TrimSuffix(name, path.Ext(name)))
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error looking up year in review token: %v", err)
+		http.Error(w, "Error drawing year in review", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=900")
+	writeYearReview(w, h.userStore, h.tripStore, share.UserID, share.Year, format)
+}
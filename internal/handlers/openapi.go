@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/internal/openapi"
+)
+
+// APIDocument describes every route under /api. Request and response
+// schemas come from the types the handlers decode and encode, so they follow
+// the handlers; a route missing here fails TestOpenAPICoversEveryAPIRoute.
+var APIDocument = sync.OnceValue(buildAPIDocument)
+
+// Security schemes of the document
+const (
+	bearerAuth = "bearerAuth"
+	cookieAuth = "cookieAuth"
+)
+
+// tripMapResponse is the body of GET /api/trips
+type tripMapResponse struct {
+	StandaloneTrips []models.Trip      `json:"standalone_trips"`
+	Itineraries     []models.Itinerary `json:"itineraries"`
+}
+
+func buildAPIDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:   "trip-tracker API",
+		Version: "1",
+		Description: "Flights, places and statistics of the signed in user. Scripts send a personal access token " +
+			"from the settings page as Authorization: Bearer <token>, the pages use the session cookie. " +
+			"Every error of /api/v1 is an ErrorResponse.",
+	})
+	doc.Tags = []openapi.Tag{
+		{Name: "trips", Description: "Flights, times are Unix seconds in UTC"},
+		{Name: "places", Description: "Places visited outside of flights"},
+		{Name: "statistics", Description: "Flights, airlines and countries per year or month"},
+		{Name: "lookups", Description: "Airport and flight lookups used by the trip form"},
+		{Name: "account", Description: "Password reset forms"},
+		{Name: "google places", Description: "Google Places search used by the places page, answers HTML"},
+		{Name: "meta", Description: "This document"},
+	}
+	doc.Components.SecuritySchemes[bearerAuth] = openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "tt_ followed by 64 hex digits",
+		Description:  "A personal access token, its scopes limit what it can call: " + scopeList(),
+	}
+	doc.Components.SecuritySchemes[cookieAuth] = openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "cookie",
+		Name:        "session_id",
+		Description: "The session of a signed in browser, it can call every route. Writes with it must send Content-Type: application/json",
+	}
+
+	// Components are added before the types that hold them
+	doc.Component("Trip", models.Trip{})
+	doc.Property("Trip", "cabin_class").Enum = models.CabinClasses
+	for _, name := range []string{"id", "user_id", "departure_lat", "departure_lon", "arrival_lat", "arrival_lon", "arrival_timezone", "departure_timezone", "deleted_at", "distance_km"} {
+		doc.Property("Trip", name).ReadOnly = true
+	}
+	doc.Property("Trip", "departure_time").Description = "Unix seconds"
+	doc.Property("Trip", "arrival_time").Description = "Unix seconds"
+	doc.Property("Trip", "distance_km").Description = "Great-circle distance between the airports"
+	doc.Component("Place", models.Place{})
+	for _, name := range []string{"id", "user_id", "created_at", "updated_at", "deleted_at"} {
+		doc.Property("Place", name).ReadOnly = true
+	}
+	doc.Property("Place", "place_id").Description = "Google Place ID"
+	doc.Property("Place", "visit_date").Description = "Unix seconds"
+	doc.Component("Itinerary", models.Itinerary{})
+	doc.Property("Itinerary", "pinned").Description = "Pinned by the user, otherwise detected from the maximum layover"
+	doc.Component("Airport", models.Airport{})
+	doc.Component("TimeSpaceAggregation", models.TimeSpaceAggregation{})
+	doc.Component("FlightAggregation", models.FlightAggregation{})
+	doc.Component("AirlineAggregation", models.AirlineAggregation{})
+	doc.Component("CountryAggregation", models.CountryAggregation{})
+	doc.Component("Statistics", models.Statistics{})
+	doc.Property("Statistics", "year").Description = "Left out when every year is summed up"
+	doc.Component("Error", models.APIError{})
+	doc.Property("Error", "fields").Description = "The problem of each invalid field or parameter"
+	doc.Component("ErrorResponse", models.APIErrorResponse{})
+
+	tripInput := doc.Component("TripInput", apiTripInput{})
+	tripInput.Required = []string{"arrival", "arrival_time", "departure", "departure_time"}
+	doc.Property("TripInput", "departure").Description = "IATA or ICAO code, stored as IATA"
+	doc.Property("TripInput", "arrival").Description = "IATA or ICAO code, stored as IATA"
+	doc.Property("TripInput", "departure_time").Description = "Unix seconds"
+	doc.Property("TripInput", "arrival_time").Description = "Unix seconds, not before departure_time"
+	doc.Property("TripInput", "cabin_class").Enum = models.CabinClasses
+	doc.Property("TripInput", "ticket_price").Minimum = openapi.Float(0)
+	doc.Property("TripInput", "ticket_currency").Pattern = "^[A-Za-z]{3}$"
+	placeInput := doc.Component("PlaceInput", apiPlaceInput{})
+	placeInput.Required = []string{"latitude", "longitude", "name", "visit_date"}
+	placeInput.Description = "latitude, longitude and place_id are only read when creating a place"
+	doc.Property("PlaceInput", "visit_date").Description = "Unix seconds"
+	doc.Property("PlaceInput", "latitude").Minimum, doc.Property("PlaceInput", "latitude").Maximum = openapi.Float(-90), openapi.Float(90)
+	doc.Property("PlaceInput", "longitude").Minimum, doc.Property("PlaceInput", "longitude").Maximum = openapi.Float(-180), openapi.Float(180)
+	doc.Property("PlaceInput", "marker_color").Pattern = markerColorPattern.String()
+	doc.Property("PlaceInput", "marker_color").Default = "#26e0b0"
+	doc.Component("TripList", apiList[models.Trip]{})
+	doc.Component("PlaceList", apiList[models.Place]{})
+	doc.Component("TripMap", tripMapResponse{})
+
+	trips := apiSecurity(models.ScopeReadTrips)
+	writeTrips := apiSecurity(models.ScopeWriteTrips)
+
+	// /api/v1
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/trips", OperationID: "listTrips", Tags: []string{"trips"},
+		Summary:     "List trips",
+		Description: "Oldest departure first, a page at a time. Send next_cursor back as cursor for the next page.",
+		Parameters: append(listParameters(),
+			query("airport", "Departing from or arriving at an IATA code", &openapi.Schema{Type: "string", Pattern: "^[A-Za-z]{3}$"}),
+			query("airline", "Airline name, case insensitive", &openapi.Schema{Type: "string"})),
+		Responses: responses(http.StatusOK, "A page of trips", doc.Schema(apiList[models.Trip]{}), http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden),
+		Security:  trips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/trips", OperationID: "createTrip", Tags: []string{"trips"},
+		Summary:     "Create a trip",
+		RequestBody: jsonBody(doc.Schema(apiTripInput{})),
+		Responses:   created("The trip", doc.Schema(models.Trip{}), "/api/v1/trips/{id}"),
+		Security:    writeTrips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/trips/{id}", OperationID: "getTrip", Tags: []string{"trips"},
+		Summary:    "Get a trip",
+		Parameters: []openapi.Parameter{idParameter()},
+		Responses:  responses(http.StatusOK, "The trip", doc.Schema(models.Trip{}), http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+		Security:   trips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodPut, Path: "/api/v1/trips/{id}", OperationID: "replaceTrip", Tags: []string{"trips"},
+		Summary:     "Replace a trip",
+		Description: "Every field is replaced, optional fields that are left out are cleared.",
+		Parameters:  []openapi.Parameter{idParameter()},
+		RequestBody: jsonBody(doc.Schema(apiTripInput{})),
+		Responses:   responses(http.StatusOK, "The trip", doc.Schema(models.Trip{}), http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity),
+		Security:    writeTrips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodDelete, Path: "/api/v1/trips/{id}", OperationID: "deleteTrip", Tags: []string{"trips"},
+		Summary:     "Delete a trip",
+		Description: "The trip is moved to the trash.",
+		Parameters:  []openapi.Parameter{idParameter()},
+		Responses:   responses(http.StatusNoContent, "Deleted", nil, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+		Security:    writeTrips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/places", OperationID: "listPlaces", Tags: []string{"places"},
+		Summary:     "List places",
+		Description: "Oldest visit first, a page at a time. Send next_cursor back as cursor for the next page.",
+		Parameters: append(listParameters(),
+			query("category", "Places of one category", &openapi.Schema{Type: "string"})),
+		Responses: responses(http.StatusOK, "A page of places", doc.Schema(apiList[models.Place]{}), http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden),
+		Security:  trips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodPost, Path: "/api/v1/places", OperationID: "createPlace", Tags: []string{"places"},
+		Summary:     "Create a place",
+		RequestBody: jsonBody(doc.Schema(apiPlaceInput{})),
+		Responses:   created("The place", doc.Schema(models.Place{}), "/api/v1/places/{id}"),
+		Security:    writeTrips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/places/{id}", OperationID: "getPlace", Tags: []string{"places"},
+		Summary:    "Get a place",
+		Parameters: []openapi.Parameter{idParameter()},
+		Responses:  responses(http.StatusOK, "The place", doc.Schema(models.Place{}), http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+		Security:   trips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodPut, Path: "/api/v1/places/{id}", OperationID: "replacePlace", Tags: []string{"places"},
+		Summary:     "Replace a place",
+		Description: "Every field but the location and place_id is replaced, optional fields that are left out are cleared.",
+		Parameters:  []openapi.Parameter{idParameter()},
+		RequestBody: jsonBody(doc.Schema(apiPlaceInput{})),
+		Responses:   responses(http.StatusOK, "The place", doc.Schema(models.Place{}), http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity),
+		Security:    writeTrips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodDelete, Path: "/api/v1/places/{id}", OperationID: "deletePlace", Tags: []string{"places"},
+		Summary:     "Delete a place",
+		Description: "The place is moved to the trash.",
+		Parameters:  []openapi.Parameter{idParameter()},
+		Responses:   responses(http.StatusNoContent, "Deleted", nil, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+		Security:    writeTrips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/v1/stats", OperationID: "getStatistics", Tags: []string{"statistics"},
+		Summary:     "Get statistics",
+		Description: "Every year with flights per year, or one year with flights per month.",
+		Parameters:  []openapi.Parameter{query("year", "Sum up one year", &openapi.Schema{Type: "integer", Minimum: openapi.Float(1900), Maximum: openapi.Float(9999)})},
+		Responses:   responses(http.StatusOK, "The statistics", doc.Schema(models.Statistics{}), http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden),
+		Security:    apiSecurity(models.ScopeReadStats),
+	})
+
+	// Unversioned calls used by the pages, their errors are plain text
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/trips", OperationID: "getTripMap", Tags: []string{"trips"},
+		Summary:     "Get every trip for the map",
+		Description: "Trips that are legs of an itinerary are only listed in the itinerary.",
+		Responses:   authenticated(plainResponses(http.StatusOK, "Standalone trips and itineraries", jsonContent(doc.Schema(tripMapResponse{})))),
+		Security:    trips,
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/airports", OperationID: "findAirports", Tags: []string{"lookups"},
+		Summary:     "Find airports",
+		Description: "One of code, lat and lon, or q is used in that order. HTMX requests get <option> elements for a datalist.",
+		Parameters: []openapi.Parameter{
+			query("code", "IATA or ICAO code of a single airport", &openapi.Schema{Type: "string"}),
+			query("lat", "Latitude to find the nearest airports", &openapi.Schema{Type: "number", Minimum: openapi.Float(-90), Maximum: openapi.Float(90)}),
+			query("lon", "Longitude to find the nearest airports", &openapi.Schema{Type: "number", Minimum: openapi.Float(-180), Maximum: openapi.Float(180)}),
+			query("q", "Search by code, name or city, departure and arrival are accepted as well", &openapi.Schema{Type: "string"}),
+			query("limit", "Number of airports", &openapi.Schema{Type: "integer", Minimum: openapi.Float(1), Maximum: openapi.Float(50), Default: 10}),
+		},
+		Responses: authenticated(plainResponses(http.StatusOK, "The airports", map[string]openapi.MediaType{
+			"application/json": {Schema: doc.Schema([]models.Airport{})},
+			"text/html":        {Schema: &openapi.Schema{Type: "string"}},
+		}, http.StatusBadRequest, http.StatusNotFound)),
+		Security: apiSecurity(""),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/flights", OperationID: "lookUpFlight", Tags: []string{"lookups"},
+		Summary:     "Look up a flight",
+		Description: "Answers the trip form filled in with the flight's schedule.",
+		Parameters:  []openapi.Parameter{required(query("flight_iata", "Flight number like JL5", &openapi.Schema{Type: "string"}))},
+		Responses:   authenticated(plainResponses(http.StatusOK, "The trip form", htmlContent(), http.StatusBadRequest)),
+		Security:    apiSecurity(""),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/statistics", OperationID: "getStatisticsPanel", Tags: []string{"statistics"},
+		Summary: "Get the statistics charts",
+		Parameters: []openapi.Parameter{
+			query("agg", "y per year or m per month", &openapi.Schema{Type: "string", Enum: []string{"y", "m"}}),
+			query("year", "Year of a per month aggregation", &openapi.Schema{Type: "integer"}),
+		},
+		Responses: authenticated(plainResponses(http.StatusOK, "The charts", htmlContent())),
+		Security:  apiSecurity(models.ScopeReadStats),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodPost, Path: "/api/forgot-password", OperationID: "forgotPassword", Tags: []string{"account"},
+		Summary:     "Send a password reset link",
+		RequestBody: formBody(map[string]*openapi.Schema{"email": {Type: "string", Format: "email"}}, "email"),
+		Responses:   plainResponses(http.StatusOK, "A confirmation", htmlContent()),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodPost, Path: "/api/reset-password", OperationID: "resetPassword", Tags: []string{"account"},
+		Summary: "Reset a password with the token of a reset link",
+		RequestBody: formBody(map[string]*openapi.Schema{
+			"token":            {Type: "string"},
+			"password":         {Type: "string", Format: "password"},
+			"confirm_password": {Type: "string", Format: "password"},
+		}, "confirm_password", "password", "token"),
+		Responses: plainResponses(http.StatusOK, "A confirmation", htmlContent(), http.StatusBadRequest),
+	})
+
+	// Google Places proxies, public like the forms that use them
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/places/search", OperationID: "searchGooglePlaces", Tags: []string{"google places"},
+		Summary:    "Search Google Places",
+		Parameters: []openapi.Parameter{query("query", "Text to complete", &openapi.Schema{Type: "string"})},
+		Responses:  plainResponses(http.StatusOK, "The suggestions", htmlContent()),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/places/details", OperationID: "getGooglePlace", Tags: []string{"google places"},
+		Summary:    "Get the add place form for a Google Place",
+		Parameters: []openapi.Parameter{required(query("place_id", "Google Place ID", &openapi.Schema{Type: "string"}))},
+		Responses:  plainResponses(http.StatusOK, "The form filled in with the place", htmlContent(), http.StatusBadRequest),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/places/modal", OperationID: "openPlaceModal", Tags: []string{"google places"},
+		Summary:   "Get the add place dialog",
+		Responses: plainResponses(http.StatusOK, "The dialog", htmlContent()),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/places/modal/close", OperationID: "closePlaceModal", Tags: []string{"google places"},
+		Summary:   "Get an empty add place form",
+		Responses: plainResponses(http.StatusOK, "The form", htmlContent()),
+	})
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/places/filter", OperationID: "filterTimeline", Tags: []string{"places"},
+		Summary: "Filter the timeline of the places page",
+		Parameters: []openapi.Parameter{
+			query("show_trips", "Include trips", &openapi.Schema{Type: "boolean"}),
+			query("show_places", "Include places", &openapi.Schema{Type: "boolean"}),
+		},
+		Responses: plainResponses(http.StatusOK, "The timeline", htmlContent()),
+		Security:  []openapi.SecurityRequirement{{cookieAuth: {}}},
+	})
+
+	doc.Add(openapi.Operation{
+		Method: http.MethodGet, Path: "/api/openapi.json", OperationID: "getOpenAPI", Tags: []string{"meta"},
+		Summary:   "Get this document",
+		Responses: plainResponses(http.StatusOK, "The OpenAPI document", map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: "object"}}}),
+	})
+	return doc
+}
+
+func scopeList() string {
+	var scopes []string
+	for _, scope := range models.AccessScopes {
+		scopes = append(scopes, scope+" ("+models.AccessScopeLabel(scope)+")")
+	}
+	return strings.Join(scopes, ", ")
+}
+
+// apiSecurity accepts a token with the scope, any token when scope is empty,
+// or a session
+func apiSecurity(scope string) []openapi.SecurityRequirement {
+	scopes := []string{}
+	if scope != "" {
+		scopes = append(scopes, scope)
+	}
+	return []openapi.SecurityRequirement{{bearerAuth: scopes}, {cookieAuth: {}}}
+}
+
+func query(name, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func required(parameter openapi.Parameter) openapi.Parameter {
+	parameter.Required = true
+	return parameter
+}
+
+func idParameter() openapi.Parameter {
+	return openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int64"}}
+}
+
+func listParameters() []openapi.Parameter {
+	return []openapi.Parameter{
+		query("from", "First day, inclusive", &openapi.Schema{Type: "string", Format: "date"}),
+		query("to", "Last day, inclusive", &openapi.Schema{Type: "string", Format: "date"}),
+		query("cursor", "next_cursor of the previous page", &openapi.Schema{Type: "string"}),
+		query("limit", "Records per page", &openapi.Schema{Type: "integer", Minimum: openapi.Float(1), Maximum: openapi.Float(apiMaxLimit), Default: apiDefaultLimit}),
+	}
+}
+
+func jsonContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: schema}}
+}
+
+func htmlContent() map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}}
+}
+
+func jsonBody(schema *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: jsonContent(schema)}
+}
+
+func formBody(properties map[string]*openapi.Schema, requiredFields ...string) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+		"application/x-www-form-urlencoded": {Schema: &openapi.Schema{Type: "object", Properties: properties, Required: requiredFields}},
+	}}
+}
+
+// apiErrorDescriptions are the error responses of /api/v1 by status
+var apiErrorDescriptions = map[int]string{
+	http.StatusBadRequest:            "invalid_json or invalid_parameter",
+	http.StatusUnauthorized:          "unauthorized or token_expired",
+	http.StatusForbidden:             "insufficient_scope, the token lacks the scope",
+	http.StatusNotFound:              "not_found, also for records of other users",
+	http.StatusRequestEntityTooLarge: "too_large",
+	http.StatusUnprocessableEntity:   "validation_failed, fields names each invalid field",
+	http.StatusInternalServerError:   "internal_error",
+}
+
+// responses answers a JSON body, or no body when schema is nil, and the
+// ErrorResponse of each error status
+func responses(status int, description string, schema *openapi.Schema, errorStatuses ...int) map[string]*openapi.Response {
+	all := map[string]*openapi.Response{strconv.Itoa(status): {Description: description}}
+	if schema != nil {
+		all[strconv.Itoa(status)].Content = jsonContent(schema)
+	}
+	for _, code := range append(errorStatuses, http.StatusInternalServerError) {
+		all[strconv.Itoa(code)] = &openapi.Response{Description: apiErrorDescriptions[code], Content: jsonContent(openapi.Ref("ErrorResponse"))}
+	}
+	return all
+}
+
+func created(description string, schema *openapi.Schema, location string) map[string]*openapi.Response {
+	all := responses(http.StatusCreated, description, schema, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity)
+	all[strconv.Itoa(http.StatusCreated)].Headers = map[string]openapi.Header{
+		"Location": {Description: "URL of the record, " + location, Schema: &openapi.Schema{Type: "string"}},
+	}
+	return all
+}
+
+// plainResponses are the responses of the unversioned routes, their errors
+// are plain text
+func plainResponses(status int, description string, content map[string]openapi.MediaType, errorStatuses ...int) map[string]*openapi.Response {
+	all := map[string]*openapi.Response{strconv.Itoa(status): {Description: description, Content: content}}
+	for _, code := range append(errorStatuses, http.StatusInternalServerError) {
+		all[strconv.Itoa(code)] = &openapi.Response{
+			Description: http.StatusText(code),
+			Content:     map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}},
+		}
+	}
+	return all
+}
+
+// authenticated adds the errors of AddAPIUserToContext to the responses of
+// an unversioned route
+func authenticated(all map[string]*openapi.Response) map[string]*openapi.Response {
+	for _, code := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		all[strconv.Itoa(code)] = &openapi.Response{Description: apiErrorDescriptions[code], Content: jsonContent(openapi.Ref("ErrorResponse"))}
+	}
+	return all
+}
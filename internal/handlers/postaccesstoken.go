@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostAccessTokenHandler struct {
+	accessTokenStore *db.AccessTokenStore
+}
+
+type PostAccessTokenHandlerParams struct {
+	AccessTokenStore *db.AccessTokenStore
+}
+
+func NewPostAccessTokenHandler(params PostAccessTokenHandlerParams) *PostAccessTokenHandler {
+	return &PostAccessTokenHandler{
+		accessTokenStore: params.AccessTokenStore,
+	}
+}
+
+// renderAccessTokens renders the token list of the settings page
+func renderAccessTokens(w http.ResponseWriter, r *http.Request, accessTokenStore *db.AccessTokenStore, userID int, created *models.AccessToken, problem string) {
+	tokens, err := accessTokenStore.GetAccessTokens(userID)
+	if err != nil {
+		log.Printf("Error getting access tokens: %v", err)
+		http.Error(w, "Error getting access tokens", http.StatusInternalServerError)
+		return
+	}
+	if err := templates.AccessTokenSettings(tokens, created, problem).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
+
+// POST /settings/tokens creates an access token from the form fields name,
+// scope (repeated) and expires_in_days (0 never expires)
+func (h *PostAccessTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	token := models.AccessToken{UserID: userID, Name: strings.TrimSpace(r.PostForm.Get("name"))}
+	for _, scope := range models.AccessScopes {
+		for _, value := range r.PostForm["scope"] {
+			if value == scope {
+				token.Scopes = append(token.Scopes, scope)
+				break
+			}
+		}
+	}
+	days, err := strconv.Atoi(r.PostForm.Get("expires_in_days"))
+
+	problem := ""
+	switch {
+	case token.Name == "":
+		problem = "Give the token a name so you know where it is used."
+	case utf8.RuneCountInString(token.Name) > 100:
+		problem = "The name can be at most 100 characters long."
+	case len(token.Scopes) == 0:
+		problem = "Choose at least one scope."
+	case err != nil || days < 0 || days > 365:
+		problem = "Choose when the token expires."
+	}
+	if problem != "" {
+		renderAccessTokens(w, r, h.accessTokenStore, userID, nil, problem)
+		return
+	}
+
+	if days > 0 {
+		expiresAt := uint32(time.Now().AddDate(0, 0, days).Unix())
+		token.ExpiresAt = &expiresAt
+	}
+	created, err := h.accessTokenStore.CreateAccessToken(token)
+	if err != nil {
+		log.Printf("Error creating access token: %v", err)
+		http.Error(w, "Error creating access token", http.StatusInternalServerError)
+		return
+	}
+	renderAccessTokens(w, r, h.accessTokenStore, userID, &created, "")
+}
@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/skywall34/trip-tracker/internal/archive"
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/ical"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+// maxAccountArchiveSize limits uploaded account archives
+const maxAccountArchiveSize = 100 << 20
+
+type PostAccountImportHandler struct {
+	userStore       *db.UserStore
+	airportStore    *db.AirportStore
+	tripStore       *db.TripStore
+	placeStore      *db.PlaceStore
+	journeyStore    *db.JourneyStore
+	attachmentStore *db.AttachmentStore
+}
+
+type PostAccountImportHandlerParams struct {
+	UserStore       *db.UserStore
+	AirportStore    *db.AirportStore
+	TripStore       *db.TripStore
+	PlaceStore      *db.PlaceStore
+	JourneyStore    *db.JourneyStore
+	AttachmentStore *db.AttachmentStore
+}
+
+func NewPostAccountImportHandler(params PostAccountImportHandlerParams) *PostAccountImportHandler {
+	return &PostAccountImportHandler{
+		userStore:       params.UserStore,
+		airportStore:    params.AirportStore,
+		tripStore:       params.TripStore,
+		placeStore:      params.PlaceStore,
+		journeyStore:    params.JourneyStore,
+		attachmentStore: params.AttachmentStore,
+	}
+}
+
+// POST /settings/account/import restores an account archive into the signed
+// in account. Records get new ids and references between them are remapped.
+// Records the account already has are kept and reported as conflicts, so an
+// archive can be imported twice without adding anything.
+func (h *PostAccountImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAccountArchiveSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose an account archive (.zip) to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	_, account, err := archive.Read(file, header.Size)
+	if err != nil {
+		http.Error(w, "Error reading archive: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := archive.Report{Sessions: len(account.Sessions)}
+	if err := h.restore(userID, account, &report); err != nil {
+		log.Printf("Error importing account archive for user %d: %v", userID, err)
+		http.Error(w, "Error importing archive", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.AccountImportReport(report).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *PostAccountImportHandler) restore(userID int, account archive.Account, report *archive.Report) error {
+	if err := h.restoreProfile(userID, account.Profile, report); err != nil {
+		return err
+	}
+	tripIDs, err := h.restoreTrips(userID, account.Trips, report)
+	if err != nil {
+		return err
+	}
+	placeIDs, err := h.restorePlaces(userID, account.Places, report)
+	if err != nil {
+		return err
+	}
+	if err := h.restoreJourneys(userID, account.Journeys, tripIDs, placeIDs, report); err != nil {
+		return err
+	}
+	if err := h.restoreItineraries(userID, account.Itineraries, tripIDs, report); err != nil {
+		return err
+	}
+	return h.restoreAttachments(userID, account.Attachments, tripIDs, placeIDs, report)
+}
+
+// restoreProfile keeps the username, email and name of the signed in account
+// and takes the layover setting from the archive
+func (h *PostAccountImportHandler) restoreProfile(userID int, profile archive.Profile, report *archive.Report) error {
+	user, err := h.userStore.GetUserGivenID(userID)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(profile.Email, user.Email) || profile.Username != user.Username {
+		report.Conflict(archive.KindProfile, 0, "the archive belongs to %s (%s), your username and email were kept",
+			profile.Username, profile.Email)
+	}
+	// The same range the settings form accepts
+	if profile.MaxLayoverMinutes >= 60 && profile.MaxLayoverMinutes <= 72*60 {
+		return h.userStore.UpdateMaxLayover(userID, profile.MaxLayoverMinutes)
+	}
+	return nil
+}
+
+// restoreTrips returns the ids the archived trips have in the account. Trips
+// at airports this instance does not know are skipped, every page reads trips
+// joined with their airports and would never show them.
+func (h *PostAccountImportHandler) restoreTrips(userID int, trips []models.Trip, report *archive.Report) (map[int]int, error) {
+	ids := make(map[int]int, len(trips))
+	knownAirports := make(map[string]bool)
+	for _, trip := range trips {
+		sourceID := trip.ID
+		trip.ID, trip.UserId, trip.DeletedAt = 0, userID, nil
+		trip.Departure = strings.ToUpper(strings.TrimSpace(trip.Departure))
+		trip.Arrival = strings.ToUpper(strings.TrimSpace(trip.Arrival))
+		if err := validateArchivedTrip(trip); err != nil {
+			report.Trips.Skipped++
+			report.Conflict(models.EntityTrip, sourceID, "skipped, %v", err)
+			continue
+		}
+		unknown, err := h.unknownAirport(knownAirports, trip.Departure, trip.Arrival)
+		if err != nil {
+			return nil, err
+		}
+		if unknown != "" {
+			report.Trips.Skipped++
+			report.Conflict(models.EntityTrip, sourceID, "%s was not imported, airport %s is not in this instance's airport list", ical.Summary(trip), unknown)
+			continue
+		}
+
+		existingID, err := h.tripStore.GetMatchingTripID(userID, trip)
+		if err == nil {
+			ids[sourceID] = existingID
+			report.Trips.Existing++
+			report.Conflict(models.EntityTrip, sourceID, "%s matches a flight you already have, yours was kept", ical.Summary(trip))
+			continue
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			return nil, err
+		}
+
+		id, err := h.tripStore.CreateTrip(trip)
+		if err != nil {
+			return nil, err
+		}
+		ids[sourceID] = int(id)
+		report.Trips.Imported++
+	}
+	return ids, nil
+}
+
+// unknownAirport returns the first of the codes that is not in the airport
+// list, known caches the lookups across trips
+func (h *PostAccountImportHandler) unknownAirport(known map[string]bool, codes ...string) (string, error) {
+	for _, code := range codes {
+		isKnown, checked := known[code]
+		if !checked {
+			_, err := h.airportStore.GetAirportByIATA(code)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				return "", err
+			}
+			isKnown = err == nil
+			known[code] = isKnown
+		}
+		if !isKnown {
+			return code, nil
+		}
+	}
+	return "", nil
+}
+
+func validateArchivedTrip(trip models.Trip) error {
+	switch {
+	case trip.Departure == "" || trip.Arrival == "":
+		return errors.New("departure and arrival are required")
+	case trip.DepartureTime == 0 || trip.ArrivalTime == 0:
+		return errors.New("departure and arrival times are required")
+	case trip.ArrivalTime < trip.DepartureTime:
+		return errors.New("arrives before it departs")
+	case trip.CabinClass != nil && !models.IsCabinClass(*trip.CabinClass):
+		return fmt.Errorf("unknown cabin class %q", *trip.CabinClass)
+	}
+	return nil
+}
+
+// restorePlaces returns the ids the archived places have in the account
+func (h *PostAccountImportHandler) restorePlaces(userID int, places []models.Place, report *archive.Report) (map[int]int, error) {
+	ids := make(map[int]int, len(places))
+	for _, place := range places {
+		sourceID := place.ID
+		place.ID, place.UserID, place.DeletedAt = 0, userID, nil
+		place.Name = strings.TrimSpace(place.Name)
+		if place.MarkerColor == "" {
+			place.MarkerColor = models.DefaultMarkerColor
+		}
+		if err := validateArchivedPlace(place); err != nil {
+			report.Places.Skipped++
+			report.Conflict(models.EntityPlace, sourceID, "skipped, %v", err)
+			continue
+		}
+
+		existingID, err := h.placeStore.GetMatchingPlaceID(userID, place)
+		if err == nil {
+			ids[sourceID] = existingID
+			report.Places.Existing++
+			report.Conflict(models.EntityPlace, sourceID, "%s matches a place you already have, yours was kept", place.Name)
+			continue
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			return nil, err
+		}
+
+		id, err := h.placeStore.CreatePlace(place)
+		if err != nil {
+			return nil, err
+		}
+		ids[sourceID] = id
+		report.Places.Imported++
+	}
+	return ids, nil
+}
+
+func validateArchivedPlace(place models.Place) error {
+	switch {
+	case place.Name == "":
+		return errors.New("name is required")
+	case place.Latitude < -90 || place.Latitude > 90 || place.Longitude < -180 || place.Longitude > 180:
+		return errors.New("location is out of range")
+	case place.VisitDate == 0:
+		return errors.New("visit date is required")
+	}
+	return nil
+}
+
+// restoreJourneys creates the journeys and adds their restored members. A
+// journey with the same title and dates as one of the account's journeys is
+// merged into it.
+func (h *PostAccountImportHandler) restoreJourneys(userID int, journeys []archive.Journey, tripIDs, placeIDs map[int]int, report *archive.Report) error {
+	existing, err := h.journeyStore.GetJourneysForUser(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, journey := range journeys {
+		title := strings.TrimSpace(journey.Title)
+		if title == "" || journey.EndDate < journey.StartDate {
+			report.Journeys.Skipped++
+			report.Conflict(archive.KindJourney, journey.ID, "skipped, a journey needs a title and an end date after its start")
+			continue
+		}
+
+		journeyID := 0
+		for _, other := range existing {
+			if other.Title == title && other.StartDate == journey.StartDate && other.EndDate == journey.EndDate {
+				journeyID = other.ID
+				break
+			}
+		}
+		if journeyID != 0 {
+			report.Journeys.Existing++
+			report.Conflict(archive.KindJourney, journey.ID, "%s matches a journey you already have, its flights and places were added to yours", title)
+		} else {
+			journeyID, err = h.journeyStore.CreateJourney(models.Journey{
+				UserID:        userID,
+				Title:         title,
+				StartDate:     journey.StartDate,
+				EndDate:       journey.EndDate,
+				CoverLocation: journey.CoverLocation,
+			})
+			if err != nil {
+				return err
+			}
+			report.Journeys.Imported++
+		}
+
+		for _, sourceID := range journey.TripIDs {
+			tripID, ok := tripIDs[sourceID]
+			if !ok {
+				report.Conflict(archive.KindJourney, journey.ID, "flight %d of %s was not restored, it is left out of the journey", sourceID, title)
+				continue
+			}
+			if err := h.journeyStore.AddTripToJourney(journeyID, tripID, userID); err != nil {
+				return err
+			}
+		}
+		for _, sourceID := range journey.PlaceIDs {
+			placeID, ok := placeIDs[sourceID]
+			if !ok {
+				report.Conflict(archive.KindJourney, journey.ID, "place %d of %s was not restored, it is left out of the journey", sourceID, title)
+				continue
+			}
+			if err := h.journeyStore.AddPlaceToJourney(journeyID, placeID, userID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreAttachments keeps the files with the restored trips and places. A
+// file the record already has is not added again.
+func (h *PostAccountImportHandler) restoreAttachments(userID int, attachments []archive.Attachment, tripIDs, placeIDs map[int]int, report *archive.Report) error {
+	for _, attachment := range attachments {
+		var entityID int
+		var ok bool
+		switch attachment.EntityType {
+		case models.EntityTrip:
+			entityID, ok = tripIDs[attachment.EntityID]
+		case models.EntityPlace:
+			entityID, ok = placeIDs[attachment.EntityID]
+		}
+		if !ok {
+			report.Attachments.Skipped++
+			report.Conflict(archive.KindAttachment, attachment.ID, "%s was not restored, its %s was not restored", attachment.Name, attachment.EntityType)
+			continue
+		}
+
+		_, created, err := h.attachmentStore.CreateAttachment(models.Attachment{
+			UserID:      userID,
+			EntityType:  attachment.EntityType,
+			EntityID:    entityID,
+			Name:        attachment.Name,
+			ContentType: attachment.ContentType,
+			Data:        attachment.Data,
+		})
+		if err != nil {
+			return err
+		}
+		if created {
+			report.Attachments.Imported++
+		} else {
+			report.Attachments.Existing++
+		}
+	}
+	return nil
+}
+
+// restoreItineraries pins the archived itineraries again. Flights the user
+// already pinned are not moved to another itinerary.
+func (h *PostAccountImportHandler) restoreItineraries(userID int, itineraries []archive.Itinerary, tripIDs map[int]int, report *archive.Report) error {
+	_, current, err := h.tripStore.GetItinerariesGivenUser(userID)
+	if err != nil {
+		return err
+	}
+	pinnedWith := make(map[int][]int)
+	for _, itinerary := range current {
+		if !itinerary.Pinned {
+			continue
+		}
+		legs := itinerary.TripIDs()
+		slices.Sort(legs)
+		for _, leg := range legs {
+			pinnedWith[leg] = legs
+		}
+	}
+
+	for _, itinerary := range itineraries {
+		var legs []int
+		for _, sourceID := range itinerary.TripIDs {
+			if tripID, ok := tripIDs[sourceID]; ok {
+				legs = append(legs, tripID)
+			}
+		}
+		if len(legs) != len(itinerary.TripIDs) || len(legs) < 2 {
+			report.Itineraries.Skipped++
+			report.Conflict(archive.KindItinerary, itinerary.ID, "skipped, not all of its flights were restored")
+			continue
+		}
+
+		slices.Sort(legs)
+		if slices.Equal(pinnedWith[legs[0]], legs) {
+			report.Itineraries.Existing++
+			continue
+		}
+		if slices.ContainsFunc(legs, func(leg int) bool { return pinnedWith[leg] != nil }) {
+			report.Itineraries.Skipped++
+			report.Conflict(archive.KindItinerary, itinerary.ID, "skipped, some of its flights are already pinned to another itinerary")
+			continue
+		}
+
+		if _, err := h.tripStore.PinItinerary(userID, legs); err != nil {
+			return err
+		}
+		for _, leg := range legs {
+			pinnedWith[leg] = legs
+		}
+		report.Itineraries.Imported++
+	}
+	return nil
+}
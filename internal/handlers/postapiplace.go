@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type PostApiPlaceHandler struct {
+	placeStore *db.PlaceStore
+}
+
+type PostApiPlaceHandlerParams struct {
+	PlaceStore *db.PlaceStore
+}
+
+func NewPostApiPlaceHandler(params PostApiPlaceHandlerParams) *PostApiPlaceHandler {
+	return &PostApiPlaceHandler{
+		placeStore: params.PlaceStore,
+	}
+}
+
+// apiPlaceInput is the body of POST and PUT /api/v1/places, the writable
+// fields of models.Place under the same names. visit_date is Unix seconds.
+type apiPlaceInput struct {
+	PlaceID     string   `json:"place_id"`
+	Name        string   `json:"name"`
+	Address     *string  `json:"address"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	VisitDate   int64    `json:"visit_date"`
+	Category    *string  `json:"category"`
+	Notes       *string  `json:"notes"`
+	MarkerColor string   `json:"marker_color"`
+}
+
+var markerColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// place validates the input. The location is only read when creating, like
+// the places page a place keeps the location it was added with.
+func (in apiPlaceInput) place(withLocation bool) (models.Place, validationErrors) {
+	problems := validationErrors{}
+	place := models.Place{
+		PlaceID:     strings.TrimSpace(in.PlaceID),
+		Name:        strings.TrimSpace(in.Name),
+		Address:     trimmed(in.Address),
+		VisitDate:   uint32(in.VisitDate),
+		Category:    trimmed(in.Category),
+		Notes:       trimmed(in.Notes),
+		MarkerColor: strings.ToLower(strings.TrimSpace(in.MarkerColor)),
+	}
+
+	if place.Name == "" {
+		problems.add("name", "is required")
+	}
+	if withLocation {
+		if in.Latitude == nil || *in.Latitude < -90 || *in.Latitude > 90 {
+			problems.add("latitude", "must be between -90 and 90")
+		} else {
+			place.Latitude = *in.Latitude
+		}
+		if in.Longitude == nil || *in.Longitude < -180 || *in.Longitude > 180 {
+			problems.add("longitude", "must be between -180 and 180")
+		} else {
+			place.Longitude = *in.Longitude
+		}
+	}
+	if in.VisitDate <= 0 || in.VisitDate > math.MaxUint32 {
+		problems.add("visit_date", "must be a Unix time in seconds")
+	}
+	if place.MarkerColor == "" {
+		place.MarkerColor = "#26e0b0"
+	} else if !markerColorPattern.MatchString(place.MarkerColor) {
+		problems.add("marker_color", "must be a color like #26e0b0")
+	}
+	return place, problems
+}
+
+// POST /api/v1/places creates a place from a JSON body and answers 201 with it
+func (h *PostApiPlaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input apiPlaceInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	place, problems := input.place(true)
+	if problems.write(w, "The place is not valid") {
+		return
+	}
+
+	place.UserID = userID
+	id, err := h.placeStore.CreatePlace(place)
+	if err != nil {
+		log.Printf("Error creating place: %v", err)
+		apiInternalError(w)
+		return
+	}
+	created, err := h.placeStore.GetPlaceByID(id, userID)
+	if err != nil {
+		log.Printf("Error reading created place %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	w.Header().Set("Location", m.GetBasePath(r.Context())+"/api/v1/places/"+strconv.Itoa(created.ID))
+	writeJSON(w, http.StatusCreated, created)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type PostApiTripHandler struct {
+	tripStore    *db.TripStore
+	airportStore *db.AirportStore
+}
+
+type PostApiTripHandlerParams struct {
+	TripStore    *db.TripStore
+	AirportStore *db.AirportStore
+}
+
+func NewPostApiTripHandler(params PostApiTripHandlerParams) *PostApiTripHandler {
+	return &PostApiTripHandler{
+		tripStore:    params.TripStore,
+		airportStore: params.AirportStore,
+	}
+}
+
+// apiTripInput is the body of POST and PUT /api/v1/trips, the writable fields
+// of models.Trip under the same names. Times are Unix seconds.
+type apiTripInput struct {
+	Departure      string   `json:"departure"`
+	Arrival        string   `json:"arrival"`
+	DepartureTime  int64    `json:"departure_time"`
+	ArrivalTime    int64    `json:"arrival_time"`
+	Airline        string   `json:"airline"`
+	FlightNumber   string   `json:"flight_number"`
+	Reservation    *string  `json:"reservation"`
+	Terminal       *string  `json:"terminal"`
+	Gate           *string  `json:"gate"`
+	Seat           *string  `json:"seat"`
+	CabinClass     *string  `json:"cabin_class"`
+	AircraftType   *string  `json:"aircraft_type"`
+	TailNumber     *string  `json:"tail_number"`
+	BookingClass   *string  `json:"booking_class"`
+	TicketPrice    *float64 `json:"ticket_price"`
+	TicketCurrency *string  `json:"ticket_currency"`
+}
+
+// trip validates the input. Airports are looked up by IATA or ICAO code and
+// stored by IATA code like the trip form does.
+func (in apiTripInput) trip(airportStore *db.AirportStore) (models.Trip, validationErrors, error) {
+	problems := validationErrors{}
+	trip := models.Trip{
+		DepartureTime:  uint32(in.DepartureTime),
+		ArrivalTime:    uint32(in.ArrivalTime),
+		Airline:        strings.TrimSpace(in.Airline),
+		FlightNumber:   strings.ToUpper(strings.ReplaceAll(in.FlightNumber, " ", "")),
+		Reservation:    trimmed(in.Reservation),
+		Terminal:       trimmed(in.Terminal),
+		Gate:           trimmed(in.Gate),
+		Seat:           trimmed(in.Seat),
+		CabinClass:     trimmed(in.CabinClass),
+		AircraftType:   trimmed(in.AircraftType),
+		TailNumber:     trimmed(in.TailNumber),
+		BookingClass:   trimmed(in.BookingClass),
+		TicketPrice:    in.TicketPrice,
+		TicketCurrency: trimmed(in.TicketCurrency),
+	}
+
+	for _, airport := range []struct {
+		field string
+		code  string
+		set   *string
+	}{
+		{"departure", in.Departure, &trip.Departure},
+		{"arrival", in.Arrival, &trip.Arrival},
+	} {
+		if strings.TrimSpace(airport.code) == "" {
+			problems.add(airport.field, "is required")
+			continue
+		}
+		found, err := airportStore.GetAirportByCode(strings.ToUpper(airport.code))
+		if errors.Is(err, sql.ErrNoRows) {
+			problems.add(airport.field, "is not a known airport code")
+			continue
+		}
+		if err != nil {
+			return trip, nil, err
+		}
+		*airport.set = found.IataCode
+	}
+
+	if in.DepartureTime <= 0 || in.DepartureTime > math.MaxUint32 {
+		problems.add("departure_time", "must be a Unix time in seconds")
+	}
+	if in.ArrivalTime <= 0 || in.ArrivalTime > math.MaxUint32 {
+		problems.add("arrival_time", "must be a Unix time in seconds")
+	} else if in.ArrivalTime < in.DepartureTime {
+		problems.add("arrival_time", "must not be before departure_time")
+	}
+	if trip.CabinClass != nil && !models.IsCabinClass(*trip.CabinClass) {
+		problems.add("cabin_class", "must be one of "+strings.Join(models.CabinClasses, ", "))
+	}
+	if trip.TicketPrice != nil && *trip.TicketPrice < 0 {
+		problems.add("ticket_price", "must not be negative")
+	}
+	for _, field := range []**string{&trip.TailNumber, &trip.BookingClass, &trip.TicketCurrency} {
+		if *field != nil {
+			upper := strings.ToUpper(**field)
+			*field = &upper
+		}
+	}
+	if trip.TicketCurrency != nil && len(*trip.TicketCurrency) != 3 {
+		problems.add("ticket_currency", "must be a three letter ISO 4217 code")
+	}
+	return trip, problems, nil
+}
+
+// POST /api/v1/trips creates a trip from a JSON body and answers 201 with it
+func (h *PostApiTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input apiTripInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	trip, problems, err := input.trip(h.airportStore)
+	if err != nil {
+		log.Printf("Error validating trip: %v", err)
+		apiInternalError(w)
+		return
+	}
+	if problems.write(w, "The trip is not valid") {
+		return
+	}
+
+	trip.UserId = userID
+	id, err := h.tripStore.CreateTrip(trip)
+	if err != nil {
+		log.Printf("Error creating trip: %v", err)
+		apiInternalError(w)
+		return
+	}
+	created, err := h.tripStore.GetTripGivenId(int(id), userID)
+	if err != nil {
+		log.Printf("Error reading created trip %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	w.Header().Set("Location", m.GetBasePath(r.Context())+"/api/v1/trips/"+strconv.Itoa(created.ID))
+	writeJSON(w, http.StatusCreated, created)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/skywall34/trip-tracker/internal/bcbp"
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+// boardingPassLeg is what became of one leg of a boarding pass
+type boardingPassLeg struct {
+	Leg     bcbp.Leg
+	Date    time.Time // Local date of the flight
+	TripID  int       // The trip that was updated, 0 when the leg was drafted
+	Drafted bool      // False when the leg was already in the drafts
+}
+
+// saveBoardingPass adds the booking reference, seat and cabin of every leg
+// to the user's trip on that flight. The barcode has the date but not the
+// times of a flight, so a leg without a trip is drafted for the user to add
+// them, see TripDrafter.
+func saveBoardingPass(airportStore *db.AirportStore, tripStore *db.TripStore, tripDraftStore *db.TripDraftStore, userID int, pass bcbp.Pass, reference time.Time) ([]boardingPassLeg, error) {
+	airports := importAirports(airportStore)
+	saved := make([]boardingPassLeg, 0, len(pass.Legs))
+	for _, leg := range pass.Legs {
+		result := boardingPassLeg{Leg: leg, Date: leg.Date(reference)}
+		var timezone string
+		if airport, ok := airports(leg.From); ok {
+			timezone = airport.Timezone
+		}
+		from := models.AirportLocalToUTC(result.Date, leg.From, timezone)
+		to := models.AirportLocalToUTC(result.Date.AddDate(0, 0, 1), leg.From, timezone)
+
+		tripID, err := tripStore.GetTripIDForFlight(userID, leg.From, leg.To, leg.FlightNumber, from, to)
+		switch {
+		case err == nil:
+			trip, err := tripStore.GetTripGivenId(tripID, userID)
+			if err != nil {
+				return saved, fmt.Errorf("getting trip %d: %w", tripID, err)
+			}
+			if trip.Airline == "" && trip.FlightNumber == "" {
+				trip.Airline, trip.FlightNumber = leg.Carrier, leg.FlightNumber
+			}
+			setBoardingPassDetails(&trip, leg)
+			if err := tripStore.EditTrip(trip, userID); err != nil {
+				return saved, fmt.Errorf("updating trip %d: %w", tripID, err)
+			}
+			result.TripID = tripID
+		case errors.Is(err, db.ErrNotFound):
+			draft := models.TripDraft{
+				UserID: userID,
+				Trip: models.Trip{
+					Airline:      leg.Carrier,
+					FlightNumber: leg.FlightNumber,
+					Departure:    leg.From,
+					Arrival:      leg.To,
+				},
+				Subject: fmt.Sprintf("Boarding pass for %s, %s", pass.Name, result.Date.Format("2 Jan 2006")),
+				// The same pass scanned twice is drafted once
+				MessageID: fmt.Sprintf("bcbp:%s/%s%s/%s%s/%03d", leg.PNR, leg.Carrier, leg.FlightNumber, leg.From, leg.To, leg.JulianDate),
+			}
+			setBoardingPassDetails(&draft.Trip, leg)
+			result.Drafted, err = tripDraftStore.QueueTripDraft(draft)
+			if err != nil {
+				return saved, fmt.Errorf("queueing a draft: %w", err)
+			}
+		default:
+			return saved, fmt.Errorf("finding the trip of %s %s: %w", leg.Carrier, leg.FlightNumber, err)
+		}
+		saved = append(saved, result)
+	}
+	return saved, nil
+}
+
+func setBoardingPassDetails(trip *models.Trip, leg bcbp.Leg) {
+	if leg.PNR != "" {
+		reservation := leg.PNR
+		trip.Reservation = &reservation
+	}
+	if leg.Seat != "" {
+		seat := leg.Seat
+		trip.Seat = &seat
+	}
+	if leg.Compartment != "" {
+		bookingClass, cabinClass := leg.Compartment, leg.CabinClass()
+		trip.BookingClass = &bookingClass
+		trip.CabinClass = &cabinClass
+	}
+}
+
+// boardingPassNotice describes what was saved, one sentence per leg
+func boardingPassNotice(saved []boardingPassLeg) string {
+	sentences := make([]string, 0, len(saved))
+	for _, result := range saved {
+		flight := fmt.Sprintf("%s %s %s→%s on %s", result.Leg.Carrier, result.Leg.FlightNumber, result.Leg.From, result.Leg.To, result.Date.Format("2 Jan"))
+		switch {
+		case result.TripID != 0 && result.Leg.Seat != "":
+			sentences = append(sentences, fmt.Sprintf("%s: seat %s was added to your trip.", flight, result.Leg.Seat))
+		case result.TripID != 0:
+			sentences = append(sentences, fmt.Sprintf("%s: your trip was updated.", flight))
+		case result.Drafted:
+			sentences = append(sentences, fmt.Sprintf("%s: add its times below to save it.", flight))
+		default:
+			sentences = append(sentences, fmt.Sprintf("%s is already in your drafts.", flight))
+		}
+	}
+	return strings.Join(sentences, " ")
+}
+
+type PostBoardingPassHandler struct {
+	airportStore   *db.AirportStore
+	tripStore      *db.TripStore
+	tripDraftStore *db.TripDraftStore
+}
+
+type PostBoardingPassHandlerParams struct {
+	AirportStore   *db.AirportStore
+	TripStore      *db.TripStore
+	TripDraftStore *db.TripDraftStore
+}
+
+func NewPostBoardingPassHandler(params PostBoardingPassHandlerParams) *PostBoardingPassHandler {
+	return &PostBoardingPassHandler{
+		airportStore:   params.AirportStore,
+		tripStore:      params.TripStore,
+		tripDraftStore: params.TripDraftStore,
+	}
+}
+
+// POST /trips/boardingpass reads the text of a boarding pass barcode, scanned
+// with the camera or pasted, and saves every leg on it
+func (h *PostBoardingPassHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	pass, err := bcbp.Parse(r.FormValue("bcbp"))
+	if errors.Is(err, bcbp.ErrNotBCBP) {
+		renderTripDrafts(w, r, h.tripDraftStore, userID, nil, "That is not the barcode of a boarding pass.")
+		return
+	}
+	if err != nil {
+		renderTripDrafts(w, r, h.tripDraftStore, userID, nil, fmt.Sprintf("The boarding pass could not be read: %v.", err))
+		return
+	}
+
+	saved, err := saveBoardingPass(h.airportStore, h.tripStore, h.tripDraftStore, userID, pass, time.Now())
+	if err != nil {
+		log.Printf("Error saving boarding pass: %v", err)
+		http.Error(w, "Error saving boarding pass", http.StatusInternalServerError)
+		return
+	}
+	for _, result := range saved {
+		if result.TripID != 0 {
+			w.Header().Set("HX-Trigger", `{"trip:updated":{}}`)
+		}
+	}
+
+	renderTripDrafts(w, r, h.tripDraftStore, userID, nil, boardingPassNotice(saved))
+}
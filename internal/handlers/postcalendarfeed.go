@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostCalendarFeedHandler struct {
+	calendarFeedStore *db.CalendarFeedStore
+}
+
+type PostCalendarFeedHandlerParams struct {
+	CalendarFeedStore *db.CalendarFeedStore
+}
+
+func NewPostCalendarFeedHandler(params PostCalendarFeedHandlerParams) *PostCalendarFeedHandler {
+	return &PostCalendarFeedHandler{
+		calendarFeedStore: params.CalendarFeedStore,
+	}
+}
+
+// absoluteURL is the URL of path on this instance as the request reached it
+func absoluteURL(ctx context.Context, r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + m.GetBasePath(ctx) + path
+}
+
+// calendarFeedURL is the absolute URL calendar apps subscribe to
+func calendarFeedURL(ctx context.Context, r *http.Request, token string) string {
+	return absoluteURL(ctx, r, "/calendar/"+token+".ics")
+}
+
+// POST /settings/calendar creates the calendar feed or replaces its token
+func (h *PostCalendarFeedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	feed, err := h.calendarFeedStore.RotateCalendarFeed(userID)
+	if err != nil {
+		http.Error(w, "Error creating calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.CalendarFeedSettings(&feed, calendarFeedURL(ctx, r, feed.Token)).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
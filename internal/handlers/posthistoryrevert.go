@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type PostHistoryRevertHandler struct {
+	historyStore *db.HistoryStore
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+}
+
+type PostHistoryRevertHandlerParams struct {
+	HistoryStore *db.HistoryStore
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+}
+
+func NewPostHistoryRevertHandler(params PostHistoryRevertHandlerParams) *PostHistoryRevertHandler {
+	return &PostHistoryRevertHandler{
+		historyStore: params.HistoryStore,
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+	}
+}
+
+// Reverts a trip or place to the version saved by the change ?id=
+func (h *PostHistoryRevertHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	changeID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid change ID", http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.historyStore.GetChange(changeID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error fetching change", http.StatusInternalServerError)
+		return
+	}
+
+	switch change.EntityType {
+	case models.EntityTrip:
+		err = h.tripStore.RevertTrip(changeID, userID)
+	case models.EntityPlace:
+		err = h.placeStore.RevertPlace(changeID, userID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "This version can not be restored", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error reverting change", http.StatusInternalServerError)
+		return
+	}
+
+	// The reverted record can be part of several lists so reload the page
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostImportCSVPlacesHandler struct {
+	placeStore *db.PlaceStore
+}
+
+type PostImportCSVPlacesHandlerParams struct {
+	PlaceStore *db.PlaceStore
+}
+
+func NewPostImportCSVPlacesHandler(params PostImportCSVPlacesHandlerParams) *PostImportCSVPlacesHandler {
+	return &PostImportCSVPlacesHandler{
+		placeStore: params.PlaceStore,
+	}
+}
+
+// POST /import/csv/places saves the rows of an uploaded places CSV, matched
+// like POST /import/csv/trips. Places without a known id are duplicates when
+// the user has a place with the same name on the same day.
+func (h *PostImportCSVPlacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose a .csv file to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rows, rowErrors, err := models.ReadPlacesCSV(file)
+	if err != nil {
+		http.Error(w, "Error reading CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := models.ImportResult{Kind: models.EntityPlace, Errors: rowErrors}
+	for _, row := range rows {
+		place := row.Place
+		place.UserID = userID
+		if err := h.savePlace(userID, place, &result); err != nil {
+			log.Printf("Error saving imported place on line %d: %v", row.Line, err)
+			http.Error(w, "Error importing places", http.StatusInternalServerError)
+			return
+		}
+	}
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Line < result.Errors[j].Line
+	})
+	result.Invalid = len(result.Errors)
+
+	err = templates.ImportResultSummary(result).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// savePlace updates the place with the row's id when the user has it and it
+// differs, otherwise creates the place unless it is a duplicate. The location
+// of an existing place is kept, like in the edit form.
+func (h *PostImportCSVPlacesHandler) savePlace(userID int, place models.Place, result *models.ImportResult) error {
+	if place.ID != 0 {
+		existing, err := h.placeStore.GetPlaceByID(place.ID, userID)
+		if err == nil {
+			if slices.Equal(storedPlaceFields(existing), storedPlaceFields(place)) {
+				result.Duplicates++
+				return nil
+			}
+			if err := h.placeStore.UpdatePlace(place, userID); err != nil {
+				return err
+			}
+			result.Updated++
+			return nil
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+	}
+
+	exists, err := h.placeStore.HasMatchingPlace(userID, place)
+	if err != nil {
+		return err
+	}
+	if exists {
+		result.Duplicates++
+		return nil
+	}
+	if _, err := h.placeStore.CreatePlace(place); err != nil {
+		return err
+	}
+	result.Imported++
+	return nil
+}
+
+// storedPlaceFields lists the values UpdatePlace writes, empty optional fields
+// and unset ones are the same. Visit dates are compared by day since that is
+// all the CSV holds.
+func storedPlaceFields(place models.Place) []string {
+	visitDate := time.Unix(int64(place.VisitDate), 0).UTC().Format(models.VisitDateLayout)
+	fields := []string{place.Name, visitDate, place.MarkerColor}
+	for _, value := range []*string{place.Address, place.Category, place.Notes} {
+		if value == nil {
+			fields = append(fields, "")
+		} else {
+			fields = append(fields, *value)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/ical"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostImportCSVTripsHandler struct {
+	airportStore *db.AirportStore
+	tripStore    *db.TripStore
+}
+
+type PostImportCSVTripsHandlerParams struct {
+	AirportStore *db.AirportStore
+	TripStore    *db.TripStore
+}
+
+func NewPostImportCSVTripsHandler(params PostImportCSVTripsHandlerParams) *PostImportCSVTripsHandler {
+	return &PostImportCSVTripsHandler{
+		airportStore: params.AirportStore,
+		tripStore:    params.TripStore,
+	}
+}
+
+// POST /import/csv/trips saves the rows of an uploaded trips CSV. A row with
+// the id of one of the user's trips updates that trip, other rows create a
+// trip unless the user already has the flight. Uploading the same file again
+// therefore changes nothing.
+func (h *PostImportCSVTripsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose a .csv file to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rows, rowErrors, err := models.ReadTripsCSV(file)
+	if err != nil {
+		http.Error(w, "Error reading CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := models.ImportResult{Kind: models.EntityTrip, Errors: rowErrors}
+	airports := airportTimezones(h.airportStore)
+	for _, row := range rows {
+		trip, err := resolveImportedTrip(row, airports)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Line: row.Line, Message: err.Error()})
+			continue
+		}
+		trip.UserId = userID
+
+		if err := h.saveTrip(userID, trip, &result); err != nil {
+			log.Printf("Error saving imported trip on line %d: %v", row.Line, err)
+			http.Error(w, "Error importing trips", http.StatusInternalServerError)
+			return
+		}
+	}
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Line < result.Errors[j].Line
+	})
+	result.Invalid = len(result.Errors)
+
+	if result.Imported > 0 || result.Updated > 0 {
+		w.Header().Set("HX-Trigger", `{"trip:created":{}}`)
+	}
+	err = templates.ImportResultSummary(result).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// saveTrip updates the trip with the row's id when the user has it and it
+// differs, otherwise creates the trip unless it is a duplicate
+func (h *PostImportCSVTripsHandler) saveTrip(userID int, trip models.Trip, result *models.ImportResult) error {
+	if trip.ID != 0 {
+		existing, err := h.tripStore.GetTripGivenId(trip.ID, userID)
+		if err == nil {
+			if slices.Equal(storedTripFields(existing), storedTripFields(trip)) {
+				result.Duplicates++
+				return nil
+			}
+			if err := h.tripStore.EditTrip(trip, userID); err != nil {
+				return err
+			}
+			result.Updated++
+			return nil
+		}
+		// The id belongs to a trip in the trash, another account or another
+		// installation, the row is matched like a new trip
+		if !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+	}
+
+	exists, err := h.tripStore.HasMatchingTrip(userID, trip)
+	if err != nil {
+		return err
+	}
+	if exists {
+		result.Duplicates++
+		return nil
+	}
+	if _, err := h.tripStore.CreateTrip(trip); err != nil {
+		return err
+	}
+	result.Imported++
+	return nil
+}
+
+// resolveImportedTrip checks the airports of a row and converts its local
+// times to UTC when the row has no UTC times
+func resolveImportedTrip(row models.TripCSVRow, airports ical.AirportTimezone) (models.Trip, error) {
+	trip := row.Trip
+	departureTZ, ok := airports(trip.Departure)
+	if !ok {
+		return trip, fmt.Errorf("unknown airport %q", trip.Departure)
+	}
+	arrivalTZ, ok := airports(trip.Arrival)
+	if !ok {
+		return trip, fmt.Errorf("unknown airport %q", trip.Arrival)
+	}
+
+	var err error
+	if trip.DepartureTime == 0 {
+		trip.DepartureTime, err = importedLocalTime("departure", row.DepartureLocal, trip.Departure, trip.DepartureTimezone, departureTZ)
+		if err != nil {
+			return trip, err
+		}
+	}
+	if trip.ArrivalTime == 0 {
+		trip.ArrivalTime, err = importedLocalTime("arrival", row.ArrivalLocal, trip.Arrival, trip.ArrivalTimezone, arrivalTZ)
+		if err != nil {
+			return trip, err
+		}
+	}
+	if trip.ArrivalTime < trip.DepartureTime {
+		return trip, errors.New("arrival is before departure")
+	}
+	return trip, nil
+}
+
+// importedLocalTime reads a *_local column like the trip form does, in the
+// timezone column of the row or else the airport's timezone
+func importedLocalTime(name, local, airport string, timezone *string, airportTZ string) (uint32, error) {
+	if local == "" {
+		return 0, fmt.Errorf("%s_time or %s_local is required", name, name)
+	}
+	if timezone != nil {
+		if _, err := time.LoadLocation(*timezone); err != nil {
+			return 0, fmt.Errorf("unknown timezone %q", *timezone)
+		}
+		airportTZ = *timezone
+	}
+	at, err := parseLocalToUTC(local, airport, airportTZ)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s_local %q, use %s", name, local, models.LocalTimeLayout)
+	}
+	return uint32(at.Unix()), nil
+}
+
+// storedTripFields lists the values an import can change, empty optional
+// fields and unset ones are the same
+func storedTripFields(trip models.Trip) []string {
+	price := ""
+	if trip.TicketPrice != nil {
+		price = strconv.FormatFloat(*trip.TicketPrice, 'f', -1, 64)
+	}
+	fields := []string{
+		trip.Departure,
+		trip.Arrival,
+		strconv.FormatUint(uint64(trip.DepartureTime), 10),
+		strconv.FormatUint(uint64(trip.ArrivalTime), 10),
+		trip.Airline,
+		trip.FlightNumber,
+		price,
+	}
+	for _, value := range []*string{trip.Reservation, trip.Terminal, trip.Gate, trip.Seat, trip.CabinClass,
+		trip.AircraftType, trip.TailNumber, trip.BookingClass, trip.TicketCurrency} {
+		if value == nil {
+			fields = append(fields, "")
+		} else {
+			fields = append(fields, *value)
+		}
+	}
+	return fields
+}
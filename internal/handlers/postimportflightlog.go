@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/importer"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostImportFlightLogHandler struct {
+	airportStore      *db.AirportStore
+	tripStore         *db.TripStore
+	importReviewStore *db.ImportReviewStore
+}
+
+type PostImportFlightLogHandlerParams struct {
+	AirportStore      *db.AirportStore
+	TripStore         *db.TripStore
+	ImportReviewStore *db.ImportReviewStore
+}
+
+func NewPostImportFlightLogHandler(params PostImportFlightLogHandlerParams) *PostImportFlightLogHandler {
+	return &PostImportFlightLogHandler{
+		airportStore:      params.AirportStore,
+		tripStore:         params.TripStore,
+		importReviewStore: params.ImportReviewStore,
+	}
+}
+
+// importAirports looks airports up in the airports table by IATA or ICAO code,
+// remembering the answers since logs mention the same airports many times.
+// Airports without a timezone fall back to the timezone reference map.
+func importAirports(airportStore *db.AirportStore) importer.Airports {
+	known := make(map[string]models.Airport)
+	unknown := make(map[string]bool)
+	return func(code string) (models.Airport, bool) {
+		if airport, ok := known[code]; ok {
+			return airport, true
+		}
+		if unknown[code] {
+			return models.Airport{}, false
+		}
+		airport, err := airportStore.GetAirportByCode(code)
+		if err != nil {
+			unknown[code] = true
+			return models.Airport{}, false
+		}
+		if airport.Timezone == "" {
+			airport.Timezone = models.AirportTimezoneLookup[airport.IataCode]
+		}
+		known[code] = airport
+		return airport, true
+	}
+}
+
+// POST /import/flightlog reads a log exported from another flight tracker in
+// the chosen format. Rows that resolve are saved unless the user already has
+// the flight, the others are added to the review queue.
+func (h *PostImportFlightLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose a flight log to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	parser, ok := importer.Lookup(r.FormValue("format"))
+	if !ok {
+		http.Error(w, "Unknown import format", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := parser.Parse(file, importAirports(h.airportStore))
+	if err != nil {
+		http.Error(w, "Error reading "+parser.Label()+" file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := models.ImportResult{Kind: models.EntityTrip}
+	for _, row := range rows {
+		if row.Problem != "" {
+			if _, err := h.importReviewStore.QueueImportReview(row.Review(parser.Name(), userID)); err != nil {
+				log.Printf("Error queueing line %d for review: %v", row.Line, err)
+				http.Error(w, "Error importing flights", http.StatusInternalServerError)
+				return
+			}
+			result.Queued++
+			continue
+		}
+
+		trip := row.Trip
+		trip.UserId = userID
+		exists, err := h.tripStore.HasMatchingTrip(userID, trip)
+		if err != nil {
+			log.Printf("Error checking imported trip for duplicates: %v", err)
+			http.Error(w, "Error importing flights", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			result.Duplicates++
+			continue
+		}
+		if _, err := h.tripStore.CreateTrip(trip); err != nil {
+			log.Printf("Error creating imported trip: %v", err)
+			http.Error(w, "Error importing flights", http.StatusInternalServerError)
+			return
+		}
+		result.Imported++
+	}
+
+	switch {
+	case result.Imported > 0 && result.Queued > 0:
+		w.Header().Set("HX-Trigger", `{"trip:created":{},"import:queued":{}}`)
+	case result.Imported > 0:
+		w.Header().Set("HX-Trigger", `{"trip:created":{}}`)
+	case result.Queued > 0:
+		w.Header().Set("HX-Trigger", `{"import:queued":{}}`)
+	}
+	err = templates.ImportResultSummary(result).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
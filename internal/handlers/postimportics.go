@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostImportICSHandler struct {
+	airportStore *db.AirportStore
+	tripStore    *db.TripStore
+}
+
+type PostImportICSHandlerParams struct {
+	AirportStore *db.AirportStore
+	TripStore    *db.TripStore
+}
+
+func NewPostImportICSHandler(params PostImportICSHandlerParams) *PostImportICSHandler {
+	return &PostImportICSHandler{
+		airportStore: params.AirportStore,
+		tripStore:    params.TripStore,
+	}
+}
+
+// POST /import/ics saves the preview rows the user kept checked. Every row is
+// the JSON of a candidate trip, it is validated again since it came back from
+// the browser. Duplicates are skipped so submitting twice is harmless.
+func (h *PostImportICSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error reading form", http.StatusBadRequest)
+		return
+	}
+
+	result := models.ImportResult{Kind: models.EntityTrip}
+	airports := airportTimezones(h.airportStore)
+	for _, row := range r.PostForm["trip"] {
+		var candidate models.Trip
+		if err := json.Unmarshal([]byte(row), &candidate); err != nil {
+			result.Invalid++
+			continue
+		}
+
+		trip := models.Trip{
+			UserId:        userID,
+			Departure:     strings.ToUpper(candidate.Departure),
+			Arrival:       strings.ToUpper(candidate.Arrival),
+			DepartureTime: candidate.DepartureTime,
+			ArrivalTime:   candidate.ArrivalTime,
+			Airline:       candidate.Airline,
+			FlightNumber:  candidate.FlightNumber,
+			Reservation:   candidate.Reservation,
+		}
+		_, departureOK := airports(trip.Departure)
+		_, arrivalOK := airports(trip.Arrival)
+		if !departureOK || !arrivalOK || trip.DepartureTime == 0 || trip.ArrivalTime < trip.DepartureTime {
+			result.Invalid++
+			continue
+		}
+
+		exists, err := h.tripStore.HasMatchingTrip(userID, trip)
+		if err != nil {
+			log.Printf("Error checking imported trip for duplicates: %v", err)
+			http.Error(w, "Error importing trips", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			result.Duplicates++
+			continue
+		}
+
+		if _, err := h.tripStore.CreateTrip(trip); err != nil {
+			log.Printf("Error creating imported trip: %v", err)
+			http.Error(w, "Error importing trips", http.StatusInternalServerError)
+			return
+		}
+		result.Imported++
+	}
+
+	if result.Imported > 0 {
+		w.Header().Set("HX-Trigger", `{"trip:created":{}}`)
+	}
+	err := templates.ImportResultSummary(result).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
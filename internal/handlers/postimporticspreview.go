@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/ical"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+// maxImportFileSize limits uploaded import files
+const maxImportFileSize = 10 << 20
+
+type PostImportICSPreviewHandler struct {
+	airportStore *db.AirportStore
+	tripStore    *db.TripStore
+}
+
+type PostImportICSPreviewHandlerParams struct {
+	AirportStore *db.AirportStore
+	TripStore    *db.TripStore
+}
+
+func NewPostImportICSPreviewHandler(params PostImportICSPreviewHandlerParams) *PostImportICSPreviewHandler {
+	return &PostImportICSPreviewHandler{
+		airportStore: params.AirportStore,
+		tripStore:    params.TripStore,
+	}
+}
+
+// airportTimezones adapts importAirports to the calendar importer, which only
+// looks for IATA codes
+func airportTimezones(airportStore *db.AirportStore) ical.AirportTimezone {
+	airports := importAirports(airportStore)
+	return func(iataCode string) (string, bool) {
+		airport, ok := airports(iataCode)
+		return airport.Timezone, ok && airport.IataCode == iataCode
+	}
+}
+
+// markDuplicates flags candidates the user already has and repeats within the list
+func markDuplicates(tripStore *db.TripStore, userID int, candidates []models.ImportCandidate) error {
+	for i := range candidates {
+		exists, err := tripStore.HasMatchingTrip(userID, candidates[i].Trip)
+		if err != nil {
+			return err
+		}
+		for _, earlier := range candidates[:i] {
+			if earlier.Trip.Departure == candidates[i].Trip.Departure &&
+				earlier.Trip.Arrival == candidates[i].Trip.Arrival &&
+				absSeconds(int64(earlier.Trip.DepartureTime)-int64(candidates[i].Trip.DepartureTime)) < int64(db.DuplicateTripWindow.Seconds()) {
+				exists = true
+			}
+		}
+		candidates[i].Duplicate = exists
+	}
+	return nil
+}
+
+func absSeconds(seconds int64) int64 {
+	if seconds < 0 {
+		return -seconds
+	}
+	return seconds
+}
+
+// POST /import/ics/preview reads an uploaded .ics file and lists the flights
+// found in it, nothing is saved yet
+func (h *PostImportICSPreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose an .ics file to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	events, err := ical.Parse(file)
+	if errors.Is(err, ical.ErrNotCalendar) {
+		http.Error(w, "The file is not an iCalendar (.ics) file", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error reading calendar: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	airports := airportTimezones(h.airportStore)
+	candidates := []models.ImportCandidate{}
+	for _, event := range events {
+		if trip, ok := ical.FlightFromEvent(event, airports); ok {
+			candidates = append(candidates, models.ImportCandidate{Trip: trip, Source: event.Summary})
+		}
+	}
+	if err := markDuplicates(h.tripStore, userID, candidates); err != nil {
+		log.Printf("Error checking imported trips for duplicates: %v", err)
+		http.Error(w, "Error checking for duplicates", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.ImportICSPreview(len(events), candidates).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/importer"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostImportReviewHandler struct {
+	airportStore      *db.AirportStore
+	tripStore         *db.TripStore
+	importReviewStore *db.ImportReviewStore
+}
+
+type PostImportReviewHandlerParams struct {
+	AirportStore      *db.AirportStore
+	TripStore         *db.TripStore
+	ImportReviewStore *db.ImportReviewStore
+}
+
+func NewPostImportReviewHandler(params PostImportReviewHandlerParams) *PostImportReviewHandler {
+	return &PostImportReviewHandler{
+		airportStore:      params.AirportStore,
+		tripStore:         params.TripStore,
+		importReviewStore: params.ImportReviewStore,
+	}
+}
+
+// POST /import/reviews saves a reviewed row as a trip with the airports and
+// local times the user corrected. When they still do not resolve the queue is
+// shown again with the problem next to the row.
+func (h *PostImportReviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid review id", http.StatusBadRequest)
+		return
+	}
+	review, err := h.importReviewStore.GetImportReview(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting import review %d: %v", id, err)
+		http.Error(w, "Error getting review", http.StatusInternalServerError)
+		return
+	}
+
+	trip := review.Trip
+	trip.Departure = strings.ToUpper(strings.TrimSpace(r.FormValue("departure")))
+	trip.Arrival = strings.ToUpper(strings.TrimSpace(r.FormValue("arrival")))
+	duration := time.Duration(review.DurationMinutes) * time.Minute
+	trip, err = importer.Resolve(trip, r.FormValue("departure_local"), r.FormValue("arrival_local"), duration, importAirports(h.airportStore))
+	if err != nil {
+		h.renderQueue(w, r, userID, map[int]string{id: err.Error()})
+		return
+	}
+	trip.UserId = userID
+
+	exists, err := h.tripStore.HasMatchingTrip(userID, trip)
+	if err != nil {
+		log.Printf("Error checking reviewed trip for duplicates: %v", err)
+		http.Error(w, "Error saving trip", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		if _, err := h.tripStore.CreateTrip(trip); err != nil {
+			log.Printf("Error creating reviewed trip: %v", err)
+			http.Error(w, "Error saving trip", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("HX-Trigger", `{"trip:created":{}}`)
+	}
+	if err := h.importReviewStore.DeleteImportReview(id, userID); err != nil {
+		log.Printf("Error removing import review %d: %v", id, err)
+		http.Error(w, "Error saving trip", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderQueue(w, r, userID, nil)
+}
+
+func (h *PostImportReviewHandler) renderQueue(w http.ResponseWriter, r *http.Request, userID int, problems map[int]string) {
+	reviews, err := h.importReviewStore.GetImportReviews(userID)
+	if err != nil {
+		log.Printf("Error getting import reviews: %v", err)
+		http.Error(w, "Error getting review queue", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.ImportReviewQueue(reviews, problems).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type PostItineraryHandler struct {
+	tripStore *db.TripStore
+}
+
+type PostItineraryHandlerParams struct {
+	TripStore *db.TripStore
+}
+
+func NewPostItineraryHandler(params PostItineraryHandlerParams) *PostItineraryHandler {
+	return &PostItineraryHandler{
+		tripStore: params.TripStore,
+	}
+}
+
+// Pins the given legs (trip_ids=1,2,3) into a persisted itinerary
+func (h *PostItineraryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var tripIDs []int
+	for _, idStr := range strings.Split(r.FormValue("trip_ids"), ",") {
+		if idStr = strings.TrimSpace(idStr); idStr == "" {
+			continue
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Invalid trip ID", http.StatusBadRequest)
+			return
+		}
+		// A flight listed twice is one leg
+		if !slices.Contains(tripIDs, id) {
+			tripIDs = append(tripIDs, id)
+		}
+	}
+	if len(tripIDs) < 2 {
+		http.Error(w, "An itinerary needs at least two flights", http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.tripStore.PinItinerary(userID, tripIDs)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error pinning itinerary", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", `{"itinerary:changed":{}}`)
+	w.WriteHeader(http.StatusNoContent)
+}
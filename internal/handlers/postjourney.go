@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+type PostJourneyHandler struct {
+	journeyStore *db.JourneyStore
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+}
+
+type PostJourneyHandlerParams struct {
+	JourneyStore *db.JourneyStore
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+}
+
+func NewPostJourneyHandler(params PostJourneyHandlerParams) *PostJourneyHandler {
+	return &PostJourneyHandler{
+		journeyStore: params.JourneyStore,
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+	}
+}
+
+// Creates a journey and redirects to its detail page. With add_suggestions set
+// every flight and place within the date range is added right away.
+func (h *PostJourneyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	journey, err := parseJourneyForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	journey.UserID = userID
+
+	journey.ID, err = h.journeyStore.CreateJourney(journey)
+	if err != nil {
+		http.Error(w, "Error creating journey", http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("add_suggestions") == "true" {
+		if err := addJourneySuggestions(h.journeyStore, journey, h.tripStore, h.placeStore); err != nil {
+			http.Error(w, "Error adding flights and places to journey", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/journey?id=%d", journey.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// parseJourneyForm reads the fields shared by the create and edit forms.
+// Dates are submitted as 2006-01-02 and stored as midnight UTC.
+func parseJourneyForm(r *http.Request) (models.Journey, error) {
+	var journey models.Journey
+
+	journey.Title = strings.TrimSpace(r.FormValue("title"))
+	if journey.Title == "" {
+		return journey, errors.New("Title is required")
+	}
+
+	start, err := time.Parse("2006-01-02", r.FormValue("start_date"))
+	if err != nil {
+		return journey, errors.New("Invalid start date")
+	}
+	end, err := time.Parse("2006-01-02", r.FormValue("end_date"))
+	if err != nil {
+		return journey, errors.New("Invalid end date")
+	}
+	if end.Before(start) {
+		return journey, errors.New("End date must not be before the start date")
+	}
+	// Dates are stored as unsigned 32 bit seconds like every other timestamp
+	if start.Unix() < 0 || end.Unix() > math.MaxUint32 {
+		return journey, errors.New("Dates must be between 1970 and 2106")
+	}
+	journey.StartDate = uint32(start.Unix())
+	journey.EndDate = uint32(end.Unix())
+
+	if cover := strings.TrimSpace(r.FormValue("cover_location")); cover != "" {
+		journey.CoverLocation = &cover
+	}
+
+	return journey, nil
+}
+
+func addJourneySuggestions(journeyStore *db.JourneyStore, journey models.Journey, tripStore *db.TripStore, placeStore *db.PlaceStore) error {
+	trips, places, err := journeyStore.GetJourneySuggestions(journey, tripStore, placeStore)
+	if err != nil {
+		return err
+	}
+	for _, trip := range trips {
+		if err := journeyStore.AddTripToJourney(journey.ID, trip.ID, journey.UserID); err != nil {
+			return err
+		}
+	}
+	for _, place := range places {
+		if err := journeyStore.AddPlaceToJourney(journey.ID, place.ID, journey.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
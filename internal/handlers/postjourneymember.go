@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostJourneyMemberHandler struct {
+	journeyStore *db.JourneyStore
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+}
+
+type PostJourneyMemberHandlerParams struct {
+	JourneyStore *db.JourneyStore
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+}
+
+func NewPostJourneyMemberHandler(params PostJourneyMemberHandlerParams) *PostJourneyMemberHandler {
+	return &PostJourneyMemberHandler{
+		journeyStore: params.JourneyStore,
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+	}
+}
+
+// Adds a flight (?trip_id=), a place (?place_id=) or every suggestion (?all=true)
+// to the journey and re-renders its members
+func (h *PostJourneyMemberHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	journeyID, err := strconv.Atoi(query.Get("journey_id"))
+	if err != nil {
+		http.Error(w, "Invalid journey ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case query.Get("all") == "true":
+		var journey models.Journey
+		journey, err = h.journeyStore.GetJourneyByID(journeyID, userID)
+		if err == nil {
+			err = addJourneySuggestions(h.journeyStore, journey, h.tripStore, h.placeStore)
+		}
+	case query.Get("trip_id") != "":
+		var tripID int
+		tripID, err = strconv.Atoi(query.Get("trip_id"))
+		if err != nil {
+			http.Error(w, "Invalid trip ID", http.StatusBadRequest)
+			return
+		}
+		err = h.journeyStore.AddTripToJourney(journeyID, tripID, userID)
+	case query.Get("place_id") != "":
+		var placeID int
+		placeID, err = strconv.Atoi(query.Get("place_id"))
+		if err != nil {
+			http.Error(w, "Invalid place ID", http.StatusBadRequest)
+			return
+		}
+		err = h.journeyStore.AddPlaceToJourney(journeyID, placeID, userID)
+	default:
+		http.Error(w, "trip_id, place_id or all is required", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error adding to journey", http.StatusInternalServerError)
+		return
+	}
+
+	renderJourneyMembers(w, r, h.journeyStore, journeyID, userID, h.tripStore, h.placeStore)
+}
+
+func renderJourneyMembers(w http.ResponseWriter, r *http.Request, journeyStore *db.JourneyStore, journeyID, userID int, tripStore *db.TripStore, placeStore *db.PlaceStore) {
+	journey, err := journeyStore.GetJourneyWithMembers(journeyID, userID, tripStore, placeStore)
+	if err != nil {
+		http.Error(w, "Error fetching journey", http.StatusInternalServerError)
+		return
+	}
+	suggestedTrips, suggestedPlaces, err := journeyStore.GetJourneySuggestions(journey, tripStore, placeStore)
+	if err != nil {
+		http.Error(w, "Error fetching suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.JourneyMembers(journey, suggestedTrips, suggestedPlaces).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
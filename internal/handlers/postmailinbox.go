@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostMailInboxHandler struct {
+	mailInboxStore *db.MailInboxStore
+}
+
+type PostMailInboxHandlerParams struct {
+	MailInboxStore *db.MailInboxStore
+}
+
+func NewPostMailInboxHandler(params PostMailInboxHandlerParams) *PostMailInboxHandler {
+	return &PostMailInboxHandler{
+		mailInboxStore: params.MailInboxStore,
+	}
+}
+
+// POST /settings/mail creates the forwarding address or replaces it
+func (h *PostMailInboxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Without a mail domain there is no address to create, the settings
+	// explain that instead
+	domain := mailDomain()
+	var inbox *models.MailInbox
+	if domain != "" {
+		created, err := h.mailInboxStore.RotateMailInbox(userID)
+		if err != nil {
+			http.Error(w, "Error creating forwarding address", http.StatusInternalServerError)
+			return
+		}
+		inbox = &created
+	}
+
+	err := templates.MailInboxSettings(inbox, domain).Render(ctx, w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
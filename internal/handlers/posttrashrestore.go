@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type PostTrashRestoreHandler struct {
+	tripStore  *db.TripStore
+	placeStore *db.PlaceStore
+}
+
+type PostTrashRestoreHandlerParams struct {
+	TripStore  *db.TripStore
+	PlaceStore *db.PlaceStore
+}
+
+func NewPostTrashRestoreHandler(params PostTrashRestoreHandlerParams) *PostTrashRestoreHandler {
+	return &PostTrashRestoreHandler{
+		tripStore:  params.TripStore,
+		placeStore: params.PlaceStore,
+	}
+}
+
+// Restores a trip or place from the trash, ?type=trip|place&id=
+func (h *PostTrashRestoreHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	query := r.URL.Query()
+	id, err := strconv.Atoi(query.Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch query.Get("type") {
+	case "trip":
+		err = h.tripStore.RestoreTrip(id, userID)
+	case "place":
+		err = h.placeStore.RestorePlace(id, userID)
+	default:
+		http.Error(w, "type must be trip or place", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error restoring from trash", http.StatusInternalServerError)
+		return
+	}
+
+	renderTrashContents(w, r, h.tripStore, h.placeStore, userID)
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	db "github.com/skywall34/trip-tracker/internal/database"
@@ -11,32 +15,25 @@ import (
 )
 
 type PostTripHandler struct {
-	tripStore *db.TripStore
+	tripStore    *db.TripStore
+	airportStore *db.AirportStore
 }
 
 type PostTripHandlerParams struct {
-	TripStore *db.TripStore
+	TripStore    *db.TripStore
+	AirportStore *db.AirportStore
 }
 
 func NewPostTripHandler(params PostTripHandlerParams) (*PostTripHandler) {
 	return &PostTripHandler{
-		tripStore: params.TripStore,
-	}
-}
-
-func getTimezoneGivenLocation(location string) (string) {
-	locationTZ, ok := models.AirportTimezoneLookup[location]
-
-	if !ok {
-		log.Println("Error loading timezone. Failed to get TZ from lookup:", location)
-		return ""
-	} else {
-		return locationTZ
+		tripStore:    params.TripStore,
+		airportStore: params.AirportStore,
 	}
 }
 
 const layout = "2006-01-02T15:04"
 
+// parseLocalToUTC reads a datetime-local input as the time at the airport
 func parseLocalToUTC(input, location string, timezone string)(time.Time, error) {
 	// Parse the user-provided time (local time format)
 	localTime, err := time.Parse(layout, input)
@@ -44,28 +41,76 @@ func parseLocalToUTC(input, location string, timezone string)(time.Time, error)
 		return time.Time{}, err
 	}
 
-	convertTimezone := getTimezoneGivenLocation(location)
-	if convertTimezone == "" {
-		convertTimezone = timezone
-	}
+	return models.AirportLocalToUTC(localTime, location, timezone), nil
+}
 
-	// Load the provided timezone
-	loc, err := time.LoadLocation(convertTimezone)
-	if err != nil {
-		log.Println("Error loading timezone, defaulting to UTC:", err)
-		loc = time.UTC
+// formTimezone is the timezone a time entered for the airport is read in, the
+// airport's from the airports table. The browser's timezone sent with the form
+// is only used for airports without one.
+func formTimezone(airportStore *db.AirportStore, iataCode string, browserTimezone string) string {
+	airport, err := airportStore.GetAirportByIATA(iataCode)
+	if err != nil || airport.Timezone == "" {
+		return browserTimezone
 	}
+	return airport.Timezone
+}
 
-	// Convert the local time to the correct timezone
-	localTime = time.Date(
-		localTime.Year(), localTime.Month(), localTime.Day(),
-		localTime.Hour(), localTime.Minute(), 0, 0, loc,
-	)
 
-	// Convert to UTC
-	return localTime.UTC(), nil
+// flightDetailFields maps the optional flight detail inputs to the trip fields
+// they set. A submitted empty input clears the field.
+func flightDetailFields(trip *models.Trip) map[string]**string {
+	return map[string]**string{
+		"seat":           &trip.Seat,
+		"cabinclass":     &trip.CabinClass,
+		"aircraft":       &trip.AircraftType,
+		"tailnumber":     &trip.TailNumber,
+		"bookingclass":   &trip.BookingClass,
+		"ticketcurrency": &trip.TicketCurrency,
+	}
 }
 
+// parseFlightDetails reads seat, cabin class, aircraft, fare and ticket price
+// into the trip. Inputs missing from the form leave the trip unchanged.
+func parseFlightDetails(r *http.Request, trip *models.Trip) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	for name, field := range flightDetailFields(trip) {
+		if !r.PostForm.Has(name) {
+			continue
+		}
+		value := strings.TrimSpace(r.PostForm.Get(name))
+		switch name {
+		case "cabinclass":
+			if value != "" && !models.IsCabinClass(value) {
+				return fmt.Errorf("unknown cabin class %q", value)
+			}
+		case "tailnumber", "bookingclass", "ticketcurrency":
+			value = strings.ToUpper(value)
+		}
+		if value == "" {
+			*field = nil
+		} else {
+			*field = &value
+		}
+	}
+
+	if r.PostForm.Has("ticketprice") {
+		value := strings.TrimSpace(r.PostForm.Get("ticketprice"))
+		if value == "" {
+			trip.TicketPrice = nil
+		} else {
+			price, err := strconv.ParseFloat(value, 64)
+			if err != nil || price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+				return fmt.Errorf("invalid ticket price %q", value)
+			}
+			trip.TicketPrice = &price
+		}
+	}
+
+	return nil
+}
 
 // TODO: Some of this input is going to have to come from the API
 func (t *PostTripHandler) ServeHTTP (w http.ResponseWriter, r *http.Request) {
@@ -89,12 +134,12 @@ func (t *PostTripHandler) ServeHTTP (w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	parsedDepartureTime, err := parseLocalToUTC(departureTimeString, departure, timezone)
+	parsedDepartureTime, err := parseLocalToUTC(departureTimeString, departure, formTimezone(t.airportStore, departure, timezone))
 	if err != nil {
 		log.Println("Error parsing departure time string:", err)
 		return
 	}
-	parsedArrivalTime, err := parseLocalToUTC(arrivalTimeString, arrival, timezone)
+	parsedArrivalTime, err := parseLocalToUTC(arrivalTimeString, arrival, formTimezone(t.airportStore, arrival, timezone))
 	if err != nil {
 		log.Println("Error parsing arrival time string:", err)
 		return
@@ -112,6 +157,10 @@ func (t *PostTripHandler) ServeHTTP (w http.ResponseWriter, r *http.Request) {
 		Terminal: &terminal,
 		Gate: &gate,
 	}
+	if err := parseFlightDetails(r, &newTrip); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Insert
 	_, err = t.tripStore.CreateTrip(newTrip)
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/skywall34/trip-tracker/internal/models"
+)
+
+func strPtr(s string) *string { return &s }
+
+func floatPtr(f float64) *float64 { return &f }
+
+func TestParseFlightDetails(t *testing.T) {
+	// The trip as it was before the edit
+	saved := func() models.Trip {
+		return models.Trip{
+			Seat:           strPtr("12A"),
+			CabinClass:     strPtr(models.CabinEconomy),
+			AircraftType:   strPtr("Boeing 777"),
+			TailNumber:     strPtr("JA731J"),
+			BookingClass:   strPtr("Y"),
+			TicketPrice:    floatPtr(900),
+			TicketCurrency: strPtr("JPY"),
+		}
+	}
+
+	tests := []struct {
+		name string
+		form url.Values
+		want func(trip *models.Trip)
+	}{
+		{
+			name: "every field",
+			form: url.Values{"seat": {" 1K "}, "cabinclass": {"business"}, "aircraft": {"Airbus A350"}, "tailnumber": {"ja01xj"},
+				"bookingclass": {"j"}, "ticketprice": {"1234.5"}, "ticketcurrency": {"usd"}},
+			want: func(trip *models.Trip) {
+				*trip = models.Trip{Seat: strPtr("1K"), CabinClass: strPtr("business"), AircraftType: strPtr("Airbus A350"), TailNumber: strPtr("JA01XJ"),
+					BookingClass: strPtr("J"), TicketPrice: floatPtr(1234.5), TicketCurrency: strPtr("USD")}
+			},
+		},
+		{
+			name: "fields missing from the form are kept",
+			form: url.Values{"seat": {"30C"}},
+			want: func(trip *models.Trip) { trip.Seat = strPtr("30C") },
+		},
+		{
+			name: "empty fields are cleared",
+			form: url.Values{"seat": {""}, "cabinclass": {""}, "ticketprice": {" "}, "ticketcurrency": {""}},
+			want: func(trip *models.Trip) {
+				trip.Seat, trip.CabinClass, trip.TicketPrice, trip.TicketCurrency = nil, nil, nil, nil
+			},
+		},
+		{
+			name: "a free ticket",
+			form: url.Values{"ticketprice": {"0"}},
+			want: func(trip *models.Trip) { trip.TicketPrice = floatPtr(0) },
+		},
+	}
+	for _, test := range tests {
+		trip, want := saved(), saved()
+		test.want(&want)
+		if err := parseFlightDetails(flightDetailsRequest(test.form), &trip); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(trip, want) {
+			t.Errorf("%s: got %s, want %s", test.name, flightDetails(trip), flightDetails(want))
+		}
+	}
+
+	for _, form := range []url.Values{
+		{"cabinclass": {"steerage"}},
+		{"ticketprice": {"abc"}},
+		{"ticketprice": {"-1"}},
+		{"ticketprice": {"NaN"}},
+		{"ticketprice": {"Inf"}},
+		{"ticketprice": {"1e400"}},
+		{"ticketprice": {"12,50"}},
+	} {
+		trip := saved()
+		if err := parseFlightDetails(flightDetailsRequest(form), &trip); err == nil {
+			t.Errorf("%v was accepted", form)
+		}
+	}
+}
+
+func flightDetailsRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/trips", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// flightDetails prints the pointer fields of a trip for failure messages
+func flightDetails(trip models.Trip) string {
+	var b strings.Builder
+	for _, field := range []*string{trip.Seat, trip.CabinClass, trip.AircraftType, trip.TailNumber, trip.BookingClass, trip.TicketCurrency} {
+		if field == nil {
+			b.WriteString("<nil> ")
+		} else {
+			b.WriteString(*field + " ")
+		}
+	}
+	if trip.TicketPrice == nil {
+		b.WriteString("<nil>")
+	} else {
+		b.WriteString(strconv.FormatFloat(*trip.TicketPrice, 'f', -1, 64))
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/importer"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type PostTripDraftHandler struct {
+	airportStore   *db.AirportStore
+	tripStore      *db.TripStore
+	tripDraftStore *db.TripDraftStore
+}
+
+type PostTripDraftHandlerParams struct {
+	AirportStore   *db.AirportStore
+	TripStore      *db.TripStore
+	TripDraftStore *db.TripDraftStore
+}
+
+func NewPostTripDraftHandler(params PostTripDraftHandlerParams) *PostTripDraftHandler {
+	return &PostTripDraftHandler{
+		airportStore:   params.AirportStore,
+		tripStore:      params.TripStore,
+		tripDraftStore: params.TripDraftStore,
+	}
+}
+
+// POST /trips/drafts confirms a draft with the airports and local times the
+// user checked and saves it as a trip. When they do not resolve the drafts are
+// shown again with the problem next to the draft.
+func (h *PostTripDraftHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid draft id", http.StatusBadRequest)
+		return
+	}
+	draft, err := h.tripDraftStore.GetTripDraft(id, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting trip draft %d: %v", id, err)
+		http.Error(w, "Error getting draft", http.StatusInternalServerError)
+		return
+	}
+
+	trip := draft.Trip
+	trip.Departure = strings.ToUpper(strings.TrimSpace(r.FormValue("departure")))
+	trip.Arrival = strings.ToUpper(strings.TrimSpace(r.FormValue("arrival")))
+	trip, err = importer.Resolve(trip, r.FormValue("departure_local"), r.FormValue("arrival_local"), 0, importAirports(h.airportStore))
+	if err != nil {
+		renderTripDrafts(w, r, h.tripDraftStore, userID, map[int]string{id: err.Error()}, "")
+		return
+	}
+	trip.UserId = userID
+
+	exists, err := h.tripStore.HasMatchingTrip(userID, trip)
+	if err != nil {
+		log.Printf("Error checking drafted trip for duplicates: %v", err)
+		http.Error(w, "Error saving trip", http.StatusInternalServerError)
+		return
+	}
+	notice := "You already have this flight, the draft was removed."
+	if !exists {
+		if _, err := h.tripStore.CreateTrip(trip); err != nil {
+			log.Printf("Error creating drafted trip: %v", err)
+			http.Error(w, "Error saving trip", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("HX-Trigger", `{"trip:created":{}}`)
+		notice = ""
+	}
+	if err := h.tripDraftStore.DeleteTripDraft(id, userID); err != nil {
+		log.Printf("Error removing trip draft %d: %v", id, err)
+		http.Error(w, "Error saving trip", http.StatusInternalServerError)
+		return
+	}
+
+	renderTripDrafts(w, r, h.tripDraftStore, userID, nil, notice)
+}
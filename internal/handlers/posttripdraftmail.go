@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/mailparse"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+// maxEmailUploadSize limits the .eml files of one upload together
+const maxEmailUploadSize = 25 << 20
+
+type PostTripDraftMailHandler struct {
+	tripDrafter    *TripDrafter
+	tripDraftStore *db.TripDraftStore
+}
+
+type PostTripDraftMailHandlerParams struct {
+	TripDrafter    *TripDrafter
+	TripDraftStore *db.TripDraftStore
+}
+
+func NewPostTripDraftMailHandler(params PostTripDraftMailHandlerParams) *PostTripDraftMailHandler {
+	return &PostTripDraftMailHandler{
+		tripDrafter:    params.TripDrafter,
+		tripDraftStore: params.TripDraftStore,
+	}
+}
+
+// POST /trips/drafts/eml reads uploaded .eml files like forwarded mail and
+// adds their flights to the drafts
+func (h *PostTripDraftMailHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxEmailUploadSize)
+	if err := r.ParseMultipartForm(maxEmailUploadSize); err != nil {
+		http.Error(w, "Upload is too large or malformed", http.StatusBadRequest)
+		return
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		return
+	}
+
+	var notices []string
+	for _, header := range files {
+		f, err := header.Open()
+		if err != nil {
+			http.Error(w, "Error reading upload", http.StatusBadRequest)
+			return
+		}
+		found, queued, err := h.tripDrafter.DraftTrips(userID, f)
+		f.Close()
+		switch {
+		case errors.Is(err, mailparse.ErrNotMessage):
+			notices = append(notices, fmt.Sprintf("%s is not an email.", header.Filename))
+		case err != nil:
+			log.Printf("Error drafting trips from %s: %v", header.Filename, err)
+			http.Error(w, "Error reading email", http.StatusInternalServerError)
+			return
+		case found == 0:
+			notices = append(notices, fmt.Sprintf("No flights found in %s.", header.Filename))
+		case queued < found:
+			notices = append(notices, fmt.Sprintf("Found %d flights in %s, %d were already drafted.", found, header.Filename, found-queued))
+		default:
+			notices = append(notices, fmt.Sprintf("Found %d flights in %s.", found, header.Filename))
+		}
+	}
+
+	renderTripDrafts(w, r, h.tripDraftStore, userID, nil, strings.Join(notices, " "))
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/skywall34/trip-tracker/internal/bcbp"
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/importer"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/internal/pkpass"
+)
+
+// maxWalletPassUploadSize limits the .pkpass files of one upload together
+const maxWalletPassUploadSize = 25 << 20
+
+// errNoDepartureTime is returned for passes without a departure or relevant date
+var errNoDepartureTime = errors.New("the pass has no departure time")
+
+var passFlightPattern = regexp.MustCompile(`^([A-Z0-9]{2})\s?0*([0-9]{1,4}[A-Z]?)$`)
+
+// walletPassTrip reads the flight of a Wallet boarding pass. The barcode
+// holds the BCBP data of the flight, the fields add the gate, terminal and
+// departure time. Passes without a BCBP barcode are read from their fields
+// alone. Few passes have the arrival time; when it is missing it is estimated
+// from the distance and true is returned.
+func walletPassTrip(pass pkpass.Pass, airports importer.Airports, airportStore *db.AirportStore) (models.Trip, bool, error) {
+	var trip models.Trip
+	if boardingPass, err := bcbp.Parse(pass.Barcode); err == nil && len(boardingPass.Legs) > 0 {
+		leg := boardingPass.Legs[0]
+		trip.Departure, trip.Arrival = leg.From, leg.To
+		trip.Airline, trip.FlightNumber = leg.Carrier, leg.FlightNumber
+		setBoardingPassDetails(&trip, leg)
+	}
+
+	isAirport := func(field pkpass.Field) string {
+		for _, value := range []string{field.Value, field.Label} {
+			code := strings.ToUpper(strings.TrimSpace(value))
+			if _, ok := airports(code); ok && len(code) == 3 {
+				return code
+			}
+		}
+		return ""
+	}
+	if trip.Departure == "" {
+		if field, ok := pass.Find("origin", "depart", "from"); ok {
+			trip.Departure = isAirport(field)
+		}
+	}
+	if trip.Arrival == "" {
+		if field, ok := pass.Find("destination", "arriv"); ok {
+			trip.Arrival = isAirport(field)
+		}
+	}
+	// Otherwise the first two airport codes on the pass, in order
+	for _, field := range pass.Fields {
+		code := isAirport(field)
+		switch {
+		case code == "" || code == trip.Departure || code == trip.Arrival:
+		case trip.Departure == "":
+			trip.Departure = code
+		case trip.Arrival == "":
+			trip.Arrival = code
+		}
+	}
+	// The location of a boarding pass is the departure airport
+	if trip.Departure == "" && len(pass.Locations) > 0 {
+		nearest, err := airportStore.NearestAirports(pass.Locations[0].Latitude, pass.Locations[0].Longitude, 1)
+		if err != nil {
+			return trip, false, err
+		}
+		if len(nearest) > 0 {
+			trip.Departure = nearest[0].IataCode
+		}
+	}
+
+	if trip.FlightNumber == "" {
+		if field, ok := pass.Find("flight"); ok {
+			value := strings.ToUpper(field.Value)
+			if match := passFlightPattern.FindStringSubmatch(value); match != nil {
+				trip.Airline, trip.FlightNumber = match[1], match[2]
+			} else {
+				trip.Airline, trip.FlightNumber = pass.OrganizationName, value
+			}
+		}
+	}
+	if trip.Reservation == nil {
+		if field, ok := pass.Find("confirmation", "pnr", "booking", "locator", "reservation"); ok {
+			trip.Reservation = &field.Value
+		}
+	}
+	if trip.Seat == nil {
+		if field, ok := pass.Find("seat"); ok {
+			trip.Seat = &field.Value
+		}
+	}
+	if field, ok := pass.Find("gate"); ok {
+		trip.Gate = &field.Value
+	}
+	if field, ok := pass.Find("terminal"); ok {
+		trip.Terminal = &field.Value
+	}
+
+	departure, ok := pass.FindDate("depart", "takeoff")
+	if !ok {
+		// Airlines set the relevant date to the departure or boarding time
+		departure = pass.RelevantDate
+	}
+	if departure.IsZero() {
+		return trip, false, errNoDepartureTime
+	}
+	from, ok := airports(trip.Departure)
+	if !ok {
+		return trip, false, fmt.Errorf("the departure airport %q is not known", trip.Departure)
+	}
+	to, ok := airports(trip.Arrival)
+	if !ok {
+		return trip, false, fmt.Errorf("the arrival airport %q is not known", trip.Arrival)
+	}
+
+	arrival, ok := pass.FindDate("arriv", "landing")
+	estimated := !ok || arrival.Before(departure)
+	if estimated {
+		distance := models.GreatCircleDistanceKm(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+		arrival = departure.Add(estimatedFlightDuration(distance))
+	}
+	trip.DepartureTime, trip.ArrivalTime = uint32(departure.Unix()), uint32(arrival.Unix())
+	return trip, estimated, nil
+}
+
+// estimatedFlightDuration is half an hour for taxiing, climb and descent and
+// the distance at a cruising speed of 800 km/h, rounded to five minutes
+func estimatedFlightDuration(distanceKm float64) time.Duration {
+	minutes := 30 + distanceKm/800*60
+	return time.Duration(math.Round(minutes/5)*5) * time.Minute
+}
+
+type PostWalletPassHandler struct {
+	airportStore    *db.AirportStore
+	tripStore       *db.TripStore
+	tripDraftStore  *db.TripDraftStore
+	attachmentStore *db.AttachmentStore
+}
+
+type PostWalletPassHandlerParams struct {
+	AirportStore    *db.AirportStore
+	TripStore       *db.TripStore
+	TripDraftStore  *db.TripDraftStore
+	AttachmentStore *db.AttachmentStore
+}
+
+func NewPostWalletPassHandler(params PostWalletPassHandlerParams) *PostWalletPassHandler {
+	return &PostWalletPassHandler{
+		airportStore:    params.AirportStore,
+		tripStore:       params.TripStore,
+		tripDraftStore:  params.TripDraftStore,
+		attachmentStore: params.AttachmentStore,
+	}
+}
+
+// POST /trips/pkpass reads uploaded Apple Wallet boarding passes. Each pass
+// creates its trip, or updates the trip when the user already has the
+// flight, and is kept as an attachment of the trip.
+func (h *PostWalletPassHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxWalletPassUploadSize)
+	if err := r.ParseMultipartForm(maxWalletPassUploadSize); err != nil {
+		http.Error(w, "Upload is too large or malformed", http.StatusBadRequest)
+		return
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		return
+	}
+
+	var notices []string
+	created, updated := false, false
+	for _, header := range files {
+		f, err := header.Open()
+		if err != nil {
+			http.Error(w, "Error reading upload", http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			http.Error(w, "Error reading upload", http.StatusBadRequest)
+			return
+		}
+
+		notice, tripCreated, tripUpdated, err := h.savePass(userID, header.Filename, data)
+		if err != nil {
+			log.Printf("Error saving Wallet pass %s: %v", header.Filename, err)
+			http.Error(w, "Error saving boarding pass", http.StatusInternalServerError)
+			return
+		}
+		notices = append(notices, notice)
+		created = created || tripCreated
+		updated = updated || tripUpdated
+	}
+
+	switch {
+	case created && updated:
+		w.Header().Set("HX-Trigger", `{"trip:created":{},"trip:updated":{}}`)
+	case created:
+		w.Header().Set("HX-Trigger", `{"trip:created":{}}`)
+	case updated:
+		w.Header().Set("HX-Trigger", `{"trip:updated":{}}`)
+	}
+	renderTripDrafts(w, r, h.tripDraftStore, userID, nil, strings.Join(notices, " "))
+}
+
+// savePass saves the flight of one pass and returns what became of it.
+// Problems with the pass are reported in the notice, the error is for
+// failures of the database.
+func (h *PostWalletPassHandler) savePass(userID int, name string, data []byte) (string, bool, bool, error) {
+	pass, err := pkpass.Read(bytes.NewReader(data), int64(len(data)))
+	switch {
+	case errors.Is(err, pkpass.ErrNotPass):
+		return fmt.Sprintf("%s is not a Wallet pass.", name), false, false, nil
+	case errors.Is(err, pkpass.ErrNotBoardingPass):
+		return fmt.Sprintf("%s is not a boarding pass.", name), false, false, nil
+	case err != nil:
+		return fmt.Sprintf("%s could not be read, %v.", name, err), false, false, nil
+	}
+
+	attach := func(tripID int) error {
+		_, _, err := h.attachmentStore.CreateAttachment(models.Attachment{
+			UserID:      userID,
+			EntityType:  models.EntityTrip,
+			EntityID:    tripID,
+			Name:        name,
+			ContentType: pkpass.ContentType,
+			Data:        data,
+		})
+		return err
+	}
+
+	trip, estimated, err := walletPassTrip(pass, importAirports(h.airportStore), h.airportStore)
+	if errors.Is(err, errNoDepartureTime) {
+		// Without a time only the barcode is left, which is read like a
+		// scanned boarding pass
+		boardingPass, parseErr := bcbp.Parse(pass.Barcode)
+		if parseErr != nil {
+			return fmt.Sprintf("%s has no departure time.", name), false, false, nil
+		}
+		saved, err := saveBoardingPass(h.airportStore, h.tripStore, h.tripDraftStore, userID, boardingPass, time.Now())
+		if err != nil {
+			return "", false, false, err
+		}
+		updated := false
+		for _, result := range saved {
+			if result.TripID != 0 {
+				if err := attach(result.TripID); err != nil {
+					return "", false, false, err
+				}
+				updated = true
+			}
+		}
+		return boardingPassNotice(saved), false, updated, nil
+	}
+	if err != nil {
+		return fmt.Sprintf("%s could not be saved, %v.", name, err), false, false, nil
+	}
+	trip.UserId = userID
+
+	flight := fmt.Sprintf("%s %s %s→%s", trip.Airline, trip.FlightNumber, trip.Departure, trip.Arrival)
+	tripID, err := h.tripStore.GetMatchingTripID(userID, trip)
+	switch {
+	case err == nil:
+		existing, err := h.tripStore.GetTripGivenId(tripID, userID)
+		if err != nil {
+			return "", false, false, err
+		}
+		mergePassDetails(&existing, trip)
+		if err := h.tripStore.EditTrip(existing, userID); err != nil {
+			return "", false, false, err
+		}
+		if err := attach(tripID); err != nil {
+			return "", false, false, err
+		}
+		return fmt.Sprintf("%s: your trip was updated from the pass.", flight), false, true, nil
+	case errors.Is(err, db.ErrNotFound):
+		id, err := h.tripStore.CreateTrip(trip)
+		if err != nil {
+			return "", false, false, err
+		}
+		if err := attach(int(id)); err != nil {
+			return "", false, false, err
+		}
+		if estimated {
+			return fmt.Sprintf("%s was added, the pass has no arrival time so it was estimated.", flight), true, false, nil
+		}
+		return fmt.Sprintf("%s was added.", flight), true, false, nil
+	default:
+		return "", false, false, err
+	}
+}
+
+// mergePassDetails copies what the pass knows about a flight the user already
+// has. The times of the trip are kept, the pass may only know the boarding time.
+func mergePassDetails(trip *models.Trip, pass models.Trip) {
+	for _, field := range []struct{ to, from **string }{
+		{&trip.Reservation, &pass.Reservation},
+		{&trip.Seat, &pass.Seat},
+		{&trip.Gate, &pass.Gate},
+		{&trip.Terminal, &pass.Terminal},
+		{&trip.BookingClass, &pass.BookingClass},
+		{&trip.CabinClass, &pass.CabinClass},
+	} {
+		if *field.from != nil && **field.from != "" {
+			*field.to = *field.from
+		}
+	}
+	if trip.FlightNumber == "" {
+		trip.Airline, trip.FlightNumber = pass.Airline, pass.FlightNumber
+	}
+}
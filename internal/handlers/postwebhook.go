@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/internal/models"
+	wh "github.com/skywall34/trip-tracker/internal/webhook"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PostWebhookHandler struct {
+	webhookStore *db.WebhookStore
+}
+
+type PostWebhookHandlerParams struct {
+	WebhookStore *db.WebhookStore
+}
+
+func NewPostWebhookHandler(params PostWebhookHandlerParams) *PostWebhookHandler {
+	return &PostWebhookHandler{
+		webhookStore: params.WebhookStore,
+	}
+}
+
+// renderWebhooks renders the webhook list of the settings page
+func renderWebhooks(w http.ResponseWriter, r *http.Request, webhookStore *db.WebhookStore, userID int, created *models.Webhook, problem string) {
+	webhooks, err := webhookStore.GetWebhooks(userID)
+	if err != nil {
+		log.Printf("Error getting webhooks: %v", err)
+		http.Error(w, "Error getting webhooks", http.StatusInternalServerError)
+		return
+	}
+	if err := templates.WebhookSettings(webhooks, created, problem).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
+
+// POST /settings/webhooks adds an endpoint from the form fields url and event
+// (repeated), the signing secret is shown once
+func (h *PostWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	webhook := models.Webhook{UserID: userID, URL: strings.TrimSpace(r.PostForm.Get("url"))}
+	for _, event := range models.WebhookEvents {
+		for _, value := range r.PostForm["event"] {
+			if value == event {
+				webhook.Events = append(webhook.Events, event)
+				break
+			}
+		}
+	}
+	endpoint, err := url.Parse(webhook.URL)
+
+	problem := ""
+	switch {
+	case webhook.URL == "":
+		problem = "Enter the URL the events are sent to."
+	case len(webhook.URL) > 2000:
+		problem = "The URL can be at most 2000 characters long."
+	case err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" || endpoint.User != nil:
+		problem = "Enter an http or https URL without a user name or password."
+	case wh.CheckURL(ctx, endpoint) != nil:
+		problem = "Webhooks can not be sent to loopback, private or link-local addresses, enter a public URL."
+	case len(webhook.Events) == 0:
+		problem = "Choose at least one event."
+	}
+	if problem != "" {
+		renderWebhooks(w, r, h.webhookStore, userID, nil, problem)
+		return
+	}
+
+	created, err := h.webhookStore.CreateWebhook(webhook)
+	if err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		http.Error(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+	renderWebhooks(w, r, h.webhookStore, userID, &created, "")
+}
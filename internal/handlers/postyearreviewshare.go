@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+)
+
+type PostYearReviewShareHandler struct {
+	tripStore            *db.TripStore
+	yearReviewShareStore *db.YearReviewShareStore
+}
+
+type PostYearReviewShareHandlerParams struct {
+	TripStore            *db.TripStore
+	YearReviewShareStore *db.YearReviewShareStore
+}
+
+func NewPostYearReviewShareHandler(params PostYearReviewShareHandlerParams) *PostYearReviewShareHandler {
+	return &PostYearReviewShareHandler{
+		tripStore:            params.TripStore,
+		yearReviewShareStore: params.YearReviewShareStore,
+	}
+}
+
+// POST /statistics/review/share publishes the card of the year at a public
+// link, or replaces its link so the previous one stops working
+func (h *PostYearReviewShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	year, ok := parseReviewYear(r.FormValue("year"))
+	if !ok {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+	share, err := h.yearReviewShareStore.RotateYearReviewShare(userID, year)
+	if err != nil {
+		log.Printf("Error publishing year in review: %v", err)
+		http.Error(w, "Error publishing year in review", http.StatusInternalServerError)
+		return
+	}
+
+	shareURL := absoluteURL(ctx, r, fmt.Sprintf("/review/%s.png", share.Token))
+	renderYearReviewPanel(w, r, h.tripStore, h.yearReviewShareStore, userID, year, shareURL)
+}
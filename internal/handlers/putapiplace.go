@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+type PutApiPlaceHandler struct {
+	placeStore *db.PlaceStore
+}
+
+type PutApiPlaceHandlerParams struct {
+	PlaceStore *db.PlaceStore
+}
+
+func NewPutApiPlaceHandler(params PutApiPlaceHandlerParams) *PutApiPlaceHandler {
+	return &PutApiPlaceHandler{
+		placeStore: params.PlaceStore,
+	}
+}
+
+// PUT /api/v1/places/{id} replaces the place with the JSON body, optional
+// fields left out are cleared. place_id, latitude and longitude can not be
+// changed and are ignored.
+func (h *PutApiPlaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+
+	// A place of someone else is not found before its body is looked at
+	if _, err := h.placeStore.GetPlaceByID(id, userID); errors.Is(err, sql.ErrNoRows) {
+		apiNotFound(w)
+		return
+	} else if err != nil {
+		log.Printf("Error getting place %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+
+	var input apiPlaceInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	place, problems := input.place(false)
+	if problems.write(w, "The place is not valid") {
+		return
+	}
+
+	place.ID = id
+	err := h.placeStore.UpdatePlace(place, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		apiNotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating place %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	updated, err := h.placeStore.GetPlaceByID(id, userID)
+	if err != nil {
+		log.Printf("Error reading updated place %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+type PutApiTripHandler struct {
+	tripStore    *db.TripStore
+	airportStore *db.AirportStore
+}
+
+type PutApiTripHandlerParams struct {
+	TripStore    *db.TripStore
+	AirportStore *db.AirportStore
+}
+
+func NewPutApiTripHandler(params PutApiTripHandlerParams) *PutApiTripHandler {
+	return &PutApiTripHandler{
+		tripStore:    params.TripStore,
+		airportStore: params.AirportStore,
+	}
+}
+
+// PUT /api/v1/trips/{id} replaces the trip with the JSON body, optional
+// fields left out are cleared
+func (h *PutApiTripHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := apiUser(w, r)
+	if !ok {
+		return
+	}
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+
+	// A trip of someone else is not found before its body is looked at
+	if _, err := h.tripStore.GetTripGivenId(id, userID); errors.Is(err, sql.ErrNoRows) {
+		apiNotFound(w)
+		return
+	} else if err != nil {
+		log.Printf("Error getting trip %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+
+	var input apiTripInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	trip, problems, err := input.trip(h.airportStore)
+	if err != nil {
+		log.Printf("Error validating trip: %v", err)
+		apiInternalError(w)
+		return
+	}
+	if problems.write(w, "The trip is not valid") {
+		return
+	}
+
+	trip.ID = id
+	err = h.tripStore.EditTrip(trip, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		apiNotFound(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating trip %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	updated, err := h.tripStore.GetTripGivenId(id, userID)
+	if err != nil {
+		log.Printf("Error reading updated trip %d: %v", id, err)
+		apiInternalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PutJourneyHandler struct {
+	journeyStore *db.JourneyStore
+	tripStore    *db.TripStore
+	placeStore   *db.PlaceStore
+}
+
+type PutJourneyHandlerParams struct {
+	JourneyStore *db.JourneyStore
+	TripStore    *db.TripStore
+	PlaceStore   *db.PlaceStore
+}
+
+func NewPutJourneyHandler(params PutJourneyHandlerParams) *PutJourneyHandler {
+	return &PutJourneyHandler{
+		journeyStore: params.JourneyStore,
+		tripStore:    params.TripStore,
+		placeStore:   params.PlaceStore,
+	}
+}
+
+// Updates the journey details and re-renders the detail page, changing the
+// dates changes the suggestions as well
+func (h *PutJourneyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	journeyID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid journey ID", http.StatusBadRequest)
+		return
+	}
+
+	journey, err := parseJourneyForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	journey.ID = journeyID
+	journey.UserID = userID
+
+	err = h.journeyStore.UpdateJourney(journey)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating journey", http.StatusInternalServerError)
+		return
+	}
+
+	journey, err = h.journeyStore.GetJourneyWithMembers(journeyID, userID, h.tripStore, h.placeStore)
+	if err != nil {
+		http.Error(w, "Error fetching journey", http.StatusInternalServerError)
+		return
+	}
+	suggestedTrips, suggestedPlaces, err := h.journeyStore.GetJourneySuggestions(journey, h.tripStore, h.placeStore)
+	if err != nil {
+		http.Error(w, "Error fetching suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.JourneyPage(journey, suggestedTrips, suggestedPlaces).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
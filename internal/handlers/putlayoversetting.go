@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/middleware"
+	"github.com/skywall34/trip-tracker/templates"
+)
+
+type PutLayoverSettingHandler struct {
+	userStore *db.UserStore
+}
+
+type PutLayoverSettingHandlerParams struct {
+	UserStore *db.UserStore
+}
+
+func NewPutLayoverSettingHandler(params PutLayoverSettingHandlerParams) *PutLayoverSettingHandler {
+	return &PutLayoverSettingHandler{
+		userStore: params.UserStore,
+	}
+}
+
+func (h *PutLayoverSettingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(m.UserKey).(int)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	hours, err := strconv.ParseFloat(r.FormValue("max_layover_hours"), 64)
+	if err != nil || hours < 1 || hours > 72 {
+		http.Error(w, "Maximum layover must be between 1 and 72 hours", http.StatusBadRequest)
+		return
+	}
+	minutes := int(hours * 60)
+
+	err = h.userStore.UpdateMaxLayover(userID, minutes)
+	if err != nil {
+		http.Error(w, "Error saving settings", http.StatusInternalServerError)
+		return
+	}
+
+	err = templates.LayoverSettingForm(minutes, true).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -51,7 +53,7 @@ func (h *PutPlaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Get existing place to preserve certain fields
 	existingPlace, err := h.placeStore.GetPlaceByID(placeID, userID)
 	if err != nil {
-		http.Error(w, "Place not found", http.StatusNotFound)
+		m.NotFound(w)
 		return
 	}
 
@@ -84,7 +86,11 @@ func (h *PutPlaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		MarkerColor: markerColor,
 	}
 
-	err = h.placeStore.UpdatePlace(updatedPlace)
+	err = h.placeStore.UpdatePlace(updatedPlace, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		m.NotFound(w)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error updating place", http.StatusInternalServerError)
 		return
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/mailparse"
+	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/internal/smtpd"
+)
+
+// mailDomain reads MAIL_DOMAIN, the domain of the forwarding addresses. Mail
+// forwarding is off when it is not set, .eml files can still be uploaded.
+func mailDomain() string {
+	return strings.ToLower(strings.TrimSpace(os.Getenv("MAIL_DOMAIN")))
+}
+
+// TripDrafter turns forwarded confirmation emails into trip drafts. Mail
+// received by the SMTP listener and .eml files uploaded on the trips page
+// both go through it.
+type TripDrafter struct {
+	airportStore   *db.AirportStore
+	tripDraftStore *db.TripDraftStore
+	mailInboxStore *db.MailInboxStore
+}
+
+type TripDrafterParams struct {
+	AirportStore   *db.AirportStore
+	TripDraftStore *db.TripDraftStore
+	MailInboxStore *db.MailInboxStore
+}
+
+func NewTripDrafter(params TripDrafterParams) *TripDrafter {
+	return &TripDrafter{
+		airportStore:   params.AirportStore,
+		tripDraftStore: params.TripDraftStore,
+		mailInboxStore: params.MailInboxStore,
+	}
+}
+
+// DraftTrips queues the flights of an email as drafts for the user. It
+// returns how many flights the email has and how many of them were new.
+func (d *TripDrafter) DraftTrips(userID int, r io.Reader) (int, int, error) {
+	msg, err := mailparse.ReadMessage(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	airports := importAirports(d.airportStore)
+	flights := mailparse.Flights(msg, func(code string) bool {
+		_, ok := airports(code)
+		return ok
+	})
+
+	queued := 0
+	for i, flight := range flights {
+		draft := models.TripDraft{
+			UserID: userID,
+			Trip: models.Trip{
+				Airline:      flight.Airline,
+				FlightNumber: flight.FlightNumber,
+				Departure:    flight.Departure,
+				Arrival:      flight.Arrival,
+			},
+			DepartureLocal: flight.DepartureLocal,
+			ArrivalLocal:   flight.ArrivalLocal,
+			Subject:        msg.Subject,
+			Sender:         msg.From,
+			MessageID:      msg.ID,
+			Position:       i,
+		}
+		if flight.Reservation != "" {
+			reservation := flight.Reservation
+			draft.Trip.Reservation = &reservation
+		}
+		added, err := d.tripDraftStore.QueueTripDraft(draft)
+		if err != nil {
+			return len(flights), queued, fmt.Errorf("queueing a draft: %w", err)
+		}
+		if added {
+			queued++
+		}
+	}
+	return len(flights), queued, nil
+}
+
+// Recipient accepts the forwarding addresses of users, see smtpd.Server
+func (d *TripDrafter) Recipient(address string) bool {
+	_, err := d.recipientUserID(address)
+	return err == nil
+}
+
+// Deliver drafts the flights of a message received over SMTP for every
+// recipient, see smtpd.Server
+func (d *TripDrafter) Deliver(from string, recipients []string, data []byte) error {
+	for _, recipient := range recipients {
+		userID, err := d.recipientUserID(recipient)
+		if err != nil {
+			// The address was revoked while the message was sent
+			continue
+		}
+		found, queued, err := d.DraftTrips(userID, bytes.NewReader(data))
+		if errors.Is(err, mailparse.ErrNotMessage) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("Mail from %q: %d flights found, %d new drafts", from, found, queued)
+	}
+	return nil
+}
+
+func (d *TripDrafter) recipientUserID(address string) (int, error) {
+	token, domain := smtpd.SplitAddress(address)
+	if domain == "" || !strings.EqualFold(domain, mailDomain()) {
+		return 0, db.ErrNotFound
+	}
+	return d.mailInboxStore.GetUserIDForMailToken(token)
+}
@@ -0,0 +1,120 @@
+package ical
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+var (
+	// "JFK → NRT", "JFK-NRT", "JFK to NRT", "JFK/NRT"
+	routePattern = regexp.MustCompile(`\b([A-Z]{3})\s*(?:→|->|>|–|—|-|/|to|TO)\s*([A-Z]{3})\b`)
+	// "Tokyo (NRT) to New York (JFK)"
+	parenthesizedRoutePattern = regexp.MustCompile(`\(([A-Z]{3})\)[^()]*\(([A-Z]{3})\)`)
+	// "JL 5", "JL005", "NH9"; the designator needs a letter so "123 45" is not a flight
+	flightNumberPattern = regexp.MustCompile(`\b([A-Z]{2}|[A-Z][0-9]|[0-9][A-Z])\s?([0-9]{1,4}[A-Z]?)\b`)
+	// Descriptions also hold gates and seats such as "B22", only numbers after
+	// the word flight are trusted there
+	labeledFlightNumberPattern = regexp.MustCompile(`(?i:flight)(?i:\s+(?:number|no\.?))?\s*[:#]?\s*([A-Z]{2}|[A-Z][0-9]|[0-9][A-Z])\s?([0-9]{1,4}[A-Z]?)\b`)
+	reservationPattern         = regexp.MustCompile(`(?i:confirmation|booking|reservation|record locator|pnr)(?i:\s+(?:code|number|no\.?|reference|ref\.?))?\s*[:#]?\s*([A-Z0-9]{6})\b`)
+)
+
+// AirportTimezone reports whether an IATA code is a known airport and returns
+// its timezone, which is empty when it is not known
+type AirportTimezone func(iataCode string) (string, bool)
+
+// FlightFromEvent turns a flight-like event into a trip. An event is a flight
+// when its summary, location or description has a route between two known
+// airports, checked in that order. The flight number is read from the summary,
+// or from the description when it follows the word flight. Floating times are
+// read in the timezone of the departure and arrival airport like times entered
+// in the trip form.
+func FlightFromEvent(event Event, airports AirportTimezone) (m.Trip, bool) {
+	var trip m.Trip
+	if event.Start.IsZero() || event.AllDay {
+		return trip, false
+	}
+
+	var departureTZ, arrivalTZ string
+	var found bool
+	trip.Departure, trip.Arrival, departureTZ, arrivalTZ, found = findRoute(airports, event.Summary, event.Location, event.Description)
+	if !found {
+		return trip, false
+	}
+
+	if airline, number, ok := flightNumber(flightNumberPattern, event.Summary); ok {
+		trip.Airline, trip.FlightNumber = airline, number
+	} else if airline, number, ok := flightNumber(labeledFlightNumberPattern, event.Description); ok {
+		trip.Airline, trip.FlightNumber = airline, number
+	}
+
+	if match := reservationPattern.FindStringSubmatch(event.Description); match != nil {
+		reservation := match[1]
+		trip.Reservation = &reservation
+	}
+
+	// Floating times are compared once they are in their airport's timezone, a
+	// westbound flight can land at an earlier wall clock than it took off
+	start, end := event.Start, event.End
+	if event.Floating {
+		start = inTimezone(start, departureTZ)
+		if !end.IsZero() {
+			end = inTimezone(end, arrivalTZ)
+		}
+	}
+	if end.IsZero() || end.Before(start) {
+		end = start
+	}
+	trip.DepartureTime = uint32(start.Unix())
+	trip.ArrivalTime = uint32(end.Unix())
+	if departureTZ != "" {
+		trip.DepartureTimezone = &departureTZ
+	}
+	if arrivalTZ != "" {
+		trip.ArrivalTimezone = &arrivalTZ
+	}
+
+	return trip, true
+}
+
+// findRoute returns the first pair of distinct known airports in the texts
+func findRoute(airports AirportTimezone, texts ...string) (string, string, string, string, bool) {
+	for _, text := range texts {
+		for _, pattern := range []*regexp.Regexp{routePattern, parenthesizedRoutePattern} {
+			for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+				if match[1] == match[2] {
+					continue
+				}
+				fromTZ, fromOK := airports(match[1])
+				toTZ, toOK := airports(match[2])
+				if fromOK && toOK {
+					return match[1], match[2], fromTZ, toTZ, true
+				}
+			}
+		}
+	}
+	return "", "", "", "", false
+}
+
+// flightNumber returns the designator and number of the first flight number
+// in text, leading zeros are dropped
+func flightNumber(pattern *regexp.Regexp, text string) (string, string, bool) {
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		if number := strings.TrimLeft(match[2], "0"); number != "" {
+			return match[1], number, true
+		}
+	}
+	return "", "", false
+}
+
+// inTimezone reads the wall clock of a floating time in the given timezone
+func inTimezone(floating time.Time, timezone string) time.Time {
+	loc, err := time.LoadLocation(timezone)
+	if timezone == "" || err != nil {
+		return floating
+	}
+	return time.Date(floating.Year(), floating.Month(), floating.Day(),
+		floating.Hour(), floating.Minute(), floating.Second(), 0, loc)
+}
@@ -0,0 +1,228 @@
+// Package ical reads and writes RFC 5545 iCalendar data. Flights are written
+// as events for calendar subscriptions, flight-like events are read back for
+// imports.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+const (
+	productID   = "-//trip-tracker//Flights//EN"
+	localLayout = "20060102T150405"
+	utcLayout   = "20060102T150405Z"
+	// lines longer than this many octets are folded (RFC 5545 3.1)
+	maxLineOctets = 75
+)
+
+// calendar writes CRLF terminated, folded content lines to w and keeps the
+// first write error
+type calendar struct {
+	w   io.Writer
+	err error
+}
+
+// WriteFlights writes one VEVENT per trip. Times are given in the timezone of
+// the departure and arrival airport, which are described by VTIMEZONE
+// components. Airports without a known timezone fall back to UTC.
+func WriteFlights(w io.Writer, name string, trips []m.Trip, now time.Time) error {
+	c := &calendar{w: w}
+	c.line("BEGIN:VCALENDAR")
+	c.line("VERSION:2.0")
+	c.line("PRODID:" + productID)
+	c.line("CALSCALE:GREGORIAN")
+	c.line("METHOD:PUBLISH")
+	c.property("X-WR-CALNAME", name)
+	c.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	c.line("X-PUBLISHED-TTL:PT1H")
+
+	zones := zoneSet{}
+	for _, trip := range trips {
+		zones.add(trip.DepartureTimezone, trip.DepartureTime)
+		zones.add(trip.ArrivalTimezone, trip.ArrivalTime)
+	}
+	zones.write(c)
+
+	stamp := now.UTC().Format(utcLayout)
+	for _, trip := range trips {
+		c.line("BEGIN:VEVENT")
+		c.line(fmt.Sprintf("UID:trip-%d@trip-tracker", trip.ID))
+		c.line("DTSTAMP:" + stamp)
+		c.line("DTSTART" + dateTime(trip.DepartureTimezone, trip.DepartureTime))
+		c.line("DTEND" + dateTime(trip.ArrivalTimezone, trip.ArrivalTime))
+		c.property("SUMMARY", Summary(trip))
+		c.property("LOCATION", trip.Departure)
+		c.property("DESCRIPTION", Description(trip))
+		c.line("TRANSP:OPAQUE")
+		c.line("END:VEVENT")
+	}
+
+	c.line("END:VCALENDAR")
+	return c.err
+}
+
+// Summary is the event title, e.g. "JAL 5 JFK → NRT"
+func Summary(trip m.Trip) string {
+	flight := strings.TrimSpace(trip.Airline + " " + trip.FlightNumber)
+	return strings.TrimSpace(flight + " " + trip.Departure + " → " + trip.Arrival)
+}
+
+// Description lists the flight number, terminal, gate, reservation and the
+// other optional flight details that are set
+func Description(trip m.Trip) string {
+	lines := []string{"Flight: " + strings.TrimSpace(trip.Airline+" "+trip.FlightNumber)}
+	add := func(label string, value *string) {
+		if value != nil && *value != "" {
+			lines = append(lines, label+": "+*value)
+		}
+	}
+	add("Terminal", trip.Terminal)
+	add("Gate", trip.Gate)
+	add("Reservation", trip.Reservation)
+	add("Seat", trip.Seat)
+	if trip.CabinClass != nil {
+		lines = append(lines, "Cabin: "+m.CabinClassLabel(*trip.CabinClass))
+	}
+	add("Aircraft", trip.AircraftType)
+	return strings.Join(lines, "\n")
+}
+
+// dateTime formats the parameters and value of a DTSTART or DTEND property
+func dateTime(timezone *string, unix uint32) string {
+	at := time.Unix(int64(unix), 0).UTC()
+	if loc := location(timezone); loc != nil {
+		return ";TZID=" + loc.String() + ":" + at.In(loc).Format(localLayout)
+	}
+	return ":" + at.Format(utcLayout)
+}
+
+func location(timezone *string) *time.Location {
+	if timezone == nil || *timezone == "" || *timezone == "UTC" {
+		return nil
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// zoneSet collects the UTC offset periods the events fall into, per timezone.
+// Every period becomes one observance of the zone's VTIMEZONE, which keeps the
+// output valid without having to describe the complete history of the zone.
+type zoneSet map[string]map[int64]time.Time
+
+func (z zoneSet) add(timezone *string, unix uint32) {
+	loc := location(timezone)
+	if loc == nil {
+		return
+	}
+	at := time.Unix(int64(unix), 0).In(loc)
+	start, _ := at.ZoneBounds()
+	if z[loc.String()] == nil {
+		z[loc.String()] = map[int64]time.Time{}
+	}
+	// Periods are keyed by the instant they start, which is the zero time for
+	// zones without transitions
+	z[loc.String()][start.Unix()] = at
+}
+
+func (z zoneSet) write(c *calendar) {
+	names := make([]string, 0, len(z))
+	for name := range z {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		periods := make([]int64, 0, len(z[name]))
+		for start := range z[name] {
+			periods = append(periods, start)
+		}
+		sort.Slice(periods, func(i, j int) bool { return periods[i] < periods[j] })
+
+		c.line("BEGIN:VTIMEZONE")
+		c.line("TZID:" + name)
+		for _, period := range periods {
+			at := z[name][period]
+			start, _ := at.ZoneBounds()
+			abbreviation, offset := at.Zone()
+			offsetFrom := offset
+			onset := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+			if !start.IsZero() {
+				_, offsetFrom = start.Add(-time.Second).Zone()
+				// DTSTART of an observance is the local time before the change
+				onset = start.UTC().Add(time.Duration(offsetFrom) * time.Second)
+			}
+
+			kind := "STANDARD"
+			if at.IsDST() {
+				kind = "DAYLIGHT"
+			}
+			c.line("BEGIN:" + kind)
+			c.line("DTSTART:" + onset.Format(localLayout))
+			c.line("TZOFFSETFROM:" + utcOffset(offsetFrom))
+			c.line("TZOFFSETTO:" + utcOffset(offset))
+			c.property("TZNAME", abbreviation)
+			c.line("END:" + kind)
+		}
+		c.line("END:VTIMEZONE")
+	}
+}
+
+// utcOffset formats seconds east of UTC as +hhmm, or +hhmmss when needed
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+// property writes a property with a TEXT value
+func (c *calendar) property(name, value string) {
+	c.line(name + ":" + EscapeText(value))
+}
+
+// EscapeText escapes a TEXT value (RFC 5545 3.3.11)
+func EscapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
+
+// line writes a content line, folding it after 75 octets without splitting
+// UTF-8 sequences
+func (c *calendar) line(content string) {
+	if c.err != nil {
+		return
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range content {
+		size := len(string(r))
+		if width+size > maxLineOctets {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	_, c.err = io.WriteString(c.w, b.String())
+}
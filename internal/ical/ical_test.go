@@ -0,0 +1,218 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// knownAirports stands in for the airports table
+func knownAirports(iataCode string) (string, bool) {
+	timezone, ok := map[string]string{
+		"JFK": "America/New_York",
+		"NRT": "Asia/Tokyo",
+		"HND": "Asia/Tokyo",
+		"LAX": "America/Los_Angeles",
+	}[iataCode]
+	return timezone, ok
+}
+
+func utc(value string) time.Time {
+	at, err := time.Parse("2006-01-02T15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return at
+}
+
+func parseFixture(t *testing.T) map[string]Event {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", "import.ics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	events, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byUID := make(map[string]Event)
+	for _, event := range events {
+		byUID[event.UID] = event
+	}
+	if len(byUID) != len(events) || len(events) != 8 {
+		t.Fatalf("read %d events: %+v", len(events), events)
+	}
+	return byUID
+}
+
+func TestParse(t *testing.T) {
+	events := parseFixture(t)
+
+	// Folded with a space and with a tab, the VALARM's description is not the event's
+	event := events["tzid@example.com"]
+	if event.Summary != "Flight JL 005 JFK → NRT" {
+		t.Errorf("summary %q", event.Summary)
+	}
+	if want := "Japan Airlines, Boeing 787-9\nConfirmation: K9QF2L\nGate B22 Seat 31K"; event.Description != want {
+		t.Errorf("description %q, want %q", event.Description, want)
+	}
+
+	tests := map[string]struct {
+		start, end       time.Time
+		allDay, floating bool
+	}{
+		"tzid@example.com":     {start: utc("2025-04-01T15:00"), end: utc("2025-04-02T05:00")},
+		"utc@example.com":      {start: utc("2025-04-10T08:00"), end: utc("2025-04-10T17:00")},
+		"floating@example.com": {start: utc("2025-04-12T17:00"), end: utc("2025-04-12T10:00"), floating: true},
+		// Windows timezone names are not known, the times are floating
+		"windows@example.com": {start: utc("2025-04-14T01:10"), end: utc("2025-04-15T05:25"), floating: true},
+		"allday@example.com":  {start: utc("2025-04-20T00:00"), end: utc("2025-04-21T00:00"), allDay: true, floating: true},
+		"unknown@example.com": {start: utc("2025-04-21T19:00")},
+	}
+	for uid, want := range tests {
+		event := events[uid]
+		if !event.Start.Equal(want.start) || !event.End.Equal(want.end) || event.AllDay != want.allDay || event.Floating != want.floating {
+			t.Errorf("%s: %v to %v all day %v floating %v, want %v to %v all day %v floating %v", uid,
+				event.Start, event.End, event.AllDay, event.Floating, want.start, want.end, want.allDay, want.floating)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		file string
+		err  string
+	}{
+		"empty":           {"", ErrNotCalendar.Error()},
+		"not a calendar":  {"BEGIN:VCARD\r\nEND:VCARD\r\n", ErrNotCalendar.Error()},
+		"END first":       {"BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\nEND:VEVENT\r\n", "line 3: END:VEVENT without BEGIN"},
+		"invalid DTSTART": {"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:tomorrow\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n", "line 3: DTSTART"},
+	} {
+		if _, err := Parse(strings.NewReader(test.file)); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: %v, want %q", name, err, test.err)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("")); !errors.Is(err, ErrNotCalendar) {
+		t.Errorf("an empty file is %v, want ErrNotCalendar", err)
+	}
+	events, err := Parse(strings.NewReader("\ufeffBEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:bom\nEND:VEVENT\nEND:VCALENDAR\n"))
+	if err != nil || len(events) != 1 || events[0].UID != "bom" {
+		t.Errorf("a file with a byte order mark: %+v %v", events, err)
+	}
+}
+
+func TestFlightFromEvent(t *testing.T) {
+	events := parseFixture(t)
+
+	tests := map[string]struct {
+		route        string
+		flight       string
+		reservation  string
+		departure    time.Time
+		arrival      time.Time
+		fromTimezone string
+		toTimezone   string
+	}{
+		"tzid@example.com": {route: "JFK-NRT", flight: "JL 5", reservation: "K9QF2L",
+			departure: utc("2025-04-01T15:00"), arrival: utc("2025-04-02T05:00"), fromTimezone: "America/New_York", toTimezone: "Asia/Tokyo"},
+		"utc@example.com": {route: "NRT-LAX", flight: "NH 9",
+			departure: utc("2025-04-10T08:00"), arrival: utc("2025-04-10T17:00"), fromTimezone: "Asia/Tokyo", toTimezone: "America/Los_Angeles"},
+		// Floating times are read in the airports' timezones, the arrival is
+		// at an earlier wall clock than the departure
+		"floating@example.com": {route: "NRT-LAX",
+			departure: utc("2025-04-12T08:00"), arrival: utc("2025-04-12T17:00"), fromTimezone: "Asia/Tokyo", toTimezone: "America/Los_Angeles"},
+		"windows@example.com": {route: "LAX-HND", flight: "NH 105",
+			departure: utc("2025-04-14T08:10"), arrival: utc("2025-04-14T20:25"), fromTimezone: "America/Los_Angeles", toTimezone: "Asia/Tokyo"},
+	}
+
+	for uid, event := range events {
+		trip, ok := FlightFromEvent(event, knownAirports)
+		want, isFlight := tests[uid]
+		if ok != isFlight {
+			t.Errorf("%s: flight %v, want %v: %+v", uid, ok, isFlight, trip)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if route := trip.Departure + "-" + trip.Arrival; route != want.route {
+			t.Errorf("%s: route %s, want %s", uid, route, want.route)
+		}
+		if flight := strings.TrimSpace(trip.Airline + " " + trip.FlightNumber); flight != want.flight {
+			t.Errorf("%s: flight %q, want %q", uid, flight, want.flight)
+		}
+		if reservation := stringValue(trip.Reservation); reservation != want.reservation {
+			t.Errorf("%s: reservation %q, want %q", uid, reservation, want.reservation)
+		}
+		if departure, arrival := time.Unix(int64(trip.DepartureTime), 0).UTC(), time.Unix(int64(trip.ArrivalTime), 0).UTC(); !departure.Equal(want.departure) || !arrival.Equal(want.arrival) {
+			t.Errorf("%s: %v to %v, want %v to %v", uid, departure, arrival, want.departure, want.arrival)
+		}
+		if from, to := stringValue(trip.DepartureTimezone), stringValue(trip.ArrivalTimezone); from != want.fromTimezone || to != want.toTimezone {
+			t.Errorf("%s: timezones %q and %q, want %q and %q", uid, from, to, want.fromTimezone, want.toTimezone)
+		}
+	}
+}
+
+// TestWriteFlightsRoundTrip reads a written calendar back, long lines are
+// folded on write and unfolded on read
+func TestWriteFlightsRoundTrip(t *testing.T) {
+	trip := m.Trip{
+		ID:                7,
+		Departure:         "JFK",
+		Arrival:           "NRT",
+		DepartureTime:     1743519600, // 2025-04-01 15:00 UTC
+		ArrivalTime:       1743570000,
+		Airline:           "JL",
+		FlightNumber:      "5",
+		Reservation:       ptr("K9QF2L"),
+		AircraftType:      ptr(strings.Repeat("Boeing 787-9 Dreamliner, ", 4)),
+		DepartureTimezone: ptr("America/New_York"),
+		ArrivalTimezone:   ptr("Asia/Tokyo"),
+	}
+	var calendar bytes.Buffer
+	if err := WriteFlights(&calendar, "Flights", []m.Trip{trip}, time.Unix(1743501600, 0)); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(calendar.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+
+	events, err := Parse(&calendar)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("read %+v %v", events, err)
+	}
+	if events[0].Description != Description(trip) {
+		t.Errorf("description %q, want %q", events[0].Description, Description(trip))
+	}
+	read, ok := FlightFromEvent(events[0], knownAirports)
+	if !ok {
+		t.Fatalf("the written flight is not read as one: %+v", events[0])
+	}
+	if read.Departure != trip.Departure || read.Arrival != trip.Arrival || read.Airline != trip.Airline || read.FlightNumber != trip.FlightNumber ||
+		read.DepartureTime != trip.DepartureTime || read.ArrivalTime != trip.ArrivalTime || stringValue(read.Reservation) != "K9QF2L" {
+		t.Errorf("read %+v, want %+v", read, trip)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,181 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT read by Parse. Start and End are zero when the event has
+// no DTSTART or DTEND. Floating times (no TZID and no Z suffix) are returned
+// in UTC with Floating set, the caller decides which timezone they are in.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Floating    bool
+}
+
+// ErrNotCalendar is returned when the input has no VCALENDAR
+var ErrNotCalendar = errors.New("not an iCalendar file")
+
+// Parse reads the VEVENTs of an iCalendar stream. Properties other than the
+// ones on Event are ignored, recurring events are returned once.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, ErrNotCalendar
+	}
+
+	var events []Event
+	var event *Event
+	var depth []string
+	for i, line := range lines {
+		name, params, value, ok := splitContentLine(line)
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "BEGIN":
+			component := strings.ToUpper(value)
+			depth = append(depth, component)
+			if component == "VEVENT" && event == nil {
+				event = &Event{}
+			}
+			continue
+		case "END":
+			if len(depth) == 0 {
+				return nil, fmt.Errorf("line %d: END:%s without BEGIN", i+1, value)
+			}
+			component := depth[len(depth)-1]
+			depth = depth[:len(depth)-1]
+			if component == "VEVENT" && event != nil {
+				events = append(events, *event)
+				event = nil
+			}
+			continue
+		}
+
+		// Properties of VALARMs and other sub-components are skipped
+		if event == nil || depth[len(depth)-1] != "VEVENT" {
+			continue
+		}
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(value)
+		case "LOCATION":
+			event.Location = unescapeText(value)
+		case "DTSTART", "DTEND":
+			at, allDay, floating, err := parseDateTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", i+1, name, err)
+			}
+			if name == "DTSTART" {
+				event.Start, event.AllDay, event.Floating = at, allDay, floating
+			} else {
+				event.End = at
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// unfold joins folded lines, a line starting with a space or tab continues the
+// previous one
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	// A UTF-8 byte order mark would hide the first BEGIN
+	if len(lines) > 0 {
+		lines[0] = strings.TrimPrefix(lines[0], "\ufeff")
+	}
+	return lines, scanner.Err()
+}
+
+// splitContentLine splits `NAME;PARAM=x;PARAM2="y":value`. Colons inside quoted
+// parameter values do not end the parameters.
+func splitContentLine(line string) (string, map[string]string, string, bool) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+func unescapeText(value string) string {
+	return strings.NewReplacer(
+		`\n`, "\n",
+		`\N`, "\n",
+		`\,`, ",",
+		`\;`, ";",
+		`\\`, `\`,
+	).Replace(value)
+}
+
+// parseDateTime reads a DATE or DATE-TIME value. TZIDs are looked up in the
+// IANA database, Windows names such as "Tokyo Standard Time" are not known and
+// are treated as floating times.
+func parseDateTime(params map[string]string, value string) (time.Time, bool, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		at, err := time.Parse("20060102", value)
+		return at, true, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		at, err := time.Parse(utcLayout, value)
+		return at, false, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if loc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			at, err := time.ParseInLocation(localLayout, value, loc)
+			return at.UTC(), false, false, err
+		}
+	}
+	at, err := time.Parse(localLayout, value)
+	return at, false, true, err
+}
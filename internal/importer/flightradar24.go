@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+func init() {
+	Register(flightradar24{})
+}
+
+// flightradar24 reads the CSV export of my.flightradar24.com ("Settings >
+// Export"):
+//
+//	Date,Flight number,From,To,Dep time,Arr time,Duration,Airline,Aircraft,Registration,Seat number,Seat type,Flight class,Flight reason,Note,Dep_id,Arr_id,Airline_id,Aircraft_id
+//
+// Airports look like "Tokyo / Narita (NRT/RJAA)", airlines like "Japan
+// Airlines (JL/JAL)" and aircraft like "Boeing 787-9 (B789)". Dep time and Arr
+// time are local clock times, the arrival date is not part of the export.
+type flightradar24 struct{}
+
+var (
+	// "(NRT/RJAA)", "(/RJAA)" or "(NRT/)" at the end of an airport
+	flightradar24AirportPattern = regexp.MustCompile(`\(([A-Z0-9]{3})?/([A-Z0-9]{4})?\)\s*$`)
+	// The codes after an airline or aircraft name
+	flightradar24CodesPattern = regexp.MustCompile(`\s*\([A-Z0-9/]*\)\s*$`)
+)
+
+// flightradar24Classes maps the Flight class column, which is a number in
+// exports and a name in older ones
+var flightradar24Classes = map[string]string{
+	"1":               m.CabinEconomy,
+	"2":               m.CabinBusiness,
+	"3":               m.CabinFirst,
+	"4":               m.CabinPremiumEconomy,
+	"economy":         m.CabinEconomy,
+	"business":        m.CabinBusiness,
+	"first":           m.CabinFirst,
+	"economy+":        m.CabinPremiumEconomy,
+	"premium economy": m.CabinPremiumEconomy,
+}
+
+func (flightradar24) Name() string  { return "flightradar24" }
+func (flightradar24) Label() string { return "myFlightradar24" }
+
+func (flightradar24) Parse(r io.Reader, airports Airports) ([]Row, error) {
+	file, err := newCSVFile(r, "date", "flight number", "from", "to", "dep time", "arr time", "flight class")
+	if err != nil {
+		return nil, err
+	}
+
+	return file.readRows(func(get func(string) string, row Row) Row {
+		row.Trip = m.Trip{
+			Departure:    flightradar24Airport(get("from")),
+			Arrival:      flightradar24Airport(get("to")),
+			Airline:      flightradar24CodesPattern.ReplaceAllString(get("airline"), ""),
+			FlightNumber: get("flight number"),
+			Seat:         optional(get("seat number")),
+			AircraftType: optional(flightradar24CodesPattern.ReplaceAllString(get("aircraft"), "")),
+			TailNumber:   optional(strings.ToUpper(get("registration"))),
+		}
+		if class, ok := flightradar24Classes[strings.ToLower(get("flight class"))]; ok {
+			row.Trip.CabinClass = &class
+		}
+
+		return resolveRow(row, schedule{
+			date:      get("date"),
+			departure: get("dep time"),
+			arrival:   get("arr time"),
+			duration:  parseDuration(get("duration")),
+		}, airports)
+	})
+}
+
+// flightradar24Airport returns the IATA code of an airport, or the ICAO code
+// for airports without one. Values that are only a code are returned as is.
+func flightradar24Airport(value string) string {
+	match := flightradar24AirportPattern.FindStringSubmatch(value)
+	if match == nil {
+		return strings.ToUpper(value)
+	}
+	if match[1] != "" {
+		return match[1]
+	}
+	return match[2]
+}
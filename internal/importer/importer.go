@@ -0,0 +1,320 @@
+// Package importer reads flight logs exported by other flight tracking
+// services. Every format is a Parser, registered by name so forms and the
+// review queue can refer to it. Parsers resolve airports through the airports
+// table and read local times like the trip form does; rows that can not be
+// resolved are returned with a Problem so they can be reviewed by hand.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Parser reads one file format
+type Parser interface {
+	// Name identifies the format in forms and the review queue, e.g. "openflights"
+	Name() string
+	// Label is shown to users, e.g. "OpenFlights"
+	Label() string
+	// Parse reads every row of the file. An error is only returned when the
+	// file itself is unusable.
+	Parse(r io.Reader, airports Airports) ([]Row, error)
+}
+
+// Airports looks up a 3 letter IATA or 4 letter ICAO code
+type Airports func(code string) (m.Airport, bool)
+
+// Row is a flight read from a log. Trip is ready to be saved when Problem is
+// empty. Otherwise Trip holds what could be read, with the airport codes as
+// they appeared in the file, and the row belongs in the review queue.
+type Row struct {
+	Line           int
+	Trip           m.Trip
+	Problem        string
+	DepartureLocal string // Wall clock at the departure airport, models.LocalTimeLayout
+	ArrivalLocal   string
+	Duration       time.Duration
+	Source         string // The row as it appeared in the file
+}
+
+// Review turns a row with a problem into an entry of the review queue
+func (r Row) Review(format string, userID int) m.ImportReview {
+	return m.ImportReview{
+		UserID:          userID,
+		Format:          format,
+		Line:            r.Line,
+		Problem:         r.Problem,
+		Trip:            r.Trip,
+		DepartureLocal:  r.DepartureLocal,
+		ArrivalLocal:    r.ArrivalLocal,
+		DurationMinutes: int(r.Duration.Minutes()),
+		Source:          r.Source,
+	}
+}
+
+var parsers = map[string]Parser{}
+
+// Register makes a parser available by its name, it panics when the name is
+// taken
+func Register(parser Parser) {
+	if _, ok := parsers[parser.Name()]; ok {
+		panic("importer: parser registered twice: " + parser.Name())
+	}
+	parsers[parser.Name()] = parser
+}
+
+// Lookup returns the parser registered under name
+func Lookup(name string) (Parser, bool) {
+	parser, ok := parsers[name]
+	return parser, ok
+}
+
+// Parsers returns every registered parser ordered by label
+func Parsers() []Parser {
+	list := make([]Parser, 0, len(parsers))
+	for _, parser := range parsers {
+		list = append(list, parser)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Label() < list[j].Label()
+	})
+	return list
+}
+
+// Resolve looks up both airports and converts the wall clock times to UTC. The
+// arrival is the departure plus duration when arrivalLocal is empty.
+func Resolve(trip m.Trip, departureLocal, arrivalLocal string, duration time.Duration, airports Airports) (m.Trip, error) {
+	departure, err := lookupAirport(trip.Departure, airports)
+	if err != nil {
+		return trip, err
+	}
+	arrival, err := lookupAirport(trip.Arrival, airports)
+	if err != nil {
+		return trip, err
+	}
+	trip.Departure, trip.Arrival = departure.IataCode, arrival.IataCode
+
+	departureWallClock, err := time.Parse(m.LocalTimeLayout, departureLocal)
+	if err != nil {
+		return trip, errors.New("no departure time")
+	}
+	departureTime := m.AirportLocalToUTC(departureWallClock, departure.IataCode, departure.Timezone)
+
+	var arrivalTime time.Time
+	switch {
+	case arrivalLocal != "":
+		arrivalWallClock, err := time.Parse(m.LocalTimeLayout, arrivalLocal)
+		if err != nil {
+			return trip, fmt.Errorf("invalid arrival time %q", arrivalLocal)
+		}
+		arrivalTime = m.AirportLocalToUTC(arrivalWallClock, arrival.IataCode, arrival.Timezone)
+	case duration > 0:
+		arrivalTime = departureTime.Add(duration)
+	default:
+		return trip, errors.New("no arrival time or duration")
+	}
+	if arrivalTime.Before(departureTime) {
+		return trip, errors.New("arrival is before departure")
+	}
+
+	trip.DepartureTime = uint32(departureTime.Unix())
+	trip.ArrivalTime = uint32(arrivalTime.Unix())
+	if departure.Timezone != "" {
+		trip.DepartureTimezone = &departure.Timezone
+	}
+	if arrival.Timezone != "" {
+		trip.ArrivalTimezone = &arrival.Timezone
+	}
+	return trip, nil
+}
+
+// lookupAirport resolves a code to an airport trips can refer to, trips store
+// IATA codes so airports that only have an ICAO code can not be used
+func lookupAirport(code string, airports Airports) (m.Airport, error) {
+	if code == "" {
+		return m.Airport{}, errors.New("missing airport")
+	}
+	airport, ok := airports(code)
+	if !ok {
+		return airport, fmt.Errorf("unknown airport %q", code)
+	}
+	if airport.IataCode == "" {
+		return airport, fmt.Errorf("airport %q has no IATA code", code)
+	}
+	return airport, nil
+}
+
+// schedule holds the times of a row as most flight logs keep them: the local
+// departure date, the local departure and arrival clocks and the block time
+type schedule struct {
+	date      string // 2006-01-02
+	departure string // 15:04 or 15:04:05, empty when unknown
+	arrival   string
+	duration  time.Duration
+}
+
+// resolveRow fills in the trip of a row or the reason it needs review. The
+// arrival clock is read on the day, out of the day before to two days after
+// the departure date, that gives the first arrival after the departure, so
+// overnight flights and flights across the date line land on the right day.
+func resolveRow(row Row, times schedule, airports Airports) Row {
+	date, err := time.Parse("2006-01-02", times.date)
+	if err != nil {
+		row.Problem = fmt.Sprintf("invalid date %q", times.date)
+		return row
+	}
+	departureClock, departureOK := parseClock(times.departure)
+	arrivalClock, arrivalOK := parseClock(times.arrival)
+	row.Duration = times.duration
+	row.DepartureLocal = date.Add(departureClock).Format(m.LocalTimeLayout)
+	if arrivalOK {
+		row.ArrivalLocal = date.Add(arrivalClock).Format(m.LocalTimeLayout)
+	}
+	if !departureOK {
+		row.Problem = "no departure time"
+		return row
+	}
+
+	departure, departureKnown := airports(row.Trip.Departure)
+	arrival, arrivalKnown := airports(row.Trip.Arrival)
+	if arrivalOK && departureKnown && arrivalKnown {
+		departureTime := m.AirportLocalToUTC(date.Add(departureClock), departure.IataCode, departure.Timezone)
+		for days := -1; days <= 2; days++ {
+			wallClock := date.AddDate(0, 0, days).Add(arrivalClock)
+			if !m.AirportLocalToUTC(wallClock, arrival.IataCode, arrival.Timezone).Before(departureTime) {
+				row.ArrivalLocal = wallClock.Format(m.LocalTimeLayout)
+				break
+			}
+		}
+	}
+
+	trip, err := Resolve(row.Trip, row.DepartureLocal, row.ArrivalLocal, row.Duration, airports)
+	if err != nil {
+		row.Problem = err.Error()
+		return row
+	}
+	row.Trip = trip
+	return row
+}
+
+// parseClock reads "15:04" or "15:04:05" as the time since midnight
+func parseClock(value string) (time.Duration, bool) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)), true
+		}
+	}
+	return 0, false
+}
+
+// parseDuration reads "13:40" or "13:40:00" as hours and minutes, hours may go
+// past 24
+func parseDuration(value string) time.Duration {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil || hours < 0 || minutes < 0 {
+		return 0
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+}
+
+// csvFile reads a CSV export with a header row
+type csvFile struct {
+	reader *csv.Reader
+	index  map[string]int
+}
+
+// newCSVFile reads the header. Column names are matched case insensitively
+// with underscores read as spaces, so "Flight_Number" and "Flight number" are
+// the same column.
+func newCSVFile(r io.Reader, required ...string) (*csvFile, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	file := &csvFile{reader: reader, index: make(map[string]int)}
+	for i, column := range header {
+		name := strings.TrimPrefix(column, "\ufeff")
+		name = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(name, "_", " ")))
+		if _, ok := file.index[name]; !ok {
+			file.index[name] = i
+		}
+	}
+	for _, column := range required {
+		if _, ok := file.index[column]; !ok {
+			return nil, fmt.Errorf("missing column %q, is this the right format?", column)
+		}
+	}
+	return file, nil
+}
+
+// next returns a getter for the values of the next row, the line it starts on
+// and the row as it appeared in the file
+func (f *csvFile) next() (func(string) string, int, string, error) {
+	record, err := f.reader.Read()
+	if err != nil {
+		return nil, 0, "", err
+	}
+	line, _ := f.reader.FieldPos(0)
+
+	var source strings.Builder
+	writer := csv.NewWriter(&source)
+	writer.Write(record)
+	writer.Flush()
+
+	get := func(column string) string {
+		if i, ok := f.index[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	return get, line, strings.TrimSuffix(source.String(), "\n"), nil
+}
+
+// readRows calls read for every row of the file. Rows the CSV reader rejects
+// are returned with a problem like any other unresolvable row.
+func (f *csvFile) readRows(read func(get func(string) string, row Row) Row) ([]Row, error) {
+	var rows []Row
+	for {
+		get, line, source, err := f.next()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The row can not be told apart from others by its content, the
+			// line keeps it from being queued twice
+			rows = append(rows, Row{
+				Line:    parseErr.StartLine,
+				Problem: parseErr.Err.Error(),
+				Source:  fmt.Sprintf("line %d: %v", parseErr.StartLine, parseErr.Err),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, read(get, Row{Line: line, Source: source}))
+	}
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
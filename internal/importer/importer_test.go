@@ -0,0 +1,228 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// testAirports stands in for the airports table. XYZ has no timezone, its
+// times are read as UTC, and XXXX only has an ICAO code.
+var testAirports = map[string]m.Airport{
+	"JFK":  {IataCode: "JFK", IcaoCode: "KJFK", Timezone: "America/New_York"},
+	"NRT":  {IataCode: "NRT", IcaoCode: "RJAA", Timezone: "Asia/Tokyo"},
+	"HND":  {IataCode: "HND", IcaoCode: "RJTT", Timezone: "Asia/Tokyo"},
+	"LAX":  {IataCode: "LAX", IcaoCode: "KLAX", Timezone: "America/Los_Angeles"},
+	"HNL":  {IataCode: "HNL", IcaoCode: "PHNL", Timezone: "Pacific/Honolulu"},
+	"XYZ":  {IataCode: "XYZ"},
+	"XXXX": {IcaoCode: "XXXX"},
+}
+
+func lookupTestAirport(code string) (m.Airport, bool) {
+	if airport, ok := testAirports[code]; ok {
+		return airport, true
+	}
+	for _, airport := range testAirports {
+		if airport.IcaoCode == code {
+			return airport, true
+		}
+	}
+	return m.Airport{}, false
+}
+
+// want is what a row of a fixture must resolve to. Times are UTC, a row with a
+// problem only checks the problem and the local times that were read.
+type want struct {
+	route             string
+	departure         string
+	arrival           string
+	departureTimezone string
+	arrivalTimezone   string
+	arrivalLocal      string
+	cabin             string
+	problem           string
+}
+
+func utc(seconds uint32) string {
+	return time.Unix(int64(seconds), 0).UTC().Format("2006-01-02T15:04")
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func parseFixture(t *testing.T, format, fixture string) []Row {
+	t.Helper()
+	parser, ok := Lookup(format)
+	if !ok {
+		t.Fatalf("no parser named %q", format)
+	}
+	file, err := os.Open(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := parser.Parse(file, lookupTestAirport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestParseFixtures(t *testing.T) {
+	cases := map[string]struct {
+		format string
+		rows   map[int]want
+	}{
+		"openflights.csv": {
+			format: "openflights",
+			rows: map[int]want{
+				2:  {route: "JFK-NRT", departure: "2025-04-01T15:00", arrival: "2025-04-02T05:00", departureTimezone: "America/New_York", arrivalTimezone: "Asia/Tokyo", cabin: m.CabinBusiness},
+				3:  {problem: "no departure time"},
+				4:  {route: "NRT-HND", departure: "2025-04-06T00:00", arrival: "2025-04-06T01:10", departureTimezone: "Asia/Tokyo", arrivalTimezone: "Asia/Tokyo", cabin: m.CabinEconomy},
+				5:  {problem: `unknown airport "QQQ"`},
+				6:  {problem: `airport "XXXX" has no IATA code`},
+				7:  {problem: "no arrival time or duration"},
+				8:  {problem: `invalid date "04/10/2025"`},
+				9:  {route: "XYZ-HND", departure: "2025-04-11T09:00", arrival: "2025-04-11T11:30", arrivalTimezone: "Asia/Tokyo", cabin: m.CabinFirst},
+				10: {problem: "missing airport"},
+			},
+		},
+		"flightradar24.csv": {
+			format: "flightradar24",
+			rows: map[int]want{
+				2: {route: "JFK-NRT", departure: "2025-04-01T15:00", arrival: "2025-04-02T05:00", departureTimezone: "America/New_York", arrivalTimezone: "Asia/Tokyo", arrivalLocal: "2025-04-02T14:00", cabin: m.CabinBusiness},
+				// Across the date line, the arrival clock is earlier on the same day
+				3: {route: "NRT-LAX", departure: "2025-04-10T08:00", arrival: "2025-04-10T17:00", departureTimezone: "Asia/Tokyo", arrivalTimezone: "America/Los_Angeles", arrivalLocal: "2025-04-10T10:00", cabin: m.CabinEconomy},
+				// Lands the day before it took off
+				4: {route: "NRT-HNL", departure: "2025-04-19T15:30", arrival: "2025-04-19T22:30", departureTimezone: "Asia/Tokyo", arrivalTimezone: "Pacific/Honolulu", arrivalLocal: "2025-04-19T12:30", cabin: m.CabinPremiumEconomy},
+				5: {problem: `airport "XXXX" has no IATA code`},
+				6: {problem: `unknown airport "QQQ"`},
+				7: {problem: "no departure time"},
+				// No arrival clock, the duration is used
+				8: {route: "NRT-HND", departure: "2025-04-24T00:00", arrival: "2025-04-24T01:15", departureTimezone: "Asia/Tokyo", arrivalTimezone: "Asia/Tokyo", cabin: m.CabinFirst},
+			},
+		},
+	}
+
+	for fixture, test := range cases {
+		t.Run(fixture, func(t *testing.T) {
+			rows := parseFixture(t, test.format, fixture)
+			if len(rows) != len(test.rows) {
+				t.Errorf("read %d rows, want %d", len(rows), len(test.rows))
+			}
+			for _, row := range rows {
+				want, ok := test.rows[row.Line]
+				if !ok {
+					t.Errorf("unexpected row on line %d: %+v", row.Line, row)
+					continue
+				}
+				if row.Problem != want.problem {
+					t.Errorf("line %d: problem %q, want %q", row.Line, row.Problem, want.problem)
+					continue
+				}
+				if want.problem != "" {
+					continue
+				}
+
+				trip := row.Trip
+				if route := trip.Departure + "-" + trip.Arrival; route != want.route {
+					t.Errorf("line %d: route %s, want %s", row.Line, route, want.route)
+				}
+				if utc(trip.DepartureTime) != want.departure || utc(trip.ArrivalTime) != want.arrival {
+					t.Errorf("line %d: %s to %s, want %s to %s", row.Line, utc(trip.DepartureTime), utc(trip.ArrivalTime), want.departure, want.arrival)
+				}
+				if deref(trip.DepartureTimezone) != want.departureTimezone || deref(trip.ArrivalTimezone) != want.arrivalTimezone {
+					t.Errorf("line %d: timezones %q and %q, want %q and %q", row.Line,
+						deref(trip.DepartureTimezone), deref(trip.ArrivalTimezone), want.departureTimezone, want.arrivalTimezone)
+				}
+				if row.ArrivalLocal != want.arrivalLocal {
+					t.Errorf("line %d: arrival local %q, want %q", row.Line, row.ArrivalLocal, want.arrivalLocal)
+				}
+				if deref(trip.CabinClass) != want.cabin {
+					t.Errorf("line %d: cabin %q, want %q", row.Line, deref(trip.CabinClass), want.cabin)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFixtureDetails(t *testing.T) {
+	openFlights := parseFixture(t, "openflights", "openflights.csv")
+	first := openFlights[0].Trip
+	if first.Airline != "Japan Airlines" || first.FlightNumber != "JL5" || deref(first.Seat) != "31K" ||
+		deref(first.AircraftType) != "Boeing 787-9" || deref(first.TailNumber) != "JA861J" {
+		t.Errorf("openflights trip %+v", first)
+	}
+	if row := openFlights[1]; row.DepartureLocal != "2025-04-05T00:00" || row.Trip.Departure != "NRT" || row.Duration != time.Hour {
+		t.Errorf("the row without a departure time lost what could be read: %+v", row)
+	}
+	if !strings.HasPrefix(openFlights[0].Source, "2025-04-01 11:00:00,jfk,nrt,JL5,") {
+		t.Errorf("source %q", openFlights[0].Source)
+	}
+
+	flightradar := parseFixture(t, "flightradar24", "flightradar24.csv")
+	first = flightradar[0].Trip
+	if first.Airline != "Japan Airlines" || deref(first.AircraftType) != "Boeing 787-9" || deref(first.TailNumber) != "JA861J" {
+		t.Errorf("flightradar24 trip %+v", first)
+	}
+	// Airports the row could not resolve keep the code of the file
+	if trip := flightradar[4].Trip; trip.Departure != "HNL" || trip.Arrival != "QQQ" {
+		t.Errorf("unresolved route %s-%s, want HNL-QQQ", trip.Departure, trip.Arrival)
+	}
+}
+
+func TestParseUnusableFiles(t *testing.T) {
+	for name, test := range map[string]struct {
+		format string
+		file   string
+	}{
+		"empty openflights":              {"openflights", ""},
+		"openflights without duration":   {"openflights", "Date,From,To,Flight_Number,Class,Plane\n2025-04-01 11:00,JFK,NRT,JL5,Y,\n"},
+		"flightradar24 in openflights":   {"openflights", "Date,Flight number,From,To,Dep time,Arr time,Flight class\n"},
+		"empty flightradar24":            {"flightradar24", ""},
+		"flightradar24 without arr time": {"flightradar24", "Date,Flight number,From,To,Dep time,Flight class\n"},
+		"openflights in flightradar24":   {"flightradar24", "Date,From,To,Flight_Number,Duration,Class,Plane\n"},
+	} {
+		parser, _ := Lookup(test.format)
+		if rows, err := parser.Parse(strings.NewReader(test.file), lookupTestAirport); err == nil {
+			t.Errorf("%s: no error, read %+v", name, rows)
+		}
+	}
+}
+
+func TestParseHeaderVariants(t *testing.T) {
+	// A byte order mark, other case and underscores for spaces
+	file := "\ufeffDATE,flight_number,FROM,to,Dep_Time,ARR TIME,Flight_Class\n" +
+		"2025-04-24,ZZ4,NRT,HND,09:00,10:15,3\n"
+	parser, _ := Lookup("flightradar24")
+	rows, err := parser.Parse(strings.NewReader(file), lookupTestAirport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Problem != "" || rows[0].Trip.FlightNumber != "ZZ4" || utc(rows[0].Trip.ArrivalTime) != "2025-04-24T01:15" {
+		t.Errorf("rows %+v", rows)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"13:40":    13*time.Hour + 40*time.Minute,
+		"25:05:00": 25*time.Hour + 5*time.Minute,
+		"":         0,
+		"soon":     0,
+		"-1:00":    0,
+	} {
+		if got := parseDuration(value); got != want {
+			t.Errorf("parseDuration(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"io"
+	"strings"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+func init() {
+	Register(openFlights{})
+}
+
+// openFlights reads the CSV export of openflights.org ("Export > CSV"):
+//
+//	Date,From,To,Flight_Number,Airline,Distance,Duration,Seat,Seat_Type,Class,Reason,Plane,Registration,Trip,Note,From_OID,To_OID,Airline_OID,Plane_OID
+//
+// Date is the local departure time, often without a clock time. Airports are
+// IATA or ICAO codes. There is no arrival time, the arrival is the departure
+// plus Duration.
+type openFlights struct{}
+
+// openFlightsClasses maps the Class column, "Y" is economy
+var openFlightsClasses = map[string]string{
+	"Y": m.CabinEconomy,
+	"P": m.CabinPremiumEconomy,
+	"C": m.CabinBusiness,
+	"F": m.CabinFirst,
+}
+
+func (openFlights) Name() string  { return "openflights" }
+func (openFlights) Label() string { return "OpenFlights" }
+
+func (openFlights) Parse(r io.Reader, airports Airports) ([]Row, error) {
+	file, err := newCSVFile(r, "date", "from", "to", "flight number", "duration", "class", "plane")
+	if err != nil {
+		return nil, err
+	}
+
+	return file.readRows(func(get func(string) string, row Row) Row {
+		row.Trip = m.Trip{
+			Departure:    strings.ToUpper(get("from")),
+			Arrival:      strings.ToUpper(get("to")),
+			Airline:      get("airline"),
+			FlightNumber: get("flight number"),
+			Seat:         optional(get("seat")),
+			AircraftType: optional(get("plane")),
+			TailNumber:   optional(strings.ToUpper(get("registration"))),
+		}
+		if class, ok := openFlightsClasses[strings.ToUpper(get("class"))]; ok {
+			row.Trip.CabinClass = &class
+		}
+
+		// "2015-02-22 14:35:00", "2015-02-22 14:35" or "2015-02-22"
+		date, clock, _ := strings.Cut(get("date"), " ")
+		return resolveRow(row, schedule{
+			date:      date,
+			departure: strings.TrimSpace(clock),
+			duration:  parseDuration(get("duration")),
+		}, airports)
+	})
+}
@@ -0,0 +1,453 @@
+package mailparse
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// KnownAirport reports whether an IATA code is an airport. Three capital
+// letters are common in emails, only known airports are read as one.
+type KnownAirport func(iataCode string) bool
+
+// Flight is a flight found in an email. Times are the local times of the
+// departure and arrival airport in models.LocalTimeLayout, ArrivalLocal is
+// empty when the email does not give it.
+type Flight struct {
+	Airline        string // The designator, e.g. "UA"
+	FlightNumber   string // Without leading zeros
+	Departure      string
+	Arrival        string
+	DepartureLocal string
+	ArrivalLocal   string
+	Reservation    string
+}
+
+var (
+	months = `(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?`
+	// "Apr 2, 2025", "Wed, April 2nd 2025"
+	monthFirstDate = regexp.MustCompile(`(?i)\b` + months + `\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	// "2 April 2025", "Tuesday 15 Apr, 2025"
+	dayFirstDate = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+` + months + `,?\s+(\d{4})\b`)
+	isoDate      = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	// "15.04.2025", day first as written across Europe
+	dottedDate = regexp.MustCompile(`\b(\d{1,2})\.(\d{1,2})\.(\d{4})\b`)
+	// "15APR" or "15APR25" in itineraries printed by a reservation system
+	gdsDate = regexp.MustCompile(`\b(\d{2})(JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)(\d{2})?\b`)
+
+	// "11:05", "2:40 PM", "23:55 +1", "6:10 a.m. (+1 day)"
+	clockTime = regexp.MustCompile(`(?i)(?:^|[^\d:.])(\d{1,2}):(\d{2})(?:\s*([ap])\.?m\b\.?)?(?:\s*\(?\+\s*(\d)(?:\s*days?)?\)?)?`)
+	// "1100", "0645+1" on itinerary lines printed by a reservation system
+	gdsTime = regexp.MustCompile(`\b([01]\d|2[0-3])([0-5]\d)(?:\+(\d))?\b`)
+
+	// "UA 837", "LH2034", "SQ  12" padded in a reservation system itinerary
+	flightNumberPattern = regexp.MustCompile(`\b([A-Z]{2}|[A-Z][0-9]|[0-9][A-Z]) {0,2}([0-9]{1,4})\b`)
+	// "Tokyo (NRT)"
+	parenthesizedAirport = regexp.MustCompile(`\(([A-Z]{3})\)`)
+	bareAirport          = regexp.MustCompile(`\b([A-Z]{3})\b`)
+	// "JFKNRT", the city pair of a reservation system itinerary line
+	gdsCityPair = regexp.MustCompile(`\b([A-Z]{3})([A-Z]{3})\b`)
+
+	reservationPattern = regexp.MustCompile(`(?i:confirmation|booking|reservation|record locator|pnr)(?i:\s+(?:code|number|no\.?|reference|ref\.?))?\s*[:#]?\s*([A-Z0-9]{6})\b`)
+
+	// Two letter words that look like a designator before a number
+	notDesignators = map[string]bool{
+		"AM": true, "PM": true, "AT": true, "BY": true, "IN": true, "NO": true,
+		"OF": true, "ON": true, "OR": true, "TO": true, "UP": true,
+		// Booking status codes
+		"HK": true, "HL": true, "KK": true, "SS": true, "TK": true, "UC": true, "UN": true,
+	}
+
+	// Forwarding adds the headers of the original message to the body, their
+	// dates are not flight dates. From and To are also labels of itineraries,
+	// they are only headers when they hold an address.
+	forwardedHeader = regexp.MustCompile(`^(?:Sent|Date|Subject):|^(?:From|To|Cc):.*[@<]`)
+)
+
+// label is what a word before a token says about it
+type label int
+
+const (
+	unlabeled label = iota
+	departure
+	arrival
+	ignored // Boarding, check-in, gates, seats and durations are not the flight's
+)
+
+// labelWords are the words that label the tokens after them on a line, they
+// match at the start of a word so "Auckland" is not a landing
+var labelWords = regexp.MustCompile(`(?i)\b(depart|dep:|leav|take ?off|arriv|arr:|land|board|check[- ]in|gate|seat|duration|flight time|travel time|layover|connection|stopover|total)`)
+
+func wordLabel(word string) label {
+	switch word = strings.ToLower(word); {
+	case strings.HasPrefix(word, "dep"), strings.HasPrefix(word, "leav"), strings.HasPrefix(word, "take"):
+		return departure
+	case strings.HasPrefix(word, "arr"), word == "land":
+		return arrival
+	}
+	return ignored
+}
+
+type tokenKind int
+
+const (
+	flightToken tokenKind = iota
+	airportToken
+	dateToken
+	timeToken
+)
+
+type token struct {
+	kind  tokenKind
+	start int
+	end   int
+	label label
+
+	designator, number string        // flightToken
+	airport            string        // airportToken
+	date               time.Time     // dateToken
+	clock              time.Duration // timeToken
+	days               int           // timeToken, "+1"
+}
+
+// Flights finds the flights in a message. A flight needs a departure and an
+// arrival airport and the departure date and time. The text is read line by
+// line: dates set the day for the times that follow them, and the words
+// before a time or airport such as "Depart" or "Arrives" say which end of the
+// flight it belongs to. Without them the first airport and time are the
+// departure and the second ones the arrival. A flight ends when a value of
+// the next one is found, or a new flight number.
+func Flights(msg Message, known KnownAirport) []Flight {
+	reference := msg.Date
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+	reservation := ""
+	if match := reservationPattern.FindStringSubmatch(msg.Text); match != nil {
+		reservation = match[1]
+	}
+
+	p := flightParser{known: known, reference: reference, reservation: reservation}
+	for _, line := range strings.Split(msg.Text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "> "))
+		if line == "" || forwardedHeader.MatchString(line) {
+			continue
+		}
+		p.line(line)
+	}
+	p.finish()
+
+	// Confirmations often repeat the itinerary in a summary
+	var flights []Flight
+	seen := make(map[Flight]bool)
+	for _, flight := range p.flights {
+		if !seen[flight] {
+			seen[flight] = true
+			flights = append(flights, flight)
+		}
+	}
+	return flights
+}
+
+type flightParser struct {
+	known       KnownAirport
+	reference   time.Time
+	reservation string
+	flights     []Flight
+
+	date    time.Time // The last date read
+	current draft
+}
+
+// draft is the flight being read
+type draft struct {
+	designator, number string
+	departure, arrival string
+	departureTime      time.Time
+	arrivalTime        time.Time
+	arrivalRolls       bool // The arrival has no date of its own and may be the next day
+}
+
+func (p *flightParser) line(line string) {
+	tokens := p.tokens(line)
+	flightNumbers := 0
+	dated := false // A date was read on this line since the last time
+
+	for _, t := range tokens {
+		switch t.kind {
+		case dateToken:
+			p.date = t.date
+			dated = true
+		case flightToken:
+			// Only the first flight number of a line, others are seats or codes
+			flightNumbers++
+			if flightNumbers > 1 || t.label == ignored {
+				continue
+			}
+			if p.current.number != "" {
+				p.finish()
+			}
+			p.current.designator, p.current.number = t.designator, t.number
+		case airportToken:
+			p.airport(t)
+		case timeToken:
+			if t.label == ignored || p.date.IsZero() {
+				continue
+			}
+			at := p.date.Add(t.clock).AddDate(0, 0, t.days)
+			p.time(t.label, at, !dated && t.days == 0)
+			dated = false
+		}
+	}
+}
+
+func (p *flightParser) airport(t token) {
+	switch {
+	case t.label == ignored:
+		// "Check-in at London Heathrow (LHR)"
+	case t.label == departure || (t.label == unlabeled && p.current.departure == ""):
+		if p.current.departure != "" {
+			p.finish()
+		}
+		p.current.departure = t.airport
+	case t.label == arrival || p.current.arrival == "":
+		if p.current.arrival != "" {
+			p.finish()
+		}
+		p.current.arrival = t.airport
+	default:
+		p.finish()
+		p.current.departure = t.airport
+	}
+}
+
+func (p *flightParser) time(l label, at time.Time, rolls bool) {
+	switch {
+	case l == departure || (l == unlabeled && p.current.departureTime.IsZero()):
+		if !p.current.departureTime.IsZero() {
+			p.finish()
+		}
+		p.current.departureTime = at
+	case l == arrival || p.current.arrivalTime.IsZero():
+		if !p.current.arrivalTime.IsZero() {
+			p.finish()
+		}
+		p.current.arrivalTime, p.current.arrivalRolls = at, rolls
+	default:
+		p.finish()
+		p.current.departureTime = at
+	}
+}
+
+// finish keeps the flight being read if it is complete and starts the next
+func (p *flightParser) finish() {
+	d := p.current
+	p.current = draft{}
+	if d.departure == "" || d.arrival == "" || d.departure == d.arrival || d.departureTime.IsZero() {
+		return
+	}
+
+	flight := Flight{
+		Airline:        d.designator,
+		FlightNumber:   strings.TrimLeft(d.number, "0"),
+		Departure:      d.departure,
+		Arrival:        d.arrival,
+		DepartureLocal: d.departureTime.Format(m.LocalTimeLayout),
+		Reservation:    p.reservation,
+	}
+	if flight.FlightNumber == "" && d.number != "" {
+		flight.FlightNumber = "0"
+	}
+	if !d.arrivalTime.IsZero() {
+		arrival := d.arrivalTime
+		// "23:10 - 06:45" without a date for the arrival lands the next day
+		if d.arrivalRolls && arrival.Before(d.departureTime) {
+			arrival = arrival.AddDate(0, 0, 1)
+		}
+		if !arrival.Before(d.departureTime.Add(-24 * time.Hour)) {
+			flight.ArrivalLocal = arrival.Format(m.LocalTimeLayout)
+		}
+	}
+	p.flights = append(p.flights, flight)
+}
+
+// tokens returns the flight numbers, airports, dates and times of a line in
+// the order they appear. Overlapping matches keep the first kind found, dates
+// are read before times and flight numbers so "15.04.2025" is one date.
+func (p *flightParser) tokens(line string) []token {
+	var tokens []token
+	taken := func(start, end int) bool {
+		for _, t := range tokens {
+			if start < t.end && t.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(t token) {
+		if !taken(t.start, t.end) {
+			t.label = labelBefore(line, t.start)
+			tokens = append(tokens, t)
+		}
+	}
+
+	for _, match := range monthFirstDate.FindAllStringSubmatchIndex(line, -1) {
+		if date, ok := makeDate(line[match[6]:match[7]], monthNumber(line[match[2]:match[3]]), line[match[4]:match[5]]); ok {
+			add(token{kind: dateToken, start: match[0], end: match[1], date: date})
+		}
+	}
+	for _, match := range dayFirstDate.FindAllStringSubmatchIndex(line, -1) {
+		if date, ok := makeDate(line[match[6]:match[7]], monthNumber(line[match[4]:match[5]]), line[match[2]:match[3]]); ok {
+			add(token{kind: dateToken, start: match[0], end: match[1], date: date})
+		}
+	}
+	for _, match := range isoDate.FindAllStringSubmatchIndex(line, -1) {
+		month, _ := strconv.Atoi(line[match[4]:match[5]])
+		if date, ok := makeDate(line[match[2]:match[3]], month, line[match[6]:match[7]]); ok {
+			add(token{kind: dateToken, start: match[0], end: match[1], date: date})
+		}
+	}
+	for _, match := range dottedDate.FindAllStringSubmatchIndex(line, -1) {
+		month, _ := strconv.Atoi(line[match[4]:match[5]])
+		if date, ok := makeDate(line[match[6]:match[7]], month, line[match[2]:match[3]]); ok {
+			add(token{kind: dateToken, start: match[0], end: match[1], date: date})
+		}
+	}
+	gds := false
+	for _, match := range gdsDate.FindAllStringSubmatchIndex(line, -1) {
+		if date, ok := p.gdsDate(line, match); ok {
+			gds = true
+			add(token{kind: dateToken, start: match[0], end: match[1], date: date})
+		}
+	}
+
+	for _, match := range flightNumberPattern.FindAllStringSubmatchIndex(line, -1) {
+		designator := line[match[2]:match[3]]
+		if notDesignators[designator] || strings.HasPrefix(line[match[1]:], ":") {
+			continue
+		}
+		add(token{kind: flightToken, start: match[0], end: match[1], designator: designator, number: line[match[4]:match[5]]})
+	}
+
+	for _, match := range clockTime.FindAllStringSubmatchIndex(line, -1) {
+		hours, _ := strconv.Atoi(line[match[2]:match[3]])
+		minutes, _ := strconv.Atoi(line[match[4]:match[5]])
+		if match[6] >= 0 {
+			switch meridiem := strings.ToLower(line[match[6]:match[7]]); {
+			case hours < 1 || hours > 12:
+				continue
+			case meridiem == "p" && hours != 12:
+				hours += 12
+			case meridiem == "a" && hours == 12:
+				hours = 0
+			}
+		}
+		if hours > 23 || minutes > 59 {
+			continue
+		}
+		days := 0
+		if match[8] >= 0 {
+			days, _ = strconv.Atoi(line[match[8]:match[9]])
+		}
+		add(token{kind: timeToken, start: match[2], end: match[1], clock: clock(hours, minutes), days: days})
+	}
+	if gds {
+		for _, match := range gdsTime.FindAllStringSubmatchIndex(line, -1) {
+			hours, _ := strconv.Atoi(line[match[2]:match[3]])
+			minutes, _ := strconv.Atoi(line[match[4]:match[5]])
+			days := 0
+			if match[6] >= 0 {
+				days, _ = strconv.Atoi(line[match[6]:match[7]])
+			}
+			add(token{kind: timeToken, start: match[0], end: match[1], clock: clock(hours, minutes), days: days})
+		}
+		for _, match := range gdsCityPair.FindAllStringSubmatchIndex(line, -1) {
+			from, to := line[match[2]:match[3]], line[match[4]:match[5]]
+			if p.known(from) && p.known(to) && !taken(match[0], match[1]) {
+				tokens = append(tokens,
+					token{kind: airportToken, start: match[2], end: match[3], airport: from},
+					token{kind: airportToken, start: match[4], end: match[5], airport: to})
+			}
+		}
+	}
+
+	for _, match := range parenthesizedAirport.FindAllStringSubmatchIndex(line, -1) {
+		if code := line[match[2]:match[3]]; p.known(code) {
+			add(token{kind: airportToken, start: match[2], end: match[3], airport: code})
+		}
+	}
+	// Capitalized words are only airports on lines that also have a flight
+	// number or a time, "SAVE ON FLIGHTS" is not a route
+	scheduled := false
+	for _, t := range tokens {
+		scheduled = scheduled || t.kind == flightToken || t.kind == timeToken
+	}
+	if scheduled {
+		for _, match := range bareAirport.FindAllStringSubmatchIndex(line, -1) {
+			if code := line[match[2]:match[3]]; p.known(code) {
+				add(token{kind: airportToken, start: match[2], end: match[3], airport: code})
+			}
+		}
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].start < tokens[j].start })
+	return tokens
+}
+
+// gdsDate reads "15APR25", or "15APR" in the year that puts it closest after
+// the message was sent
+func (p *flightParser) gdsDate(line string, match []int) (time.Time, bool) {
+	month := monthNumber(line[match[4]:match[5]])
+	if match[6] >= 0 {
+		return makeDate("20"+line[match[6]:match[7]], month, line[match[2]:match[3]])
+	}
+	year := p.reference.Year()
+	date, ok := makeDate(strconv.Itoa(year), month, line[match[2]:match[3]])
+	if ok && date.Before(p.reference.AddDate(0, 0, -31)) {
+		date, ok = makeDate(strconv.Itoa(year+1), month, line[match[2]:match[3]])
+	}
+	return date, ok
+}
+
+// labelBefore returns the last label on the line before a token
+func labelBefore(line string, start int) label {
+	words := labelWords.FindAllString(line[:start], -1)
+	if len(words) == 0 {
+		return unlabeled
+	}
+	return wordLabel(words[len(words)-1])
+}
+
+func makeDate(year string, month int, day string) (time.Time, bool) {
+	y, err := strconv.Atoi(year)
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	d, err := strconv.Atoi(day)
+	if err != nil || d < 1 || d > 31 {
+		return time.Time{}, false
+	}
+	date := time.Date(y, time.Month(month), d, 0, 0, 0, 0, time.UTC)
+	if date.Day() != d {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func monthNumber(name string) int {
+	name = strings.ToLower(name)
+	for i, month := range []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"} {
+		if strings.HasPrefix(name, month) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func clock(hours, minutes int) time.Duration {
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+}
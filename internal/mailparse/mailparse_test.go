@@ -0,0 +1,127 @@
+package mailparse
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// knownAirports stands in for the airports table. AND and THE are airports
+// too, capitalized words in emails must not be read as one.
+func knownAirports(code string) bool {
+	switch code {
+	case "SFO", "NRT", "HND", "JFK", "FRA", "SIN", "LHR", "EDI", "MUC", "HAM", "AND", "THE":
+		return true
+	}
+	return false
+}
+
+func TestFlightsFromFixtures(t *testing.T) {
+	cases := map[string]struct {
+		subject string
+		flights []Flight
+	}{
+		"united_multipart_qp.eml": {
+			subject: "Your trip confirmation – San Francisco to Tokyo",
+			flights: []Flight{
+				{Airline: "UA", FlightNumber: "837", Departure: "SFO", Arrival: "NRT", DepartureLocal: "2025-04-02T11:05", ArrivalLocal: "2025-04-03T14:40", Reservation: "K9QF2L"},
+				{Airline: "UA", FlightNumber: "838", Departure: "NRT", Arrival: "SFO", DepartureLocal: "2025-04-13T17:00", ArrivalLocal: "2025-04-13T09:40", Reservation: "K9QF2L"},
+			},
+		},
+		"html_base64_table.eml": {
+			subject: "Ihre Buchungsbestätigung M4RK9E",
+			flights: []Flight{
+				{Airline: "LH", FlightNumber: "2034", Departure: "MUC", Arrival: "HAM", DepartureLocal: "2025-04-15T07:05", ArrivalLocal: "2025-04-15T08:15"},
+				{Airline: "LH", FlightNumber: "2043", Departure: "HAM", Arrival: "MUC", DepartureLocal: "2025-04-18T20:50", ArrivalLocal: "2025-04-18T22:00"},
+			},
+		},
+		"gds_itinerary.eml": {
+			subject: "Itinerary for TRAVELER/PAT MS",
+			flights: []Flight{
+				{Airline: "LH", FlightNumber: "401", Departure: "JFK", Arrival: "FRA", DepartureLocal: "2026-01-15T17:45", ArrivalLocal: "2026-01-16T07:35", Reservation: "QX7T4M"},
+				{Airline: "LH", FlightNumber: "778", Departure: "FRA", Arrival: "SIN", DepartureLocal: "2026-01-16T11:50", ArrivalLocal: "2026-01-17T06:40", Reservation: "QX7T4M"},
+				{Airline: "SQ", FlightNumber: "12", Departure: "SIN", Arrival: "NRT", DepartureLocal: "2026-01-27T08:05", ArrivalLocal: "2026-01-27T16:10", Reservation: "QX7T4M"},
+			},
+		},
+		"forwarded_round_trip.eml": {
+			subject: "Fwd: Your booking confirmation K7XJ2P",
+			flights: []Flight{
+				{Airline: "ZZ", FlightNumber: "101", Departure: "LHR", Arrival: "EDI", DepartureLocal: "2025-04-15T08:40", ArrivalLocal: "2025-04-15T10:05", Reservation: "K7XJ2P"},
+				{Airline: "ZZ", FlightNumber: "104", Departure: "EDI", Arrival: "LHR", DepartureLocal: "2025-04-20T18:15", ArrivalLocal: "2025-04-20T19:40", Reservation: "K7XJ2P"},
+			},
+		},
+		"forwarded_attachment.eml": {
+			subject: "Fwd: Reservation confirmed",
+			flights: []Flight{
+				{Airline: "ZZ", FlightNumber: "4242", Departure: "HND", Arrival: "SIN", DepartureLocal: "2025-12-20T23:10", ArrivalLocal: "2025-12-21T06:45", Reservation: "HND5ZZ"},
+			},
+		},
+		"newsletter.eml": {
+			subject: "Spring sale: Tokyo from $599",
+		},
+	}
+
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		name := filepath.Base(fixture)
+		want, ok := cases[name]
+		if !ok {
+			t.Errorf("testdata/%s has no expected flights", name)
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			msg, err := ReadMessage(f)
+			if err != nil {
+				t.Fatalf("reading the message: %v", err)
+			}
+			if msg.Subject != want.subject {
+				t.Errorf("subject: got %q, want %q", msg.Subject, want.subject)
+			}
+			if msg.ID == "" {
+				t.Error("the message has no ID")
+			}
+
+			got := Flights(msg, knownAirports)
+			if !reflect.DeepEqual(got, want.flights) {
+				t.Errorf("flights:\n got %+v\nwant %+v\ntext:\n%s", got, want.flights, msg.Text)
+			}
+		})
+	}
+}
+
+func TestReadMessageDecodesBodies(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "html_base64_table.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	msg, err := ReadMessage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Buchungsbestätigung", "LH 2034 | München (MUC) | Hamburg (HAM)"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text does not contain %q:\n%s", want, msg.Text)
+		}
+	}
+	for _, hidden := range []string{"padding", "tracking", "Ihre Buchung\n"} {
+		if strings.Contains(msg.Text, hidden) {
+			t.Errorf("text contains %q from outside the body:\n%s", hidden, msg.Text)
+		}
+	}
+
+	if _, err := ReadMessage(strings.NewReader("just some text\n")); err != ErrNotMessage {
+		t.Errorf("plain text: got %v, want ErrNotMessage", err)
+	}
+}
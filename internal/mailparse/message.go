@@ -0,0 +1,255 @@
+// Package mailparse reads airline confirmation emails. ReadMessage decodes a
+// raw RFC 5322 message, including forwarded messages attached to it, into
+// plain text, and Flights finds the flights in that text.
+package mailparse
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxParts limits how many MIME parts of a message are read
+const maxParts = 100
+
+// Message is a decoded email. ID is the Message-ID, or a hash of the message
+// when it has none, so the same email delivered twice has the same ID.
+type Message struct {
+	ID      string
+	From    string
+	Subject string
+	Date    time.Time
+	Text    string // The text/plain parts, or the text/html parts converted to text
+}
+
+// ErrNotMessage is returned when the input has no message headers
+var ErrNotMessage = errors.New("not an email message")
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// ReadMessage reads a message as saved in a .eml file or received over SMTP
+func ReadMessage(r io.Reader) (Message, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return Message{}, err
+	}
+	msg, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil || len(msg.Header) == 0 {
+		return Message{}, ErrNotMessage
+	}
+
+	message := Message{
+		ID:      strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>"),
+		From:    decodeHeader(msg.Header.Get("From")),
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+	}
+	if message.ID == "" {
+		sum := sha256.Sum256(raw)
+		message.ID = hex.EncodeToString(sum[:])
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		message.Date = date
+	}
+
+	var body bodyText
+	parts := 0
+	if err := body.read(msg.Header, msg.Body, &parts); err != nil {
+		return message, err
+	}
+	message.Text = body.String()
+	return message, nil
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// bodyText collects the text of every part, plain text is preferred over
+// HTML for the parts of a multipart/alternative
+type bodyText struct {
+	plain []string
+	html  []string
+}
+
+func (b *bodyText) String() string {
+	if len(b.plain) > 0 {
+		return strings.Join(b.plain, "\n")
+	}
+	texts := make([]string, 0, len(b.html))
+	for _, part := range b.html {
+		texts = append(texts, HTMLToText(part))
+	}
+	return strings.Join(texts, "\n")
+}
+
+type partHeader interface {
+	Get(key string) string
+}
+
+func (b *bodyText) read(header partHeader, body io.Reader, parts *int) error {
+	*parts++
+	if *parts > maxParts {
+		return fmt.Errorf("the message has more than %d parts", maxParts)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition")); disposition == "attachment" && mediaType != "message/rfc822" {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", mediaType, err)
+			}
+			if err := b.read(part.Header, part, parts); err != nil {
+				return err
+			}
+		}
+	case mediaType == "message/rfc822":
+		// A message forwarded as an attachment
+		msg, err := mail.ReadMessage(bufio.NewReader(decodeTransfer(header, body)))
+		if err != nil {
+			return nil
+		}
+		return b.read(msg.Header, msg.Body, parts)
+	case mediaType == "text/plain" || mediaType == "text/html":
+		data, err := io.ReadAll(decodeTransfer(header, body))
+		if err != nil {
+			return fmt.Errorf("decoding %s: %w", mediaType, err)
+		}
+		text := decodeCharset(data, params["charset"])
+		if mediaType == "text/plain" {
+			b.plain = append(b.plain, text)
+		} else {
+			b.html = append(b.html, text)
+		}
+	}
+	return nil
+}
+
+func decodeTransfer(header partHeader, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: body})
+	}
+	return body
+}
+
+// base64Cleaner drops the line breaks and spaces between base64 lines
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[kept] = b
+			kept++
+		}
+	}
+	if kept == 0 && n > 0 && err == nil {
+		return c.Read(p)
+	}
+	return kept, err
+}
+
+// decodeCharset converts Latin-1 and Windows-1252 text to UTF-8, other
+// charsets are read as UTF-8
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		return windows1252(data)
+	}
+	if !utf8.Valid(data) {
+		return windows1252(data)
+	}
+	return string(data)
+}
+
+// cp1252 holds the characters Windows-1252 puts where Latin-1 has control
+// codes, from 0x80 to 0x9f
+var cp1252 = []rune("€\u0081‚ƒ„…†‡ˆ‰Š‹Œ\u008dŽ\u008f\u0090‘’“”•–—˜™š›œ\u009džŸ")
+
+// windows1252 decodes Windows-1252, a superset of Latin-1
+func windows1252(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		if b >= 0x80 && b < 0xa0 {
+			runes[i] = cp1252[b-0x80]
+		} else {
+			runes[i] = rune(b)
+		}
+	}
+	return string(runes)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decodeCharset(data, charset)), nil
+}
+
+var (
+	hiddenElements = regexp.MustCompile(`(?is)<(script|style|head|title)\b.*?</(script|style|head|title)\s*>`)
+	htmlComments   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	lineBreakTags  = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/li|/h[1-6]|/table|p|div|tr|li|h[1-6]|table)\b[^>]*>`)
+	cellTags       = regexp.MustCompile(`(?i)<(td|th)\b[^>]*>`)
+	otherTags      = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceRuns      = regexp.MustCompile(`[ \t\x{00a0}]+`)
+)
+
+// HTMLToText keeps the text of an HTML email, one line per block element and
+// table row. Table cells on a row are separated by " | ".
+func HTMLToText(source string) string {
+	text := hiddenElements.ReplaceAllString(source, "")
+	text = htmlComments.ReplaceAllString(text, "")
+	text = strings.NewReplacer("\r", "", "\n", " ").Replace(text)
+	text = lineBreakTags.ReplaceAllString(text, "\n")
+	text = cellTags.ReplaceAllString(text, " | ")
+	text = otherTags.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = spaceRuns.ReplaceAllString(line, " ")
+		line = strings.Trim(line, " |")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
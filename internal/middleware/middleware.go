@@ -4,12 +4,17 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	db "github.com/skywall34/trip-tracker/internal/database"
+	"github.com/skywall34/trip-tracker/internal/models"
 )
 
 type key string
@@ -194,12 +199,14 @@ func GetBasePath(ctx context.Context) string {
 
 type AuthMiddleware struct {
 	sessionStore      *db.SessionStore
+	accessTokenStore  *db.AccessTokenStore
 	sessionCookieName string
 }
 
-func NewAuthMiddleware(sessionStore *db.SessionStore, sessionCookieName string) *AuthMiddleware {
+func NewAuthMiddleware(sessionStore *db.SessionStore, accessTokenStore *db.AccessTokenStore, sessionCookieName string) *AuthMiddleware {
 	return &AuthMiddleware{
 		sessionStore:      sessionStore,
+		accessTokenStore:  accessTokenStore,
 		sessionCookieName: sessionCookieName,
 	}
 }
@@ -244,6 +251,74 @@ func (m *AuthMiddleware) AddUserToContext(next http.HandlerFunc) http.HandlerFun
 	})
 }
 
+// WriteAPIError answers an API call with a JSON error object
+func WriteAPIError(w http.ResponseWriter, status int, apiErr models.APIError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.APIErrorResponse{Error: apiErr})
+}
+
+// AddAPIUserToContext authenticates API calls with an access token sent as
+// "Authorization: Bearer <token>" or with the session cookie. Tokens need the
+// scope, any valid token will do when scope is empty. Calls that are not
+// authenticated get a JSON 401 instead of the redirect to /login. Writes with
+// the cookie must send JSON, see jsonRequest.
+func (m *AuthMiddleware) AddAPIUserToContext(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			secret, ok := strings.CutPrefix(authorization, "Bearer ")
+			if !ok {
+				WriteAPIError(w, http.StatusUnauthorized, models.APIError{Code: "unauthorized", Message: "Send the access token as Authorization: Bearer <token>"})
+				return
+			}
+			token, err := m.accessTokenStore.AuthenticateAccessToken(strings.TrimSpace(secret))
+			switch {
+			case errors.Is(err, db.ErrAccessTokenExpired):
+				WriteAPIError(w, http.StatusUnauthorized, models.APIError{Code: "token_expired", Message: "The access token has expired"})
+				return
+			case errors.Is(err, db.ErrNotFound):
+				WriteAPIError(w, http.StatusUnauthorized, models.APIError{Code: "unauthorized", Message: "The access token is not valid"})
+				return
+			case err != nil:
+				log.Printf("Error authenticating access token: %v", err)
+				WriteAPIError(w, http.StatusInternalServerError, models.APIError{Code: "internal_error", Message: "Something went wrong, try again later"})
+				return
+			}
+			if scope != "" && !token.HasScope(scope) {
+				WriteAPIError(w, http.StatusForbidden, models.APIError{Code: "insufficient_scope", Message: "The access token needs the " + scope + " scope"})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserKey, token.UserID)))
+			return
+		}
+
+		cookie, err := r.Cookie(m.sessionCookieName)
+		if err != nil || cookie.Value == "" {
+			WriteAPIError(w, http.StatusUnauthorized, models.APIError{Code: "unauthorized", Message: "Sign in or send an access token"})
+			return
+		}
+		userID, err := m.sessionStore.GetUserFromSession(cookie.Value)
+		if err != nil {
+			WriteAPIError(w, http.StatusUnauthorized, models.APIError{Code: "unauthorized", Message: "The session has expired, sign in again"})
+			return
+		}
+		// Browsers send the cookie along with forms posted from other sites.
+		// Those can not be JSON, so writes with the session have to be.
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !jsonRequest(r) {
+			WriteAPIError(w, http.StatusUnsupportedMediaType, models.APIError{Code: "json_required", Message: "Send Content-Type: application/json, or an access token"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserKey, userID)))
+	})
+}
+
+// jsonRequest reports whether the body is declared as JSON, which a page on
+// another site can only send after a CORS preflight this app never answers
+func jsonRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
 // TODO: Once using store change false return to nil and return userStore
 func GetUserUsingContext(ctx context.Context) int {
 	userId, ok := ctx.Value(UserKey).(int)
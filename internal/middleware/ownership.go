@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+/***********************************Ownership Middleware**********************************************/
+
+// OwnedParam names a request parameter that holds the id of a user owned record.
+// When Resource is empty the resource is read from the parameter named TypeParam
+// instead, e.g. /trash?type=place&id=4.
+type OwnedParam struct {
+	Name      string
+	Resource  db.Resource
+	TypeParam string
+}
+
+type OwnershipMiddleware struct {
+	authorizer *db.Authorizer
+}
+
+func NewOwnershipMiddleware(authorizer *db.Authorizer) *OwnershipMiddleware {
+	return &OwnershipMiddleware{authorizer: authorizer}
+}
+
+// NotFound is the single response for records that do not exist or belong to
+// another user, so handlers and the ownership check can not be told apart
+func NotFound(w http.ResponseWriter) {
+	http.Error(w, "Not found", http.StatusNotFound)
+}
+
+// RequireOwnership must run after AddUserToContext. Every listed parameter that
+// is present on the request has to name a record owned by the logged in user,
+// otherwise the request ends with a 404. Handlers read ids from the query
+// string or the form body, so both are checked: r.FormValue alone prefers the
+// body and a request could name the user's own record there and another
+// user's in the query. The body is only read for requests that may have one.
+// Missing or malformed parameters are left to the handler to reject. Requests
+// without a user are passed on so the handler can redirect to /login as usual.
+func (o *OwnershipMiddleware) RequireOwnership(params []OwnedParam, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(UserKey).(int)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sources := []url.Values{r.URL.Query()}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			// Also parses url encoded bodies, ErrNotMultipart is expected for those
+			r.ParseMultipartForm(32 << 20)
+			sources = append(sources, r.PostForm)
+		}
+
+		for _, values := range sources {
+			for _, param := range params {
+				for _, value := range values[param.Name] {
+					id, err := strconv.Atoi(value)
+					if err != nil {
+						continue
+					}
+
+					resource := param.Resource
+					if resource == "" {
+						resource, err = db.ParseResource(values.Get(param.TypeParam))
+						if err != nil {
+							continue
+						}
+					}
+
+					err = o.authorizer.RequireOwner(resource, id, userID)
+					if errors.Is(err, db.ErrNotFound) {
+						NotFound(w)
+						return
+					}
+					if err != nil {
+						log.Printf("Error checking ownership of %s %d: %v", resource, id, err)
+						http.Error(w, "Error checking permissions", http.StatusInternalServerError)
+						return
+					}
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
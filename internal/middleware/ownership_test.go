@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+func TestRequireOwnership(t *testing.T) {
+	database, err := db.InitDB("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	users := db.NewUserStore(db.NewUserStoreParams{DB: database})
+	newTrip := func(name string) (int, string) {
+		t.Helper()
+		userID, err := users.CreateUser(m.User{Username: name, Password: "x", Email: name + "@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := database.Exec(`
+			INSERT INTO trips (user_id, departure, arrival, departure_time, arrival_time, airline, flight_number)
+			VALUES (?, 'JFK', 'NRT', 1, 2, '', '')`, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tripID, _ := result.LastInsertId()
+		return userID, strconv.Itoa(int(tripID))
+	}
+	userID, own := newTrip("owner")
+	_, others := newTrip("other")
+
+	ownership := NewOwnershipMiddleware(db.NewAuthorizer(db.NewAuthorizerParams{DB: database}))
+	handler := func(params []OwnedParam) http.HandlerFunc {
+		return ownership.RequireOwnership(params, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	ownedTrip := handler([]OwnedParam{{Name: "id", Resource: db.ResourceTrip}})
+	ownedByType := handler([]OwnedParam{{Name: "id", TypeParam: "type"}})
+
+	form := func(method, target, body string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+	multipartForm := func(method, target, id string) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("id", id)
+		writer.Close()
+		r := httptest.NewRequest(method, target, &body)
+		r.Header.Set("Content-Type", writer.FormDataContentType())
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		request *http.Request
+		want    int
+	}{
+		{"own trip in the query", ownedTrip, httptest.NewRequest("GET", "/trips?id="+own, nil), http.StatusNoContent},
+		{"other user's trip in the query", ownedTrip, httptest.NewRequest("GET", "/trips?id="+others, nil), http.StatusNotFound},
+		{"other user's trip in the body", ownedTrip, form("PUT", "/trips", "id="+others), http.StatusNotFound},
+		{"other user's trip in the query, own in the body", ownedTrip, form("PUT", "/trips?id="+others, "id="+own), http.StatusNotFound},
+		{"own trip in the query, other user's in the body", ownedTrip, form("POST", "/trips?id="+own, "id="+others), http.StatusNotFound},
+		{"own trip in both", ownedTrip, form("PUT", "/trips?id="+own, "id="+own), http.StatusNoContent},
+		{"other user's trip in a multipart body", ownedTrip, multipartForm("POST", "/trips?id="+own, others), http.StatusNotFound},
+		{"other user's trip as the second value", ownedTrip, httptest.NewRequest("GET", "/trips?id="+own+"&id="+others, nil), http.StatusNotFound},
+		{"a GET body is not read", ownedTrip, form("GET", "/trips?id="+own, "id="+others), http.StatusNoContent},
+		{"malformed id", ownedTrip, httptest.NewRequest("GET", "/trips?id=x", nil), http.StatusNoContent},
+		{"trip that does not exist", ownedTrip, httptest.NewRequest("DELETE", "/trips?id=9999", nil), http.StatusNotFound},
+		{"other user's trip by type", ownedByType, httptest.NewRequest("DELETE", "/trash?type=trip&id="+others, nil), http.StatusNotFound},
+		{"type and id from the body", ownedByType, form("POST", "/trash/restore", "type=trip&id="+others), http.StatusNotFound},
+		{"unknown type", ownedByType, httptest.NewRequest("DELETE", "/trash?type=boat&id="+others, nil), http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			test.handler(rec, test.request.WithContext(context.WithValue(test.request.Context(), UserKey, userID)))
+			if rec.Code != test.want {
+				t.Errorf("got %d %q, want %d", rec.Code, rec.Body.String(), test.want)
+			}
+		})
+	}
+
+	// Without a user the handler redirects to /login itself
+	rec := httptest.NewRecorder()
+	ownedTrip(rec, httptest.NewRequest("GET", "/trips?id="+others, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("request without a user: got %d", rec.Code)
+	}
+}
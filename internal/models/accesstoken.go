@@ -0,0 +1,54 @@
+package models
+
+import "slices"
+
+// Scopes an access token can be given. The trip scopes cover places as well.
+const (
+	ScopeReadTrips  = "trips:read"
+	ScopeWriteTrips = "trips:write"
+	ScopeReadStats  = "stats:read"
+)
+
+// AccessScopes lists the scopes in the order they are offered
+var AccessScopes = []string{ScopeReadTrips, ScopeWriteTrips, ScopeReadStats}
+
+var accessScopeLabels = map[string]string{
+	ScopeReadTrips:  "Read trips and places",
+	ScopeWriteTrips: "Create, change and delete trips and places",
+	ScopeReadStats:  "Read statistics",
+}
+
+func IsAccessScope(scope string) bool {
+	_, ok := accessScopeLabels[scope]
+	return ok
+}
+
+// AccessScopeLabel returns the description of a scope
+func AccessScopeLabel(scope string) string {
+	if label, ok := accessScopeLabels[scope]; ok {
+		return label
+	}
+	return scope
+}
+
+// AccessToken is a personal access token of a user for the API. The token
+// itself is only known right after it has been generated.
+type AccessToken struct {
+	ID         int      `json:"id"`
+	UserID     int      `json:"user_id"`
+	Name       string   `json:"name"`
+	Token      string   `json:"token,omitempty"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  uint32   `json:"created_at"`
+	LastUsedAt *uint32  `json:"last_used_at,omitempty"`
+	ExpiresAt  *uint32  `json:"expires_at,omitempty"` // Never expires when nil
+}
+
+func (t AccessToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token can no longer be used at now (Unix seconds)
+func (t AccessToken) Expired(now uint32) bool {
+	return t.ExpiresAt != nil && *t.ExpiresAt <= now
+}
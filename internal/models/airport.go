@@ -1,10 +1,145 @@
 package models
 
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
 type Airport struct {
-	IataCode  string `json:"iata_code"`
-	Name	  string `json:"name"`
-	Country   string `json:"country"`
-	Latitude  string `json:"latitude"`
-	Longitude string `json:"longitude"`
-	Region    string `json:"region"`
+	IataCode   string  `json:"iata_code"`
+	IcaoCode   string  `json:"icao_code,omitempty"`
+	Name       string  `json:"name"`
+	City       string  `json:"city,omitempty"`
+	Country    string  `json:"country"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Region     string  `json:"region"`
+	Timezone   string  `json:"timezone,omitempty"`
+	DistanceKm float64 `json:"distance_km,omitempty"` // Only set by nearest airport queries
+}
+
+// Header names accepted for each column, OurAirports names come first
+var airportCSVColumns = map[string][]string{
+	"iata":      {"iata_code", "iata"},
+	"icao":      {"icao_code", "gps_code", "icao", "ident"},
+	"name":      {"name"},
+	"city":      {"municipality", "city"},
+	"country":   {"iso_country", "country"},
+	"region":    {"iso_region", "region"},
+	"latitude":  {"latitude_deg", "latitude", "lat"},
+	"longitude": {"longitude_deg", "longitude", "lon"},
+	"timezone":  {"timezone", "tz", "tz_database_time_zone"},
+	"type":      {"type"},
+}
+
+// LoadAirportsFromCSV reads an OurAirports style airports.csv (https://ourairports.com/data/).
+// Only rows with an IATA code are returned since trips reference airports by IATA code.
+// Closed airports are skipped. The timezone column is optional, see MergeAirportTimezones.
+func LoadAirportsFromCSV(path string) ([]Airport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	// A field can be backed by several columns (OurAirports has icao_code, gps_code
+	// and ident) so keep all of them in order of preference
+	index := make(map[string][]int)
+	for field, names := range airportCSVColumns {
+		for _, name := range names {
+			for i, column := range header {
+				if strings.EqualFold(strings.TrimSpace(column), name) {
+					index[field] = append(index[field], i)
+				}
+			}
+		}
+	}
+	for _, required := range []string{"iata", "name", "latitude", "longitude"} {
+		if len(index[required]) == 0 {
+			return nil, fmt.Errorf("missing required column %q", airportCSVColumns[required][0])
+		}
+	}
+
+	get := func(record []string, field string) string {
+		for _, i := range index[field] {
+			if i < len(record) && strings.TrimSpace(record[i]) != "" {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	var airports []Airport
+	line := 1
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		iata := strings.ToUpper(get(record, "iata"))
+		if len(iata) != 3 || get(record, "type") == "closed" {
+			continue
+		}
+
+		lat, err := strconv.ParseFloat(get(record, "latitude"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude: %w", line, err)
+		}
+		lon, err := strconv.ParseFloat(get(record, "longitude"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude: %w", line, err)
+		}
+
+		icao := strings.ToUpper(get(record, "icao"))
+		if len(icao) != 4 {
+			icao = ""
+		}
+
+		airports = append(airports, Airport{
+			IataCode:  iata,
+			IcaoCode:  icao,
+			Name:      get(record, "name"),
+			City:      get(record, "city"),
+			Country:   strings.ToUpper(get(record, "country")),
+			Latitude:  lat,
+			Longitude: lon,
+			Region:    get(record, "region"),
+			Timezone:  get(record, "timezone"),
+		})
+	}
+
+	return airports, nil
+}
+
+// MergeAirportTimezones fills in missing timezones from an IATA -> IANA timezone lookup
+// such as AirportTimezoneLookup. Returns the number of airports that are still missing one.
+func MergeAirportTimezones(airports []Airport, lookup map[string]string) int {
+	missing := 0
+	for i := range airports {
+		if airports[i].Timezone == "" {
+			airports[i].Timezone = lookup[airports[i].IataCode]
+		}
+		if airports[i].Timezone == "" {
+			missing++
+		}
+	}
+	return missing
 }
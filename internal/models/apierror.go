@@ -0,0 +1,14 @@
+package models
+
+// APIError is the body of every error response of the JSON API, clients can
+// switch on Code
+type APIError struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"` // The problem of each invalid field or parameter
+}
+
+// APIErrorResponse wraps an APIError as {"error": {...}}
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Attachment is a file kept with a trip or place. Data is only read when the
+// file itself is needed, lists of attachments leave it empty.
+type Attachment struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"user_id"`
+	EntityType  string `json:"entity_type"` // EntityTrip or EntityPlace
+	EntityID    int    `json:"entity_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	Data        []byte `json:"-"`
+	CreatedAt   uint32 `json:"created_at"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// CalendarFeed is the secret iCalendar subscription of a user. The token itself
+// is only known right after it has been generated.
+type CalendarFeed struct {
+	UserID    int    `json:"user_id"`
+	Token     string `json:"token,omitempty"`
+	CreatedAt uint32 `json:"created_at"`
+}
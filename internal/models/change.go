@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EntityTrip  = "trip"
+	EntityPlace = "place"
+)
+
+const (
+	ChangeCreate  = "create"
+	ChangeUpdate  = "update"
+	ChangeDelete  = "delete"
+	ChangeRestore = "restore"
+	ChangeRevert  = "revert"
+)
+
+// Change is one entry in the audit trail of a trip or place. Before and After
+// hold the row as JSON, Before is empty for a create.
+type Change struct {
+	ID         int             `json:"id"`
+	UserID     int             `json:"user_id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	Action     string          `json:"action"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  uint32          `json:"created_at"`
+}
+
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Fields that change on every write, or are joined from airports rather than
+// stored on the trip, would only clutter the diff
+var ignoredChangeFields = map[string]bool{
+	"id":            true,
+	"user_id":       true,
+	"created_at":    true,
+	"updated_at":    true,
+	"deleted_at":    true,
+	"departure_lat": true,
+	"departure_lon": true,
+	"arrival_lat":   true,
+	"arrival_lon":   true,
+	"distance_km":   true,
+}
+
+// Diff lists the fields that differ between Before and After, ordered by name
+func (c Change) Diff() []FieldChange {
+	before := map[string]any{}
+	after := map[string]any{}
+	if len(c.Before) > 0 {
+		json.Unmarshal(c.Before, &before)
+	}
+	if len(c.After) > 0 {
+		json.Unmarshal(c.After, &after)
+	}
+
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	var diff []FieldChange
+	for field := range fields {
+		if ignoredChangeFields[field] {
+			continue
+		}
+		// Compared as shown, an empty text and a missing value are both blank
+		b := formatChangeValue(field, before[field])
+		a := formatChangeValue(field, after[field])
+		if a == b {
+			continue
+		}
+		diff = append(diff, FieldChange{Field: field, Before: b, After: a})
+	}
+
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Field < diff[j].Field
+	})
+	return diff
+}
+
+// Revertable reports whether the change holds a version of the record that can be restored
+func (c Change) Revertable() bool {
+	return len(c.After) > 0 && c.Action != ChangeDelete
+}
+
+// Timestamps are stored as unix seconds, show them as UTC dates instead
+func formatChangeValue(field string, value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "—"
+	case string:
+		if v == "" {
+			return "—"
+		}
+		return v
+	case float64:
+		if strings.HasSuffix(field, "_time") || strings.HasSuffix(field, "_date") {
+			return time.Unix(int64(v), 0).UTC().Format("2006-01-02 15:04 UTC")
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
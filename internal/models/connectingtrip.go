@@ -1,7 +0,0 @@
-package models
-
-
-type ConnectingTrip struct {
-	FromTrip Trip
-	ToTrip   Trip
-}
\ No newline at end of file
@@ -0,0 +1,7 @@
+package models
+
+type FlightDetailAggregation struct {
+	Label   string `json:"label"`   // Cabin class or aircraft type
+	Flights int    `json:"flights"` // Number of flights
+	Km      int    `json:"km"`      // Great-circle kilometers flown
+}
@@ -0,0 +1,19 @@
+package models
+
+import "math"
+
+const EarthRadiusKm = 6371.0
+
+// GreatCircleDistanceKm returns the haversine distance between two coordinates.
+// Uses the same earth radius as the mileage query in TripStore.GetTotalMileageAndTime.
+func GreatCircleDistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Pow(math.Sin(dLon/2), 2)
+
+	return EarthRadiusKm * 2 * math.Asin(math.Sqrt(a))
+}
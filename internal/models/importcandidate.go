@@ -0,0 +1,33 @@
+package models
+
+import "fmt"
+
+// ImportCandidate is a trip read from an imported file. Candidates are shown
+// for review and only saved once the user confirms them.
+type ImportCandidate struct {
+	Trip      Trip   `json:"trip"`
+	Source    string `json:"source"`    // What the trip was read from, e.g. the calendar event summary
+	Duplicate bool   `json:"duplicate"` // The user already has this flight, or it appears earlier in the file
+}
+
+// ImportResult counts what happened to the rows of a confirmed import
+type ImportResult struct {
+	Kind       string           `json:"kind"` // EntityTrip or EntityPlace
+	Imported   int              `json:"imported"`
+	Updated    int              `json:"updated"`    // Rows with the id of an existing record that changed it
+	Duplicates int              `json:"duplicates"` // Skipped, the user already has the record unchanged
+	Invalid    int              `json:"invalid"`    // Skipped, unknown airports or impossible times
+	Queued     int              `json:"queued"`     // Waiting in the review queue
+	Errors     []ImportRowError `json:"errors,omitempty"`
+}
+
+// ImportRowError is a row of an imported file that could not be saved. Line is
+// the line in the file, the header is line 1.
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ImportRowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
@@ -0,0 +1,18 @@
+package models
+
+// ImportReview is a row of an imported flight log that could not be turned
+// into a trip, for example because an airport is not in the airports table.
+// It waits in the review queue until the user fixes and saves it or discards it.
+type ImportReview struct {
+	ID              int    `json:"id"`
+	UserID          int    `json:"user_id"`
+	Format          string `json:"format"` // Name of the importer parser, e.g. "openflights"
+	Line            int    `json:"line"`
+	Problem         string `json:"problem"`
+	Trip            Trip   `json:"trip"`            // What could be read, Departure and Arrival hold the codes from the file
+	DepartureLocal  string `json:"departure_local"` // LocalTimeLayout, empty when unknown
+	ArrivalLocal    string `json:"arrival_local"`
+	DurationMinutes int    `json:"duration_minutes"` // Used for the arrival when ArrivalLocal is empty
+	Source          string `json:"source"`           // The row as it appeared in the file
+	CreatedAt       uint32 `json:"created_at"`
+}
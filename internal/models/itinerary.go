@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Itinerary is an ordered chain of connecting flights, e.g. JFK→LHR→DXB→SIN.
+// Itineraries are either detected automatically from the user's maximum layover
+// or pinned by the user, in which case they are persisted and have an ID.
+type Itinerary struct {
+	ID     int    `json:"id,omitempty"`
+	UserID int    `json:"user_id"`
+	Pinned bool   `json:"pinned"`
+	Legs   []Trip `json:"legs"`
+}
+
+const DefaultMaxLayover = 24 * time.Hour
+
+func (i Itinerary) Origin() string {
+	if len(i.Legs) == 0 {
+		return ""
+	}
+	return i.Legs[0].Departure
+}
+
+func (i Itinerary) Destination() string {
+	if len(i.Legs) == 0 {
+		return ""
+	}
+	return i.Legs[len(i.Legs)-1].Arrival
+}
+
+// DepartureTime of the first leg, used for filtering upcoming and past itineraries
+func (i Itinerary) DepartureTime() uint32 {
+	if len(i.Legs) == 0 {
+		return 0
+	}
+	return i.Legs[0].DepartureTime
+}
+
+// LastDepartureTime is when the final leg departs, an itinerary whose first
+// leg has departed is still under way until then
+func (i Itinerary) LastDepartureTime() uint32 {
+	if len(i.Legs) == 0 {
+		return 0
+	}
+	return i.Legs[len(i.Legs)-1].DepartureTime
+}
+
+// Layover returns the time on the ground between leg and leg+1
+func (i Itinerary) Layover(leg int) time.Duration {
+	if leg < 0 || leg+1 >= len(i.Legs) {
+		return 0
+	}
+	return time.Duration(int64(i.Legs[leg+1].DepartureTime)-int64(i.Legs[leg].ArrivalTime)) * time.Second
+}
+
+// Stops returns the layover airports in order
+func (i Itinerary) Stops() []string {
+	var stops []string
+	for leg := 0; leg+1 < len(i.Legs); leg++ {
+		stops = append(stops, i.Legs[leg].Arrival)
+	}
+	return stops
+}
+
+func (i Itinerary) TripIDs() []int {
+	ids := make([]int, 0, len(i.Legs))
+	for _, leg := range i.Legs {
+		ids = append(ids, leg.ID)
+	}
+	return ids
+}
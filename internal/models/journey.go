@@ -0,0 +1,74 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Journey groups flights and places into one named trip, e.g. "Japan 2025".
+// StartDate and EndDate are midnight UTC of the first and last day.
+type Journey struct {
+	ID            int     `json:"id"`
+	UserID        int     `json:"user_id"`
+	Title         string  `json:"title"`
+	StartDate     uint32  `json:"start_date"`
+	EndDate       uint32  `json:"end_date"`
+	CoverLocation *string `json:"cover_location,omitempty"`
+	CreatedAt     uint32  `json:"created_at"`
+	UpdatedAt     uint32  `json:"updated_at"`
+	TripCount     int     `json:"trip_count"`
+	PlaceCount    int     `json:"place_count"`
+	Trips         []Trip  `json:"trips,omitempty"`
+	Places        []Place `json:"places,omitempty"`
+}
+
+// ContainsDay reports whether the calendar day of t, in t's location, is one of
+// the journey's days
+func (j Journey) ContainsDay(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()
+	return day >= int64(j.StartDate) && day <= int64(j.EndDate)
+}
+
+// ContainsTrip reports whether the flight departs on one of the journey's days.
+// The day is read in the departure airport's timezone, the date the flight is
+// listed under, so an evening departure in New York is not moved to the next
+// day by its UTC time.
+func (j Journey) ContainsTrip(trip Trip) bool {
+	departure := time.Unix(int64(trip.DepartureTime), 0).UTC()
+	if trip.DepartureTimezone != nil {
+		if loc, err := time.LoadLocation(*trip.DepartureTimezone); err == nil {
+			departure = departure.In(loc)
+		}
+	}
+	return j.ContainsDay(departure)
+}
+
+// ContainsPlace reports whether the place was visited on one of the journey's
+// days, visit dates are stored as midnight UTC like the journey's dates
+func (j Journey) ContainsPlace(place Place) bool {
+	return j.ContainsDay(time.Unix(int64(place.VisitDate), 0).UTC())
+}
+
+// Timeline returns the member flights and places in the order they happened
+func (j Journey) Timeline() []TimelineItem {
+	var timeline []TimelineItem
+	for i := range j.Trips {
+		timeline = append(timeline, TimelineItem{
+			Type:      "trip",
+			Trip:      &j.Trips[i],
+			Timestamp: j.Trips[i].DepartureTime,
+		})
+	}
+	for i := range j.Places {
+		timeline = append(timeline, TimelineItem{
+			Type:      "place",
+			Place:     &j.Places[i],
+			Timestamp: j.Places[i].VisitDate,
+		})
+	}
+
+	sort.SliceStable(timeline, func(a, b int) bool {
+		return timeline[a].Timestamp < timeline[b].Timestamp
+	})
+	return timeline
+}
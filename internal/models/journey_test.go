@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJourneyContains(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2025-04-01 to 2025-04-10
+	journey := Journey{
+		StartDate: uint32(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		EndDate:   uint32(time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC).Unix()),
+	}
+	departing := func(at time.Time) Trip {
+		trip := Trip{DepartureTime: uint32(at.Unix())}
+		if at.Location() != time.UTC {
+			trip.DepartureTimezone = ptr(at.Location().String())
+		}
+		return trip
+	}
+
+	trips := []struct {
+		name string
+		trip Trip
+		want bool
+	}{
+		{"last day in New York, the day after in UTC", departing(time.Date(2025, 4, 10, 20, 0, 0, 0, newYork)), true},
+		{"day before in New York, first day in UTC", departing(time.Date(2025, 3, 31, 22, 0, 0, 0, newYork)), false},
+		{"first day in Tokyo, the day before in UTC", departing(time.Date(2025, 4, 1, 8, 0, 0, 0, tokyo)), true},
+		{"day after in Tokyo, last day in UTC", departing(time.Date(2025, 4, 11, 7, 0, 0, 0, tokyo)), false},
+		{"last minute of the last day", departing(time.Date(2025, 4, 10, 23, 59, 0, 0, tokyo)), true},
+		{"no timezone, read in UTC", departing(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)), true},
+		{"no timezone, the day after", departing(time.Date(2025, 4, 11, 0, 0, 0, 0, time.UTC)), false},
+		{"unknown timezone, read in UTC", Trip{DepartureTime: uint32(time.Date(2025, 3, 31, 23, 0, 0, 0, time.UTC).Unix()), DepartureTimezone: ptr("Nowhere/Special")}, false},
+	}
+	for _, test := range trips {
+		if got := journey.ContainsTrip(test.trip); got != test.want {
+			t.Errorf("%s: ContainsTrip = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	for day, want := range map[int]bool{31: false, 1: true, 10: true, 11: false} {
+		month := time.April
+		if day == 31 {
+			month = time.March
+		}
+		place := Place{VisitDate: uint32(time.Date(2025, month, day, 0, 0, 0, 0, time.UTC).Unix())}
+		if got := journey.ContainsPlace(place); got != want {
+			t.Errorf("place visited %s: ContainsPlace = %v, want %v", time.Unix(int64(place.VisitDate), 0).UTC().Format(time.DateOnly), got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+// ListCursor is the position after the last record of a page: records are
+// listed by time, ties broken by id
+type ListCursor struct {
+	Time uint32
+	ID   int
+}
+
+// TripFilter narrows a list of trips, zero values do not filter
+type TripFilter struct {
+	From    uint32 // Earliest departure time
+	To      uint32 // Latest departure time
+	Airport string // IATA code of the departure or arrival airport
+	Airline string // Matched without case
+	After   *ListCursor
+	Limit   int
+}
+
+// PlaceFilter narrows a list of places, zero values do not filter
+type PlaceFilter struct {
+	From     uint32 // Earliest visit date
+	To       uint32 // Latest visit date
+	Category string
+	After    *ListCursor
+	Limit    int
+}
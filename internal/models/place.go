@@ -14,6 +14,7 @@ type Place struct {
 	MarkerColor string  `json:"marker_color"`
 	CreatedAt   uint32  `json:"created_at"`
 	UpdatedAt   uint32  `json:"updated_at"`
+	DeletedAt   *uint32 `json:"deleted_at,omitempty"` // Set while the place is in the trash
 }
 
 // For combining places and trips in the timeline
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlaceCSVColumns is the header of a places export. visit_date is a calendar
+// date (VisitDateLayout) like in the place form, created_at and updated_at are
+// RFC 3339 and ignored on import.
+var PlaceCSVColumns = []string{
+	"id",
+	"place_id",
+	"name",
+	"address",
+	"latitude",
+	"longitude",
+	"visit_date",
+	"category",
+	"notes",
+	"marker_color",
+	"created_at",
+	"updated_at",
+}
+
+const VisitDateLayout = "2006-01-02"
+
+// DefaultMarkerColor is used for places saved without a marker color
+const DefaultMarkerColor = "#26e0b0"
+
+var markerColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// WritePlacesCSV writes places in the PlaceCSVColumns format
+func WritePlacesCSV(w io.Writer, places []Place) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(PlaceCSVColumns); err != nil {
+		return err
+	}
+
+	for _, place := range places {
+		err := writer.Write([]string{
+			strconv.Itoa(place.ID),
+			place.PlaceID,
+			place.Name,
+			stringValue(place.Address),
+			strconv.FormatFloat(place.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(place.Longitude, 'f', -1, 64),
+			time.Unix(int64(place.VisitDate), 0).UTC().Format(VisitDateLayout),
+			stringValue(place.Category),
+			stringValue(place.Notes),
+			place.MarkerColor,
+			time.Unix(int64(place.CreatedAt), 0).UTC().Format(time.RFC3339),
+			time.Unix(int64(place.UpdatedAt), 0).UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// PlaceCSVRow is a place read from a CSV file
+type PlaceCSVRow struct {
+	Line  int
+	Place Place
+}
+
+// ReadPlacesCSV reads a file in the PlaceCSVColumns format, see ReadTripsCSV
+func ReadPlacesCSV(r io.Reader) ([]PlaceCSVRow, []ImportRowError, error) {
+	reader, index, err := newCSVReader(r, []string{"name", "latitude", "longitude", "visit_date"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []PlaceCSVRow
+	var rowErrors []ImportRowError
+	for {
+		record, line, err := readCSVRecord(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+
+		place, err := readPlaceRecord(index.getter(record))
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, PlaceCSVRow{Line: line, Place: place})
+	}
+
+	return rows, rowErrors, nil
+}
+
+func readPlaceRecord(get func(string) string) (Place, error) {
+	place := Place{
+		PlaceID:     get("place_id"),
+		Name:        get("name"),
+		Address:     optionalString(get("address")),
+		Category:    optionalString(get("category")),
+		Notes:       optionalString(get("notes")),
+		MarkerColor: get("marker_color"),
+	}
+	if place.Name == "" {
+		return place, errors.New("name is required")
+	}
+
+	var err error
+	if place.ID, err = optionalInt(get("id")); err != nil {
+		return place, err
+	}
+	if place.Latitude, err = strconv.ParseFloat(get("latitude"), 64); err != nil || place.Latitude < -90 || place.Latitude > 90 {
+		return place, fmt.Errorf("invalid latitude %q", get("latitude"))
+	}
+	if place.Longitude, err = strconv.ParseFloat(get("longitude"), 64); err != nil || place.Longitude < -180 || place.Longitude > 180 {
+		return place, fmt.Errorf("invalid longitude %q", get("longitude"))
+	}
+
+	visitDate, err := time.Parse(VisitDateLayout, get("visit_date"))
+	if err != nil {
+		return place, fmt.Errorf("invalid visit date %q, use %s", get("visit_date"), VisitDateLayout)
+	}
+	place.VisitDate = uint32(visitDate.Unix())
+
+	if place.MarkerColor == "" {
+		place.MarkerColor = DefaultMarkerColor
+	}
+	if !markerColorPattern.MatchString(place.MarkerColor) {
+		return place, fmt.Errorf("invalid marker color %q, use a hex color such as %s", place.MarkerColor, DefaultMarkerColor)
+	}
+	if place.Category != nil {
+		category := strings.ToLower(*place.Category)
+		place.Category = &category
+	}
+
+	return place, nil
+}
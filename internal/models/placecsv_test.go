@@ -0,0 +1,111 @@
+package models
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var csvPlaces = []Place{
+	{
+		ID:          3,
+		PlaceID:     "ChIJ51cu8IcbXWARiRtXIothAS4",
+		Name:        "Shibuya Crossing",
+		Address:     ptr("2 Chome-2-1 Dogenzaka, Shibuya, Tokyo"),
+		Latitude:    35.6595,
+		Longitude:   139.7005,
+		VisitDate:   1743465600, // 2025-04-01
+		Category:    ptr("landmark"),
+		Notes:       ptr("Busy, \"scramble\"\ncrossing"),
+		MarkerColor: "#ff8800",
+		CreatedAt:   1743501600,
+		UpdatedAt:   1743505200,
+	},
+	{
+		ID:          4,
+		Name:        "Nowhere",
+		Latitude:    -45,
+		Longitude:   -180,
+		VisitDate:   1743552000,
+		MarkerColor: DefaultMarkerColor,
+		CreatedAt:   1743501600,
+		UpdatedAt:   1743501600,
+	},
+}
+
+func TestPlacesCSVRoundTrip(t *testing.T) {
+	var exported bytes.Buffer
+	if err := WritePlacesCSV(&exported, csvPlaces); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, rowErrors, err := ReadPlacesCSV(bytes.NewReader(exported.Bytes()))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("reading the export: %v %+v", err, rowErrors)
+	}
+	if len(rows) != len(csvPlaces) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(csvPlaces))
+	}
+
+	var imported []Place
+	for i, row := range rows {
+		want := csvPlaces[i]
+		want.CreatedAt, want.UpdatedAt = 0, 0
+		if !reflect.DeepEqual(row.Place, want) {
+			t.Errorf("row %d:\n got %+v\nwant %+v", i, row.Place, want)
+		}
+		place := row.Place
+		place.CreatedAt, place.UpdatedAt = csvPlaces[i].CreatedAt, csvPlaces[i].UpdatedAt
+		imported = append(imported, place)
+	}
+
+	var reexported bytes.Buffer
+	if err := WritePlacesCSV(&reexported, imported); err != nil {
+		t.Fatal(err)
+	}
+	if reexported.String() != exported.String() {
+		t.Errorf("the export changed after a round trip:\n%s\n%s", exported.String(), reexported.String())
+	}
+}
+
+func TestReadPlacesCSV(t *testing.T) {
+	file := "name,visit_date,longitude,latitude,marker_color,category\n" +
+		"Skytree,2025-04-02,139.81,35.71,,Landmark\n" +
+		",2025-04-02,139.81,35.71,,\n" +
+		"Skytree,2025-04-02,181,35.71,,\n" +
+		"Skytree,2025-04-02,139.81,north,,\n" +
+		"Skytree,02/04/2025,139.81,35.71,,\n" +
+		"Skytree,2025-04-02,139.81,35.71,red,\n"
+
+	rows, rowErrors, err := ReadPlacesCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("read %d rows, want 1: %+v", len(rows), rows)
+	}
+	if place := rows[0].Place; place.VisitDate != 1743552000 || place.MarkerColor != DefaultMarkerColor || *place.Category != "landmark" {
+		t.Errorf("place %+v", place)
+	}
+
+	want := map[int]string{
+		3: "name is required",
+		4: `invalid longitude "181"`,
+		5: `invalid latitude "north"`,
+		6: `invalid visit date "02/04/2025"`,
+		7: `invalid marker color "red"`,
+	}
+	if len(rowErrors) != len(want) {
+		t.Errorf("got %d row errors, want %d: %+v", len(rowErrors), len(want), rowErrors)
+	}
+	for _, rowError := range rowErrors {
+		if message, ok := want[rowError.Line]; !ok || !strings.Contains(rowError.Message, message) {
+			t.Errorf("line %d: %q, want %q", rowError.Line, rowError.Message, message)
+		}
+	}
+
+	if _, _, err := ReadPlacesCSV(strings.NewReader("name,latitude,longitude\nSkytree,35.71,139.81\n")); err == nil {
+		t.Errorf("a file without visit_date was read")
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SearchResult is a trip or place matching a search. Title, Subtitle and Body
+// are split into segments so the matched words can be highlighted.
+type SearchResult struct {
+	EntityType string             `json:"entity_type"`
+	EntityID   int                `json:"entity_id"`
+	Date       uint32             `json:"date"` // Departure time of a trip, visit date of a place
+	Title      []HighlightSegment `json:"title"`
+	Subtitle   []HighlightSegment `json:"subtitle"`
+	Body       []HighlightSegment `json:"body"`
+}
+
+type HighlightSegment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match"`
+}
+
+// SearchTerms splits a query into lower case words. Everything that is not a
+// letter or digit separates words, so "JL-5" searches for "jl" and "5".
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Highlight marks every word in text that starts with one of the terms, which
+// mirrors the prefix matching of the search index
+func Highlight(text string, terms []string) []HighlightSegment {
+	var segments []HighlightSegment
+	add := func(s string, match bool) {
+		if s == "" {
+			return
+		}
+		if n := len(segments); n > 0 && segments[n-1].Match == match {
+			segments[n-1].Text += s
+			return
+		}
+		segments = append(segments, HighlightSegment{Text: s, Match: match})
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			start := i
+			for i < len(runes) && !isWordRune(runes[i]) {
+				i++
+			}
+			add(string(runes[start:i]), false)
+			continue
+		}
+
+		start := i
+		for i < len(runes) && isWordRune(runes[i]) {
+			i++
+		}
+		word := string(runes[start:i])
+		matched := 0
+		for _, term := range terms {
+			if strings.HasPrefix(strings.ToLower(word), term) && len([]rune(term)) > matched {
+				matched = len([]rune(term))
+			}
+		}
+		add(string(runes[start:start+matched]), true)
+		add(string(runes[start+matched:i]), false)
+	}
+	return segments
+}
+
+// MatchesTerms reports whether every term starts a word of one of the texts
+func MatchesTerms(terms []string, texts ...string) bool {
+	var words []string
+	for _, text := range texts {
+		words = append(words, strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !isWordRune(r) })...)
+	}
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -1,7 +1,10 @@
 package models
 
+import "time"
+
 type Session struct {
 	ID        int  `json:"id"`
 	SessionID string `json:"session_id"`
 	UserID    int   `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
\ No newline at end of file
@@ -0,0 +1,10 @@
+package models
+
+// Statistics sums up the user's flights of every year, or of one year
+type Statistics struct {
+	Year      int                  `json:"year,omitempty"` // 0 for every year
+	Totals    TimeSpaceAggregation `json:"totals"`
+	Flights   []FlightAggregation  `json:"flights"` // Per year, or per month of Year
+	Airlines  []AirlineAggregation `json:"airlines"`
+	Countries []CountryAggregation `json:"countries"`
+}
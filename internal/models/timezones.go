@@ -2,7 +2,9 @@ package models
 
 import (
 	"encoding/json"
+	"log"
 	"os"
+	"time"
 )
 
 // Country struct represents the ISO country code and name
@@ -15,4 +17,25 @@ func LoadAirportTimezonesFromFile(path string) error {
         return err
     }
     return json.Unmarshal(file, &AirportTimezoneLookup)
-}
\ No newline at end of file
+}
+
+// AirportLocalToUTC reads the wall clock of local as a time at the airport.
+// timezone is the airport's timezone from the airports table, airports without
+// one fall back to the airport2timezone.json reference. UTC is used when
+// neither is known.
+func AirportLocalToUTC(local time.Time, iataCode string, timezone string) time.Time {
+	if timezone == "" {
+		timezone = AirportTimezoneLookup[iataCode]
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Println("Error loading timezone, defaulting to UTC:", err)
+		loc = time.UTC
+	}
+
+	return time.Date(
+		local.Year(), local.Month(), local.Day(),
+		local.Hour(), local.Minute(), 0, 0, loc,
+	).UTC()
+}
@@ -12,10 +12,54 @@ type Trip struct {
     Reservation          *string `json:"reservation,omitempty"`
     Terminal             *string `json:"terminal,omitempty"`
     Gate                 *string `json:"gate,omitempty"`
+    Seat                 *string `json:"seat,omitempty"`
+    CabinClass           *string `json:"cabin_class,omitempty"` // One of CabinClasses
+    AircraftType         *string `json:"aircraft_type,omitempty"` // e.g. "Boeing 787-9"
+    TailNumber           *string `json:"tail_number,omitempty"` // Aircraft registration, e.g. "JA861J"
+    BookingClass         *string `json:"booking_class,omitempty"` // Fare letter, e.g. "Y" or "J"
+    TicketPrice          *float64 `json:"ticket_price,omitempty"`
+    TicketCurrency       *string `json:"ticket_currency,omitempty"` // ISO 4217 code
     DepartureLat         float64 `json:"departure_lat"`
     DepartureLon         float64 `json:"departure_lon"`
     ArrivalLat           float64 `json:"arrival_lat"`
     ArrivalLon           float64 `json:"arrival_lon"`
-    ArrivalTimezone      *string `json:"arrival_timezone,omitempty"` // Not part of DB, we add this later
-    DepartureTimezone    *string `json:"departure_timezone,omitempty"` // Not part of DB, we add this later via timezone reference map
+    ArrivalTimezone      *string `json:"arrival_timezone,omitempty"` // Joined from airports.timezone
+    DepartureTimezone    *string `json:"departure_timezone,omitempty"` // Joined from airports.timezone, falls back to the timezone reference map
+    DeletedAt            *uint32 `json:"deleted_at,omitempty"` // Set while the trip is in the trash
+    DistanceKm           float64 `json:"distance_km"` // Great-circle distance between the airports, computed on read
+}
+
+const (
+    CabinEconomy        = "economy"
+    CabinPremiumEconomy = "premium_economy"
+    CabinBusiness       = "business"
+    CabinFirst          = "first"
+)
+
+// CabinClasses are the values accepted for Trip.CabinClass, cheapest first
+var CabinClasses = []string{CabinEconomy, CabinPremiumEconomy, CabinBusiness, CabinFirst}
+
+var cabinClassLabels = map[string]string{
+    CabinEconomy:        "Economy",
+    CabinPremiumEconomy: "Premium Economy",
+    CabinBusiness:       "Business",
+    CabinFirst:          "First",
+}
+
+func IsCabinClass(class string) bool {
+    _, ok := cabinClassLabels[class]
+    return ok
+}
+
+// CabinClassLabel returns the display name of a cabin class
+func CabinClassLabel(class string) string {
+    if label, ok := cabinClassLabels[class]; ok {
+        return label
+    }
+    return class
+}
+
+// SetDistance computes DistanceKm from the airport coordinates joined onto the trip
+func (t *Trip) SetDistance() {
+    t.DistanceKm = GreatCircleDistanceKm(t.DepartureLat, t.DepartureLon, t.ArrivalLat, t.ArrivalLon)
 }
@@ -0,0 +1,304 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TripCSVColumns is the header of a trips export. Times are written twice: as
+// UTC in RFC 3339 and as the local wall clock (LocalTimeLayout) with the
+// airport's IANA timezone. The coordinates and distance come from the airports
+// table and are ignored on import.
+var TripCSVColumns = []string{
+	"id",
+	"departure",
+	"arrival",
+	"departure_time",
+	"arrival_time",
+	"departure_local",
+	"departure_timezone",
+	"arrival_local",
+	"arrival_timezone",
+	"airline",
+	"flight_number",
+	"reservation",
+	"terminal",
+	"gate",
+	"seat",
+	"cabin_class",
+	"aircraft_type",
+	"tail_number",
+	"booking_class",
+	"ticket_price",
+	"ticket_currency",
+	"departure_lat",
+	"departure_lon",
+	"arrival_lat",
+	"arrival_lon",
+	"distance_km",
+}
+
+// LocalTimeLayout is the wall clock format of the *_local columns, the same as
+// the datetime-local inputs of the trip form
+const LocalTimeLayout = "2006-01-02T15:04"
+
+// WriteTripsCSV writes trips in the TripCSVColumns format
+func WriteTripsCSV(w io.Writer, trips []Trip) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(TripCSVColumns); err != nil {
+		return err
+	}
+
+	for _, trip := range trips {
+		departureLocal, departureTZ := localTime(trip.DepartureTime, trip.DepartureTimezone)
+		arrivalLocal, arrivalTZ := localTime(trip.ArrivalTime, trip.ArrivalTimezone)
+		price := ""
+		if trip.TicketPrice != nil {
+			price = strconv.FormatFloat(*trip.TicketPrice, 'f', -1, 64)
+		}
+
+		err := writer.Write([]string{
+			strconv.Itoa(trip.ID),
+			trip.Departure,
+			trip.Arrival,
+			time.Unix(int64(trip.DepartureTime), 0).UTC().Format(time.RFC3339),
+			time.Unix(int64(trip.ArrivalTime), 0).UTC().Format(time.RFC3339),
+			departureLocal,
+			departureTZ,
+			arrivalLocal,
+			arrivalTZ,
+			trip.Airline,
+			trip.FlightNumber,
+			stringValue(trip.Reservation),
+			stringValue(trip.Terminal),
+			stringValue(trip.Gate),
+			stringValue(trip.Seat),
+			stringValue(trip.CabinClass),
+			stringValue(trip.AircraftType),
+			stringValue(trip.TailNumber),
+			stringValue(trip.BookingClass),
+			price,
+			stringValue(trip.TicketCurrency),
+			strconv.FormatFloat(trip.DepartureLat, 'f', -1, 64),
+			strconv.FormatFloat(trip.DepartureLon, 'f', -1, 64),
+			strconv.FormatFloat(trip.ArrivalLat, 'f', -1, 64),
+			strconv.FormatFloat(trip.ArrivalLon, 'f', -1, 64),
+			strconv.FormatFloat(trip.DistanceKm, 'f', 0, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// localTime is the wall clock at the airport, in UTC when its timezone is unknown
+func localTime(unix uint32, timezone *string) (string, string) {
+	at := time.Unix(int64(unix), 0).UTC()
+	if timezone != nil {
+		if loc, err := time.LoadLocation(*timezone); err == nil {
+			return at.In(loc).Format(LocalTimeLayout), *timezone
+		}
+	}
+	return at.Format(LocalTimeLayout), "UTC"
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// TripCSVRow is a trip read from a CSV file. A row may give its times as UTC
+// (DepartureTime and ArrivalTime are set) or only as local times, which the
+// importer converts with the airport's timezone.
+type TripCSVRow struct {
+	Line           int
+	Trip           Trip
+	DepartureLocal string
+	ArrivalLocal   string
+}
+
+// ReadTripsCSV reads a file in the TripCSVColumns format. Columns are matched
+// by name, so they may be in any order and columns that are not needed can be
+// left out. Rows that cannot be read are returned as errors, the others are
+// still returned. An error is only returned when the file itself is unusable.
+func ReadTripsCSV(r io.Reader) ([]TripCSVRow, []ImportRowError, error) {
+	reader, index, err := newCSVReader(r, []string{"departure", "arrival"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []TripCSVRow
+	var rowErrors []ImportRowError
+	for {
+		record, line, err := readCSVRecord(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		get := index.getter(record)
+
+		row := TripCSVRow{
+			Line:           line,
+			DepartureLocal: get("departure_local"),
+			ArrivalLocal:   get("arrival_local"),
+			Trip: Trip{
+				Departure:      strings.ToUpper(get("departure")),
+				Arrival:        strings.ToUpper(get("arrival")),
+				Airline:        get("airline"),
+				FlightNumber:   get("flight_number"),
+				Reservation:    optionalString(get("reservation")),
+				Terminal:       optionalString(get("terminal")),
+				Gate:           optionalString(get("gate")),
+				Seat:           optionalString(get("seat")),
+				CabinClass:     optionalString(strings.ToLower(get("cabin_class"))),
+				AircraftType:   optionalString(get("aircraft_type")),
+				TailNumber:     optionalString(strings.ToUpper(get("tail_number"))),
+				BookingClass:   optionalString(strings.ToUpper(get("booking_class"))),
+				TicketCurrency: optionalString(strings.ToUpper(get("ticket_currency"))),
+			},
+		}
+		if row.Trip.Departure == "" || row.Trip.Arrival == "" {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: "departure and arrival are required"})
+			continue
+		}
+
+		var rowErr error
+		row.Trip.ID, rowErr = optionalInt(get("id"))
+		if rowErr == nil {
+			row.Trip.DepartureTime, rowErr = optionalRFC3339(get("departure_time"))
+		}
+		if rowErr == nil {
+			row.Trip.ArrivalTime, rowErr = optionalRFC3339(get("arrival_time"))
+		}
+		if rowErr == nil {
+			row.Trip.TicketPrice, rowErr = optionalPrice(get("ticket_price"))
+		}
+		if rowErr == nil && row.Trip.CabinClass != nil && !IsCabinClass(*row.Trip.CabinClass) {
+			rowErr = fmt.Errorf("unknown cabin class %q", *row.Trip.CabinClass)
+		}
+		if rowErr == nil {
+			if tz := get("departure_timezone"); tz != "" {
+				row.Trip.DepartureTimezone = &tz
+			}
+			if tz := get("arrival_timezone"); tz != "" {
+				row.Trip.ArrivalTimezone = &tz
+			}
+		}
+		if rowErr != nil {
+			rowErrors = append(rowErrors, ImportRowError{Line: line, Message: rowErr.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// csvIndex maps lower case column names to their position
+type csvIndex map[string]int
+
+// newCSVReader reads the header and checks the required columns are present
+func newCSVReader(r io.Reader, required []string) (*csv.Reader, csvIndex, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	index := make(csvIndex)
+	for i, column := range header {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+	for _, column := range required {
+		if _, ok := index[column]; !ok {
+			return nil, nil, fmt.Errorf("missing required column %q", column)
+		}
+	}
+	return reader, index, nil
+}
+
+// readCSVRecord returns the next record and the line it starts on, quoted
+// values may span several lines
+func readCSVRecord(reader *csv.Reader) ([]string, int, error) {
+	record, err := reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, parseErr.StartLine, errors.New(parseErr.Err.Error())
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	line, _ := reader.FieldPos(0)
+	return record, line, nil
+}
+
+func (index csvIndex) getter(record []string) func(string) string {
+	return func(column string) string {
+		if i, ok := index[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func optionalInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid id %q", value)
+	}
+	return n, nil
+}
+
+func optionalRFC3339(value string) (uint32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use RFC 3339 such as 2025-04-01T10:00:00Z", value)
+	}
+	return uint32(at.Unix()), nil
+}
+
+func optionalPrice(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(price >= 0) || math.IsInf(price, 0) {
+		return nil, fmt.Errorf("invalid ticket price %q", value)
+	}
+	return &price, nil
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// csvTrips covers every column, text that needs quoting and a timezone that
+// is not known, which is written in UTC
+var csvTrips = []Trip{
+	{
+		ID:                7,
+		Departure:         "JFK",
+		Arrival:           "NRT",
+		DepartureTime:     1743501600, // 2025-04-01 10:00 UTC
+		ArrivalTime:       1743552000,
+		Airline:           "Test Air, Inc.",
+		FlightNumber:      "TA1",
+		Reservation:       ptr("ABC123"),
+		Terminal:          ptr("4"),
+		Gate:              ptr("B22"),
+		Seat:              ptr("31K"),
+		CabinClass:        ptr(CabinBusiness),
+		AircraftType:      ptr(`Boeing 787-9 "Dreamliner"`),
+		TailNumber:        ptr("JA861J"),
+		BookingClass:      ptr("J"),
+		TicketPrice:       ptr(1234.5),
+		TicketCurrency:    ptr("USD"),
+		DepartureTimezone: ptr("America/New_York"),
+		ArrivalTimezone:   ptr("Asia/Tokyo"),
+		DepartureLat:      40.64,
+		DepartureLon:      -73.78,
+		ArrivalLat:        35.76,
+		ArrivalLon:        140.39,
+		DistanceKm:        10848,
+	},
+	{
+		ID:            8,
+		Departure:     "NRT",
+		Arrival:       "HND",
+		DepartureTime: 1743570000,
+		ArrivalTime:   1743573600,
+		Airline:       "Line\nbreak Air",
+		DepartureLat:  35.76,
+		DepartureLon:  140.39,
+		ArrivalLat:    35.55,
+		ArrivalLon:    139.78,
+		DistanceKm:    58,
+	},
+}
+
+// TestTripsCSVRoundTrip exports, imports and exports again, the files must be
+// the same and the trips must only lose the columns that are export only
+func TestTripsCSVRoundTrip(t *testing.T) {
+	var exported bytes.Buffer
+	if err := WriteTripsCSV(&exported, csvTrips); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, rowErrors, err := ReadTripsCSV(bytes.NewReader(exported.Bytes()))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("reading the export: %v %+v", err, rowErrors)
+	}
+	if len(rows) != len(csvTrips) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(csvTrips))
+	}
+
+	if rows[0].Line != 2 || rows[1].Line != 3 {
+		t.Errorf("rows are on lines %d and %d, want 2 and 3", rows[0].Line, rows[1].Line)
+	}
+
+	var imported []Trip
+	for i, row := range rows {
+		want := csvTrips[i]
+		want.DepartureLat, want.DepartureLon, want.ArrivalLat, want.ArrivalLon, want.DistanceKm = 0, 0, 0, 0, 0
+		if want.DepartureTimezone == nil {
+			want.DepartureTimezone, want.ArrivalTimezone = ptr("UTC"), ptr("UTC")
+		}
+		if !reflect.DeepEqual(row.Trip, want) {
+			t.Errorf("row %d:\n got %+v\nwant %+v", i, row.Trip, want)
+		}
+
+		trip := row.Trip
+		trip.DepartureLat, trip.DepartureLon = csvTrips[i].DepartureLat, csvTrips[i].DepartureLon
+		trip.ArrivalLat, trip.ArrivalLon = csvTrips[i].ArrivalLat, csvTrips[i].ArrivalLon
+		trip.DistanceKm = csvTrips[i].DistanceKm
+		imported = append(imported, trip)
+	}
+
+	var reexported bytes.Buffer
+	if err := WriteTripsCSV(&reexported, imported); err != nil {
+		t.Fatal(err)
+	}
+	if reexported.String() != exported.String() {
+		t.Errorf("the export changed after a round trip:\n%s\n%s", exported.String(), reexported.String())
+	}
+	if !strings.Contains(exported.String(), "2025-04-01T06:00,America/New_York,") {
+		t.Errorf("the local departure time is not in New York:\n%s", exported.String())
+	}
+}
+
+func TestReadTripsCSV(t *testing.T) {
+	file := "\ufeffArrival,Departure,departure_local,arrival_time,departure_time,cabin_class,ticket_price,id,tail_number\n" +
+		"nrt,jfk,2025-04-01T06:00,2025-04-02T00:00:00Z,,Business,,,ja861j\n" + // local time only, lower case
+		"NRT,,,,,,,,\n" +
+		"NRT,JFK,,,yesterday,,,,\n" +
+		"NRT,JFK,,,,steerage,,,\n" +
+		"NRT,JFK,,,,,-3,,\n" +
+		"NRT,JFK,,,,,,x,\n" +
+		"NRT,JFK,,\"unterminated,\n"
+
+	rows, rowErrors, err := ReadTripsCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("read %d rows, want 1: %+v", len(rows), rows)
+	}
+	row := rows[0]
+	if row.Trip.Departure != "JFK" || row.Trip.Arrival != "NRT" || row.DepartureLocal != "2025-04-01T06:00" ||
+		row.Trip.DepartureTime != 0 || row.Trip.ArrivalTime != 1743552000 ||
+		*row.Trip.CabinClass != CabinBusiness || *row.Trip.TailNumber != "JA861J" {
+		t.Errorf("row %+v %+v", row, row.Trip)
+	}
+
+	want := map[int]string{
+		3: "departure and arrival are required",
+		4: `invalid time "yesterday"`,
+		5: `unknown cabin class "steerage"`,
+		6: `invalid ticket price "-3"`,
+		7: `invalid id "x"`,
+		8: "quote",
+	}
+	if len(rowErrors) != len(want) {
+		t.Errorf("got %d row errors, want %d: %+v", len(rowErrors), len(want), rowErrors)
+	}
+	for _, rowError := range rowErrors {
+		if message, ok := want[rowError.Line]; !ok || !strings.Contains(rowError.Message, message) {
+			t.Errorf("line %d: %q, want %q", rowError.Line, rowError.Message, message)
+		}
+	}
+
+	for name, file := range map[string]string{
+		"empty":           "",
+		"missing arrival": "departure,departure_time\nJFK,2025-04-01T10:00:00Z\n",
+	} {
+		if _, _, err := ReadTripsCSV(strings.NewReader(file)); err == nil {
+			t.Errorf("%s file: no error", name)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+// TripDraft is a flight read from a forwarded confirmation email, waiting for
+// the user to confirm it. Position is the order of the flight in the email.
+type TripDraft struct {
+	ID             int    `json:"id"`
+	UserID         int    `json:"user_id"`
+	Trip           Trip   `json:"trip"`            // Departure and Arrival hold the codes from the email
+	DepartureLocal string `json:"departure_local"` // LocalTimeLayout
+	ArrivalLocal   string `json:"arrival_local"`   // Empty when the email did not give it
+	Subject        string `json:"subject"`
+	Sender         string `json:"sender"`
+	MessageID      string `json:"message_id"`
+	Position       int    `json:"position"`
+	CreatedAt      uint32 `json:"created_at"`
+}
+
+// MailInbox is the secret forwarding address of a user. The token is the
+// local part of the address and only known right after it has been generated.
+type MailInbox struct {
+	UserID    int    `json:"user_id"`
+	Token     string `json:"token,omitempty"`
+	CreatedAt uint32 `json:"created_at"`
+}
+
+// Address is the forwarding address on the instance's mail domain
+func (i MailInbox) Address(domain string) string {
+	return i.Token + "@" + domain
+}
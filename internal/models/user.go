@@ -12,4 +12,5 @@ type User struct {
     GoogleID     string `json:"google_id"`
     AuthProvider string `json:"auth_provider"`
     CreatedAt    time.Time `json:"created_at"`
+    MaxLayoverMinutes int `json:"max_layover_minutes"` // Longest layover for flights to be chained into an itinerary
 }
\ No newline at end of file
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Webhook events, named <entity>.<what happened>. Moving a record to the
+// trash is a delete, restoring or reverting it an update.
+const (
+	EventTripCreated  = "trip.created"
+	EventTripUpdated  = "trip.updated"
+	EventTripDeleted  = "trip.deleted"
+	EventPlaceCreated = "place.created"
+	EventPlaceUpdated = "place.updated"
+	EventPlaceDeleted = "place.deleted"
+)
+
+// WebhookEvents lists the events in the order they are offered
+var WebhookEvents = []string{EventTripCreated, EventTripUpdated, EventTripDeleted, EventPlaceCreated, EventPlaceUpdated, EventPlaceDeleted}
+
+func IsWebhookEvent(event string) bool {
+	return slices.Contains(WebhookEvents, event)
+}
+
+// WebhookEventFor returns the event of a change to a trip or place
+func WebhookEventFor(entityType, action string) (string, bool) {
+	var what string
+	switch action {
+	case ChangeCreate:
+		what = "created"
+	case ChangeUpdate, ChangeRestore, ChangeRevert:
+		what = "updated"
+	case ChangeDelete:
+		what = "deleted"
+	default:
+		return "", false
+	}
+	event := entityType + "." + what
+	return event, IsWebhookEvent(event)
+}
+
+// Webhook is an endpoint of the user that is sent the events it subscribed
+// to. Secret signs every request and is only shown when the webhook is created.
+type Webhook struct {
+	ID        int      `json:"id"`
+	UserID    int      `json:"user_id"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"`
+	Events    []string `json:"events"`
+	CreatedAt uint32   `json:"created_at"`
+}
+
+func (w Webhook) Subscribed(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // No attempts are left
+)
+
+// WebhookDelivery is one event queued for one webhook together with the
+// outcome of its latest attempt
+type WebhookDelivery struct {
+	ID             int     `json:"id"`
+	WebhookID      int     `json:"webhook_id"`
+	Event          string  `json:"event"`
+	Payload        string  `json:"payload"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	NextAttemptAt  *uint32 `json:"next_attempt_at,omitempty"` // Set while pending
+	LastAttemptAt  *uint32 `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int    `json:"response_status,omitempty"`
+	Error          *string `json:"error,omitempty"` // Why the latest attempt failed
+	CreatedAt      uint32  `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body of every webhook request. ID is the same
+// for every endpoint and every retry of an event, receivers use it to drop
+// duplicates. Data is the trip or place as stored after the change.
+type WebhookPayload struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt uint32          `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
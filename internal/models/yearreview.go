@@ -0,0 +1,20 @@
+package models
+
+// YearInReview is what the year in review card shows for one calendar year (UTC)
+type YearInReview struct {
+	Year            int                  `json:"year"`
+	Name            string               `json:"name"`              // First name of the traveller, may be empty
+	Flights         int                  `json:"flights"`           // Flights departing in the year
+	FlightsPerMonth []FlightAggregation  `json:"flights_per_month"` // Twelve months, January first
+	Countries       []CountryAggregation `json:"countries"`         // ISO codes of the arrival countries
+	Totals          TimeSpaceAggregation `json:"totals"`
+}
+
+// YearReviewShare is the public link to a year in review card. The token
+// itself is only known right after it has been generated.
+type YearReviewShare struct {
+	UserID    int    `json:"user_id"`
+	Year      int    `json:"year"`
+	Token     string `json:"token,omitempty"`
+	CreatedAt uint32 `json:"created_at"`
+}
@@ -0,0 +1,321 @@
+// Package openapi builds OpenAPI 3 documents. Operations are declared in code
+// and the schemas of request and response bodies are read from the Go types
+// with reflection, so the document follows the json tags of the models.
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	types      map[reflect.Type]string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Method      string               `json:"-"`
+	Path        string               `json:"-"`
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Security lists the accepted schemes with their scopes, an empty list
+	// means the operation is public
+	Security []SecurityRequirement `json:"security"`
+}
+
+type SecurityRequirement map[string][]string
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]SecurityScheme),
+		},
+		types: make(map[reflect.Type]string),
+	}
+}
+
+// Component adds the schema of v's type under name. Other schemas refer to
+// the type by name from then on, so components are added before the types
+// that hold them.
+func (d *Document) Component(name string, v any) *Schema {
+	t := reflect.TypeOf(v)
+	schema := d.schema(t)
+	d.Components.Schemas[name] = schema
+	d.types[t] = name
+	return schema
+}
+
+// Property returns a property of a component to add what the type does not
+// say, e.g. an enum or a description
+func (d *Document) Property(component, name string) *Schema {
+	schema, ok := d.Components.Schemas[component]
+	if !ok || schema.Properties[name] == nil {
+		panic(fmt.Sprintf("openapi: %s has no property %s", component, name))
+	}
+	return schema.Properties[name]
+}
+
+// Schema returns the schema of v's type, a reference when it is a component
+func (d *Document) Schema(v any) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+// Ref returns a reference to a component
+func Ref(component string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + component}
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	if name, ok := d.types[t]; ok {
+		return Ref(name)
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := d.schema(t.Elem())
+		if schema.Ref != "" {
+			// Siblings of $ref are ignored in OpenAPI 3.0
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		d.addFields(schema, t)
+		sort.Strings(schema.Required)
+		return schema
+	default:
+		// Interfaces and anything else can hold any value
+		return &Schema{}
+	}
+}
+
+// addFields adds the fields encoding/json writes for t. Fields that are
+// pointers or omitempty can be left out and are not required.
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = d.schema(field.Type)
+		omitEmpty := strings.Contains(","+options+",", ",omitempty,")
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// Add adds an operation, an operation for the same method and path replaces it
+func (d *Document) Add(op Operation) {
+	item, ok := d.Paths[op.Path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[op.Path] = item
+	}
+	if op.Security == nil {
+		op.Security = []SecurityRequirement{}
+	}
+	item[strings.ToLower(op.Method)] = &op
+}
+
+// Has reports whether the document describes the method on the path
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[path][strings.ToLower(method)]
+	return ok
+}
+
+// methodOrder sorts the operations of a path like a reader expects them
+var methodOrder = map[string]int{"get": 0, "post": 1, "put": 2, "patch": 3, "delete": 4}
+
+// Operations returns every operation sorted by path, then method
+func (d *Document) Operations() []*Operation {
+	var ops []*Operation
+	for _, item := range d.Paths {
+		for _, op := range item {
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return methodOrder[strings.ToLower(ops[i].Method)] < methodOrder[strings.ToLower(ops[j].Method)]
+	})
+	return ops
+}
+
+// Resolve follows a reference to its component, other schemas are returned
+// as they are
+func (d *Document) Resolve(schema *Schema) *Schema {
+	if schema == nil || schema.Ref == "" {
+		return schema
+	}
+	if component, ok := d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]; ok {
+		return component
+	}
+	return schema
+}
+
+// RefName returns the component a schema refers to, empty when it is inline
+func RefName(schema *Schema) string {
+	if schema == nil {
+		return ""
+	}
+	return strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+}
+
+// TypeName describes a schema in a few words, e.g. "array of Trip" or
+// "integer (int64)"
+func TypeName(schema *Schema) string {
+	switch {
+	case schema == nil:
+		return ""
+	case schema.Ref != "":
+		return RefName(schema)
+	case schema.Type == "array":
+		return "array of " + TypeName(schema.Items)
+	case schema.Type == "object" && schema.AdditionalProperties != nil:
+		return "map of " + TypeName(schema.AdditionalProperties)
+	case schema.Type == "":
+		return "any"
+	case schema.Format != "":
+		return schema.Type + " (" + schema.Format + ")"
+	}
+	return schema.Type
+}
+
+// Float is a helper for Minimum and Maximum
+func Float(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testLeg struct {
+	From string `json:"from"`
+}
+
+type testBase struct {
+	ID int `json:"id"`
+}
+
+type testRecord struct {
+	testBase
+	Name     string            `json:"name"`
+	Note     *string           `json:"note,omitempty"`
+	Score    float32           `json:"score,omitempty"`
+	Legs     []testLeg         `json:"legs"`
+	Labels   map[string]string `json:"labels"`
+	Secret   string            `json:"-"`
+	Untagged bool
+	hidden   int
+}
+
+func TestComponentSchema(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Component("Leg", testLeg{})
+	record := doc.Component("Record", testRecord{})
+
+	if record.Type != "object" {
+		t.Fatalf("type %q, want object", record.Type)
+	}
+	var names []string
+	for name := range record.Properties {
+		names = append(names, name)
+	}
+	if len(names) != 7 {
+		t.Errorf("properties %v, want id, name, note, score, legs, labels and Untagged", names)
+	}
+	if want := []string{"Untagged", "id", "labels", "legs", "name"}; !reflect.DeepEqual(record.Required, want) {
+		t.Errorf("required %v, want %v", record.Required, want)
+	}
+
+	if note := record.Properties["note"]; note.Type != "string" || !note.Nullable {
+		t.Errorf("note is %+v, want a nullable string", note)
+	}
+	if score := record.Properties["score"]; score.Type != "number" || score.Format != "float" {
+		t.Errorf("score is %+v, want a float", score)
+	}
+	if id := record.Properties["id"]; id.Type != "integer" {
+		t.Errorf("embedded id is %+v, want an integer", id)
+	}
+	if legs := record.Properties["legs"]; legs.Type != "array" || legs.Items.Ref != "#/components/schemas/Leg" {
+		t.Errorf("legs is %+v, want an array of Leg", legs)
+	}
+	if labels := record.Properties["labels"]; labels.Type != "object" || labels.AdditionalProperties.Type != "string" {
+		t.Errorf("labels is %+v, want a map of strings", labels)
+	}
+	if got := TypeName(doc.Schema([]testRecord{})); got != "array of Record" {
+		t.Errorf("TypeName of []testRecord is %q", got)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add(Operation{Method: "DELETE", Path: "/b", OperationID: "deleteB"})
+	doc.Add(Operation{Method: "GET", Path: "/b", OperationID: "getB"})
+	doc.Add(Operation{Method: "POST", Path: "/a", OperationID: "postA"})
+
+	var ids []string
+	for _, op := range doc.Operations() {
+		ids = append(ids, op.OperationID)
+	}
+	if want := []string{"postA", "getB", "deleteB"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("operations %v, want %v", ids, want)
+	}
+	if !doc.Has("GET", "/b") || doc.Has("GET", "/a") {
+		t.Errorf("Has does not match the added operations")
+	}
+	if doc.Paths["/a"]["post"].Security == nil {
+		t.Errorf("a public operation must have an empty security list, not inherit one")
+	}
+}
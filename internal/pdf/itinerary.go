@@ -0,0 +1,273 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Itinerary is the content of an itinerary document. Each of Groups is a
+// chain of connecting flights, a flight without connections is a group of one.
+type Itinerary struct {
+	Title  string
+	Groups []m.Itinerary
+	// Airports looks up the airport names and timezones, it may be nil
+	Airports func(code string) (m.Airport, bool)
+}
+
+const (
+	margin     = 48.0
+	bottom     = PageHeight - 56
+	legHeight  = 100.0
+	groupSpace = 18.0
+)
+
+var (
+	titleStyle   = Style{Font: HelveticaBold, Size: 20, Color: Black}
+	headingStyle = Style{Font: HelveticaBold, Size: 12, Color: White}
+	flightStyle  = Style{Font: HelveticaBold, Size: 11, Color: Black}
+	codeStyle    = Style{Font: HelveticaBold, Size: 18, Color: Black}
+	timeStyle    = Style{Font: HelveticaBold, Size: 11, Color: Black}
+	textStyle    = Style{Font: Helvetica, Size: 9, Color: Black}
+	noteStyle    = Style{Font: Helvetica, Size: 9, Color: Gray}
+	labelStyle   = Style{Font: Helvetica, Size: 7, Color: Gray}
+
+	accent = Color{16, 122, 96}
+	shade  = Color{238, 244, 242}
+	rule   = Color{200, 200, 200}
+)
+
+// WriteItinerary writes the itinerary as a PDF with every leg, its local
+// departure and arrival times, terminals, gates, seats and reservation codes,
+// and the layover between connecting legs.
+func WriteItinerary(w io.Writer, itinerary Itinerary, now time.Time) error {
+	doc := New(itinerary.Title)
+	layout := &itineraryLayout{doc: doc, airports: itinerary.Airports}
+	layout.page = doc.AddPage()
+
+	layout.page.Text(margin, margin+20, titleStyle, Truncate(titleStyle, itinerary.Title, PageWidth-2*margin))
+	layout.page.Text(margin, margin+38, noteStyle, itinerarySummary(itinerary.Groups))
+	layout.y = margin + 62
+
+	if len(itinerary.Groups) == 0 {
+		layout.page.Text(margin, layout.y, textStyle, "There are no flights in this itinerary.")
+	}
+	for _, group := range itinerary.Groups {
+		layout.group(group)
+	}
+
+	pages := doc.Pages()
+	for i, page := range pages {
+		footer := fmt.Sprintf("Generated %s · times are local to each airport", now.UTC().Format("2 Jan 2006 15:04 MST"))
+		page.Line(margin, PageHeight-40, PageWidth-margin, PageHeight-40, 0.5, rule)
+		page.Text(margin, PageHeight-28, noteStyle, footer)
+		page.TextRight(PageWidth-margin, PageHeight-28, noteStyle, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+	return doc.Write(w, now)
+}
+
+// itinerarySummary is the date range and number of flights under the title
+func itinerarySummary(groups []m.Itinerary) string {
+	var first, last *m.Trip
+	flights := 0
+	for g := range groups {
+		for l := range groups[g].Legs {
+			leg := &groups[g].Legs[l]
+			if first == nil || leg.DepartureTime < first.DepartureTime {
+				first = leg
+			}
+			if last == nil || leg.ArrivalTime > last.ArrivalTime {
+				last = leg
+			}
+			flights++
+		}
+	}
+	if flights == 0 {
+		return ""
+	}
+	start := time.Unix(int64(first.DepartureTime), 0).UTC()
+	end := time.Unix(int64(last.ArrivalTime), 0).UTC()
+	noun := "flights"
+	if flights == 1 {
+		noun = "flight"
+	}
+	if start.Format("2006-01-02") == end.Format("2006-01-02") {
+		return fmt.Sprintf("%s · %d %s", start.Format("2 Jan 2006"), flights, noun)
+	}
+	return fmt.Sprintf("%s – %s · %d %s", start.Format("2 Jan 2006"), end.Format("2 Jan 2006"), flights, noun)
+}
+
+type itineraryLayout struct {
+	doc      *Document
+	page     *Page
+	y        float64
+	airports func(code string) (m.Airport, bool)
+}
+
+// need starts a new page unless height fits below the cursor
+func (l *itineraryLayout) need(height float64) {
+	if l.y+height > bottom {
+		l.page = l.doc.AddPage()
+		l.y = margin
+	}
+}
+
+func (l *itineraryLayout) group(group m.Itinerary) {
+	if len(group.Legs) == 0 {
+		return
+	}
+	l.need(28 + legHeight)
+
+	heading := group.Origin() + " to " + group.Destination()
+	if stops := group.Stops(); len(stops) > 0 {
+		heading += " via " + strings.Join(stops, ", ")
+	}
+	first, last := group.Legs[0], group.Legs[len(group.Legs)-1]
+	total := time.Duration(int64(last.ArrivalTime)-int64(first.DepartureTime)) * time.Second
+	l.page.Rect(margin, l.y, PageWidth-2*margin, 24, accent)
+	l.page.Text(margin+10, l.y+16, headingStyle, heading)
+	l.page.TextRight(PageWidth-margin-10, l.y+16, headingStyle, "Total "+formatDuration(total))
+	l.y += 24
+
+	for i, leg := range group.Legs {
+		if i > 0 {
+			l.need(24 + legHeight)
+			l.layover(leg.Departure, group.Layover(i-1))
+		} else {
+			l.need(legHeight)
+		}
+		l.leg(leg)
+	}
+	l.y += groupSpace
+}
+
+func (l *itineraryLayout) layover(airport string, layover time.Duration) {
+	l.page.Rect(margin, l.y, PageWidth-2*margin, 20, shade)
+	text := fmt.Sprintf("Layover in %s: %s", airport, formatDuration(layover))
+	width := TextWidth(textStyle, text)
+	l.page.Text((PageWidth-width)/2, l.y+13, textStyle, text)
+	l.y += 20
+}
+
+// leg draws one flight: the flight and airline on top, the departure and
+// arrival in two columns and the booking details on the right
+func (l *itineraryLayout) leg(trip m.Trip) {
+	top := l.y
+	left, right := margin+10, PageWidth-margin-10
+
+	flight := strings.TrimSpace(trip.FlightNumber)
+	if flight == "" {
+		flight = "Flight"
+	}
+	if trip.Airline != "" && !strings.EqualFold(trip.Airline, flight) {
+		flight += " · " + trip.Airline
+	}
+	duration := time.Duration(int64(trip.ArrivalTime)-int64(trip.DepartureTime)) * time.Second
+	l.page.Text(left, top+18, flightStyle, Truncate(flightStyle, flight, 300))
+	l.page.TextRight(right, top+18, noteStyle, formatDuration(duration)+" flight")
+
+	columns := []struct {
+		label    string
+		airport  string
+		unix     uint32
+		timezone *string
+		x        float64
+	}{
+		{"DEPART", trip.Departure, trip.DepartureTime, trip.DepartureTimezone, left},
+		{"ARRIVE", trip.Arrival, trip.ArrivalTime, trip.ArrivalTimezone, left + 160},
+	}
+	for _, column := range columns {
+		at := l.localTime(column.unix, column.airport, column.timezone)
+		l.page.Text(column.x, top+34, labelStyle, column.label)
+		l.page.Text(column.x, top+52, codeStyle, column.airport)
+		l.page.Text(column.x+TextWidth(codeStyle, column.airport)+6, top+52, timeStyle, at.Format("15:04 MST"))
+		l.page.Text(column.x, top+65, textStyle, at.Format("Mon 2 Jan 2006"))
+		l.page.Text(column.x, top+77, noteStyle, Truncate(noteStyle, l.airportName(column.airport, ""), 150))
+	}
+	if trip.Terminal != nil && *trip.Terminal != "" || trip.Gate != nil && *trip.Gate != "" {
+		var where []string
+		if trip.Terminal != nil && *trip.Terminal != "" {
+			where = append(where, "Terminal "+*trip.Terminal)
+		}
+		if trip.Gate != nil && *trip.Gate != "" {
+			where = append(where, "Gate "+*trip.Gate)
+		}
+		l.page.Text(left, top+88, textStyle, strings.Join(where, " · "))
+	}
+
+	type detail struct{ label, value string }
+	var details []detail
+	if trip.Reservation != nil && *trip.Reservation != "" {
+		details = append(details, detail{"RESERVATION", *trip.Reservation})
+	}
+	if trip.Seat != nil && *trip.Seat != "" {
+		details = append(details, detail{"SEAT", *trip.Seat})
+	}
+	if trip.CabinClass != nil && *trip.CabinClass != "" {
+		cabin := m.CabinClassLabel(*trip.CabinClass)
+		if trip.BookingClass != nil && *trip.BookingClass != "" {
+			cabin += " (" + *trip.BookingClass + ")"
+		}
+		details = append(details, detail{"CABIN", cabin})
+	}
+	if trip.AircraftType != nil && *trip.AircraftType != "" {
+		details = append(details, detail{"AIRCRAFT", *trip.AircraftType})
+	}
+	for i, detail := range details {
+		x := left + 330 + float64(i%2)*90
+		y := top + 34 + float64(i/2)*26
+		l.page.Text(x, y, labelStyle, detail.label)
+		l.page.Text(x, y+12, flightStyle, Truncate(flightStyle, detail.value, 84))
+	}
+
+	l.y = top + legHeight
+	l.page.Line(margin, l.y, PageWidth-margin, l.y, 0.5, rule)
+}
+
+// localTime is the wall clock at the airport in the timezone the trip was read
+// with from the airports table. The timezone reference map is only used for
+// airports without one, UTC when neither is known.
+func (l *itineraryLayout) localTime(unix uint32, airport string, timezone *string) time.Time {
+	name := ""
+	if timezone != nil {
+		name = *timezone
+	}
+	if name == "" && l.airports != nil {
+		if known, ok := l.airports(airport); ok {
+			name = known.Timezone
+		}
+	}
+	if name == "" {
+		name = m.AirportTimezoneLookup[airport]
+	}
+	at := time.Unix(int64(unix), 0).UTC()
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return at.In(loc)
+	}
+	return at
+}
+
+// airportName is the name of the airport with its city, or fallback
+func (l *itineraryLayout) airportName(code, fallback string) string {
+	if l.airports == nil {
+		return fallback
+	}
+	airport, ok := l.airports(code)
+	if !ok || airport.Name == "" {
+		return fallback
+	}
+	if airport.City != "" && !strings.Contains(airport.Name, airport.City) {
+		return airport.City + ", " + airport.Name
+	}
+	return airport.Name
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,256 @@
+// Package pdf writes simple PDF documents: text in the standard Helvetica
+// fonts, lines and filled rectangles on A4 pages. The standard fonts are not
+// embedded, every PDF reader has them, and their text is WinAnsi encoded so
+// characters outside Windows-1252 are printed as "?".
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+// Font is one of the standard fonts
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold"}
+
+// Color is an RGB color
+type Color struct{ R, G, B uint8 }
+
+var (
+	Black = Color{0, 0, 0}
+	Gray  = Color{110, 110, 110}
+	White = Color{255, 255, 255}
+)
+
+// Style is how text is drawn
+type Style struct {
+	Font  Font
+	Size  float64
+	Color Color
+}
+
+// Document is a PDF being built page by page
+type Document struct {
+	Title string
+	pages []*Page
+}
+
+// Page is one A4 page. Coordinates are points from the top left corner.
+type Page struct {
+	content bytes.Buffer
+}
+
+func New(title string) *Document {
+	return &Document{Title: title}
+}
+
+// AddPage appends an empty page and returns it
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages in order, e.g. to number them once the layout is done
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Text draws text with its baseline at y
+func (p *Page) Text(x, y float64, style Style, text string) {
+	fmt.Fprintf(&p.content, "%s rg BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		style.Color.operands(), style.Font+1, number(style.Size), number(x), number(PageHeight-y), escape(encode(text)))
+}
+
+// TextRight draws text ending at x
+func (p *Page) TextRight(x, y float64, style Style, text string) {
+	p.Text(x-TextWidth(style, text), y, style, text)
+}
+
+// Line draws a straight line
+func (p *Page) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n",
+		color.operands(), number(width), number(x1), number(PageHeight-y1), number(x2), number(PageHeight-y2))
+}
+
+// Rect fills a rectangle whose top left corner is at x, y
+func (p *Page) Rect(x, y, width, height float64, color Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n",
+		color.operands(), number(x), number(PageHeight-y-height), number(width), number(height))
+}
+
+// TextWidth is the width of text in points
+func TextWidth(style Style, text string) float64 {
+	widths := helveticaWidths
+	if style.Font == HelveticaBold {
+		widths = helveticaBoldWidths
+	}
+	total := 0
+	for _, b := range encode(text) {
+		if b >= 32 && b < 127 {
+			total += widths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * style.Size / 1000
+}
+
+// Truncate shortens text with an ellipsis until it fits in width
+func Truncate(style Style, text string, width float64) string {
+	if TextWidth(style, text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		shortened := strings.TrimSpace(string(runes)) + "…"
+		if TextWidth(style, shortened) <= width {
+			return shortened
+		}
+	}
+	return ""
+}
+
+// Write writes the document. The page streams are compressed, the rest of
+// the file stays readable.
+func (d *Document) Write(w io.Writer, now time.Time) error {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{}}
+	}
+
+	var b bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1 to 5 are fixed, each page adds a page and a content object
+	const firstPage = 6
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	for _, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (Trip Tracker) /CreationDate (D:%s) >>",
+		escape(encode(d.Title)), now.UTC().Format("20060102150405Z")))
+
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			number(PageWidth), number(PageHeight), firstPage+2*i+1))
+
+		var stream bytes.Buffer
+		z := zlib.NewWriter(&stream)
+		z.Write(page.content.Bytes())
+		if err := z.Close(); err != nil {
+			return err
+		}
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets), stream.Len())
+		b.Write(stream.Bytes())
+		b.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (c Color) operands() string {
+	return fmt.Sprintf("%s %s %s", number(float64(c.R)/255), number(float64(c.G)/255), number(float64(c.B)/255))
+}
+
+// number formats a coordinate without exponent or trailing zeros
+func number(f float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.3f", f), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// windows1252 are the characters of Windows-1252 between 0x80 and 0x9f
+var windows1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// encode converts text to WinAnsiEncoding
+func encode(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			encoded = append(encoded, ' ')
+		case r >= 32 && r < 127, r >= 0xa0 && r <= 0xff:
+			encoded = append(encoded, byte(r))
+		case windows1252[r] != 0:
+			encoded = append(encoded, windows1252[r])
+		case r == '→':
+			encoded = append(encoded, '-', '>')
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
+
+// escape makes encoded text a PDF string literal
+func escape(text []byte) string {
+	var b strings.Builder
+	for _, c := range text {
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Advance widths of the characters 32 to 126 in 1/1000 of the font size,
+// from the Adobe font metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
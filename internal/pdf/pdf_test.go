@@ -0,0 +1,68 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	doc := New("Trip (draft)")
+	doc.AddPage().Text(10, 20, Style{Font: HelveticaBold, Size: 12}, `Gate B22 (Zürich) \ 1 €`)
+	doc.AddPage().Rect(0, 0, 10, 10, Gray)
+
+	var b bytes.Buffer
+	if err := doc.Write(&b, time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatalf("not a PDF file:\n%s", out)
+	}
+	if !strings.Contains(out, "/Title (Trip \\(draft\\))") || !strings.Contains(out, "/Count 2") {
+		t.Errorf("catalog:\n%s", out)
+	}
+
+	// Every entry of the cross-reference table points at its object
+	xref, err := strconv.Atoi(regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(out)[1])
+	if err != nil || !strings.HasPrefix(out[xref:], "xref\n0 10\n") {
+		t.Fatalf("startxref %d does not point at the table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[xref:], -1)
+	if len(entries) != 9 {
+		t.Fatalf("got %d objects, want 9", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !strings.HasPrefix(out[offset:], want) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+
+	// The first page holds the WinAnsi encoded text
+	start := strings.Index(out, "stream\n") + len("stream\n")
+	z, err := zlib.NewReader(strings.NewReader(out[start:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(z)
+	if want := "/F2 12 Tf 10 822 Td (Gate B22 \\(Z\xfcrich\\) \\\\ 1 \x80) Tj"; !strings.Contains(string(content), want) {
+		t.Errorf("page content %q does not have %q", content, want)
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	regular := Style{Font: Helvetica, Size: 10}
+	if got := TextWidth(regular, "JFK"); got != 17.78 {
+		t.Errorf("width of JFK is %v, want 17.78", got)
+	}
+	if got := Truncate(regular, "Narita International Airport", 60); TextWidth(regular, got) > 60 || !strings.HasSuffix(got, "…") {
+		t.Errorf("truncated to %q", got)
+	}
+}
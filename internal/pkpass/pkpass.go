@@ -0,0 +1,214 @@
+// Package pkpass reads Apple Wallet passes. A .pkpass file is a zip archive
+// with the pass description in pass.json next to its images and signature;
+// only pass.json is read. The signature is not verified, the pass is the
+// user's own upload.
+package pkpass
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of .pkpass files
+const ContentType = "application/vnd.apple.pkpass"
+
+// maxPassJSONSize limits pass.json, real passes are a few kilobytes
+const maxPassJSONSize = 1 << 20
+
+// ErrNotPass is returned when the file is not a zip archive with a pass.json
+var ErrNotPass = errors.New("not a Wallet pass")
+
+// ErrNotBoardingPass is returned for passes of another style, such as event
+// tickets or store cards
+var ErrNotBoardingPass = errors.New("not a boarding pass")
+
+// Pass is the part of pass.json that describes a boarding pass
+type Pass struct {
+	Description      string
+	OrganizationName string
+	TransitType      string    // e.g. "PKTransitTypeAir"
+	Barcode          string    // Message of the first barcode, usually the BCBP text
+	RelevantDate     time.Time // When the pass is shown on the lock screen, zero when not set
+	Locations        []Location
+	Fields           []Field // Every field of the pass, front to back
+}
+
+// Location is a place where the pass is relevant, for boarding passes the
+// departure airport
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Field is one of the labelled values on the pass. Airlines choose their own
+// keys, "origin", "depart" and "from" all name the departure airport.
+type Field struct {
+	Key   string
+	Label string
+	Value string // Numbers and dates as they appear in pass.json
+}
+
+type passFile struct {
+	Description      string     `json:"description"`
+	OrganizationName string     `json:"organizationName"`
+	RelevantDate     string     `json:"relevantDate"`
+	Locations        []Location `json:"locations"`
+	Barcode          *barcode   `json:"barcode"`
+	Barcodes         []barcode  `json:"barcodes"`
+	BoardingPass     *struct {
+		TransitType     string      `json:"transitType"`
+		HeaderFields    []passField `json:"headerFields"`
+		PrimaryFields   []passField `json:"primaryFields"`
+		SecondaryFields []passField `json:"secondaryFields"`
+		AuxiliaryFields []passField `json:"auxiliaryFields"`
+		BackFields      []passField `json:"backFields"`
+	} `json:"boardingPass"`
+}
+
+type barcode struct {
+	Message string `json:"message"`
+	Format  string `json:"format"`
+}
+
+type passField struct {
+	Key   string          `json:"key"`
+	Label string          `json:"label"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Read reads the pass.json of a .pkpass file
+func Read(r io.ReaderAt, size int64) (Pass, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return Pass{}, ErrNotPass
+	}
+	var data []byte
+	for _, f := range z.File {
+		if f.Name != "pass.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return Pass{}, fmt.Errorf("opening pass.json: %w", err)
+		}
+		data, err = io.ReadAll(io.LimitReader(rc, maxPassJSONSize+1))
+		rc.Close()
+		if err != nil {
+			return Pass{}, fmt.Errorf("reading pass.json: %w", err)
+		}
+		if len(data) > maxPassJSONSize {
+			return Pass{}, errors.New("pass.json is too large")
+		}
+	}
+	if data == nil {
+		return Pass{}, ErrNotPass
+	}
+	return Parse(data)
+}
+
+// Parse reads pass.json
+func Parse(data []byte) (Pass, error) {
+	var file passFile
+	// Some issuers write pass.json with a byte order mark
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(string(data), "\ufeff")), &file); err != nil {
+		return Pass{}, fmt.Errorf("reading pass.json: %w", err)
+	}
+	if file.BoardingPass == nil {
+		return Pass{}, ErrNotBoardingPass
+	}
+
+	pass := Pass{
+		Description:      file.Description,
+		OrganizationName: file.OrganizationName,
+		TransitType:      file.BoardingPass.TransitType,
+		Locations:        file.Locations,
+	}
+	if len(file.Barcodes) > 0 {
+		pass.Barcode = file.Barcodes[0].Message
+	} else if file.Barcode != nil {
+		pass.Barcode = file.Barcode.Message
+	}
+	if file.RelevantDate != "" {
+		pass.RelevantDate, _ = ParseDate(file.RelevantDate)
+	}
+	for _, fields := range [][]passField{
+		file.BoardingPass.HeaderFields,
+		file.BoardingPass.PrimaryFields,
+		file.BoardingPass.SecondaryFields,
+		file.BoardingPass.AuxiliaryFields,
+		file.BoardingPass.BackFields,
+	} {
+		for _, field := range fields {
+			pass.Fields = append(pass.Fields, Field{
+				Key:   field.Key,
+				Label: strings.TrimSpace(field.Label),
+				Value: fieldValue(field.Value),
+			})
+		}
+	}
+	return pass, nil
+}
+
+// fieldValue returns strings without their quotes and numbers as written
+func fieldValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// dateLayouts are the W3C date formats pass.json uses, with and without
+// seconds
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+}
+
+// ParseDate reads a date of pass.json. Dates always carry their offset, so
+// the result is an exact instant.
+func ParseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// Find returns the first field that is not a date and whose key or label
+// contains one of the words, compared without case
+func (p Pass) Find(words ...string) (Field, bool) {
+	for _, field := range p.Fields {
+		if _, err := ParseDate(field.Value); err != nil && field.Value != "" && field.matches(words) {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// FindDate returns the first date whose field key or label contains one of
+// the words
+func (p Pass) FindDate(words ...string) (time.Time, bool) {
+	for _, field := range p.Fields {
+		if t, err := ParseDate(field.Value); err == nil && field.matches(words) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (f Field) matches(words []string) bool {
+	key, label := strings.ToLower(f.Key), strings.ToLower(f.Label)
+	for _, word := range words {
+		if strings.Contains(key, word) || strings.Contains(label, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package pkpass
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Issuers sometimes write a byte order mark
+const boardingPass = "\ufeff" + `{
+	"formatVersion": 1,
+	"organizationName": "Test Air",
+	"relevantDate": "2025-09-01T09:30-04:00",
+	"locations": [{"latitude": 40.6413, "longitude": -73.7781}],
+	"barcode": {"message": "legacy", "format": "PKBarcodeFormatPDF417"},
+	"barcodes": [{"message": "M1TRAVELER/PAT", "format": "PKBarcodeFormatAztec"}],
+	"boardingPass": {
+		"transitType": "PKTransitTypeAir",
+		"headerFields": [{"key": "gate", "label": " GATE ", "value": "B22"}],
+		"primaryFields": [{"key": "origin", "label": "New York", "value": "JFK"}],
+		"auxiliaryFields": [
+			{"key": "departs", "label": "DEPARTS", "value": "2025-09-01T10:15:00-04:00"},
+			{"key": "terminal", "label": "TERMINAL", "value": 4}
+		]
+	}
+}`
+
+func TestRead(t *testing.T) {
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	f, _ := z.Create("pass.json")
+	f.Write([]byte(boardingPass))
+	z.Close()
+
+	pass, err := Read(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pass.Barcode != "M1TRAVELER/PAT" || pass.TransitType != "PKTransitTypeAir" || len(pass.Locations) != 1 || len(pass.Fields) != 4 {
+		t.Errorf("pass: %+v", pass)
+	}
+	if want := time.Date(2025, 9, 1, 13, 30, 0, 0, time.UTC); !pass.RelevantDate.Equal(want) {
+		t.Errorf("relevant date %v, want %v", pass.RelevantDate, want)
+	}
+	if field, ok := pass.Find("gate"); !ok || field.Value != "B22" || field.Label != "GATE" {
+		t.Errorf("gate: %+v", field)
+	}
+	if field, ok := pass.Find("terminal"); !ok || field.Value != "4" {
+		t.Errorf("terminal: %+v", field)
+	}
+	// The departure date is not a departure airport
+	if field, ok := pass.Find("depart", "origin"); !ok || field.Value != "JFK" {
+		t.Errorf("origin: %+v", field)
+	}
+	if departs, ok := pass.FindDate("depart"); !ok || !departs.Equal(time.Date(2025, 9, 1, 14, 15, 0, 0, time.UTC)) {
+		t.Errorf("departs: %v", departs)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	if _, err := Read(bytes.NewReader([]byte("not a zip")), 9); !errors.Is(err, ErrNotPass) {
+		t.Errorf("text file: %v", err)
+	}
+	if _, err := Parse([]byte(`{"eventTicket": {}}`)); !errors.Is(err, ErrNotBoardingPass) {
+		t.Errorf("event ticket: %v", err)
+	}
+}
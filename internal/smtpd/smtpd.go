@@ -0,0 +1,308 @@
+// Package smtpd is a small SMTP server that receives mail for the instance's
+// forwarding addresses. It only accepts mail for recipients the app knows,
+// does not relay and does not authenticate clients; it is meant to sit behind
+// the MX of the mail domain or to be reachable from a forwarding rule.
+package smtpd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxSize = 10 << 20
+	defaultTimeout = 5 * time.Minute
+	maxRecipients  = 50
+	maxLineLength  = 2048 // For commands, message lines are limited by MaxSize
+)
+
+// Server receives messages. Recipient is asked for every RCPT TO address and
+// Deliver is called once per message with the accepted recipients.
+type Server struct {
+	Addr     string        // TCP address to listen on, e.g. ":2525"
+	Hostname string        // Name used in the greeting
+	MaxSize  int64         // Largest message accepted in bytes, 10 MB when 0
+	Timeout  time.Duration // Idle time before a connection is closed, 5 minutes when 0
+
+	Recipient func(address string) bool
+	Deliver   func(from string, recipients []string, data []byte) error
+}
+
+// ListenAndServe listens on Addr and serves connections until it fails
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on the listener, each one is served in its own
+// goroutine
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) maxSize() int64 {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+	return defaultMaxSize
+}
+
+func (s *Server) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return defaultTimeout
+}
+
+// session is the state of one connection
+type session struct {
+	server     *Server
+	text       *textproto.Conn
+	greeted    bool
+	from       *string
+	recipients []string
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	sess := &session{
+		server: s,
+		text:   textproto.NewConn(conn),
+	}
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname = "localhost"
+	}
+
+	sess.reply(220, "%s ESMTP trip-tracker", hostname)
+	for {
+		conn.SetDeadline(time.Now().Add(s.timeout()))
+		line, err := sess.readCommand()
+		if errors.Is(err, errLineTooLong) {
+			sess.reply(500, "Line too long")
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			sess.reset()
+			sess.greeted = true
+			sess.reply(250, "%s", hostname)
+		case "EHLO":
+			sess.reset()
+			sess.greeted = true
+			sess.reply(250, "%s\n8BITMIME\nSIZE %d", hostname, s.maxSize())
+		case "MAIL":
+			sess.mail(arg)
+		case "RCPT":
+			sess.rcpt(arg)
+		case "DATA":
+			sess.data()
+		case "RSET":
+			sess.reset()
+			sess.reply(250, "OK")
+		case "NOOP":
+			sess.reply(250, "OK")
+		case "VRFY":
+			sess.reply(252, "Cannot verify the user, but will accept the message")
+		case "QUIT":
+			sess.reply(221, "Bye")
+			return
+		default:
+			sess.reply(502, "Command not implemented")
+		}
+	}
+}
+
+var errLineTooLong = errors.New("line too long")
+
+func (sess *session) readCommand() (string, error) {
+	var line []byte
+	for {
+		part, more, err := sess.text.R.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if len(line)+len(part) > maxLineLength {
+			// Drain the rest of the line so the next command starts clean
+			for more {
+				if _, more, err = sess.text.R.ReadLine(); err != nil {
+					return "", err
+				}
+			}
+			return "", errLineTooLong
+		}
+		line = append(line, part...)
+		if !more {
+			return string(line), nil
+		}
+	}
+}
+
+// reply writes a reply, lines of a multi-line reply are separated by "\n"
+func (sess *session) reply(code int, format string, args ...any) {
+	lines := strings.Split(fmt.Sprintf(format, args...), "\n")
+	for i, line := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		fmt.Fprintf(sess.text.W, "%d%s%s\r\n", code, separator, line)
+	}
+	sess.text.W.Flush()
+}
+
+func (sess *session) reset() {
+	sess.from = nil
+	sess.recipients = nil
+}
+
+func (sess *session) mail(arg string) {
+	if !sess.greeted {
+		sess.reply(503, "Send HELO or EHLO first")
+		return
+	}
+	if sess.from != nil {
+		sess.reply(503, "Sender already given")
+		return
+	}
+	address, params, ok := pathArgument(arg, "FROM:")
+	if !ok {
+		sess.reply(501, "Syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, param := range params {
+		if name, value, _ := strings.Cut(param, "="); strings.EqualFold(name, "SIZE") {
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > sess.server.maxSize() {
+				sess.reply(552, "Message too large, the limit is %d bytes", sess.server.maxSize())
+				return
+			}
+		}
+	}
+	sess.from = &address
+	sess.reply(250, "OK")
+}
+
+func (sess *session) rcpt(arg string) {
+	if sess.from == nil {
+		sess.reply(503, "Send MAIL first")
+		return
+	}
+	address, _, ok := pathArgument(arg, "TO:")
+	if !ok || address == "" {
+		sess.reply(501, "Syntax: RCPT TO:<address>")
+		return
+	}
+	if len(sess.recipients) >= maxRecipients {
+		sess.reply(452, "Too many recipients")
+		return
+	}
+	if sess.server.Recipient == nil || !sess.server.Recipient(address) {
+		sess.reply(550, "No such user here")
+		return
+	}
+	sess.recipients = append(sess.recipients, address)
+	sess.reply(250, "OK")
+}
+
+func (sess *session) data() {
+	if len(sess.recipients) == 0 {
+		sess.reply(503, "Send RCPT first")
+		return
+	}
+	sess.reply(354, "End data with <CR><LF>.<CR><LF>")
+
+	max := sess.server.maxSize()
+	body := sess.text.DotReader()
+	var b bytes.Buffer
+	n, err := io.Copy(&b, io.LimitReader(body, max+1))
+	if err != nil {
+		sess.reset()
+		sess.reply(451, "Error reading the message")
+		return
+	}
+	if n > max {
+		// Read what is left of the message before answering
+		io.Copy(io.Discard, body)
+		sess.reset()
+		sess.reply(552, "Message too large, the limit is %d bytes", max)
+		return
+	}
+
+	from, recipients := *sess.from, sess.recipients
+	sess.reset()
+	if sess.server.Deliver != nil {
+		if err := sess.server.Deliver(from, recipients, b.Bytes()); err != nil {
+			log.Printf("Error delivering mail from %q: %v", from, err)
+			sess.reply(451, "Error processing the message, try again later")
+			return
+		}
+	}
+	sess.reply(250, "OK")
+}
+
+// pathArgument reads "FROM:<address> PARAM=value" of MAIL and RCPT
+func pathArgument(arg, prefix string) (string, []string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	fields := strings.Fields(strings.TrimSpace(arg[len(prefix):]))
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	path := fields[0]
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", nil, false
+	}
+	address := path[1 : len(path)-1]
+	if address == "" {
+		// The null sender of bounces
+		return "", fields[1:], true
+	}
+	// Drop source routes, "<@relay:user@example.com>"
+	if i := strings.LastIndex(address, ":"); strings.HasPrefix(address, "@") && i >= 0 {
+		address = address[i+1:]
+	}
+	if _, err := mail.ParseAddress(address); err != nil {
+		return "", nil, false
+	}
+	return address, fields[1:], true
+}
+
+// SplitAddress returns the local part and the domain of an address
+func SplitAddress(address string) (string, string) {
+	i := strings.LastIndex(address, "@")
+	if i < 0 {
+		return address, ""
+	}
+	return address[:i], address[i+1:]
+}
@@ -0,0 +1,253 @@
+package smtpd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer accepts mail for known@example.com and records the deliveries
+type testServer struct {
+	*Server
+	mu         sync.Mutex
+	deliveries []delivery
+	deliverErr error
+}
+
+type delivery struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+func newTestServer() *testServer {
+	s := &testServer{}
+	s.Server = &Server{
+		Hostname:  "mail.example.com",
+		Recipient: func(address string) bool { return strings.EqualFold(address, "known@example.com") },
+		Deliver: func(from string, recipients []string, data []byte) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.deliverErr != nil {
+				return s.deliverErr
+			}
+			s.deliveries = append(s.deliveries, delivery{from, recipients, string(data)})
+			return nil
+		},
+	}
+	return s
+}
+
+func (s *testServer) delivered() []delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]delivery(nil), s.deliveries...)
+}
+
+type client struct {
+	t    *testing.T
+	text *textproto.Conn
+}
+
+// dial serves one end of a pipe and reads the greeting on the other
+func dial(t *testing.T, s *Server) *client {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	go s.serveConn(serverConn)
+	c := &client{t: t, text: textproto.NewConn(clientConn)}
+	t.Cleanup(func() { c.text.Close() })
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+	if code, msg, err := c.text.ReadResponse(0); err != nil || code != 220 {
+		t.Fatalf("greeting %d %q: %v", code, msg, err)
+	}
+	return c
+}
+
+// cmd sends a command and checks the code of the reply
+func (c *client) cmd(want int, line string) string {
+	c.t.Helper()
+	if err := c.text.PrintfLine("%s", line); err != nil {
+		c.t.Fatalf("%s: %v", line, err)
+	}
+	code, msg, err := c.text.ReadResponse(0)
+	if err != nil {
+		c.t.Fatalf("%s: %v", line, err)
+	}
+	if code != want {
+		c.t.Errorf("%s: %d %s, want %d", line, code, msg, want)
+	}
+	return msg
+}
+
+// send writes a message after DATA was accepted and returns the reply code
+func (c *client) send(message string) int {
+	c.t.Helper()
+	w := c.text.DotWriter()
+	if _, err := io.WriteString(w, message); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		c.t.Fatal(err)
+	}
+	code, _, err := c.text.ReadResponse(0)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return code
+}
+
+func TestDelivery(t *testing.T) {
+	s := newTestServer()
+	c := dial(t, s.Server)
+
+	if msg := c.cmd(250, "EHLO client.example.org"); !strings.Contains(msg, "SIZE 10485760") {
+		t.Errorf("EHLO does not announce the size limit: %q", msg)
+	}
+	c.cmd(250, "MAIL FROM:<sender@example.org> BODY=8BITMIME SIZE=200")
+	c.cmd(550, "RCPT TO:<unknown@example.com>")
+	c.cmd(250, "RCPT TO:<@relay.example.org:Known@example.com>")
+	c.cmd(250, "RCPT TO:<known@example.com>")
+	c.cmd(354, "DATA")
+	if code := c.send("Subject: Your flight\n\n.leading dot\nJL5 JFK-NRT\n"); code != 250 {
+		t.Fatalf("message: %d", code)
+	}
+
+	want := []delivery{{
+		from:       "sender@example.org",
+		recipients: []string{"Known@example.com", "known@example.com"},
+		data:       "Subject: Your flight\n\n.leading dot\nJL5 JFK-NRT\n",
+	}}
+	if got := s.delivered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered %+v, want %+v", got, want)
+	}
+
+	// The transaction ends with the message, the next one starts with MAIL
+	c.cmd(503, "RCPT TO:<known@example.com>")
+	c.cmd(250, "MAIL FROM:<>")
+	c.cmd(250, "RCPT TO:<known@example.com>")
+	c.cmd(354, "DATA")
+	if code := c.send("bounce\n"); code != 250 {
+		t.Errorf("a bounce from the null sender: %d", code)
+	}
+	if got := s.delivered(); len(got) != 2 || got[1].from != "" {
+		t.Errorf("delivered %+v", got)
+	}
+
+	// A failed delivery is answered with a temporary error
+	s.mu.Lock()
+	s.deliverErr = errors.New("database is locked")
+	s.mu.Unlock()
+	c.cmd(250, "MAIL FROM:<sender@example.org>")
+	c.cmd(250, "RCPT TO:<known@example.com>")
+	c.cmd(354, "DATA")
+	if code := c.send("retry me\n"); code != 451 {
+		t.Errorf("a failed delivery: %d, want 451", code)
+	}
+	c.cmd(221, "QUIT")
+}
+
+func TestCommandOrder(t *testing.T) {
+	s := newTestServer()
+	c := dial(t, s.Server)
+
+	c.cmd(503, "MAIL FROM:<sender@example.org>")
+	c.cmd(503, "RCPT TO:<known@example.com>")
+	c.cmd(503, "DATA")
+	c.cmd(250, "HELO client.example.org")
+	c.cmd(503, "RCPT TO:<known@example.com>")
+	c.cmd(501, "MAIL sender@example.org")
+	c.cmd(501, "MAIL FROM:<not an address>")
+	c.cmd(250, "mail from:<sender@example.org>")
+	c.cmd(503, "MAIL FROM:<sender@example.org>")
+	c.cmd(503, "DATA")
+	c.cmd(501, "RCPT TO:<>")
+	c.cmd(550, "RCPT TO:<unknown@example.com>")
+	c.cmd(503, "DATA") // Every recipient was refused
+
+	// RSET and a new greeting both end the transaction
+	c.cmd(250, "RCPT TO:<known@example.com>")
+	c.cmd(250, "RSET")
+	c.cmd(503, "DATA")
+	c.cmd(250, "MAIL FROM:<sender@example.org>")
+	c.cmd(250, "RCPT TO:<known@example.com>")
+	c.cmd(250, "EHLO client.example.org")
+	c.cmd(503, "RCPT TO:<known@example.com>")
+
+	c.cmd(250, "MAIL FROM:<sender@example.org>")
+	for i := 0; i < maxRecipients; i++ {
+		c.cmd(250, "RCPT TO:<known@example.com>")
+	}
+	c.cmd(452, "RCPT TO:<known@example.com>")
+
+	c.cmd(250, "NOOP")
+	c.cmd(252, "VRFY known")
+	c.cmd(502, "AUTH PLAIN")
+	c.cmd(500, "NOOP "+strings.Repeat("x", maxLineLength))
+	c.cmd(250, "NOOP") // The long line was read to its end
+	if got := s.delivered(); len(got) != 0 {
+		t.Errorf("delivered %+v", got)
+	}
+}
+
+func TestUnknownRecipientsWithoutLookup(t *testing.T) {
+	s := newTestServer()
+	s.Recipient = nil
+	c := dial(t, s.Server)
+	c.cmd(250, "HELO client.example.org")
+	c.cmd(250, "MAIL FROM:<sender@example.org>")
+	c.cmd(550, "RCPT TO:<known@example.com>")
+}
+
+func TestMaxSize(t *testing.T) {
+	s := newTestServer()
+	s.MaxSize = 100
+	c := dial(t, s.Server)
+	c.cmd(250, "EHLO client.example.org")
+
+	c.cmd(552, "MAIL FROM:<sender@example.org> SIZE=101")
+
+	// 101 bytes once the dots and line endings are undone
+	c.cmd(250, "MAIL FROM:<sender@example.org> SIZE=100")
+	c.cmd(250, "RCPT TO:<known@example.com>")
+	c.cmd(354, "DATA")
+	if code := c.send(strings.Repeat("x", 100) + "\n" + strings.Repeat("y", 1000) + "\n"); code != 552 {
+		t.Errorf("an oversized message: %d, want 552", code)
+	}
+	c.cmd(503, "RCPT TO:<known@example.com>") // The transaction was reset
+
+	// Exactly the limit
+	c.cmd(250, "MAIL FROM:<sender@example.org>")
+	c.cmd(250, "RCPT TO:<known@example.com>")
+	c.cmd(354, "DATA")
+	if code := c.send(strings.Repeat("x", 99) + "\n"); code != 250 {
+		t.Errorf("a message at the limit: %d, want 250", code)
+	}
+	if got := s.delivered(); len(got) != 1 || len(got[0].data) != 100 {
+		t.Errorf("delivered %+v", got)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s := newTestServer()
+	s.Timeout = 100 * time.Millisecond
+	c := dial(t, s.Server)
+
+	// Every command starts the idle time again
+	for i := 0; i < 5; i++ {
+		time.Sleep(40 * time.Millisecond)
+		c.cmd(250, "NOOP")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if err := c.text.PrintfLine("NOOP"); err == nil {
+		if _, _, err := c.text.ReadResponse(0); err == nil {
+			t.Errorf("the idle connection was not closed")
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for endpoints on the server's own network.
+// Webhook URLs are chosen by users, without this check they could make the
+// server send requests to itself, the local network or the cloud metadata
+// service at 169.254.169.254.
+var ErrBlockedAddress = errors.New("webhooks can not be sent to loopback, private or link-local addresses")
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// allowedNetworks are exempt from the blocked addresses, see AllowNetworks
+var allowedNetworks []*net.IPNet
+
+// AllowNetworks exempts the comma separated networks in list, CIDRs such as
+// 10.0.0.0/8 or single addresses, from the blocked addresses so webhooks can
+// reach receivers on the local network of a self-hosted server. main sets it
+// from WEBHOOK_ALLOWED_NETWORKS at startup, an empty list blocks them all.
+func AllowNetworks(list string) error {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip)
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("allowed network %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	allowedNetworks = networks
+	return nil
+}
+
+// refusedIP reports whether webhooks may not be sent to ip, it is blocked and
+// not in one of the allowed networks
+func refusedIP(ip net.IP) bool {
+	if !blockedIP(ip) {
+		return false
+	}
+	for _, network := range allowedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockedIP reports whether ip is loopback, private, link-local, multicast,
+// unspecified or otherwise not a public address. IPv4 addresses written as
+// IPv6, such as ::ffff:127.0.0.1, are checked as IPv4.
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip) ||
+		(ip.To4() != nil && ip.To4()[0] == 0)
+}
+
+// CheckURL rejects an endpoint whose host is, or resolves to, a blocked
+// address outside the allowed networks, so users learn about it when they add the webhook. Hosts that do
+// not resolve are accepted, their deliveries fail and are logged like any
+// other; the dispatcher checks the address again when it connects, which also
+// covers names that resolve differently later.
+func CheckURL(ctx context.Context, endpoint *url.URL) error {
+	host := endpoint.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if refusedIP(ip) {
+			return ErrBlockedAddress
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if refusedIP(addr.IP) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// newClient returns the dispatcher's default client. Its dialer checks the
+// address it is about to connect to, after the name was resolved, and no
+// proxy is used so the check applies to the endpoint itself.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || refusedIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+func TestBlockedIP(t *testing.T) {
+	for address, want := range map[string]bool{
+		"127.0.0.1":              true,
+		"127.8.8.8":              true,
+		"::1":                    true,
+		"10.1.2.3":               true,
+		"172.16.0.1":             true,
+		"192.168.1.1":            true,
+		"169.254.169.254":        true,
+		"fe80::1":                true,
+		"fd00:ec2::254":          true,
+		"0.0.0.0":                true,
+		"0.1.2.3":                true,
+		"::":                     true,
+		"100.64.0.1":             true,
+		"224.0.0.1":              true,
+		"ff02::1":                true,
+		"::ffff:127.0.0.1":       true,
+		"::ffff:169.254.169.254": true,
+		"93.184.216.34":          false,
+		"8.8.8.8":                false,
+		"172.32.0.1":             false,
+		"100.128.0.1":            false,
+		"2606:4700::1111":        false,
+	} {
+		if got := blockedIP(net.ParseIP(address)); got != want {
+			t.Errorf("blockedIP(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for endpoint, want := range map[string]error{
+		"http://127.0.0.1:8080/hook":           ErrBlockedAddress,
+		"http://169.254.169.254/latest/":       ErrBlockedAddress,
+		"https://10.0.0.5/hook":                ErrBlockedAddress,
+		"http://[::1]/hook":                    ErrBlockedAddress,
+		"http://[::ffff:a9fe:a9fe]/":           ErrBlockedAddress,
+		"http://localhost/hook":                ErrBlockedAddress,
+		"https://93.184.216.34/hook":           nil,
+		"https://[2606:4700::1111]/hook":       nil,
+		"https://hooks.example.invalid/trips/": nil, // does not resolve, delivery fails later
+	} {
+		parsed, err := url.Parse(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckURL(context.Background(), parsed); !errors.Is(err, want) {
+			t.Errorf("CheckURL(%s) = %v, want %v", endpoint, err, want)
+		}
+	}
+}
+
+// TestDefaultClientRefusesLocalAddresses sends a delivery to an endpoint that
+// got into the table without the check, the connection must not be made
+func TestDefaultClientRefusesLocalAddresses(t *testing.T) {
+	f := newFixture(t)
+	f.dispatcher = NewDispatcher(DispatcherParams{Store: f.store, Now: func() time.Time { return f.now }})
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	webhook := f.webhook(t, server.URL, m.EventTripCreated)
+	if _, err := f.tripStore.CreateTrip(m.Trip{UserId: f.userID, Departure: "JFK", Arrival: "NRT", DepartureTime: 1743501600, ArrivalTime: 1743552000}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.deliverDue(t)
+	if requests := rc.received(); len(requests) != 0 {
+		t.Errorf("the local endpoint got %d requests", len(requests))
+	}
+	delivery := f.deliveries(t, webhook.ID)[0]
+	if delivery.Status != m.DeliveryPending || delivery.Error == nil || !strings.Contains(*delivery.Error, ErrBlockedAddress.Error()) {
+		t.Errorf("delivery %+v, want a pending retry with the blocked address error", delivery)
+	}
+}
+
+// allowNetworks sets the allowlist for one test
+func allowNetworks(t *testing.T, list string) {
+	t.Helper()
+	if err := AllowNetworks(list); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { allowedNetworks = nil })
+}
+
+func TestAllowNetworks(t *testing.T) {
+	for _, list := range []string{"10.0.0.0/33", "local", "192.168.1.0/24,localhost"} {
+		if err := AllowNetworks(list); err == nil {
+			t.Errorf("AllowNetworks(%q) accepted an invalid network", list)
+		}
+	}
+	if allowedNetworks != nil {
+		t.Errorf("an invalid list changed the allowed networks: %v", allowedNetworks)
+	}
+	allowNetworks(t, " 192.168.1.0/24, 10.0.0.7 ,fd00::/8")
+
+	for endpoint, want := range map[string]error{
+		"http://192.168.1.20/hook":       nil,
+		"http://[::ffff:192.168.1.20]/":  nil,
+		"http://10.0.0.7/hook":           nil,
+		"http://[fd00::1]/hook":          nil,
+		"http://10.0.0.8/hook":           ErrBlockedAddress,
+		"http://192.168.2.1/hook":        ErrBlockedAddress,
+		"http://127.0.0.1/hook":          ErrBlockedAddress,
+		"http://localhost/hook":          ErrBlockedAddress,
+		"http://169.254.169.254/latest/": ErrBlockedAddress,
+		"https://93.184.216.34/hook":     nil,
+	} {
+		parsed, err := url.Parse(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckURL(context.Background(), parsed); !errors.Is(err, want) {
+			t.Errorf("CheckURL(%s) = %v, want %v", endpoint, err, want)
+		}
+	}
+}
+
+// TestDefaultClientReachesAllowedNetworks delivers to the local test server
+// once loopback is allowed
+func TestDefaultClientReachesAllowedNetworks(t *testing.T) {
+	allowNetworks(t, "127.0.0.0/8,::1")
+	f := newFixture(t)
+	f.dispatcher = NewDispatcher(DispatcherParams{Store: f.store, Now: func() time.Time { return f.now }})
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckURL(context.Background(), endpoint); err != nil {
+		t.Fatalf("CheckURL(%s) = %v", server.URL, err)
+	}
+	webhook := f.webhook(t, server.URL, m.EventTripCreated)
+	if _, err := f.tripStore.CreateTrip(m.Trip{UserId: f.userID, Departure: "JFK", Arrival: "NRT", DepartureTime: 1743501600, ArrivalTime: 1743552000}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.deliverDue(t)
+	if requests := rc.received(); len(requests) != 1 {
+		t.Errorf("the allowed endpoint got %d requests", len(requests))
+	}
+	if delivery := f.deliveries(t, webhook.ID)[0]; delivery.Status != m.DeliveryDelivered {
+		t.Errorf("delivery %+v, want it sent", delivery)
+	}
+}
@@ -0,0 +1,207 @@
+// Package webhook sends the queued webhook deliveries. Each request is a POST
+// of the JSON payload signed with the webhook's secret; failed attempts are
+// retried with exponential backoff until MaxAttempts.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+)
+
+// Request headers, the signature covers the timestamp so a captured request
+// can not be replayed later
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// MaxAttempts is the number of attempts before a delivery fails for good,
+	// the backoff spreads them over about four hours
+	MaxAttempts = 10
+	firstRetry  = 30 * time.Second
+	maxRetry    = 2 * time.Hour
+	// batchSize is the number of due deliveries sent per run
+	batchSize = 50
+	// maxErrorLength keeps response bodies out of the delivery log
+	maxErrorLength = 200
+)
+
+// Sign returns the X-Webhook-Signature of a body sent at timestamp:
+// "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>"
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a received webhook request, for
+// receivers written in Go
+func Verify(secret string, header http.Header, body []byte) bool {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(Sign(secret, timestamp, body)))
+}
+
+// Backoff is the wait after the given number of failed attempts: 30 seconds
+// after the first, doubling up to two hours
+func Backoff(failed int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < failed && wait < maxRetry; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetry)
+}
+
+type Dispatcher struct {
+	store  *db.WebhookStore
+	client *http.Client
+	now    func() time.Time
+}
+
+type DispatcherParams struct {
+	Store *db.WebhookStore
+	// Client defaults to a client with a 10 second timeout that refuses to
+	// connect to loopback, private and link-local addresses. Tests pass the
+	// client of their local httptest server.
+	Client *http.Client
+	// Now defaults to time.Now, tests move it forward to reach retries
+	Now func() time.Time
+}
+
+func NewDispatcher(params DispatcherParams) *Dispatcher {
+	d := &Dispatcher{store: params.Store, client: params.Client, now: params.Now}
+	if d.client == nil {
+		d.client = newClient()
+	}
+	// A redirect is an answer of the endpoint, not something to follow with
+	// the signed payload
+	client := *d.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	d.client = &client
+	if d.now == nil {
+		d.now = time.Now
+	}
+	return d
+}
+
+// Run sends due deliveries every interval until ctx is done and prunes
+// finished deliveries older than 30 days once a day
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		}
+		if now := d.now(); now.Sub(pruned) > 24*time.Hour {
+			if _, err := d.store.PurgeWebhookDeliveriesBefore(now.AddDate(0, 0, -30)); err != nil {
+				log.Printf("Error purging webhook deliveries: %v", err)
+			}
+			pruned = now
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends every due delivery once and returns how many were sent,
+// successfully or not
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		due, err := d.store.GetDueWebhookDeliveries(d.now(), batchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, delivery := range due {
+			if ctx.Err() != nil {
+				return sent, ctx.Err()
+			}
+			if err := d.deliver(ctx, delivery); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+		if len(due) < batchSize {
+			return sent, nil
+		}
+	}
+}
+
+// deliver makes one attempt and records its outcome, the error is only set
+// when the outcome could not be stored
+func (d *Dispatcher) deliver(ctx context.Context, delivery db.DueWebhookDelivery) error {
+	at := d.now()
+	status, problem := d.post(ctx, delivery, at)
+
+	var next *time.Time
+	if problem != "" && delivery.Attempts+1 < MaxAttempts {
+		retry := at.Add(Backoff(delivery.Attempts + 1))
+		next = &retry
+	}
+	return d.store.RecordWebhookAttempt(delivery.ID, at, status, problem, next)
+}
+
+// post sends the payload and returns the response status and what went
+// wrong, empty for a 2xx answer
+func (d *Dispatcher) post(ctx context.Context, delivery db.DueWebhookDelivery, at time.Time) (int, string) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, truncate(err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "trip-tracker-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(at.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, at.Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var urlErr interface{ Timeout() bool }
+		if errors.As(err, &urlErr) && urlErr.Timeout() {
+			return 0, "timed out"
+		}
+		return 0, truncate(err.Error())
+	}
+	defer resp.Body.Close()
+	// Read a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("answered %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return resp.StatusCode, ""
+}
+
+func truncate(s string) string {
+	if len(s) <= maxErrorLength {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxErrorLength], "") + "…"
+}
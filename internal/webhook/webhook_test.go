@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	db "github.com/skywall34/trip-tracker/internal/database"
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// receiver is a local endpoint that records the requests it gets and answers
+// with the next status of statuses, 204 once they run out
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, receivedRequest{r.Header.Clone(), body})
+	status := http.StatusNoContent
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedRequest(nil), rc.requests...)
+}
+
+type fixture struct {
+	store      *db.WebhookStore
+	tripStore  *db.TripStore
+	placeStore *db.PlaceStore
+	userID     int
+	now        time.Time
+	dispatcher *Dispatcher
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	conn, err := db.InitDB("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	userID, err := db.NewUserStore(db.NewUserStoreParams{DB: conn}).CreateUser(m.User{Username: "owner", Password: "x", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{
+		store:      db.NewWebhookStore(db.NewWebhookStoreParams{DB: conn}),
+		tripStore:  db.NewTripStore(db.NewTripStoreParams{DB: conn}),
+		placeStore: db.NewPlaceStore(conn),
+		userID:     userID,
+		now:        time.Now(),
+	}
+	// The receivers listen on 127.0.0.1, which the default client refuses
+	f.dispatcher = NewDispatcher(DispatcherParams{Store: f.store, Client: &http.Client{Timeout: 10 * time.Second}, Now: func() time.Time { return f.now }})
+	return f
+}
+
+func (f *fixture) webhook(t *testing.T, url string, events ...string) m.Webhook {
+	t.Helper()
+	webhook, err := f.store.CreateWebhook(m.Webhook{UserID: f.userID, URL: url, Events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+func (f *fixture) deliverDue(t *testing.T) int {
+	t.Helper()
+	sent, err := f.dispatcher.DeliverDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sent
+}
+
+func (f *fixture) deliveries(t *testing.T, webhookID int) []m.WebhookDelivery {
+	t.Helper()
+	deliveries, err := f.store.GetWebhookDeliveries(webhookID, f.userID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func TestSignedDeliveries(t *testing.T) {
+	f := newFixture(t)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	trips := f.webhook(t, server.URL+"/trips", m.EventTripCreated, m.EventTripUpdated, m.EventTripDeleted)
+	places := f.webhook(t, server.URL+"/places", m.EventPlaceDeleted)
+
+	id, err := f.tripStore.CreateTrip(m.Trip{UserId: f.userID, Departure: "JFK", Arrival: "NRT", DepartureTime: 1743501600, ArrivalTime: 1743552000, Airline: "Test Air", FlightNumber: "TA1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.tripStore.DeleteTrip(int(id), f.userID); err != nil {
+		t.Fatal(err)
+	}
+	placeID, err := f.placeStore.CreatePlace(m.Place{UserID: f.userID, Name: "Shibuya", VisitDate: 1743600000, MarkerColor: "#26e0b0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is sent before the dispatcher runs, and place.created has no subscriber
+	if sent := f.deliverDue(t); sent != 2 {
+		t.Fatalf("sent %d deliveries, want trip.created and trip.deleted", sent)
+	}
+	requests := rc.received()
+	if len(requests) != 2 {
+		t.Fatalf("received %d requests, want 2", len(requests))
+	}
+	for i, want := range []string{m.EventTripCreated, m.EventTripDeleted} {
+		request := requests[i]
+		if !Verify(trips.Secret, request.header, request.body) {
+			t.Errorf("%s: signature %q does not verify", want, request.header.Get(HeaderSignature))
+		}
+		if Verify(places.Secret, request.header, request.body) {
+			t.Errorf("%s: verifies with the secret of another webhook", want)
+		}
+		if got := request.header.Get(HeaderEvent); got != want {
+			t.Errorf("event header %q, want %q", got, want)
+		}
+		if request.header.Get("Content-Type") != "application/json" {
+			t.Errorf("content type %q", request.header.Get("Content-Type"))
+		}
+		var payload struct {
+			ID    string `json:"id"`
+			Event string `json:"event"`
+			Data  m.Trip `json:"data"`
+		}
+		if err := json.Unmarshal(request.body, &payload); err != nil {
+			t.Fatalf("payload %s: %v", request.body, err)
+		}
+		if payload.Event != want || payload.ID == "" || payload.Data.ID != int(id) || payload.Data.FlightNumber != "TA1" {
+			t.Errorf("payload %s", request.body)
+		}
+		if want == m.EventTripDeleted && payload.Data.DeletedAt == nil {
+			t.Errorf("trip.deleted payload has no deleted_at: %s", request.body)
+		}
+	}
+
+	tampered := requests[0].header.Clone()
+	if Verify(trips.Secret, tampered, append(requests[0].body, ' ')) {
+		t.Errorf("a changed body verifies")
+	}
+	tampered.Set(HeaderTimestamp, "1")
+	if Verify(trips.Secret, tampered, requests[0].body) {
+		t.Errorf("a changed timestamp verifies")
+	}
+
+	for _, delivery := range f.deliveries(t, trips.ID) {
+		if delivery.Status != m.DeliveryDelivered || delivery.Attempts != 1 || delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusNoContent {
+			t.Errorf("delivery %+v, want delivered on the first attempt", delivery)
+		}
+	}
+
+	// Deleting a place is announced to the webhook subscribed to it
+	if err := f.placeStore.DeletePlace(placeID, f.userID); err != nil {
+		t.Fatal(err)
+	}
+	if sent := f.deliverDue(t); sent != 1 {
+		t.Fatalf("sent %d deliveries, want place.deleted", sent)
+	}
+	if deliveries := f.deliveries(t, places.ID); len(deliveries) != 1 || deliveries[0].Event != m.EventPlaceDeleted || deliveries[0].Status != m.DeliveryDelivered {
+		t.Errorf("place deliveries %+v", deliveries)
+	}
+	if sent := f.deliverDue(t); sent != 0 {
+		t.Errorf("delivered deliveries were sent again")
+	}
+}
+
+func TestRetriesWithBackoff(t *testing.T) {
+	f := newFixture(t)
+	rc := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusFound}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	webhook := f.webhook(t, server.URL, m.EventPlaceCreated)
+	if _, err := f.placeStore.CreatePlace(m.Place{UserID: f.userID, Name: "Shibuya", VisitDate: 1743600000, MarkerColor: "#26e0b0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.deliverDue(t)
+	delivery := f.deliveries(t, webhook.ID)[0]
+	if delivery.Status != m.DeliveryPending || delivery.Attempts != 1 || delivery.Error == nil || *delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("after a 500 the delivery is %+v, want pending with the error", delivery)
+	}
+	if want := uint32(f.now.Add(Backoff(1)).Unix()); delivery.NextAttemptAt == nil || *delivery.NextAttemptAt != want {
+		t.Errorf("next attempt %v, want %d", delivery.NextAttemptAt, want)
+	}
+
+	// Not due before the backoff has passed
+	if sent := f.deliverDue(t); sent != 0 {
+		t.Errorf("retried before the backoff passed")
+	}
+
+	// A redirect is not followed and counts as a failure
+	f.now = f.now.Add(Backoff(1))
+	f.deliverDue(t)
+	delivery = f.deliveries(t, webhook.ID)[0]
+	if delivery.Status != m.DeliveryPending || delivery.Attempts != 2 || *delivery.ResponseStatus != http.StatusFound {
+		t.Fatalf("after a redirect the delivery is %+v", delivery)
+	}
+	if want := uint32(f.now.Add(Backoff(2)).Unix()); *delivery.NextAttemptAt != want {
+		t.Errorf("next attempt %d, want %d, twice the first wait", *delivery.NextAttemptAt, want)
+	}
+
+	f.now = f.now.Add(Backoff(2))
+	f.deliverDue(t)
+	delivery = f.deliveries(t, webhook.ID)[0]
+	if delivery.Status != m.DeliveryDelivered || delivery.Attempts != 3 || delivery.Error != nil || delivery.NextAttemptAt != nil {
+		t.Errorf("after a 204 the delivery is %+v, want delivered", delivery)
+	}
+
+	requests := rc.received()
+	if len(requests) != 3 || string(requests[0].body) != string(requests[2].body) || requests[0].header.Get(HeaderDelivery) != requests[2].header.Get(HeaderDelivery) {
+		t.Errorf("retries must send the same delivery and payload")
+	}
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	f := newFixture(t)
+	// Nothing listens on a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	webhook := f.webhook(t, server.URL, m.EventTripCreated)
+	if _, err := f.tripStore.CreateTrip(m.Trip{UserId: f.userID, Departure: "JFK", Arrival: "NRT", DepartureTime: 1743501600, ArrivalTime: 1743552000}); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		if sent := f.deliverDue(t); sent != 1 {
+			t.Fatalf("attempt %d: sent %d", attempt, sent)
+		}
+		f.now = f.now.Add(Backoff(attempt))
+	}
+	delivery := f.deliveries(t, webhook.ID)[0]
+	if delivery.Status != m.DeliveryFailed || delivery.Attempts != MaxAttempts || delivery.NextAttemptAt != nil || delivery.Error == nil {
+		t.Errorf("delivery %+v, want failed after %d attempts", delivery, MaxAttempts)
+	}
+	if sent := f.deliverDue(t); sent != 0 {
+		t.Errorf("a failed delivery was sent again")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for failed, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		9:  2 * time.Hour,
+		30: 2 * time.Hour,
+	} {
+		if got := Backoff(failed); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", failed, got, want)
+		}
+	}
+}
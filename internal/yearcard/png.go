@@ -0,0 +1,290 @@
+package yearcard
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func writePNG(w io.Writer, elements []element) error {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	for _, e := range elements {
+		e.draw(img)
+	}
+	return png.Encode(w, img)
+}
+
+func (r rect) draw(img *image.RGBA) {
+	bounds := image.Rect(int(math.Round(r.x)), int(math.Round(r.y)), int(math.Round(r.x+r.width)), int(math.Round(r.y+r.height)))
+	draw.Draw(img, bounds, image.NewUniform(r.fill), image.Point{}, draw.Over)
+}
+
+func (t text) draw(img *image.RGBA) {
+	scale := max(1, int(math.Round(t.size/8)))
+	value := []rune(strings.ToUpper(t.value))
+	width := float64(len(value)*(glyphWidth+1)*scale - scale)
+	x := t.x
+	switch t.anchor {
+	case anchorMiddle:
+		x -= width / 2
+	case anchorEnd:
+		x -= width
+	}
+	left, top := int(math.Round(x)), int(math.Round(t.y))-glyphHeight*scale
+	fill := image.NewUniform(t.fill)
+	for i, r := range value {
+		rows := glyph(r)
+		for row, bits := range rows {
+			for column, bit := range bits {
+				if bit != '#' {
+					continue
+				}
+				px := left + (i*(glyphWidth+1)+column)*scale
+				py := top + row*scale
+				draw.Draw(img, image.Rect(px, py, px+scale, py+scale), fill, image.Point{}, draw.Over)
+			}
+		}
+	}
+}
+
+func (w worldMap) draw(img *image.RGBA) {
+	for _, country := range w.countries {
+		polygons, err := parsePath(country.path)
+		if err != nil {
+			continue
+		}
+		for _, polygon := range polygons {
+			for i := range polygon {
+				polygon[i] = point{w.x + polygon[i].x*w.scale, w.y + polygon[i].y*w.scale}
+			}
+		}
+		fillPolygons(img, polygons, country.fill)
+	}
+}
+
+type point struct{ x, y float64 }
+
+// parsePath reads the outlines of an SVG path. Only straight lines are
+// supported (M, L, H, V and Z, absolute and relative), which is all the
+// country outlines use.
+func parsePath(d string) ([][]point, error) {
+	var polygons [][]point
+	var current []point
+	var at, start point
+	command := byte(0)
+	i := 0
+
+	number := func() (float64, error) {
+		for i < len(d) && (d[i] == ' ' || d[i] == ',' || d[i] == '\n' || d[i] == '\t') {
+			i++
+		}
+		begin := i
+		if i < len(d) && (d[i] == '-' || d[i] == '+') {
+			i++
+		}
+		dot := false
+		for i < len(d) {
+			c := d[i]
+			switch {
+			case c >= '0' && c <= '9':
+			case c == '.' && !dot:
+				dot = true
+			case (c == 'e' || c == 'E') && i+1 < len(d):
+				i++
+				if d[i] != '-' && d[i] != '+' && (d[i] < '0' || d[i] > '9') {
+					return 0, errors.New("invalid number in path")
+				}
+			default:
+				return strconv.ParseFloat(d[begin:i], 64)
+			}
+			i++
+		}
+		return strconv.ParseFloat(d[begin:i], 64)
+	}
+	closePolygon := func() {
+		if len(current) > 2 {
+			polygons = append(polygons, current)
+		}
+		current = nil
+	}
+
+	for {
+		for i < len(d) && (d[i] == ' ' || d[i] == ',' || d[i] == '\n' || d[i] == '\t') {
+			i++
+		}
+		if i >= len(d) {
+			break
+		}
+		if c := d[i]; c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' {
+			command = c
+			i++
+			if command == 'Z' || command == 'z' {
+				closePolygon()
+				at = start
+				continue
+			}
+		}
+
+		relative := command >= 'a'
+		var next point
+		switch command | 0x20 {
+		case 'm', 'l':
+			x, err := number()
+			if err != nil {
+				return nil, err
+			}
+			y, err := number()
+			if err != nil {
+				return nil, err
+			}
+			next = point{x, y}
+			if relative {
+				next = point{at.x + x, at.y + y}
+			}
+		case 'h':
+			x, err := number()
+			if err != nil {
+				return nil, err
+			}
+			next = point{x, at.y}
+			if relative {
+				next.x += at.x
+			}
+		case 'v':
+			y, err := number()
+			if err != nil {
+				return nil, err
+			}
+			next = point{at.x, y}
+			if relative {
+				next.y += at.y
+			}
+		default:
+			return nil, errors.New("unsupported path command " + string(command))
+		}
+
+		if command|0x20 == 'm' {
+			closePolygon()
+			start = next
+			// Coordinates after a move are lines
+			command = 'L' | (command & 0x20)
+		}
+		current = append(current, next)
+		at = next
+	}
+	closePolygon()
+	return polygons, nil
+}
+
+// samples is the number of rows sampled per pixel row, the coverage along a
+// row is exact
+const samples = 4
+
+type edge struct {
+	top, bottom point
+	direction   int
+}
+
+// fillPolygons fills the polygons with the nonzero rule like SVG does,
+// antialiased
+func fillPolygons(img *image.RGBA, polygons [][]point, fill color.RGBA) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	var edges []edge
+	for _, polygon := range polygons {
+		for i, a := range polygon {
+			b := polygon[(i+1)%len(polygon)]
+			minX, maxX = math.Min(minX, a.x), math.Max(maxX, a.x)
+			minY, maxY = math.Min(minY, a.y), math.Max(maxY, a.y)
+			switch {
+			case a.y < b.y:
+				edges = append(edges, edge{a, b, 1})
+			case a.y > b.y:
+				edges = append(edges, edge{b, a, -1})
+			}
+		}
+	}
+	bounds := img.Bounds()
+	x0, x1 := max(bounds.Min.X, int(math.Floor(minX))), min(bounds.Max.X, int(math.Ceil(maxX)))
+	y0, y1 := max(bounds.Min.Y, int(math.Floor(minY))), min(bounds.Max.Y, int(math.Ceil(maxY)))
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+
+	type crossing struct {
+		x         float64
+		direction int
+	}
+	var crossings []crossing
+	coverage := make([]float64, x1-x0)
+	for y := y0; y < y1; y++ {
+		clear(coverage)
+		covered := false
+		for s := 0; s < samples; s++ {
+			sy := float64(y) + (float64(s)+0.5)/samples
+			crossings = crossings[:0]
+			for _, e := range edges {
+				if sy >= e.top.y && sy < e.bottom.y {
+					x := e.top.x + (sy-e.top.y)*(e.bottom.x-e.top.x)/(e.bottom.y-e.top.y)
+					crossings = append(crossings, crossing{x, e.direction})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding, spanStart := 0, 0.0
+			for _, c := range crossings {
+				previous := winding
+				winding += c.direction
+				if previous == 0 && winding != 0 {
+					spanStart = c.x
+				} else if previous != 0 && winding == 0 {
+					addSpan(coverage, spanStart-float64(x0), c.x-float64(x0), 1.0/samples)
+					covered = true
+				}
+			}
+		}
+		if !covered {
+			continue
+		}
+		for i, c := range coverage {
+			if c > 0 {
+				blend(img, x0+i, y, fill, math.Min(c, 1))
+			}
+		}
+	}
+}
+
+// addSpan adds weight to the pixels from a to b, partly covered pixels at
+// the ends get their share
+func addSpan(coverage []float64, a, b, weight float64) {
+	a, b = math.Max(a, 0), math.Min(b, float64(len(coverage)))
+	if b <= a {
+		return
+	}
+	first, last := int(a), int(b)
+	if first == last {
+		coverage[first] += (b - a) * weight
+		return
+	}
+	coverage[first] += (float64(first+1) - a) * weight
+	for i := first + 1; i < last; i++ {
+		coverage[i] += weight
+	}
+	if last < len(coverage) {
+		coverage[last] += (b - float64(last)) * weight
+	}
+}
+
+func blend(img *image.RGBA, x, y int, fill color.RGBA, alpha float64) {
+	dst := img.RGBAAt(x, y)
+	mix := func(d, s uint8) uint8 {
+		return uint8(math.Round(float64(d)*(1-alpha) + float64(s)*alpha))
+	}
+	img.SetRGBA(x, y, color.RGBA{mix(dst.R, fill.R), mix(dst.G, fill.G), mix(dst.B, fill.B), 0xff})
+}
@@ -0,0 +1,91 @@
+package yearcard
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// element is a shape of the card that can be written as SVG and drawn on
+// the PNG
+type element interface {
+	svg(b *strings.Builder)
+	draw(img *image.RGBA)
+}
+
+type anchor string
+
+const (
+	anchorStart  anchor = "start"
+	anchorMiddle anchor = "middle"
+	anchorEnd    anchor = "end"
+)
+
+type rect struct {
+	x, y, width, height float64
+	fill                color.RGBA
+}
+
+// text is a single line with its baseline at y
+type text struct {
+	x, y, size float64
+	bold       bool
+	anchor     anchor
+	fill       color.RGBA
+	value      string
+}
+
+type worldMap struct {
+	x, y, scale float64
+	countries   []mapCountry
+}
+
+type mapCountry struct {
+	code, path string
+	fill       color.RGBA
+}
+
+func writeSVG(w io.Writer, elements []element) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", Width, Height, Width, Height)
+	for _, e := range elements {
+		e.svg(&b)
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r rect) svg(b *strings.Builder) {
+	fmt.Fprintf(b, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`+"\n", r.x, r.y, r.width, r.height, hex(r.fill))
+}
+
+func (t text) svg(b *strings.Builder) {
+	weight := "normal"
+	if t.bold {
+		weight = "bold"
+	}
+	fmt.Fprintf(b, `<text x="%g" y="%g" font-family="Helvetica, Arial, sans-serif" font-size="%g" font-weight="%s" text-anchor="%s" fill="%s">`,
+		t.x, t.y, t.size, weight, t.anchor, hex(t.fill))
+	xml.EscapeText(b, []byte(t.value))
+	b.WriteString("</text>\n")
+}
+
+func (w worldMap) svg(b *strings.Builder) {
+	fmt.Fprintf(b, `<g transform="translate(%g %g) scale(%g)">`+"\n", w.x, w.y, w.scale)
+	for _, country := range w.countries {
+		fmt.Fprintf(b, `<path fill="%s" d="`, hex(country.fill))
+		xml.EscapeText(b, []byte(country.path))
+		b.WriteString(`"><title>`)
+		xml.EscapeText(b, []byte(country.code))
+		b.WriteString("</title></path>\n")
+	}
+	b.WriteString("</g>\n")
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
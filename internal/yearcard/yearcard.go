@@ -0,0 +1,197 @@
+// Package yearcard draws the year in review card: the number of flights,
+// kilometers, hours and countries of a year, the flights per month and a world
+// map with the visited countries. The card is laid out once and written as SVG
+// or as PNG; the PNG is rasterized here with a built-in pixel font so it needs
+// no fonts or image libraries on the server.
+package yearcard
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+// Width and Height of the card in pixels, the size of link previews on social
+// networks
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// Format is one of the image formats
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+// Formats lists the image formats in the order they are offered
+var Formats = []Format{FormatPNG, FormatSVG}
+
+// ParseFormat reads a format name such as "svg"
+func ParseFormat(name string) (Format, bool) {
+	for _, format := range Formats {
+		if string(format) == strings.ToLower(name) {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+func (f Format) ContentType() string {
+	if f == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Label is the name shown on download links
+func (f Format) Label() string {
+	return strings.ToUpper(string(f))
+}
+
+// Write draws the card of the review. countries are the map outlines, see
+// models.CountryMap; the arrival countries of the review are highlighted.
+func Write(w io.Writer, format Format, review m.YearInReview, countries []m.Country) error {
+	elements := layout(review, countries)
+	switch format {
+	case FormatSVG:
+		return writeSVG(w, elements)
+	case FormatPNG:
+		return writePNG(w, elements)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+var (
+	background = color.RGBA{0x0a, 0x0b, 0x10, 0xff} // ink-900
+	land       = color.RGBA{0x1e, 0x23, 0x33, 0xff}
+	visited    = color.RGBA{0x26, 0xe0, 0xb0, 0xff} // mint-500
+	highlight  = color.RGBA{0x37, 0xf5, 0xc0, 0xff} // mint-400
+	grape      = color.RGBA{0xa7, 0x8b, 0xff, 0xff} // grape-400
+	white      = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	muted      = color.RGBA{0x94, 0xa3, 0xb8, 0xff}
+	faint      = color.RGBA{0x64, 0x74, 0x8b, 0xff}
+)
+
+// The map outlines are drawn in a 1010 by 666 box
+const (
+	mapWidth  = 1010.0
+	mapHeight = 666.0
+)
+
+// maxCountryCodes are listed under the map, the others are counted
+const maxCountryCodes = 16
+
+// layout places everything on the card, back to front
+func layout(review m.YearInReview, countries []m.Country) []element {
+	elements := []element{
+		rect{0, 0, Width, Height, background},
+		rect{0, 0, Width, 6, visited},
+		text{60, 118, 80, true, anchorStart, highlight, strconv.Itoa(review.Year)},
+	}
+	title := "YEAR IN REVIEW"
+	if review.Name != "" {
+		title = strings.ToUpper(review.Name) + "'S " + title
+	}
+	elements = append(elements, text{60, 160, 22, true, anchorStart, white, title})
+
+	// Four numbers in two rows
+	stats := []struct {
+		value, label string
+		fill         color.RGBA
+	}{
+		{strconv.Itoa(review.Flights), "FLIGHTS", white},
+		{thousands(review.Totals.TotalKm), "KILOMETERS", white},
+		{thousands(int(math.Round(float64(review.Totals.TotalHours)))), "HOURS IN THE AIR", grape},
+		{strconv.Itoa(len(review.Countries)), "COUNTRIES", grape},
+	}
+	for i, stat := range stats {
+		x := 60.0 + float64(i%2)*250
+		y := 250.0 + float64(i/2)*100
+		elements = append(elements,
+			text{x, y, 48, true, anchorStart, stat.fill, stat.value},
+			text{x, y + 28, 16, false, anchorStart, muted, stat.label},
+		)
+	}
+
+	// Flights per month as bars over the initials of the months
+	elements = append(elements, text{60, 440, 16, false, anchorStart, muted, "FLIGHTS PER MONTH"})
+	most := 0
+	for _, month := range review.FlightsPerMonth {
+		most = max(most, month.Count)
+	}
+	const chartBottom, chartHeight, slot = 550.0, 80.0, 40.0
+	for i, initial := range strings.Split("JFMAMJJASOND", "") {
+		x := 60 + float64(i)*slot
+		height := 3.0
+		fill := land
+		if i < len(review.FlightsPerMonth) && review.FlightsPerMonth[i].Count > 0 && most > 0 {
+			height = math.Max(6, math.Round(chartHeight*float64(review.FlightsPerMonth[i].Count)/float64(most)))
+			fill = visited
+		}
+		elements = append(elements,
+			rect{x + 6, chartBottom - height, slot - 12, height, fill},
+			text{x + slot/2, chartBottom + 24, 16, false, anchorMiddle, muted, initial},
+		)
+	}
+
+	// The world map on the right with the arrival countries highlighted
+	arrived := make(map[string]bool)
+	var codes []string
+	for _, country := range review.Countries {
+		code := strings.ToUpper(country.Label)
+		if !arrived[code] {
+			arrived[code] = true
+			codes = append(codes, code)
+		}
+	}
+	world := worldMap{x: 600, y: 100, scale: 560 / mapWidth}
+	for _, country := range countries {
+		fill := land
+		if arrived[strings.ToUpper(country.ISOCode)] {
+			fill = visited
+		}
+		world.countries = append(world.countries, mapCountry{code: country.ISOCode, path: country.Path, fill: fill})
+	}
+	elements = append(elements, world)
+
+	listed := codes
+	if len(listed) > maxCountryCodes {
+		listed = listed[:maxCountryCodes]
+	}
+	mapBottom := world.y + mapHeight*world.scale
+	for line := 0; line*8 < len(listed); line++ {
+		row := listed[line*8 : min(len(listed), line*8+8)]
+		value := strings.Join(row, "  ")
+		if line*8+8 >= len(listed) && len(codes) > len(listed) {
+			value += fmt.Sprintf("  +%d", len(codes)-len(listed))
+		}
+		elements = append(elements, text{600, mapBottom + 40 + float64(line)*28, 18, true, anchorStart, white, value})
+	}
+
+	elements = append(elements, text{Width - 40, Height - 28, 14, false, anchorEnd, faint, "TRIP TRACKER"})
+	return elements
+}
+
+// thousands formats n with comma separators, 12345 is "12,345"
+func thousands(n int) string {
+	digits := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + thousands(-n)
+	}
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package yearcard
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	m "github.com/skywall34/trip-tracker/internal/models"
+)
+
+func TestParsePath(t *testing.T) {
+	polygons, err := parsePath("M10,10 L20,10 l0,10 H10 Z m5 5 h2 v2 h-2 z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]point{
+		{{10, 10}, {20, 10}, {20, 20}, {10, 20}},
+		{{15, 15}, {17, 15}, {17, 17}, {15, 17}},
+	}
+	if len(polygons) != len(want) {
+		t.Fatalf("got %d polygons, want %d", len(polygons), len(want))
+	}
+	for i := range want {
+		if len(polygons[i]) != len(want[i]) {
+			t.Fatalf("polygon %d is %v, want %v", i, polygons[i], want[i])
+		}
+		for j := range want[i] {
+			if polygons[i][j] != want[i][j] {
+				t.Errorf("polygon %d is %v, want %v", i, polygons[i], want[i])
+				break
+			}
+		}
+	}
+
+	if _, err := parsePath("M0 0 C1 1 2 2 3 3"); err == nil {
+		t.Errorf("curves are not supported")
+	}
+}
+
+func TestThousands(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567", -12345: "-12,345"} {
+		if got := thousands(n); got != want {
+			t.Errorf("thousands(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	square := m.Country{ISOCode: "JP", Path: "M0,0 H1010 V666 H0 Z"}
+	review := m.YearInReview{
+		Year:      2025,
+		Name:      "Ada",
+		Flights:   2,
+		Countries: []m.CountryAggregation{{Label: "JP", Count: 2}},
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, FormatPNG, review, []m.Country{square}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != Width || size.Y != Height {
+		t.Fatalf("the card is %v", size)
+	}
+	// The visited country covers the whole map
+	if r, g, bl, _ := img.At(880, 250).RGBA(); r>>8 != 0x26 || g>>8 != 0xe0 || bl>>8 != 0xb0 {
+		t.Errorf("the map is #%02x%02x%02x, want the visited color", r>>8, g>>8, bl>>8)
+	}
+
+	b.Reset()
+	if err := Write(&b, FormatSVG, review, []m.Country{square}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`width="1200" height="630"`, ">ADA&#39;S YEAR IN REVIEW</text>", `fill="#26e0b0" d="M0,0 H1010 V666 H0 Z"`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("the SVG does not have %s:\n%s", want, b.String())
+		}
+	}
+
+	if err := Write(&b, Format("gif"), review, nil); err == nil {
+		t.Errorf("unknown formats are an error")
+	}
+}
@@ -2,19 +2,26 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/oauth2"
 
 	"github.com/skywall34/trip-tracker/internal/api"
 	"github.com/skywall34/trip-tracker/internal/database"
 	"github.com/skywall34/trip-tracker/internal/handlers"
 	m "github.com/skywall34/trip-tracker/internal/middleware"
 	"github.com/skywall34/trip-tracker/internal/models"
+	"github.com/skywall34/trip-tracker/internal/smtpd"
+	"github.com/skywall34/trip-tracker/internal/webhook"
 )
 
 // basePathMiddleware injects the base path into request context and rewrites
@@ -44,56 +51,218 @@ func (bw *basePathResponseWriter) WriteHeader(code int) {
 	bw.ResponseWriter.WriteHeader(code)
 }
 
-func main() {
+const dbPath = "file:./internal/database/database.db?_enable_math_functions=1"
 
-	dotenvPath := os.Getenv("DOTENV_PATH")
-	if dotenvPath == "" {
-		dotenvPath = ".env" // default if not set
+// runMigrateCommand handles the -migrate flag. "status" lists every migration and
+// whether it has been applied, "dry-run" prints the SQL that would be applied and
+// "up" applies pending migrations without starting the server.
+func runMigrateCommand(mode string) error {
+	db, err := database.OpenDB(dbPath)
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
-	if err := godotenv.Load(dotenvPath); err != nil {
-		log.Fatalf("Error loading .env file %s", err)
+	switch mode {
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			status := "pending"
+			if state.Applied {
+				status = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", state.Version, state.Name, status)
+		}
+	case "dry-run":
+		pending, err := database.PendingMigrations(db)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			fmt.Println("Database is up to date")
+		}
+		for _, migration := range pending {
+			fmt.Printf("-- %04d_%s\n%s\n", migration.Version, migration.Name, migration.SQL)
+		}
+	case "up":
+		return database.Migrate(db)
+	default:
+		return fmt.Errorf("unknown migrate mode %q, expected status, dry-run or up", mode)
 	}
+	return nil
+}
 
-	basePath := os.Getenv("BASE_PATH") // "" for local dev, "/fromnto" for production
-
-	db, err := database.InitDB("file:./internal/database/database.db?_enable_math_functions=1")
+// runImportAirportsCommand handles the -import-airports flag. Airports are upserted
+// by IATA code so the same file can be imported repeatedly. Timezones missing from
+// the CSV are merged in from airport2timezone.json.
+func runImportAirportsCommand(csvPath string) error {
+	db, err := database.InitDB(dbPath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer db.Close()
 
-	// Load the countries from a JSON file into memory
-	if err := models.LoadCountriesFromFile("./static/data/countries.json"); err != nil {
-		log.Fatalf("Failed to load countries: %v", err)
+	airports, err := models.LoadAirportsFromCSV(csvPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", csvPath, err)
 	}
+
 	if err := models.LoadAirportTimezonesFromFile("./static/data/airport2timezone.json"); err != nil {
-		log.Fatalf("Failed to load airport timezones: %v", err)
+		log.Printf("Skipping timezone merge, could not load airport timezones: %v", err)
+	}
+	missing := models.MergeAirportTimezones(airports, models.AirportTimezoneLookup)
+
+	airportStore := database.NewAirportStore(database.NewAirportStoreParams{DB: db})
+	count, err := airportStore.UpsertAirports(airports)
+	if err != nil {
+		return err
+	}
+	if err := database.RebuildSearchIndex(db); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d airports (%d without a timezone)\n", count, missing)
+	return nil
+}
+
+const defaultTrashRetentionDays = 30
+
+// trashRetentionDays reads TRASH_RETENTION_DAYS, how long deleted trips and places
+// stay in the trash before the purge job removes them
+func trashRetentionDays() int {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return defaultTrashRetentionDays
+	}
+	return days
+}
+
+// startTrashPurgeJob permanently deletes trips and places that have been in the
+// trash for longer than the retention period. It runs at startup and then hourly.
+func startTrashPurgeJob(tripStore *database.TripStore, placeStore *database.PlaceStore, retentionDays int) {
+	purge := func() {
+		purgeTrash(tripStore, placeStore, time.Now().AddDate(0, 0, -retentionDays))
 	}
 
+	go func() {
+		purge()
+		for range time.Tick(time.Hour) {
+			purge()
+		}
+	}()
+}
+
+// purgeTrash runs one pass of the purge job, removing every user's trips and
+// places that went to the trash before the cutoff
+func purgeTrash(tripStore *database.TripStore, placeStore *database.PlaceStore, cutoff time.Time) {
+	trips, err := tripStore.PurgeTripsDeletedBefore(cutoff)
+	if err != nil {
+		log.Printf("Error purging deleted trips: %v", err)
+	}
+	places, err := placeStore.PurgePlacesDeletedBefore(cutoff)
+	if err != nil {
+		log.Printf("Error purging deleted places: %v", err)
+	}
+	if trips > 0 || places > 0 {
+		log.Printf("Purged %d trips and %d places from the trash", trips, places)
+	}
+}
+
+// startMailServer receives forwarded confirmation emails when SMTP_ADDR is set.
+// Mail is accepted for the forwarding addresses on MAIL_DOMAIN, see
+// handlers.TripDrafter.
+func startMailServer(db *sql.DB) {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return
+	}
+	domain := os.Getenv("MAIL_DOMAIN")
+	if domain == "" {
+		log.Printf("SMTP_ADDR is set without MAIL_DOMAIN, all mail will be refused")
+	}
+
+	drafter := handlers.NewTripDrafter(handlers.TripDrafterParams{
+		AirportStore:   database.NewAirportStore(database.NewAirportStoreParams{DB: db}),
+		TripDraftStore: database.NewTripDraftStore(database.NewTripDraftStoreParams{DB: db}),
+		MailInboxStore: database.NewMailInboxStore(database.NewMailInboxStoreParams{DB: db}),
+	})
+	server := &smtpd.Server{
+		Addr:      addr,
+		Hostname:  domain,
+		Recipient: drafter.Recipient,
+		Deliver:   drafter.Deliver,
+	}
+
+	go func() {
+		fmt.Printf("Receiving mail on %s\n", addr)
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("Mail server stopped: %v", err)
+		}
+	}()
+}
+
+// startWebhookDispatcher sends the webhook deliveries queued by trip and place
+// changes, see internal/webhook
+func startWebhookDispatcher(db *sql.DB) {
+	dispatcher := webhook.NewDispatcher(webhook.DispatcherParams{
+		Store: database.NewWebhookStore(database.NewWebhookStoreParams{DB: db}),
+	})
+	go dispatcher.Run(context.Background(), 5*time.Second)
+}
+
+// newAppMux registers every route of the app. main_test.go checks that each route
+// registered here is covered by the regression suite.
+func newAppMux(db *sql.DB, emailService models.EmailService, googleOauthConfig *oauth2.Config, retentionDays int) *http.ServeMux {
+	airportStore := database.NewAirportStore(database.NewAirportStoreParams{DB: db})
 	userStore := database.NewUserStore(database.NewUserStoreParams{DB: db})
 	tripStore := database.NewTripStore(database.NewTripStoreParams{DB: db})
 	sessionStore := database.NewSessionStore(database.NewSessionStoreParams{DB: db})
 	passwordResetStore := database.NewPasswordResetStore(database.PasswordResetStoreParams{DB: db})
 	placeStore := database.NewPlaceStore(db)
-	//TODO: Chaining middleware seems to break css for some reason
-	authMiddleware := m.NewAuthMiddleware(sessionStore, "session_id")
+	journeyStore := database.NewJourneyStore(database.NewJourneyStoreParams{DB: db})
+	historyStore := database.NewHistoryStore(database.NewHistoryStoreParams{DB: db})
+	searchStore := database.NewSearchStore(database.NewSearchStoreParams{DB: db})
+	calendarFeedStore := database.NewCalendarFeedStore(database.NewCalendarFeedStoreParams{DB: db})
+	importReviewStore := database.NewImportReviewStore(database.NewImportReviewStoreParams{DB: db})
+	tripDraftStore := database.NewTripDraftStore(database.NewTripDraftStoreParams{DB: db})
+	mailInboxStore := database.NewMailInboxStore(database.NewMailInboxStoreParams{DB: db})
+	attachmentStore := database.NewAttachmentStore(database.NewAttachmentStoreParams{DB: db})
+	yearReviewShareStore := database.NewYearReviewShareStore(database.NewYearReviewShareStoreParams{DB: db})
+	accessTokenStore := database.NewAccessTokenStore(database.NewAccessTokenStoreParams{DB: db})
+	webhookStore := database.NewWebhookStore(database.NewWebhookStoreParams{DB: db})
 
-	// Google OAuth Initilization to Add the Environemnt Variables
-	googleOauthConfig := api.NewGoogleOauthConfig()
+	//TODO: Chaining middleware seems to break css for some reason
+	authMiddleware := m.NewAuthMiddleware(sessionStore, accessTokenStore, "session_id")
 
-	// Email Service for Resetting Passwords
-	// Placeholders for now
-	gmailUser := os.Getenv("GMAIL_SERVICE_APP_USERNAME")
-	gmailPsw := os.Getenv("GMAIL_SERVICE_APP_PASSWORD")
-	emailService := models.EmailService{
-		SMTPHost: "smtp.gmail.com",
-		SMTPPort: 587,
-		Username: gmailUser,
-		Password: gmailPsw, // use App Password, not real password
-		From:     gmailUser,
+	// Routes that take a record id only run when the record belongs to the user
+	ownership := m.NewOwnershipMiddleware(database.NewAuthorizer(database.NewAuthorizerParams{DB: db}))
+	ownedTrip := []m.OwnedParam{{Name: "id", Resource: database.ResourceTrip}}
+	ownedPlace := []m.OwnedParam{{Name: "id", Resource: database.ResourcePlace}}
+	ownedJourney := []m.OwnedParam{{Name: "id", Resource: database.ResourceJourney}}
+	ownedItinerary := []m.OwnedParam{
+		{Name: "id", Resource: database.ResourceItinerary},
+		{Name: "trip_id", Resource: database.ResourceTrip},
+	}
+	ownedJourneyMember := []m.OwnedParam{
+		{Name: "journey_id", Resource: database.ResourceJourney},
+		{Name: "trip_id", Resource: database.ResourceTrip},
+		{Name: "place_id", Resource: database.ResourcePlace},
+	}
+	ownedByType := []m.OwnedParam{{Name: "id", TypeParam: "type"}}
+	ownedChange := []m.OwnedParam{{Name: "id", Resource: database.ResourceChange}}
+	ownedReview := []m.OwnedParam{{Name: "id", Resource: database.ResourceReview}}
+	ownedDraft := []m.OwnedParam{{Name: "id", Resource: database.ResourceDraft}}
+	ownedAttachment := []m.OwnedParam{{Name: "id", Resource: database.ResourceAttachment}}
+	ownedAccessToken := []m.OwnedParam{{Name: "id", Resource: database.ResourceToken}}
+	ownedWebhook := []m.OwnedParam{{Name: "id", Resource: database.ResourceWebhook}}
+	ownedExportJourney := []m.OwnedParam{{Name: "journey", Resource: database.ResourceJourney}}
+	ownedExportItinerary := []m.OwnedParam{
+		{Name: "trip", Resource: database.ResourceTrip},
+		{Name: "journey", Resource: database.ResourceJourney},
 	}
-
-	//
 
 	appMux := http.NewServeMux()
 
@@ -130,26 +299,221 @@ func main() {
 					m.LoggingMiddleware(
 						handlers.NewPostTripHandler(
 							handlers.PostTripHandlerParams{
-								TripStore: tripStore}).ServeHTTP)))))
+								TripStore:    tripStore,
+								AirportStore: airportStore}).ServeHTTP)))))
 
 	appMux.Handle("PUT /trips",
 		authMiddleware.AddUserToContext(
 			m.CSPMiddleware(
 				m.TextHTMLMiddleware(
-					m.LoggingMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedTrip,
 						handlers.NewEditTripHandler(
 							handlers.EditTripHandlerParams{
-								TripStore: tripStore}).ServeHTTP)))))
+								TripStore:    tripStore,
+								AirportStore: airportStore}).ServeHTTP))))))
 
 	appMux.Handle("DELETE /trips",
 		authMiddleware.AddUserToContext(
 			m.CSPMiddleware(
 				m.TextHTMLMiddleware(
-					m.LoggingMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedTrip,
 						handlers.NewDeleteTripHandler(
 							handlers.DeleteTripHandlerParams{
+								TripStore: tripStore}).ServeHTTP))))))
+
+	// Flights read from forwarded confirmation emails, see handlers.TripDrafter
+	appMux.Handle("GET /trips/drafts",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetTripDraftsHandler(
+							handlers.GetTripDraftsHandlerParams{
+								TripDraftStore: tripDraftStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /trips/drafts",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedDraft,
+						handlers.NewPostTripDraftHandler(
+							handlers.PostTripDraftHandlerParams{
+								AirportStore:   airportStore,
+								TripStore:      tripStore,
+								TripDraftStore: tripDraftStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("DELETE /trips/drafts",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedDraft,
+						handlers.NewDeleteTripDraftHandler(
+							handlers.DeleteTripDraftHandlerParams{
+								TripDraftStore: tripDraftStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("POST /trips/drafts/eml",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostTripDraftMailHandler(
+							handlers.PostTripDraftMailHandlerParams{
+								TripDrafter: handlers.NewTripDrafter(handlers.TripDrafterParams{
+									AirportStore:   airportStore,
+									TripDraftStore: tripDraftStore,
+									MailInboxStore: mailInboxStore,
+								}),
+								TripDraftStore: tripDraftStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /trips/boardingpass",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostBoardingPassHandler(
+							handlers.PostBoardingPassHandlerParams{
+								AirportStore:   airportStore,
+								TripStore:      tripStore,
+								TripDraftStore: tripDraftStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /trips/pkpass",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostWalletPassHandler(
+							handlers.PostWalletPassHandlerParams{
+								AirportStore:    airportStore,
+								TripStore:       tripStore,
+								TripDraftStore:  tripDraftStore,
+								AttachmentStore: attachmentStore,
+							}).ServeHTTP)))))
+
+	// Files kept with trips and places
+	appMux.Handle("GET /attachments",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedByType,
+						handlers.NewGetAttachmentsHandler(
+							handlers.GetAttachmentsHandlerParams{
+								AttachmentStore: attachmentStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("GET /attachments/file",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.LoggingMiddleware(ownership.RequireOwnership(ownedAttachment,
+					handlers.NewGetAttachmentFileHandler(
+						handlers.GetAttachmentFileHandlerParams{
+							AttachmentStore: attachmentStore,
+						}).ServeHTTP)))))
+
+	// Itinerary Routes
+	appMux.Handle("POST /itineraries",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostItineraryHandler(
+							handlers.PostItineraryHandlerParams{
 								TripStore: tripStore}).ServeHTTP)))))
 
+	appMux.Handle("DELETE /itineraries",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedItinerary,
+						handlers.NewDeleteItineraryHandler(
+							handlers.DeleteItineraryHandlerParams{
+								TripStore: tripStore}).ServeHTTP))))))
+
+	// Journey Routes
+	appMux.Handle("GET /journeys",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetJourneysHandler(
+							handlers.GetJourneysHandlerParams{
+								JourneyStore: journeyStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /journeys",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostJourneyHandler(
+							handlers.PostJourneyHandlerParams{
+								JourneyStore: journeyStore,
+								TripStore:    tripStore,
+								PlaceStore:   placeStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("PUT /journeys",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedJourney,
+						handlers.NewPutJourneyHandler(
+							handlers.PutJourneyHandlerParams{
+								JourneyStore: journeyStore,
+								TripStore:    tripStore,
+								PlaceStore:   placeStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("DELETE /journeys",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedJourney,
+						handlers.NewDeleteJourneyHandler(
+							handlers.DeleteJourneyHandlerParams{
+								JourneyStore: journeyStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("GET /journey",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedJourney,
+						handlers.NewGetJourneyHandler(
+							handlers.GetJourneyHandlerParams{
+								JourneyStore: journeyStore,
+								TripStore:    tripStore,
+								PlaceStore:   placeStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("POST /journeys/members",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedJourneyMember,
+						handlers.NewPostJourneyMemberHandler(
+							handlers.PostJourneyMemberHandlerParams{
+								JourneyStore: journeyStore,
+								TripStore:    tripStore,
+								PlaceStore:   placeStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("DELETE /journeys/members",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedJourneyMember,
+						handlers.NewDeleteJourneyMemberHandler(
+							handlers.DeleteJourneyMemberHandlerParams{
+								JourneyStore: journeyStore,
+								TripStore:    tripStore,
+								PlaceStore:   placeStore,
+							}).ServeHTTP))))))
+
 	// Places Routes
 	appMux.Handle("GET /places",
 		authMiddleware.AddUserToContext(
@@ -176,31 +540,31 @@ func main() {
 		authMiddleware.AddUserToContext(
 			m.CSPMiddleware(
 				m.TextHTMLMiddleware(
-					m.LoggingMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedPlace,
 						handlers.NewPutPlaceHandler(
 							handlers.PutPlaceHandlerParams{
 								PlaceStore: placeStore,
-							}).ServeHTTP)))))
+							}).ServeHTTP))))))
 
 	appMux.Handle("DELETE /places",
 		authMiddleware.AddUserToContext(
 			m.CSPMiddleware(
 				m.TextHTMLMiddleware(
-					m.LoggingMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedPlace,
 						handlers.NewDeletePlaceHandler(
 							handlers.DeletePlaceHandlerParams{
 								PlaceStore: placeStore,
-							}).ServeHTTP)))))
+							}).ServeHTTP))))))
 
 	appMux.Handle("GET /editplaceform",
 		authMiddleware.AddUserToContext(
 			m.CSPMiddleware(
 				m.TextHTMLMiddleware(
-					m.LoggingMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedPlace,
 						handlers.NewGetEditPlaceFormHandler(
 							handlers.GetEditPlaceFormHandlerParams{
 								PlaceStore: placeStore,
-							}).ServeHTTP)))))
+							}).ServeHTTP))))))
 
 	// Google Places API routes
 	appMux.Handle("GET /api/places/search",
@@ -284,6 +648,423 @@ func main() {
 								TripStore: tripStore,
 							}).ServeHTTP)))))
 
+	// The year in review card is an image, see internal/yearcard
+	appMux.Handle("GET /statistics/review",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetYearReviewHandler(
+						handlers.GetYearReviewHandlerParams{
+							UserStore: userStore,
+							TripStore: tripStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("GET /statistics/review/panel",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetYearReviewPanelHandler(
+							handlers.GetYearReviewPanelHandlerParams{
+								TripStore:            tripStore,
+								YearReviewShareStore: yearReviewShareStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /statistics/review/share",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostYearReviewShareHandler(
+							handlers.PostYearReviewShareHandlerParams{
+								TripStore:            tripStore,
+								YearReviewShareStore: yearReviewShareStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("DELETE /statistics/review/share",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewDeleteYearReviewShareHandler(
+							handlers.DeleteYearReviewShareHandlerParams{
+								TripStore:            tripStore,
+								YearReviewShareStore: yearReviewShareStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("GET /settings",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetSettingsHandler(
+							handlers.GetSettingsHandlerParams{
+								UserStore:         userStore,
+								CalendarFeedStore: calendarFeedStore,
+								MailInboxStore:    mailInboxStore,
+								AccessTokenStore:  accessTokenStore,
+								WebhookStore:      webhookStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("PUT /settings/layover",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPutLayoverSettingHandler(
+							handlers.PutLayoverSettingHandlerParams{
+								UserStore: userStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /settings/calendar",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostCalendarFeedHandler(
+							handlers.PostCalendarFeedHandlerParams{
+								CalendarFeedStore: calendarFeedStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("DELETE /settings/calendar",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewDeleteCalendarFeedHandler(
+							handlers.DeleteCalendarFeedHandlerParams{
+								CalendarFeedStore: calendarFeedStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /settings/mail",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostMailInboxHandler(
+							handlers.PostMailInboxHandlerParams{
+								MailInboxStore: mailInboxStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("DELETE /settings/mail",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewDeleteMailInboxHandler(
+							handlers.DeleteMailInboxHandlerParams{
+								MailInboxStore: mailInboxStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /settings/tokens",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostAccessTokenHandler(
+							handlers.PostAccessTokenHandlerParams{
+								AccessTokenStore: accessTokenStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("DELETE /settings/tokens",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedAccessToken,
+						handlers.NewDeleteAccessTokenHandler(
+							handlers.DeleteAccessTokenHandlerParams{
+								AccessTokenStore: accessTokenStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("POST /settings/webhooks",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostWebhookHandler(
+							handlers.PostWebhookHandlerParams{
+								WebhookStore: webhookStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("DELETE /settings/webhooks",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedWebhook,
+						handlers.NewDeleteWebhookHandler(
+							handlers.DeleteWebhookHandlerParams{
+								WebhookStore: webhookStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("GET /settings/webhooks/deliveries",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedWebhook,
+						handlers.NewGetWebhookDeliveriesHandler(
+							handlers.GetWebhookDeliveriesHandlerParams{
+								WebhookStore: webhookStore,
+							}).ServeHTTP))))))
+
+	// Account archives are file downloads and uploads, see internal/archive
+	appMux.Handle("GET /settings/account/export",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetAccountExportHandler(
+						handlers.GetAccountExportHandlerParams{
+							UserStore:       userStore,
+							SessionStore:    sessionStore,
+							TripStore:       tripStore,
+							PlaceStore:      placeStore,
+							JourneyStore:    journeyStore,
+							AttachmentStore: attachmentStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("POST /settings/account/import",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostAccountImportHandler(
+							handlers.PostAccountImportHandlerParams{
+								UserStore:       userStore,
+								AirportStore:    airportStore,
+								TripStore:       tripStore,
+								PlaceStore:      placeStore,
+								JourneyStore:    journeyStore,
+								AttachmentStore: attachmentStore,
+							}).ServeHTTP)))))
+
+	// Calendar apps subscribe without a session, the secret token in the path identifies the user
+	appMux.Handle("GET /calendar/{token}",
+		m.LoggingMiddleware(
+			handlers.NewGetCalendarFeedHandler(
+				handlers.GetCalendarFeedHandlerParams{
+					CalendarFeedStore: calendarFeedStore,
+					TripStore:         tripStore,
+				}).ServeHTTP))
+
+	// Published year in review cards are public, the secret token in the path identifies the user and the year
+	appMux.Handle("GET /review/{token}",
+		m.LoggingMiddleware(
+			handlers.NewGetYearReviewShareHandler(
+				handlers.GetYearReviewShareHandlerParams{
+					UserStore:            userStore,
+					TripStore:            tripStore,
+					YearReviewShareStore: yearReviewShareStore,
+				}).ServeHTTP))
+
+	appMux.Handle("GET /search",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetSearchHandler(
+							handlers.GetSearchHandlerParams{
+								SearchStore: searchStore,
+							}).ServeHTTP)))))
+
+	// Import Routes
+	appMux.Handle("GET /import",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetImportHandler(
+							handlers.GetImportHandlerParams{
+								TripStore:    tripStore,
+								PlaceStore:   placeStore,
+								JourneyStore: journeyStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /import/ics/preview",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostImportICSPreviewHandler(
+							handlers.PostImportICSPreviewHandlerParams{
+								AirportStore: airportStore,
+								TripStore:    tripStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /import/ics",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostImportICSHandler(
+							handlers.PostImportICSHandlerParams{
+								AirportStore: airportStore,
+								TripStore:    tripStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /import/csv/trips",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostImportCSVTripsHandler(
+							handlers.PostImportCSVTripsHandlerParams{
+								AirportStore: airportStore,
+								TripStore:    tripStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /import/csv/places",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostImportCSVPlacesHandler(
+							handlers.PostImportCSVPlacesHandlerParams{
+								PlaceStore: placeStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /import/flightlog",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewPostImportFlightLogHandler(
+							handlers.PostImportFlightLogHandlerParams{
+								AirportStore:      airportStore,
+								TripStore:         tripStore,
+								ImportReviewStore: importReviewStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("GET /import/reviews",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetImportReviewsHandler(
+							handlers.GetImportReviewsHandlerParams{
+								ImportReviewStore: importReviewStore,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /import/reviews",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedReview,
+						handlers.NewPostImportReviewHandler(
+							handlers.PostImportReviewHandlerParams{
+								AirportStore:      airportStore,
+								TripStore:         tripStore,
+								ImportReviewStore: importReviewStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("DELETE /import/reviews",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedReview,
+						handlers.NewDeleteImportReviewHandler(
+							handlers.DeleteImportReviewHandlerParams{
+								ImportReviewStore: importReviewStore,
+							}).ServeHTTP))))))
+
+	// Export Routes
+	appMux.Handle("GET /export/trips.csv",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetExportTripsHandler(
+						handlers.GetExportTripsHandlerParams{
+							TripStore: tripStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("GET /export/places.csv",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetExportPlacesHandler(
+						handlers.GetExportPlacesHandlerParams{
+							PlaceStore: placeStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("GET /export/map",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.LoggingMiddleware(ownership.RequireOwnership(ownedExportJourney,
+					handlers.NewGetExportMapHandler(
+						handlers.GetExportMapHandlerParams{
+							TripStore:    tripStore,
+							PlaceStore:   placeStore,
+							JourneyStore: journeyStore,
+						}).ServeHTTP)))))
+
+	appMux.Handle("GET /export/itinerary",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.LoggingMiddleware(ownership.RequireOwnership(ownedExportItinerary,
+					handlers.NewGetExportItineraryHandler(
+						handlers.GetExportItineraryHandlerParams{
+							AirportStore: airportStore,
+							TripStore:    tripStore,
+							PlaceStore:   placeStore,
+							JourneyStore: journeyStore,
+						}).ServeHTTP)))))
+
+	// History Routes
+	appMux.Handle("GET /history",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedByType,
+						handlers.NewGetHistoryHandler(
+							handlers.GetHistoryHandlerParams{
+								HistoryStore: historyStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("POST /history/revert",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedChange,
+						handlers.NewPostHistoryRevertHandler(
+							handlers.PostHistoryRevertHandlerParams{
+								HistoryStore: historyStore,
+								TripStore:    tripStore,
+								PlaceStore:   placeStore,
+							}).ServeHTTP))))))
+
+	// Trash Routes
+	appMux.Handle("GET /trash",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(
+						handlers.NewGetTrashHandler(
+							handlers.GetTrashHandlerParams{
+								TripStore:     tripStore,
+								PlaceStore:    placeStore,
+								RetentionDays: retentionDays,
+							}).ServeHTTP)))))
+
+	appMux.Handle("POST /trash/restore",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedByType,
+						handlers.NewPostTrashRestoreHandler(
+							handlers.PostTrashRestoreHandlerParams{
+								TripStore:  tripStore,
+								PlaceStore: placeStore,
+							}).ServeHTTP))))))
+
+	appMux.Handle("DELETE /trash",
+		authMiddleware.AddUserToContext(
+			m.CSPMiddleware(
+				m.TextHTMLMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedByType,
+						handlers.NewDeleteTrashHandler(
+							handlers.DeleteTrashHandlerParams{
+								TripStore:  tripStore,
+								PlaceStore: placeStore,
+							}).ServeHTTP))))))
+
 	appMux.Handle("GET /worldmap",
 		authMiddleware.AddUserToContext(
 			m.CSPMiddleware(
@@ -312,11 +1093,11 @@ func main() {
 		authMiddleware.AddUserToContext(
 			m.CSPMiddleware(
 				m.TextHTMLMiddleware(
-					m.LoggingMiddleware(
+					m.LoggingMiddleware(ownership.RequireOwnership(ownedTrip,
 						handlers.NewGetEditTripHandlerParmas(
 							handlers.GetEditTripHandlerParams{
 								TripStore: tripStore,
-							}).ServeHTTP)))))
+							}).ServeHTTP))))))
 
 	appMux.Handle("GET /forgot-password",
 		m.CSPMiddleware(
@@ -330,24 +1111,136 @@ func main() {
 				m.LoggingMiddleware(
 					handlers.NewGetResetPasswordHandlerParams().ServeHTTP))))
 
-	// API CALLS TODO: Possibly version
+	// JSON API, errors are JSON objects as well, see internal/handlers/apiv1.go.
+	// Calls are authenticated by the session or by an access token with the scope.
+	appMux.Handle("GET /api/v1/trips",
+		authMiddleware.AddAPIUserToContext(models.ScopeReadTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetApiTripsHandler(
+						handlers.GetApiTripsHandlerParams{
+							TripStore: tripStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("POST /api/v1/trips",
+		authMiddleware.AddAPIUserToContext(models.ScopeWriteTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewPostApiTripHandler(
+						handlers.PostApiTripHandlerParams{
+							TripStore:    tripStore,
+							AirportStore: airportStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("GET /api/v1/trips/{id}",
+		authMiddleware.AddAPIUserToContext(models.ScopeReadTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetApiTripHandler(
+						handlers.GetApiTripHandlerParams{
+							TripStore: tripStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("PUT /api/v1/trips/{id}",
+		authMiddleware.AddAPIUserToContext(models.ScopeWriteTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewPutApiTripHandler(
+						handlers.PutApiTripHandlerParams{
+							TripStore:    tripStore,
+							AirportStore: airportStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("DELETE /api/v1/trips/{id}",
+		authMiddleware.AddAPIUserToContext(models.ScopeWriteTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewDeleteApiTripHandler(
+						handlers.DeleteApiTripHandlerParams{
+							TripStore: tripStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("GET /api/v1/places",
+		authMiddleware.AddAPIUserToContext(models.ScopeReadTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetApiPlacesHandler(
+						handlers.GetApiPlacesHandlerParams{
+							PlaceStore: placeStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("POST /api/v1/places",
+		authMiddleware.AddAPIUserToContext(models.ScopeWriteTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewPostApiPlaceHandler(
+						handlers.PostApiPlaceHandlerParams{
+							PlaceStore: placeStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("GET /api/v1/places/{id}",
+		authMiddleware.AddAPIUserToContext(models.ScopeReadTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetApiPlaceHandler(
+						handlers.GetApiPlaceHandlerParams{
+							PlaceStore: placeStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("PUT /api/v1/places/{id}",
+		authMiddleware.AddAPIUserToContext(models.ScopeWriteTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewPutApiPlaceHandler(
+						handlers.PutApiPlaceHandlerParams{
+							PlaceStore: placeStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("DELETE /api/v1/places/{id}",
+		authMiddleware.AddAPIUserToContext(models.ScopeWriteTrips,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewDeleteApiPlaceHandler(
+						handlers.DeleteApiPlaceHandlerParams{
+							PlaceStore: placeStore,
+						}).ServeHTTP))))
+
+	appMux.Handle("GET /api/v1/stats",
+		authMiddleware.AddAPIUserToContext(models.ScopeReadStats,
+			m.CSPMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetApiStatsHandler(
+						handlers.GetApiStatsHandlerParams{
+							TripStore: tripStore,
+						}).ServeHTTP))))
+
+	// Unversioned API calls used by the pages. Airport and flight lookups hold
+	// no user data, any access token may call them.
 	appMux.Handle("GET /api/flights",
-		authMiddleware.AddUserToContext(
+		authMiddleware.AddAPIUserToContext("",
 			m.CSPMiddleware(
 				m.TextHTMLMiddleware(
 					m.LoggingMiddleware(
 						handlers.NewGetFlightHandler().ServeHTTP)))))
 
 	appMux.Handle("GET /api/trips",
-		authMiddleware.AddUserToContext(
+		authMiddleware.AddAPIUserToContext(models.ScopeReadTrips,
 			m.CSPMiddleware(
 				m.LoggingMiddleware(
 					handlers.NewGetTripMapApiHandler(
 						handlers.GetTripMapApiHandlerParams{
 							TripStore: tripStore}).ServeHTTP))))
 
+	appMux.Handle("GET /api/airports",
+		authMiddleware.AddAPIUserToContext("",
+			m.LoggingMiddleware(
+				handlers.NewGetAirportsHandler(
+					handlers.GetAirportsHandlerParams{
+						AirportStore: airportStore,
+					}).ServeHTTP)))
+
 	appMux.Handle("GET /api/statistics",
-		authMiddleware.AddUserToContext(
+		authMiddleware.AddAPIUserToContext(models.ScopeReadStats,
 			m.CSPMiddleware(
 				m.LoggingMiddleware(
 					handlers.NewGetStatisticsHandlerParams(
@@ -373,6 +1266,18 @@ func main() {
 						PasswordResetStore: passwordResetStore,
 					}).ServeHTTP)))
 
+	// OpenAPI document of the routes above and its reference page, public
+	// since they hold no user data
+	appMux.Handle("GET /api/openapi.json",
+		m.LoggingMiddleware(
+			handlers.NewGetOpenAPIHandler().ServeHTTP))
+
+	appMux.Handle("GET /docs/api",
+		m.CSPMiddleware(
+			m.TextHTMLMiddleware(
+				m.LoggingMiddleware(
+					handlers.NewGetAPIDocsHandler().ServeHTTP))))
+
 	// Google Auth
 	appMux.HandleFunc("/auth/google/login",
 		api.NewGoogleLoginHandlerParams(
@@ -388,6 +1293,81 @@ func main() {
 				GoogleOauthConfig: googleOauthConfig,
 			}).ServeHTTP)
 
+	return appMux
+}
+
+func main() {
+
+	migrateMode := flag.String("migrate", "", "run a migration command and exit: status, dry-run or up")
+	importAirports := flag.String("import-airports", "", "upsert airports from an OurAirports style CSV file and exit")
+	flag.Parse()
+
+	if *migrateMode != "" {
+		if err := runMigrateCommand(*migrateMode); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *importAirports != "" {
+		if err := runImportAirportsCommand(*importAirports); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	dotenvPath := os.Getenv("DOTENV_PATH")
+	if dotenvPath == "" {
+		dotenvPath = ".env" // default if not set
+	}
+
+	if err := godotenv.Load(dotenvPath); err != nil {
+		log.Fatalf("Error loading .env file %s", err)
+	}
+
+	basePath := os.Getenv("BASE_PATH") // "" for local dev, "/fromnto" for production
+
+	db, err := database.InitDB(dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Load the countries from a JSON file into memory
+	if err := models.LoadCountriesFromFile("./static/data/countries.json"); err != nil {
+		log.Fatalf("Failed to load countries: %v", err)
+	}
+	if err := models.LoadAirportTimezonesFromFile("./static/data/airport2timezone.json"); err != nil {
+		log.Fatalf("Failed to load airport timezones: %v", err)
+	}
+
+	tripStore := database.NewTripStore(database.NewTripStoreParams{DB: db})
+	placeStore := database.NewPlaceStore(db)
+
+	retentionDays := trashRetentionDays()
+	startTrashPurgeJob(tripStore, placeStore, retentionDays)
+	if err := webhook.AllowNetworks(os.Getenv("WEBHOOK_ALLOWED_NETWORKS")); err != nil {
+		log.Fatalf("Invalid WEBHOOK_ALLOWED_NETWORKS: %v", err)
+	}
+	startMailServer(db)
+	startWebhookDispatcher(db)
+
+	// Google OAuth Initilization to Add the Environemnt Variables
+	googleOauthConfig := api.NewGoogleOauthConfig()
+
+	// Email Service for Resetting Passwords
+	// Placeholders for now
+	gmailUser := os.Getenv("GMAIL_SERVICE_APP_USERNAME")
+	gmailPsw := os.Getenv("GMAIL_SERVICE_APP_PASSWORD")
+	emailService := models.EmailService{
+		SMTPHost: "smtp.gmail.com",
+		SMTPPort: 587,
+		Username: gmailUser,
+		Password: gmailPsw, // use App Password, not real password
+		From:     gmailUser,
+	}
+
+	appMux := newAppMux(db, emailService, googleOauthConfig, retentionDays)
+
 	// Mount appMux under basePath (or root if basePath is empty)
 	mux := http.NewServeMux()
 	if basePath != "" {
@@ -45,18 +45,18 @@ document.addEventListener("DOMContentLoaded", function () {
     .then((response) => response.json())
     .then((data) => {
       // Check if data has the expected structure - if not, assume old API format
-      if (!data.hasOwnProperty('standalone_trips') || !data.hasOwnProperty('connecting_trips')) {
+      if (!data.hasOwnProperty('standalone_trips') || !data.hasOwnProperty('itineraries')) {
         // Convert old format to new format
         const oldData = data;
         data = {
-          standalone_trips: Array.isArray(oldData) ? oldData : [],
-          connecting_trips: []
+          standalone_trips: Array.isArray(oldData) ? oldData : (oldData.standalone_trips || []),
+          itineraries: []
         };
       }
 
-      // Ensure connecting_trips is never null
-      if (data.connecting_trips === null) {
-        data.connecting_trips = [];
+      // Ensure itineraries is never null
+      if (data.itineraries === null) {
+        data.itineraries = [];
       }
 
       const airportMarkers = new Map(); // Track unique airports
@@ -94,53 +94,40 @@ document.addEventListener("DOMContentLoaded", function () {
       });
       }
 
-      // Process connecting trips
-      if (data.connecting_trips && Array.isArray(data.connecting_trips)) {
-        data.connecting_trips.forEach((conn) => {
-        let departure = [conn.FromTrip.departure_lat, conn.FromTrip.departure_lon];
-        let layover = [conn.FromTrip.arrival_lat, conn.FromTrip.arrival_lon];
-        let arrival = [conn.ToTrip.arrival_lat, conn.ToTrip.arrival_lon];
-
-        // First leg - solid line
-        L.polyline([departure, layover], {
-          color: "#37f5c0",
-          weight: 4,
-          opacity: 0.9
-        })
-          .addTo(map)
-          .bindPopup(`<div class="text-white font-semibold">Leg 1: ${conn.FromTrip.airline} ${conn.FromTrip.flight_number}<br><span class="text-mint-400">${conn.FromTrip.departure} → ${conn.FromTrip.arrival}</span></div>`);
-
-        // Second leg - solid line
-        L.polyline([layover, arrival], {
-          color: "#37f5c0",
-          weight: 4,
-          opacity: 0.9
-        })
-          .addTo(map)
-          .bindPopup(`<div class="text-white font-semibold">Leg 2: ${conn.ToTrip.airline} ${conn.ToTrip.flight_number}<br><span class="text-mint-400">${conn.ToTrip.departure} → ${conn.ToTrip.arrival}</span></div>`);
-
-        // Track airports
-        if (!airportMarkers.has(conn.FromTrip.departure)) {
-          airportMarkers.set(conn.FromTrip.departure, {
-            position: departure,
-            code: conn.FromTrip.departure,
-            type: 'departure'
-          });
-        }
-        if (!airportMarkers.has(conn.FromTrip.arrival)) {
-          airportMarkers.set(conn.FromTrip.arrival, {
-            position: layover,
-            code: conn.FromTrip.arrival,
-            type: 'layover'
-          });
-        }
-        if (!airportMarkers.has(conn.ToTrip.arrival)) {
-          airportMarkers.set(conn.ToTrip.arrival, {
-            position: arrival,
-            code: conn.ToTrip.arrival,
-            type: 'arrival'
-          });
-        }
+      // Process itineraries, every leg is drawn and the stops in between are layovers
+      if (data.itineraries && Array.isArray(data.itineraries)) {
+        data.itineraries.forEach((itinerary) => {
+        const legs = itinerary.legs || [];
+        legs.forEach((leg, index) => {
+          let departure = [leg.departure_lat, leg.departure_lon];
+          let arrival = [leg.arrival_lat, leg.arrival_lon];
+
+          L.polyline([departure, arrival], {
+            color: "#37f5c0",
+            weight: 4,
+            opacity: 0.9
+          })
+            .addTo(map)
+            .bindPopup(`<div class="text-white font-semibold">Leg ${index + 1}: ${leg.airline} ${leg.flight_number}<br><span class="text-mint-400">${leg.departure} → ${leg.arrival}</span></div>`);
+
+          // Track airports, a layover marker wins over a plain departure/arrival marker
+          const departureType = index === 0 ? 'departure' : 'layover';
+          const arrivalType = index === legs.length - 1 ? 'arrival' : 'layover';
+          if (!airportMarkers.has(leg.departure) || departureType === 'layover') {
+            airportMarkers.set(leg.departure, {
+              position: departure,
+              code: leg.departure,
+              type: departureType
+            });
+          }
+          if (!airportMarkers.has(leg.arrival) || arrivalType === 'layover') {
+            airportMarkers.set(leg.arrival, {
+              position: arrival,
+              code: leg.arrival,
+              type: arrivalType
+            });
+          }
+        });
         });
       }
 
@@ -9,6 +9,7 @@ class PWAFeatures {
   initializeFeatures() {
     this.setupGeolocation();
     this.setupCamera();
+    this.setupBoardingPassScanner();
     this.setupPullToRefresh();
     this.setupOfflineSync();
     this.setupShareAPI();
@@ -39,16 +40,16 @@ class PWAFeatures {
 
       const { latitude, longitude } = position.coords;
 
-      // Reverse geocoding to get airport/city
+      // Find the nearest airport to the current position
       const response = await fetch(
-        _basePath + `/api/location?lat=${latitude}&lng=${longitude}`
+        _basePath + `/api/airports?lat=${latitude}&lon=${longitude}&limit=1`
       );
-      const location = await response.json();
+      const airports = await response.json();
 
       // Fill in form fields
-      const originField = document.querySelector("#origin");
-      if (originField && location.airport_code) {
-        originField.value = location.airport_code;
+      const originField = document.querySelector("#origin") || document.querySelector('input[name="departure"]');
+      if (originField && airports.length > 0) {
+        originField.value = airports[0].iata_code;
         htmx.trigger(originField, "change");
       }
     } catch (error) {
@@ -60,7 +61,7 @@ class PWAFeatures {
   // Camera access for trip photos
   setupCamera() {
     const cameraInputs = document.querySelectorAll(
-      'input[type="file"][accept*="image"]'
+      'input[type="file"][accept*="image"]:not([data-boarding-pass-image])'
     );
     cameraInputs.forEach((input) => {
       // Add camera capture attribute for mobile
@@ -92,6 +93,48 @@ class PWAFeatures {
     }
   }
 
+  // Boarding pass barcodes, read with the BarcodeDetector API where the
+  // browser has it. The scan button is added by htmx with the trip drafts, so
+  // the handlers are delegated.
+  setupBoardingPassScanner() {
+    if (!("BarcodeDetector" in window)) return;
+
+    const showScanButtons = () => {
+      document.querySelectorAll("[data-scan-boarding-pass]").forEach((label) => {
+        label.classList.remove("hidden");
+      });
+    };
+    showScanButtons();
+    document.addEventListener("htmx:afterSwap", showScanButtons);
+
+    document.addEventListener("change", async (event) => {
+      const input = event.target;
+      if (!input.matches || !input.matches("[data-boarding-pass-image]")) return;
+      const file = input.files[0];
+      if (!file) return;
+
+      try {
+        const detector = new BarcodeDetector({
+          formats: ["pdf417", "aztec", "qr_code", "data_matrix"],
+        });
+        const codes = await detector.detect(await createImageBitmap(file));
+        const pass = codes.find((code) => /^M[1-9]/.test(code.rawValue));
+        if (!pass) {
+          this.showToast("No boarding pass barcode found in the photo.", "error");
+          return;
+        }
+        const form = input.closest("form");
+        form.querySelector('textarea[name="bcbp"]').value = pass.rawValue;
+        form.requestSubmit();
+      } catch (error) {
+        console.error("Error reading barcode:", error);
+        this.showToast("The barcode could not be read, paste its text instead.", "error");
+      } finally {
+        input.value = "";
+      }
+    });
+  }
+
   async compressImage(file, quality = 0.8) {
     const canvas = document.createElement("canvas");
     const ctx = canvas.getContext("2d");
@@ -92,13 +92,13 @@ async function loadTrips() {
   let data = await response.json();
 
   // Check if data has the expected structure - if not, assume old API format
-  if (!data.hasOwnProperty('standalone_trips') || !data.hasOwnProperty('connecting_trips')) {
+  if (!data.hasOwnProperty('standalone_trips') || !data.hasOwnProperty('itineraries')) {
     console.log("Old API format detected, converting to new format");
     // Convert old format to new format
     const oldData = data;
     data = {
-      standalone_trips: Array.isArray(oldData) ? oldData : [],
-      connecting_trips: []
+      standalone_trips: Array.isArray(oldData) ? oldData : (oldData.standalone_trips || []),
+      itineraries: []
     };
   }
 
@@ -124,39 +124,24 @@ async function loadTrips() {
     });
   }
 
-  // Process connecting trips
-  if (data.connecting_trips && Array.isArray(data.connecting_trips)) {
-    data.connecting_trips.forEach((conn) => {
+  // Process itineraries, each one is drawn as a chain of legs
+  if (data.itineraries && Array.isArray(data.itineraries)) {
+    data.itineraries.forEach((itinerary) => {
     trips.push({
       type: 'connecting',
-      legs: [
-        {
-          from: {
-            lat: conn.FromTrip.departure_lat,
-            lon: conn.FromTrip.departure_lon,
-            name: conn.FromTrip.departure,
-          },
-          to: {
-            lat: conn.FromTrip.arrival_lat,
-            lon: conn.FromTrip.arrival_lon,
-            name: conn.FromTrip.arrival,
-          },
-          flight: `${conn.FromTrip.airline} ${conn.FromTrip.flight_number}`
+      legs: (itinerary.legs || []).map((leg) => ({
+        from: {
+          lat: leg.departure_lat,
+          lon: leg.departure_lon,
+          name: leg.departure,
         },
-        {
-          from: {
-            lat: conn.ToTrip.departure_lat,
-            lon: conn.ToTrip.departure_lon,
-            name: conn.ToTrip.departure,
-          },
-          to: {
-            lat: conn.ToTrip.arrival_lat,
-            lon: conn.ToTrip.arrival_lon,
-            name: conn.ToTrip.arrival,
-          },
-          flight: `${conn.ToTrip.airline} ${conn.ToTrip.flight_number}`
-        }
-      ]
+        to: {
+          lat: leg.arrival_lat,
+          lon: leg.arrival_lon,
+          name: leg.arrival,
+        },
+        flight: `${leg.airline} ${leg.flight_number}`
+      }))
     });
     });
   }